@@ -5,6 +5,7 @@ go 1.23.4
 require (
 	github.com/kr/pretty v0.3.0
 	github.com/tuneinsight/lattigo/v6 v6.1.0
+	golang.org/x/crypto v0.18.0
 )
 
 require (
@@ -15,7 +16,6 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/rogpeppe/go-internal v1.9.0 // indirect
 	github.com/stretchr/testify v1.8.0 // indirect
-	golang.org/x/crypto v0.18.0 // indirect
 	golang.org/x/exp v0.0.0-20250106191152-7588d65b2ba8 // indirect
 	golang.org/x/sys v0.16.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -2,6 +2,8 @@ package owchcca
 
 import (
 	"crypto/rand"
+	"fmt"
+	"io"
 
 	"github.com/MingLLuo/OW-ChCCA-KEM/pkg"
 )
@@ -13,6 +15,101 @@ type (
 	Parameters = pkg.Parameters
 )
 
+// Encapsulator encapsulates shared keys to the public key it was created for. Clients that only
+// ever encapsulate need nothing else.
+type Encapsulator interface {
+	Encapsulate() (ciphertext, sharedKey []byte, err error)
+	PublicKey() *PublicKey
+}
+
+// Decapsulator recovers shared keys with the private key it was created for
+type Decapsulator interface {
+	Decapsulate(ciphertext []byte) (sharedKey []byte, err error)
+	PublicKeyDigest() []byte
+}
+
+var (
+	_ Encapsulator = (*pkg.Encapsulator)(nil)
+	_ Encapsulator = (*randEncapsulator)(nil)
+	_ Decapsulator = (*pkg.Decapsulator)(nil)
+)
+
+// Option configures NewEncapsulator, NewDecapsulator, Encapsulate, Decapsulate and GenerateKeyPair
+type Option func(*options)
+
+type options struct {
+	rand            io.Reader
+	allowToy        bool
+	keyConfirmation bool
+}
+
+// WithRandomSource makes an Encapsulator draw its seeds, and GenerateKeyPair its randomness,
+// from r instead of crypto/rand
+func WithRandomSource(r io.Reader) Option {
+	return func(o *options) {
+		o.rand = r
+	}
+}
+
+// WithAllowToyParameters lets GenerateKeyPair generate keys with toy parameter sets, which it
+// refuses by default (see pkg.SecurityClassToy)
+func WithAllowToyParameters(allow bool) Option {
+	return func(o *options) {
+		o.allowToy = allow
+	}
+}
+
+// WithKeyConfirmation makes encapsulation append, and decapsulation expect, a key confirmation
+// tag (see pkg.OwChCCAKEM.WithKeyConfirmation). Both sides must use the same setting.
+func WithKeyConfirmation(enable bool) Option {
+	return func(o *options) {
+		o.keyConfirmation = enable
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// randEncapsulator is an Encapsulator that draws its seeds from a configured source
+type randEncapsulator struct {
+	*pkg.Encapsulator
+	rand io.Reader
+}
+
+func (e randEncapsulator) Encapsulate() (ciphertext, sharedKey []byte, err error) {
+	return e.EncapsulateFrom(e.rand)
+}
+
+// NewEncapsulator validates pk and precomputes what every encapsulation to it needs
+func NewEncapsulator(pk *PublicKey, opts ...Option) (Encapsulator, error) {
+	if pk == nil {
+		return nil, pkg.ErrInvalidPublicKey
+	}
+	o := newOptions(opts)
+	enc, err := pk.NewEncapsulator(pkg.WithKeyConfirmation(o.keyConfirmation))
+	if err != nil {
+		return nil, err
+	}
+	if o.rand != nil {
+		return randEncapsulator{Encapsulator: enc, rand: o.rand}, nil
+	}
+	return enc, nil
+}
+
+// NewDecapsulator validates sk and precomputes what every decapsulation with it needs.
+// Of the options only WithKeyConfirmation applies to decapsulation.
+func NewDecapsulator(sk *PrivateKey, opts ...Option) (Decapsulator, error) {
+	if sk == nil {
+		return nil, pkg.ErrInvalidPrivateKey
+	}
+	return sk.NewDecapsulator(pkg.WithKeyConfirmation(newOptions(opts).keyConfirmation))
+}
+
 // NewKEM creates a new KEM instance with the specified parameters
 func NewKEM(params Parameters) KEM {
 	return KEM{
@@ -20,54 +117,116 @@ func NewKEM(params Parameters) KEM {
 	}
 }
 
-// Encapsulate generates a shared key and encapsulates it for the given public key
-func Encapsulate(pk *PublicKey) (ciphertext, sharedKey []byte, err error) {
-	if pk == nil {
-		return nil, nil, pkg.ErrInvalidPublicKey
+// NewKEMByName creates a KEM for the registered parameter set with the given name
+func NewKEMByName(name string) (*KEM, error) {
+	params, err := pkg.GetParameterSet(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", pkg.ErrParameterValidation, err)
 	}
-	kem := NewKEM(pk.Parameters())
-	return kem.Encapsulate(pk)
+	kem := NewKEM(params)
+	return &kem, nil
 }
 
-// Decapsulate recovers a shared key from a ciphertext using the given private key
-func Decapsulate(sk *PrivateKey, ciphertext []byte) (sharedKey []byte, err error) {
-	if sk == nil || sk.Public() == nil {
-		return nil, pkg.ErrInvalidPrivateKey
+// NewKEMForLevel creates a KEM for the default parameter set of the given security level,
+// calculating and registering it if needed
+func NewKEMForLevel(level pkg.SecurityLevel) (*KEM, error) {
+	params, err := pkg.LookupParameters(level)
+	if err != nil {
+		return nil, fmt.Errorf("%w: security level %d: %v", pkg.ErrParameterValidation, level, err)
 	}
-	public := sk.Public()
-	kem := NewKEM(public.Parameters())
-	return kem.Decapsulate(sk, ciphertext)
+	kem := NewKEM(params)
+	return &kem, nil
 }
 
-// GenerateKeyPair generates a new key pair with the specified parameters
-func GenerateKeyPair(params Parameters) (*PublicKey, *PrivateKey, error) {
-	kem := NewKEM(params)
-	return kem.GenerateKeyPair(rand.Reader)
+// Encapsulate generates a shared key and encapsulates it for the given public key.
+// Use NewEncapsulator to encapsulate to the same key repeatedly.
+func Encapsulate(pk *PublicKey, opts ...Option) (ciphertext, sharedKey []byte, err error) {
+	enc, err := NewEncapsulator(pk, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return enc.Encapsulate()
+}
+
+// Decapsulate recovers a shared key from a ciphertext using the given private key.
+// Use NewDecapsulator to decapsulate with the same key repeatedly.
+func Decapsulate(sk *PrivateKey, ciphertext []byte, opts ...Option) (sharedKey []byte, err error) {
+	dec, err := NewDecapsulator(sk, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return dec.Decapsulate(ciphertext)
+}
+
+// GenerateKeyPair generates a new key pair with the specified parameters, drawing randomness
+// from crypto/rand unless WithRandomSource is given. Toy parameter sets are refused with
+// pkg.ErrToyParameters unless WithAllowToyParameters(true) is given.
+func GenerateKeyPair(params Parameters, opts ...Option) (*PublicKey, *PrivateKey, error) {
+	o := newOptions(opts)
+	kem := NewKEM(params).WithAllowToyParameters(o.allowToy)
+	randSource := o.rand
+	if randSource == nil {
+		randSource = rand.Reader
+	}
+	return kem.GenerateKeyPair(randSource)
 }
 
-// ParsePublicKey parses a serialized public key
-func ParsePublicKey(data []byte, params *Parameters) (*PublicKey, error) {
+// ParseOption configures ParsePublicKey and ParsePrivateKey
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	lenient bool
+}
+
+// WithLenientParsing restores the legacy decoding that ignores trailing data, trusts the matrix
+// dimension headers and reduces out-of-range elements; use it only to read old stored keys
+func WithLenientParsing() ParseOption {
+	return func(o *parseOptions) {
+		o.lenient = true
+	}
+}
+
+func newParseOptions(opts []ParseOption) parseOptions {
+	var o parseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ParsePublicKey parses a serialized public key.
+// By default the input must be exactly the encoded size with canonical contents.
+func ParsePublicKey(data []byte, params *Parameters, opts ...ParseOption) (*PublicKey, error) {
 	if params == nil {
 		return nil, pkg.ErrParameterValidation
 	}
 	pk := PublicKey{
 		Params: *params,
 	}
-	if err := pk.UnmarshalBinary(data); err != nil {
+	unmarshal := pk.UnmarshalBinaryStrict
+	if newParseOptions(opts).lenient {
+		unmarshal = pk.UnmarshalBinary
+	}
+	if err := unmarshal(data); err != nil {
 		return nil, err
 	}
 	return &pk, nil
 }
 
-// ParsePrivateKey parses a serialized private key
-func ParsePrivateKey(data []byte, pk *PublicKey) (*PrivateKey, error) {
+// ParsePrivateKey parses a serialized private key.
+// By default the input must be exactly the encoded size with canonical contents.
+func ParsePrivateKey(data []byte, pk *PublicKey, opts ...ParseOption) (*PrivateKey, error) {
 	if pk == nil {
 		return nil, pkg.ErrInvalidPublicKey
 	}
 	sk := PrivateKey{
 		Pk: pk,
 	}
-	if err := sk.UnmarshalBinary(data); err != nil {
+	unmarshal := sk.UnmarshalBinaryStrict
+	if newParseOptions(opts).lenient {
+		unmarshal = sk.UnmarshalBinary
+	}
+	if err := unmarshal(data); err != nil {
 		return nil, err
 	}
 	return &sk, nil
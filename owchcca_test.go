@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"crypto/rand"
 	"errors"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/MingLLuo/OW-ChCCA-KEM/pkg"
@@ -20,7 +22,7 @@ func TestKEMConsistency(t *testing.T) {
 		}
 		t.Run(params.Name, func(t *testing.T) {
 			// Generate a key pair
-			pk, sk, err := GenerateKeyPair(params)
+			pk, sk, err := GenerateKeyPair(params, WithAllowToyParameters(true))
 			if err != nil {
 				t.Fatalf("GenerateKeyPair failed: %v", err)
 			}
@@ -62,7 +64,7 @@ func TestKEMSerialization(t *testing.T) {
 	params := pkg.GetDefaultParameterSet()
 
 	// Generate a key pair
-	pk1, sk1, err := GenerateKeyPair(params)
+	pk1, sk1, err := GenerateKeyPair(params, WithAllowToyParameters(true))
 	if err != nil {
 		t.Fatalf("GenerateKeyPair failed: %v", err)
 	}
@@ -131,7 +133,7 @@ func TestInvalidInputs(t *testing.T) {
 	params := pkg.GetDefaultParameterSet()
 
 	// Generate a key pair
-	pk, sk, err := GenerateKeyPair(params)
+	pk, sk, err := GenerateKeyPair(params, WithAllowToyParameters(true))
 	if err != nil {
 		t.Fatalf("GenerateKeyPair failed: %v", err)
 	}
@@ -168,7 +170,7 @@ func TestInvalidInputs(t *testing.T) {
 
 func TestNilAndTruncatedInputs(t *testing.T) {
 	params := pkg.GetDefaultParameterSet()
-	pk, sk, err := GenerateKeyPair(params)
+	pk, sk, err := GenerateKeyPair(params, WithAllowToyParameters(true))
 	if err != nil {
 		t.Fatalf("GenerateKeyPair failed: %v", err)
 	}
@@ -200,6 +202,80 @@ func TestNilAndTruncatedInputs(t *testing.T) {
 	}
 }
 
+func TestStrictParsing(t *testing.T) {
+	params := pkg.GetDefaultParameterSet()
+	pk, sk, err := GenerateKeyPair(params, WithAllowToyParameters(true))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	pkBytes := mustBytes(t, pk)
+	skBytes := mustBytes(t, sk)
+
+	// Trailing data is only accepted by the lenient decoder
+	padded := append(append([]byte(nil), pkBytes...), 0)
+	if _, err := ParsePublicKey(padded, &params); !errors.Is(err, pkg.ErrDeserializationError) {
+		t.Fatalf("strict ParsePublicKey should reject trailing data, got %v", err)
+	}
+	lenientPK, err := ParsePublicKey(padded, &params, WithLenientParsing())
+	if err != nil {
+		t.Fatalf("lenient ParsePublicKey failed: %v", err)
+	}
+	if !lenientPK.Equal(pk) {
+		t.Fatalf("lenient ParsePublicKey returned a different key")
+	}
+
+	// Corrupt the dimension header of U0
+	layoutA := 8 + params.LatticeParams.N*params.LatticeParams.M*((params.LatticeParams.Q.BitLen()+7)/8)
+	badHeader := append([]byte(nil), pkBytes...)
+	badHeader[layoutA+3]++
+	_, err = ParsePublicKey(badHeader, &params)
+	if !errors.Is(err, pkg.ErrDeserializationError) || !strings.Contains(err.Error(), "matrix U0") {
+		t.Fatalf("strict ParsePublicKey should name U0 for a bad header, got %v", err)
+	}
+
+	// Replace the first element of A with a value >= q
+	unreduced := append([]byte(nil), pkBytes...)
+	for i := 8; i < 8+(params.LatticeParams.Q.BitLen()+7)/8; i++ {
+		unreduced[i] = 0xFF
+	}
+	_, err = ParsePublicKey(unreduced, &params)
+	if !errors.Is(err, pkg.ErrDeserializationError) || !strings.Contains(err.Error(), "offset 8") {
+		t.Fatalf("strict ParsePublicKey should reject unreduced elements at offset 8, got %v", err)
+	}
+	if _, err := ParsePublicKey(unreduced, &params, WithLenientParsing()); err != nil {
+		t.Fatalf("lenient ParsePublicKey should reduce elements, got %v", err)
+	}
+
+	// Private keys: trailing data and an invalid b flag
+	if _, err := ParsePrivateKey(append(append([]byte(nil), skBytes...), 0), pk); !errors.Is(err, pkg.ErrDeserializationError) {
+		t.Fatalf("strict ParsePrivateKey should reject trailing data, got %v", err)
+	}
+	badFlag := append([]byte(nil), skBytes...)
+	badFlag[len(badFlag)-1] = 2
+	if _, err := ParsePrivateKey(badFlag, pk); !errors.Is(err, pkg.ErrDeserializationError) {
+		t.Fatalf("strict ParsePrivateKey should reject b flag 2, got %v", err)
+	}
+	if _, err := ParsePrivateKey(badFlag, pk, WithLenientParsing()); err != nil {
+		t.Fatalf("lenient ParsePrivateKey failed: %v", err)
+	}
+
+	// The embedded public key is checked against the supplied one, which is never overwritten
+	if parsed, err := ParsePrivateKey(skBytes, pk); err != nil || parsed.Pk != pk {
+		t.Fatalf("strict ParsePrivateKey did not keep the supplied public key: %v", err)
+	}
+	other, _, err := GenerateKeyPair(params, WithAllowToyParameters(true))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	otherBytes := mustBytes(t, other)
+	if _, err := ParsePrivateKey(skBytes, other); !errors.Is(err, pkg.ErrDeserializationError) {
+		t.Fatalf("strict ParsePrivateKey should reject a mismatched public key, got %v", err)
+	}
+	if !bytes.Equal(mustBytes(t, other), otherBytes) {
+		t.Fatalf("strict ParsePrivateKey modified the supplied public key")
+	}
+}
+
 func BenchmarkKEM(b *testing.B) {
 	testParams := pkg.ListParameterSets()
 	for _, paramName := range testParams {
@@ -209,14 +285,14 @@ func BenchmarkKEM(b *testing.B) {
 		}
 		b.Logf("Benchmarking parameter set %s", params.Name)
 		// Generate a key pair outside the benchmark loop
-		pk, sk, err := GenerateKeyPair(params)
+		pk, sk, err := GenerateKeyPair(params, WithAllowToyParameters(true))
 		if err != nil {
 			b.Fatalf("GenerateKeyPair failed: %v", err)
 		}
 
 		b.Run("KeyGen", func(b *testing.B) {
 			for i := 0; i < b.N; i++ {
-				_, _, err := GenerateKeyPair(params)
+				_, _, err := GenerateKeyPair(params, WithAllowToyParameters(true))
 				if err != nil {
 					b.Fatalf("GenerateKeyPair failed: %v", err)
 				}
@@ -257,3 +333,143 @@ func mustBytes(t *testing.T, v interface{ Bytes() ([]byte, error) }) []byte {
 	}
 	return b
 }
+
+func TestNewKEMByName(t *testing.T) {
+	kem, err := NewKEMByName("OWChCCA-16")
+	if err != nil {
+		t.Fatalf("NewKEMByName failed: %v", err)
+	}
+	if kem.Params.Name != "OWChCCA-16" {
+		t.Fatalf("NewKEMByName returned parameters %s", kem.Params.Name)
+	}
+	if err := kem.HealthCheck(); err != nil {
+		t.Fatalf("KEM from NewKEMByName is not functional: %v", err)
+	}
+
+	_, err = NewKEMByName("OWChCCA-unknown")
+	if !errors.Is(err, pkg.ErrParameterValidation) || !strings.Contains(err.Error(), "OWChCCA-unknown") {
+		t.Fatalf("unregistered name: got %v", err)
+	}
+
+	byLevel, err := NewKEMForLevel(pkg.Security16)
+	if err != nil {
+		t.Fatalf("NewKEMForLevel failed: %v", err)
+	}
+	if byLevel.Params.Name != kem.Params.Name {
+		t.Fatalf("NewKEMForLevel(Security16) returned %s", byLevel.Params.Name)
+	}
+}
+
+func TestGenerateKeyPairToyParameters(t *testing.T) {
+	params := pkg.GetDefaultParameterSet()
+	if _, _, err := GenerateKeyPair(params); !errors.Is(err, pkg.ErrToyParameters) {
+		t.Fatalf("GenerateKeyPair(%s) without WithAllowToyParameters: got %v", params.Name, err)
+	}
+	if _, _, err := GenerateKeyPair(params, WithAllowToyParameters(true), WithRandomSource(rand.Reader)); err != nil {
+		t.Fatalf("GenerateKeyPair with WithAllowToyParameters failed: %v", err)
+	}
+}
+
+func TestEncapsulatorDecapsulator(t *testing.T) {
+	params := pkg.GetDefaultParameterSet()
+	pk, sk, err := GenerateKeyPair(params, WithAllowToyParameters(true))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	dec, err := NewDecapsulator(sk)
+	if err != nil {
+		t.Fatalf("NewDecapsulator failed: %v", err)
+	}
+	enc, err := NewEncapsulator(pk)
+	if err != nil {
+		t.Fatalf("NewEncapsulator failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		ct, ss, err := enc.Encapsulate()
+		if err != nil {
+			t.Fatalf("Encapsulate failed: %v", err)
+		}
+		got, err := dec.Decapsulate(ct)
+		if err != nil || !bytes.Equal(got, ss) {
+			t.Fatalf("Decapsulate does not recover the shared key: %v", err)
+		}
+	}
+
+	// A fixed random source makes encapsulation deterministic
+	seed := bytes.Repeat([]byte{0x42}, 64)
+	encA, _ := NewEncapsulator(pk, WithRandomSource(bytes.NewReader(seed)))
+	encB, _ := NewEncapsulator(pk, WithRandomSource(bytes.NewReader(seed)))
+	ctA, _, errA := encA.Encapsulate()
+	ctB, _, errB := encB.Encapsulate()
+	if errA != nil || errB != nil || !bytes.Equal(ctA, ctB) {
+		t.Fatalf("encapsulations from the same source differ: %v, %v", errA, errB)
+	}
+	if encA.PublicKey() != pk {
+		t.Fatalf("PublicKey returned a different key")
+	}
+
+	// Key confirmation must be requested on both sides
+	ct, ss, err := Encapsulate(pk, WithKeyConfirmation(true))
+	if err != nil {
+		t.Fatalf("Encapsulate with key confirmation failed: %v", err)
+	}
+	if got, err := Decapsulate(sk, ct, WithKeyConfirmation(true)); err != nil || !bytes.Equal(got, ss) {
+		t.Fatalf("Decapsulate with key confirmation failed: %v", err)
+	}
+	if _, err := Decapsulate(sk, ct); err == nil {
+		t.Fatalf("Decapsulate without key confirmation accepted a tagged ciphertext")
+	}
+	confirmedDec, err := NewDecapsulator(sk, WithKeyConfirmation(true))
+	if err != nil {
+		t.Fatalf("NewDecapsulator with key confirmation failed: %v", err)
+	}
+	if got, err := confirmedDec.Decapsulate(ct); err != nil || !bytes.Equal(got, ss) {
+		t.Fatalf("Decapsulator with key confirmation failed: %v", err)
+	}
+
+	if _, err := NewEncapsulator(nil); !errors.Is(err, pkg.ErrInvalidPublicKey) {
+		t.Fatalf("NewEncapsulator(nil): got %v", err)
+	}
+	if _, err := NewDecapsulator(nil); !errors.Is(err, pkg.ErrInvalidPrivateKey) {
+		t.Fatalf("NewDecapsulator(nil): got %v", err)
+	}
+}
+
+func TestConcurrentParsePublicKey(t *testing.T) {
+	// Each parse decodes a full copy of A, so fewer goroutines keep -race within memory
+	const goroutines = 16
+	params, err := pkg.GetParameterSet("OWChCCA-16")
+	if err != nil {
+		t.Fatalf("GetParameterSet failed: %v", err)
+	}
+	pk, _, err := GenerateKeyPair(params, WithAllowToyParameters(true))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	data, err := pk.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+
+	errs := make([]error, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Compare in place so only the in-flight copies are held at once
+			parsed, err := ParsePublicKey(data, &params)
+			if err == nil && !parsed.Equal(pk) {
+				err = errors.New("parsed key differs")
+			}
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent ParsePublicKey %d failed: %v", i, err)
+		}
+	}
+}
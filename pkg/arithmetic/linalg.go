@@ -0,0 +1,176 @@
+package arithmetic
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// ErrNotInvertible indicates a singular matrix or an element without a modular inverse
+	ErrNotInvertible = errors.New("not invertible")
+
+	// ErrNoSolution indicates an inconsistent linear system
+	ErrNoSolution = errors.New("linear system has no solution")
+
+	// ErrNotPrime indicates an operation that needs a prime modulus
+	ErrNotPrime = errors.New("modulus is not prime")
+)
+
+// checkPrimeModulus checks that modulus is (probably) prime, so every nonzero element is invertible
+func checkPrimeModulus(modulus *big.Int) error {
+	if modulus == nil || !modulus.ProbablyPrime(20) {
+		return fmt.Errorf("%w: %v", ErrNotPrime, modulus)
+	}
+	return nil
+}
+
+// gaussJordan brings the rows of w to reduced row echelon form modulo the prime q, pivoting only
+// on the first cols columns; any further columns are carried along as an augmented part.
+// The pivot for step c is the first nonzero entry, scanning rows from top to bottom, of the
+// leftmost column at or after c that has one below the pivots found so far. If that column is
+// not c it is swapped into place. It returns the rank and the column permutation: column c of
+// the reduced rows is column perm[c] of the input.
+func gaussJordan(w [][]*big.Int, cols int, q *big.Int) (rank int, perm []int) {
+	perm = make([]int, cols)
+	for c := range perm {
+		perm[c] = c
+	}
+
+	tmp := new(big.Int)
+	for c := 0; c < cols && rank < len(w); c++ {
+		pivotRow, pivotCol := -1, -1
+		for cc := c; cc < cols && pivotRow < 0; cc++ {
+			for r := rank; r < len(w); r++ {
+				if w[r][cc].Sign() != 0 {
+					pivotRow, pivotCol = r, cc
+					break
+				}
+			}
+		}
+		if pivotRow < 0 {
+			break
+		}
+
+		if pivotCol != c {
+			for _, row := range w {
+				row[c], row[pivotCol] = row[pivotCol], row[c]
+			}
+			perm[c], perm[pivotCol] = perm[pivotCol], perm[c]
+		}
+		w[rank], w[pivotRow] = w[pivotRow], w[rank]
+
+		// Scale the pivot row so the pivot is 1
+		pivot := w[rank]
+		inv := new(big.Int).ModInverse(pivot[c], q)
+		for j := range pivot {
+			pivot[j].Mul(pivot[j], inv).Mod(pivot[j], q)
+		}
+
+		// Clear column c in every other row
+		for r, row := range w {
+			if r == rank || row[c].Sign() == 0 {
+				continue
+			}
+			factor := new(big.Int).Set(row[c])
+			for j := range row {
+				row[j].Sub(row[j], tmp.Mul(factor, pivot[j])).Mod(row[j], q)
+			}
+		}
+		rank++
+	}
+	return rank, perm
+}
+
+// augmentedCopy returns a copy of the rows of m reduced modulo Q, each followed by extra zero columns
+func (m *Matrix) augmentedCopy(extra int) [][]*big.Int {
+	w := make([][]*big.Int, m.Rows)
+	for i := range w {
+		w[i] = make([]*big.Int, m.Cols+extra)
+		for j := range w[i] {
+			w[i][j] = new(big.Int)
+			if j < m.Cols {
+				w[i][j].Mod(m.Values[i][j], m.Modulus)
+			}
+		}
+	}
+	return w
+}
+
+// Rank returns the rank of m over Z_q by Gaussian elimination. Q must be prime.
+func (m *Matrix) Rank() (int, error) {
+	if err := checkPrimeModulus(m.Modulus); err != nil {
+		return 0, err
+	}
+	rank, _ := gaussJordan(m.augmentedCopy(0), m.Cols, m.Modulus)
+	return rank, nil
+}
+
+// Inverse returns the inverse of the square matrix m over Z_q by Gauss-Jordan elimination.
+// Q must be prime; a singular matrix gives ErrNotInvertible.
+func (m *Matrix) Inverse() (Matrix, error) {
+	if m.Rows != m.Cols {
+		return Matrix{}, ErrInvalidDimensions
+	}
+	if err := checkPrimeModulus(m.Modulus); err != nil {
+		return Matrix{}, err
+	}
+
+	n := m.Rows
+	w := m.augmentedCopy(n)
+	for i := 0; i < n; i++ {
+		w[i][n+i].SetInt64(1)
+	}
+	rank, perm := gaussJordan(w, n, m.Modulus)
+	if rank < n {
+		return Matrix{}, ErrNotInvertible
+	}
+
+	// The reduced rows hold (A·P)^-1 = P^-1·A^-1, so row i is row perm[i] of A^-1
+	result := NewMatrix(n, n, m.Modulus)
+	for i := 0; i < n; i++ {
+		copy(result.Values[perm[i]], w[i][n:])
+	}
+	return result, nil
+}
+
+// SolveLinear returns a solution x of m·x = b over Z_q by Gaussian elimination. Q must be prime.
+// If the system is underdetermined the free variables are set to zero; if it is inconsistent
+// the error is ErrNoSolution.
+func (m *Matrix) SolveLinear(b *Vector) (*Vector, error) {
+	if b == nil || b.Length() != m.Rows {
+		return nil, ErrInvalidDimensions
+	}
+	if err := checkPrimeModulus(m.Modulus); err != nil {
+		return nil, err
+	}
+
+	w := m.augmentedCopy(1)
+	for i, val := range b.Values {
+		w[i][m.Cols].Mod(val, m.Modulus)
+	}
+	rank, perm := gaussJordan(w, m.Cols, m.Modulus)
+	for i := rank; i < m.Rows; i++ {
+		if w[i][m.Cols].Sign() != 0 {
+			return nil, ErrNoSolution
+		}
+	}
+
+	x := NewVector(m.Cols, m.Modulus)
+	for i := 0; i < rank; i++ {
+		x.Values[perm[i]].Set(w[i][m.Cols])
+	}
+	return x, nil
+}
+
+// ModInverse returns the element-wise inverses of v modulo Q. Q need not be prime, but every
+// element must be coprime to it; otherwise the error is ErrNotInvertible and names the index.
+func (v *Vector) ModInverse() (*Vector, error) {
+	result := NewVector(v.Length(), v.Modulus)
+	for i, val := range v.Values {
+		if result.Values[i].ModInverse(val, v.Modulus) == nil {
+			return nil, fmt.Errorf("%w: element %d", ErrNotInvertible, i)
+		}
+	}
+	return result, nil
+}
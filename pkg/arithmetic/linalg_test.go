@@ -0,0 +1,178 @@
+package arithmetic
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func matrixFromInts(rows [][]int64, modulus *big.Int) Matrix {
+	m := NewMatrix(len(rows), len(rows[0]), modulus)
+	for i, row := range rows {
+		for j, x := range row {
+			m.Set(i, j, big.NewInt(x))
+		}
+	}
+	return m
+}
+
+func vectorFromInts(values []int64, modulus *big.Int) *Vector {
+	v := NewVector(len(values), modulus)
+	for i, x := range values {
+		v.Set(i, big.NewInt(x))
+	}
+	return v
+}
+
+func TestInverseSmall(t *testing.T) {
+	q := big.NewInt(7)
+	a := matrixFromInts([][]int64{{1, 2}, {3, 4}}, q)
+	inv, err := a.Inverse()
+	if err != nil {
+		t.Fatalf("Inverse failed: %v", err)
+	}
+	// det = -2 = 5 and 5^-1 = 3, so A^-1 = 3·[[4, -2], [-3, 1]] = [[5, 1], [5, 3]]
+	if want := matrixFromInts([][]int64{{5, 1}, {5, 3}}, q); !inv.Equal(want) {
+		t.Fatalf("Inverse = %v, want %v", inv.Values, want.Values)
+	}
+
+	// A zero leading entry needs a row swap
+	swap := matrixFromInts([][]int64{{0, 1}, {1, 0}}, q)
+	if inv, err := swap.Inverse(); err != nil || !inv.Equal(swap) {
+		t.Fatalf("Inverse of a permutation matrix: %v, %v", inv.Values, err)
+	}
+
+	singular := matrixFromInts([][]int64{{1, 2}, {2, 4}}, q)
+	if _, err := singular.Inverse(); !errors.Is(err, ErrNotInvertible) {
+		t.Fatalf("Inverse of a singular matrix: got %v", err)
+	}
+	wide := matrixFromInts([][]int64{{1, 2, 3}, {4, 5, 6}}, q)
+	if _, err := wide.Inverse(); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Inverse of a non-square matrix: got %v", err)
+	}
+	composite := matrixFromInts([][]int64{{1, 2}, {3, 4}}, big.NewInt(8))
+	if _, err := composite.Inverse(); !errors.Is(err, ErrNotPrime) {
+		t.Fatalf("Inverse modulo a composite: got %v", err)
+	}
+}
+
+func TestInverseRandom(t *testing.T) {
+	q := big.NewInt(65537)
+	for _, n := range []int{1, 2, 5, 12} {
+		var a, inv Matrix
+		for {
+			var err error
+			a, _ = GenerateRandomMatrix(n, n, q, crand.Reader)
+			if inv, err = a.Inverse(); err == nil {
+				break
+			} else if !errors.Is(err, ErrNotInvertible) {
+				t.Fatalf("Inverse failed: %v", err)
+			}
+		}
+		id := identityMatrix(n, q)
+		if prod, err := a.Multiply(inv); err != nil || !prod.Equal(id) {
+			t.Fatalf("%dx%d: A·A^-1 is not the identity: %v", n, n, err)
+		}
+		if prod, err := inv.Multiply(a); err != nil || !prod.Equal(id) {
+			t.Fatalf("%dx%d: A^-1·A is not the identity: %v", n, n, err)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	q := big.NewInt(7)
+	cases := []struct {
+		rows [][]int64
+		want int
+	}{
+		{[][]int64{{1, 2}, {3, 4}}, 2},
+		{[][]int64{{1, 2, 3}, {2, 4, 6}, {0, 0, 1}}, 2},
+		{[][]int64{{0, 1}, {0, 2}}, 1},
+		{[][]int64{{0, 0, 0}, {0, 0, 0}}, 0},
+		{[][]int64{{1, 0, 0, 2}, {0, 0, 1, 3}}, 2},
+		{[][]int64{{1}, {2}, {3}}, 1},
+		// 7 ≡ 0, so the second row vanishes modulo q
+		{[][]int64{{1, 1}, {7, 14}}, 1},
+	}
+	for _, c := range cases {
+		m := matrixFromInts(c.rows, q)
+		before := m.Clone()
+		if got, err := m.Rank(); err != nil || got != c.want {
+			t.Fatalf("Rank(%v) = %d, %v, want %d", c.rows, got, err, c.want)
+		}
+		if !m.Equal(before) {
+			t.Fatalf("Rank modified its receiver")
+		}
+	}
+	composite := matrixFromInts([][]int64{{1}}, big.NewInt(9))
+	if _, err := composite.Rank(); !errors.Is(err, ErrNotPrime) {
+		t.Fatalf("Rank modulo a composite: got %v", err)
+	}
+}
+
+func TestSolveLinear(t *testing.T) {
+	q := big.NewInt(7)
+	a := matrixFromInts([][]int64{{1, 2}, {3, 4}}, q)
+	x, err := a.SolveLinear(vectorFromInts([]int64{1, 0}, q))
+	if err != nil {
+		t.Fatalf("SolveLinear failed: %v", err)
+	}
+	if want := vectorFromInts([]int64{5, 5}, q); !x.Equal(want) {
+		t.Fatalf("SolveLinear = %v, want %v", x.Values, want.Values)
+	}
+
+	// The first column is zero, so the pivots come from swapped-in columns and x0 is free
+	under := matrixFromInts([][]int64{{0, 1, 0}, {0, 0, 1}}, q)
+	b := vectorFromInts([]int64{3, 4}, q)
+	x, err = under.SolveLinear(b)
+	if err != nil {
+		t.Fatalf("SolveLinear of an underdetermined system failed: %v", err)
+	}
+	if want := vectorFromInts([]int64{0, 3, 4}, q); !x.Equal(want) {
+		t.Fatalf("SolveLinear = %v, want %v", x.Values, want.Values)
+	}
+
+	inconsistent := matrixFromInts([][]int64{{1, 2}, {2, 4}}, q)
+	if _, err := inconsistent.SolveLinear(vectorFromInts([]int64{1, 0}, q)); !errors.Is(err, ErrNoSolution) {
+		t.Fatalf("SolveLinear of an inconsistent system: got %v", err)
+	}
+	if _, err := a.SolveLinear(vectorFromInts([]int64{1, 2, 3}, q)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("SolveLinear with the wrong length: got %v", err)
+	}
+
+	// Random overdetermined but consistent systems
+	p := big.NewInt(65537)
+	m, _ := GenerateRandomMatrix(8, 5, p, crand.Reader)
+	want, _ := GenerateRandomVector(5, p, crand.Reader)
+	rhs, _ := m.MultiplyVector(want)
+	got, err := m.SolveLinear(rhs)
+	if err != nil {
+		t.Fatalf("SolveLinear failed: %v", err)
+	}
+	if check, _ := m.MultiplyVector(got); !check.Equal(rhs) {
+		t.Fatalf("SolveLinear returned a vector that does not solve the system")
+	}
+}
+
+func TestVectorModInverse(t *testing.T) {
+	q := big.NewInt(7)
+	inv, err := vectorFromInts([]int64{1, 2, 3, 6}, q).ModInverse()
+	if err != nil {
+		t.Fatalf("ModInverse failed: %v", err)
+	}
+	if want := vectorFromInts([]int64{1, 4, 5, 6}, q); !inv.Equal(want) {
+		t.Fatalf("ModInverse = %v, want %v", inv.Values, want.Values)
+	}
+
+	if _, err := vectorFromInts([]int64{1, 0}, q).ModInverse(); !errors.Is(err, ErrNotInvertible) {
+		t.Fatalf("ModInverse of zero: got %v", err)
+	}
+	// A composite modulus is fine as long as every element is a unit
+	if inv, err := vectorFromInts([]int64{3, 5}, big.NewInt(8)).ModInverse(); err != nil || !inv.Equal(vectorFromInts([]int64{3, 5}, big.NewInt(8))) {
+		t.Fatalf("ModInverse modulo 8: %v, %v", inv, err)
+	}
+	if _, err := vectorFromInts([]int64{3, 2}, big.NewInt(8)).ModInverse(); !errors.Is(err, ErrNotInvertible) {
+		t.Fatalf("ModInverse of a non-unit: got %v", err)
+	}
+}
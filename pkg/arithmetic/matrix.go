@@ -1,16 +1,27 @@
 // Package arithmetic provides matrix and vector operations for OW-ChCCA-KEM
+//
+// Unless a method says otherwise, operations never alias their inputs: results are freshly
+// allocated, Get returns a copy and Set stores a reduced copy of its argument. The exported
+// Values and Modulus fields are the only way to share *big.Int pointers between structures;
+// use Clone to take an independent copy before mutating them directly.
 package arithmetic
 
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"runtime"
+	"slices"
+	"strings"
 	"sync"
 
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/sha3"
 	"github.com/tuneinsight/lattigo/v6/ring"
 	"github.com/tuneinsight/lattigo/v6/utils/sampling"
 )
@@ -24,10 +35,19 @@ var (
 
 	// ErrDeserializationError indicates an error during deserialization
 	ErrDeserializationError = errors.New("deserialization error")
+
+	// ErrInvalidRing indicates a degree and modulus that do not define an NTT-friendly ring
+	ErrInvalidRing = errors.New("invalid ring parameters")
+
+	// ErrInvalidArgument indicates a scalar argument outside the range an operation accepts
+	ErrInvalidArgument = errors.New("invalid argument")
 )
 
 var ParallelStart = 10
 
+// MultiplyBlockSize is the tile edge length used by Matrix.Multiply and Matrix.MultiplyParallel
+var MultiplyBlockSize = 32
+
 // Vector represents a vector of big.Int Values with operations in a finite field
 type Vector struct {
 	Values  []*big.Int
@@ -70,21 +90,265 @@ func NewMatrix(rows, cols int, modulus *big.Int) Matrix {
 	}
 }
 
+// checkModulus rejects a modulus that is nil or not greater than 1
+func checkModulus(modulus *big.Int) error {
+	if modulus == nil || modulus.Cmp(big.NewInt(1)) <= 0 {
+		return fmt.Errorf("%w: modulus must be greater than 1", ErrInvalidDimensions)
+	}
+	return nil
+}
+
+// NewVectorChecked is NewVector for arguments that come from outside the package: it returns
+// ErrInvalidDimensions for a non-positive length or a modulus that is nil or not above 1,
+// instead of panicking or building a vector that fails later.
+func NewVectorChecked(length int, modulus *big.Int) (*Vector, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("%w: vector length %d is not positive", ErrInvalidDimensions, length)
+	}
+	if err := checkModulus(modulus); err != nil {
+		return nil, err
+	}
+	return NewVector(length, modulus), nil
+}
+
+// NewMatrixChecked is NewMatrix with the checks of NewVectorChecked applied to both dimensions
+func NewMatrixChecked(rows, cols int, modulus *big.Int) (Matrix, error) {
+	if rows <= 0 || cols <= 0 {
+		return Matrix{}, fmt.Errorf("%w: %dx%d matrix has a non-positive dimension", ErrInvalidDimensions, rows, cols)
+	}
+	if err := checkModulus(modulus); err != nil {
+		return Matrix{}, err
+	}
+	return NewMatrix(rows, cols, modulus), nil
+}
+
+// Clone returns a deep copy of the vector that shares no *big.Int with v
+func (v *Vector) Clone() *Vector {
+	result := NewVector(v.Length(), v.Modulus)
+	for i, val := range v.Values {
+		result.Values[i].Set(val)
+	}
+	return result
+}
+
+// Clone returns a deep copy of the matrix that shares no *big.Int with m
+func (m *Matrix) Clone() Matrix {
+	result := NewMatrix(m.Rows, m.Cols, m.Modulus)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			result.Values[i][j].Set(m.Values[i][j])
+		}
+	}
+	return result
+}
+
+// FromBigInts builds a vector modulo modulus from values. With share the vector uses values and
+// its *big.Int elements directly, so changes through either are visible in both; otherwise every
+// element is copied. Elements are not reduced. A nil element is reported as ErrInvalidDimensions.
+func FromBigInts(values []*big.Int, modulus *big.Int, share bool) (*Vector, error) {
+	for i, val := range values {
+		if val == nil {
+			return nil, fmt.Errorf("%w: element %d is nil", ErrInvalidDimensions, i)
+		}
+	}
+	if share {
+		return &Vector{Values: values, Modulus: new(big.Int).Set(modulus)}, nil
+	}
+	result := NewVector(len(values), modulus)
+	for i, val := range values {
+		result.Values[i].Set(val)
+	}
+	return result, nil
+}
+
+// FromBigIntRows builds a matrix modulo modulus from rows, which must all have the same length.
+// With share the matrix uses rows and its elements directly; otherwise every element is copied.
+// Elements are not reduced.
+func FromBigIntRows(rows [][]*big.Int, modulus *big.Int, share bool) (Matrix, error) {
+	cols := 0
+	if len(rows) > 0 {
+		cols = len(rows[0])
+	}
+	for i, row := range rows {
+		if len(row) != cols {
+			return Matrix{}, fmt.Errorf("%w: row %d has %d values, expected %d", ErrInvalidDimensions, i, len(row), cols)
+		}
+		for j, val := range row {
+			if val == nil {
+				return Matrix{}, fmt.Errorf("%w: element (%d, %d) is nil", ErrInvalidDimensions, i, j)
+			}
+		}
+	}
+	if share {
+		return Matrix{Rows: len(rows), Cols: cols, Values: rows, Modulus: new(big.Int).Set(modulus)}, nil
+	}
+	result := NewMatrix(len(rows), cols, modulus)
+	for i, row := range rows {
+		for j, val := range row {
+			result.Values[i][j].Set(val)
+		}
+	}
+	return result, nil
+}
+
+// RawValues returns the elements of v. With share it returns v.Values itself; otherwise a deep
+// copy that shares no *big.Int with v.
+func (v *Vector) RawValues(share bool) []*big.Int {
+	if share {
+		return v.Values
+	}
+	return v.Clone().Values
+}
+
+// RawRows returns the rows of m. With share it returns m.Values itself; otherwise a deep copy
+// that shares no *big.Int with m.
+func (m *Matrix) RawRows(share bool) [][]*big.Int {
+	if share {
+		return m.Values
+	}
+	clone := m.Clone()
+	return clone.Values
+}
+
 // Length returns the length of the vector
 func (v *Vector) Length() int {
 	return len(v.Values)
 }
 
-// Get returns the value at the specified index
+// AssertLength checks that v is non-nil and has n elements
+func (v *Vector) AssertLength(n int) error {
+	if v == nil {
+		return fmt.Errorf("%w: vector is nil", ErrInvalidDimensions)
+	}
+	if len(v.Values) != n {
+		return fmt.Errorf("%w: vector has length %d, expected %d", ErrInvalidDimensions, len(v.Values), n)
+	}
+	return nil
+}
+
+// Get returns a copy of the value at the specified index
 func (v *Vector) Get(index int) *big.Int {
 	return new(big.Int).Set(v.Values[index])
 }
 
-// Set sets the value at the specified index
+// ElementAt is Get with bounds checking: it returns a copy of the value at index i, or
+// ErrInvalidDimensions if i is out of range
+func (v *Vector) ElementAt(i int) (*big.Int, error) {
+	if i < 0 || i >= len(v.Values) {
+		return nil, fmt.Errorf("%w: index %d out of range [0, %d)", ErrInvalidDimensions, i, len(v.Values))
+	}
+	return v.Get(i), nil
+}
+
+// Set stores a copy of value mod Q at the specified index
 func (v *Vector) Set(index int, value *big.Int) {
 	v.Values[index] = new(big.Int).Mod(value, v.Modulus)
 }
 
+// CyclicShift returns a new vector with result[i] = v[(i-offset) mod n]. A positive offset
+// moves elements towards higher indices, a negative one towards lower indices; in
+// Z_Q[x]/(x^n - 1) this is multiplication by x^offset.
+func (v *Vector) CyclicShift(offset int) *Vector {
+	n := v.Length()
+	result := NewVector(n, v.Modulus)
+	if n == 0 {
+		return result
+	}
+
+	offset %= n
+	if offset < 0 {
+		offset += n
+	}
+	for i := range result.Values {
+		result.Values[i].Set(v.Values[(i-offset+n)%n])
+	}
+	return result
+}
+
+// NegaCyclicShift returns a new vector with result[i] = ±v[(i-offset) mod n], negated modulo Q
+// for every wrap around the end. In Z_Q[x]/(x^n + 1) this is multiplication by x^offset, so a
+// shift by n negates the vector and a shift by 2n is the identity.
+func (v *Vector) NegaCyclicShift(offset int) *Vector {
+	n := v.Length()
+	result := NewVector(n, v.Modulus)
+	if n == 0 {
+		return result
+	}
+
+	offset %= 2 * n
+	if offset < 0 {
+		offset += 2 * n
+	}
+	for i, r := range result.Values {
+		src, wraps := i-offset, 0
+		for src < 0 {
+			src += n
+			wraps++
+		}
+		r.Set(v.Values[src])
+		if wraps%2 == 1 && r.Sign() != 0 {
+			r.Sub(v.Modulus, r)
+		}
+	}
+	return result
+}
+
+// Pad returns a copy of v extended to newLength elements, filling with value mod Q.
+// newLength must be at least v.Length().
+func (v *Vector) Pad(newLength int, value *big.Int) (*Vector, error) {
+	if newLength < v.Length() {
+		return nil, ErrInvalidDimensions
+	}
+	result := NewVector(newLength, v.Modulus)
+	for i, r := range result.Values {
+		if i < v.Length() {
+			r.Set(v.Values[i])
+		} else {
+			r.Mod(value, v.Modulus)
+		}
+	}
+	return result, nil
+}
+
+// Truncate returns a copy of the first newLength elements of v; newLength must be in [0, v.Length()]
+func (v *Vector) Truncate(newLength int) (*Vector, error) {
+	if newLength < 0 || newLength > v.Length() {
+		return nil, ErrInvalidDimensions
+	}
+	result := NewVector(newLength, v.Modulus)
+	for i, r := range result.Values {
+		r.Set(v.Values[i])
+	}
+	return result, nil
+}
+
+// checkPermutation reports whether perm contains every index in [0, n) exactly once
+func checkPermutation(perm []int, n int) error {
+	if len(perm) != n {
+		return fmt.Errorf("%w: permutation has %d entries, expected %d", ErrInvalidDimensions, len(perm), n)
+	}
+	seen := make([]bool, n)
+	for i, p := range perm {
+		if p < 0 || p >= n || seen[p] {
+			return fmt.Errorf("%w: permutation entry %d (%d) is out of range or repeated", ErrInvalidDimensions, i, p)
+		}
+		seen[p] = true
+	}
+	return nil
+}
+
+// Permute returns a new vector with result[i] = v[perm[i]]; perm must be a permutation of [0, n)
+func (v *Vector) Permute(perm []int) (*Vector, error) {
+	if err := checkPermutation(perm, v.Length()); err != nil {
+		return nil, err
+	}
+	result := NewVector(v.Length(), v.Modulus)
+	for i, p := range perm {
+		result.Values[i].Set(v.Values[p])
+	}
+	return result, nil
+}
+
 // Equal checks if two vectors are equal
 func (v *Vector) Equal(other *Vector) bool {
 	if v.Length() != other.Length() {
@@ -102,8 +366,8 @@ func (v *Vector) Equal(other *Vector) bool {
 
 // Add adds two vectors
 func (v *Vector) Add(other *Vector) (*Vector, error) {
-	if v.Length() != other.Length() {
-		return nil, ErrInvalidDimensions
+	if err := other.AssertLength(v.Length()); err != nil {
+		return nil, err
 	}
 	if v.Length() > ParallelStart {
 		return v.ParallelAdd(other)
@@ -120,8 +384,8 @@ func (v *Vector) Add(other *Vector) (*Vector, error) {
 
 // ParallelAdd Parallel vector addition
 func (v *Vector) ParallelAdd(other *Vector) (*Vector, error) {
-	if v.Length() != other.Length() {
-		return nil, ErrInvalidDimensions
+	if err := other.AssertLength(v.Length()); err != nil {
+		return nil, err
 	}
 
 	result := NewVector(v.Length(), v.Modulus)
@@ -147,8 +411,8 @@ func (v *Vector) ParallelAdd(other *Vector) (*Vector, error) {
 
 // Subtract subtracts one vector from another
 func (v *Vector) Subtract(other *Vector) (*Vector, error) {
-	if v.Length() != other.Length() {
-		return nil, ErrInvalidDimensions
+	if err := other.AssertLength(v.Length()); err != nil {
+		return nil, err
 	}
 
 	result := NewVector(v.Length(), v.Modulus)
@@ -160,6 +424,45 @@ func (v *Vector) Subtract(other *Vector) (*Vector, error) {
 	return result, nil
 }
 
+// reduceInPlace reduces the sum or difference of two reduced values into [0, modulus) with a
+// single correction, which unlike Mod does not allocate; other inputs fall back to Mod
+func reduceInPlace(val, modulus *big.Int) {
+	if val.Sign() < 0 {
+		val.Add(val, modulus)
+	} else if val.Cmp(modulus) >= 0 {
+		val.Sub(val, modulus)
+	}
+	if val.Sign() < 0 || val.Cmp(modulus) >= 0 {
+		val.Mod(val, modulus)
+	}
+}
+
+// AddInPlace sets v to v + other mod Q, reusing the elements of v
+func (v *Vector) AddInPlace(other *Vector) error {
+	if err := other.AssertLength(v.Length()); err != nil {
+		return err
+	}
+
+	for i, val := range v.Values {
+		val.Add(val, other.Values[i])
+		reduceInPlace(val, v.Modulus)
+	}
+	return nil
+}
+
+// SubtractInPlace sets v to v - other mod Q, reusing the elements of v
+func (v *Vector) SubtractInPlace(other *Vector) error {
+	if err := other.AssertLength(v.Length()); err != nil {
+		return err
+	}
+
+	for i, val := range v.Values {
+		val.Sub(val, other.Values[i])
+		reduceInPlace(val, v.Modulus)
+	}
+	return nil
+}
+
 // ScalarMultiply multiplies a vector by a scalar
 func (v *Vector) ScalarMultiply(scalar *big.Int) (*Vector, error) {
 	result := NewVector(v.Length(), v.Modulus)
@@ -171,10 +474,26 @@ func (v *Vector) ScalarMultiply(scalar *big.Int) (*Vector, error) {
 	return result, nil
 }
 
+// AXPY returns v + scalar·x mod Q, computing each element in place in the result
+func (v *Vector) AXPY(scalar *big.Int, x *Vector) (*Vector, error) {
+	if err := x.AssertLength(v.Length()); err != nil {
+		return nil, err
+	}
+
+	result := NewVector(v.Length(), v.Modulus)
+	for i, r := range result.Values {
+		r.Mul(x.Values[i], scalar)
+		r.Add(r, v.Values[i])
+		r.Mod(r, v.Modulus)
+	}
+
+	return result, nil
+}
+
 // DotProduct computes the dot product of two vectors
 func (v *Vector) DotProduct(other *Vector) (*big.Int, error) {
-	if v.Length() != other.Length() {
-		return nil, ErrInvalidDimensions
+	if err := other.AssertLength(v.Length()); err != nil {
+		return nil, err
 	}
 
 	result := new(big.Int)
@@ -198,253 +517,1768 @@ func (v *Vector) Sum() *big.Int {
 	return sum
 }
 
-// MarshalBinary implements the encoding.BinaryMarshaler interface
-func (v *Vector) MarshalBinary() ([]byte, error) {
-	// Calculate the size needed for serialization
-	elementSize := (v.Modulus.BitLen() + 7) / 8 // Number of bytes needed to represent each element
-	totalSize := 4 + v.Length()*elementSize     // 4 bytes for length + space for elements
+// ForEach calls f for every element in index order. v is the live element, not a copy;
+// f must not modify it.
+func (v *Vector) ForEach(f func(i int, v *big.Int)) {
+	for i, x := range v.Values {
+		f(i, x)
+	}
+}
 
-	// Create the buffer
-	buf := make([]byte, totalSize)
+// Reduce folds f over the elements in index order, starting from init, and returns the final
+// accumulator. Elements are passed live and must not be modified; init may be.
+func (v *Vector) Reduce(init *big.Int, f func(acc, v *big.Int) *big.Int) *big.Int {
+	acc := init
+	for _, x := range v.Values {
+		acc = f(acc, x)
+	}
+	return acc
+}
 
-	// Write the length
-	binary.BigEndian.PutUint32(buf[:4], uint32(v.Length()))
+// L2NormSquared returns the sum of squares of the centered representatives min(x, Q-x).
+// The accumulator is not reduced, so the result may exceed the modulus.
+func (v *Vector) L2NormSquared() *big.Int {
+	sum := new(big.Int)
+	square := new(big.Int)
+	for _, val := range v.Values {
+		c := centeredAbs(val, v.Modulus)
+		sum.Add(sum, square.Mul(c, c))
+	}
+	return sum
+}
 
-	// Write each element
-	for i, val := range v.Values {
-		offset := 4 + i*elementSize
-		valBytes := val.Bytes()
-		// Pad with leading zeros if necessary
-		padding := elementSize - len(valBytes)
-		if padding < 0 {
-			return nil, fmt.Errorf("%w: element too large", ErrSerializationError)
+// Min returns the smallest element in centered representation (-Q/2, Q/2], or nil for an empty vector
+func (v *Vector) Min() *big.Int {
+	var result *big.Int
+	for _, val := range v.Values {
+		c := centered(val, v.Modulus)
+		if result == nil || c.Cmp(result) < 0 {
+			result = c
 		}
-		copy(buf[offset+padding:offset+elementSize], valBytes)
 	}
-
-	return buf, nil
+	return result
 }
 
-// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
-func (v *Vector) UnmarshalBinary(data []byte) error {
-	if len(data) < 4 {
-		return fmt.Errorf("%w: data too short", ErrDeserializationError)
+// Max returns the largest element in centered representation (-Q/2, Q/2], or nil for an empty vector
+func (v *Vector) Max() *big.Int {
+	var result *big.Int
+	for _, val := range v.Values {
+		c := centered(val, v.Modulus)
+		if result == nil || c.Cmp(result) > 0 {
+			result = c
+		}
 	}
+	return result
+}
 
-	// Read the length
-	length := int(binary.BigEndian.Uint32(data[:4]))
+// Statistics computes Min, Max and L2NormSquared in a single pass.
+// minCentered and maxCentered are nil for an empty vector.
+func (v *Vector) Statistics() (minCentered, maxCentered, l2sq *big.Int) {
+	l2sq = new(big.Int)
+	square := new(big.Int)
+	for _, val := range v.Values {
+		c := centered(val, v.Modulus)
+		if minCentered == nil || c.Cmp(minCentered) < 0 {
+			minCentered = c
+		}
+		if maxCentered == nil || c.Cmp(maxCentered) > 0 {
+			maxCentered = c
+		}
+		l2sq.Add(l2sq, square.Mul(c, c))
+	}
+	return minCentered, maxCentered, l2sq
+}
 
-	// Calculate element size
-	elementSize := (v.Modulus.BitLen() + 7) / 8
+// Mean returns the mean of the centered elements as the reduced fraction numerator/denominator,
+// with a positive denominator. The mean of an empty vector is 0/1.
+func (v *Vector) Mean() (numerator, denominator *big.Int) {
+	if len(v.Values) == 0 {
+		return new(big.Int), big.NewInt(1)
+	}
+	sum := new(big.Int)
+	for _, val := range v.Values {
+		sum.Add(sum, centered(val, v.Modulus))
+	}
+	mean := new(big.Rat).SetFrac(sum, big.NewInt(int64(len(v.Values))))
+	return new(big.Int).Set(mean.Num()), new(big.Int).Set(mean.Denom())
+}
 
-	// Verify that the buffer is large enough
-	if len(data) < 4+length*elementSize {
-		return fmt.Errorf("%w: data too short for specified length", ErrDeserializationError)
+// Variance returns the population variance sum((x_i - mean)^2) / n of the centered elements as
+// the reduced fraction numerator/denominator, with a positive denominator. It is computed
+// exactly as (n*sum(x_i^2) - sum(x_i)^2) / n^2. The variance of an empty vector is 0/1.
+func (v *Vector) Variance() (numerator, denominator *big.Int) {
+	n := int64(len(v.Values))
+	if n == 0 {
+		return new(big.Int), big.NewInt(1)
+	}
+	sum, sumSq, square := new(big.Int), new(big.Int), new(big.Int)
+	for _, val := range v.Values {
+		c := centered(val, v.Modulus)
+		sum.Add(sum, c)
+		sumSq.Add(sumSq, square.Mul(c, c))
 	}
+	nBig := big.NewInt(n)
+	num := new(big.Int).Mul(nBig, sumSq)
+	num.Sub(num, square.Mul(sum, sum))
+	variance := new(big.Rat).SetFrac(num, new(big.Int).Mul(nBig, nBig))
+	return new(big.Int).Set(variance.Num()), new(big.Int).Set(variance.Denom())
+}
 
-	// Resize the vector if necessary
-	if length != v.Length() {
-		v.Values = make([]*big.Int, length)
-		for i := range v.Values {
-			v.Values[i] = new(big.Int)
-		}
+// Sort returns a copy of v with its elements in ascending order of magnitude min(x, Q-x), so
+// that small Gaussian samples come first whatever their sign. Elements of equal magnitude are
+// ordered by their centered representative, negative before positive.
+func (v *Vector) Sort() *Vector {
+	type keyed struct {
+		abs, centered, val *big.Int
+	}
+	keys := make([]keyed, len(v.Values))
+	for i, val := range v.Values {
+		keys[i] = keyed{centeredAbs(val, v.Modulus), centered(val, v.Modulus), val}
 	}
+	slices.SortStableFunc(keys, func(a, b keyed) int {
+		if c := a.abs.Cmp(b.abs); c != 0 {
+			return c
+		}
+		return a.centered.Cmp(b.centered)
+	})
 
-	// Read each element
-	for i := 0; i < length; i++ {
-		offset := 4 + i*elementSize
-		v.Values[i] = new(big.Int).SetBytes(data[offset : offset+elementSize])
-		v.Values[i].Mod(v.Values[i], v.Modulus)
+	result := NewVector(len(keys), v.Modulus)
+	for i, k := range keys {
+		result.Values[i].Set(k.val)
 	}
+	return result
+}
 
-	return nil
+// centered maps x in [0, Q) to its representative in (-Q/2, Q/2]
+func centered(x, modulus *big.Int) *big.Int {
+	halfQ := new(big.Int).Rsh(modulus, 1)
+	if x.Cmp(halfQ) > 0 {
+		return new(big.Int).Sub(x, modulus)
+	}
+	return new(big.Int).Set(x)
 }
 
-// EncodedSize returns the size of the encoded vector in bytes
-func (v *Vector) EncodedSize() int {
-	elementSize := (v.Modulus.BitLen() + 7) / 8
-	return 4 + v.Length()*elementSize
+// centeredAbs returns min(x, Q-x) for x in [0, Q)
+func centeredAbs(x, modulus *big.Int) *big.Int {
+	neg := new(big.Int).Sub(modulus, x)
+	if neg.Cmp(x) < 0 {
+		return neg
+	}
+	return new(big.Int).Set(x)
 }
 
-// Equal checks if two matrices are equal
-func (m *Matrix) Equal(other Matrix) bool {
-	if m.Rows != other.Rows || m.Cols != other.Cols {
-		return false
+// IsAllZero reports whether every element of the vector is zero
+func (v *Vector) IsAllZero() bool {
+	for _, val := range v.Values {
+		if val.Sign() != 0 {
+			return false
+		}
 	}
+	return true
+}
 
-	for i := 0; i < m.Rows; i++ {
-		for j := 0; j < m.Cols; j++ {
-			if m.Values[i][j].Cmp(other.Values[i][j]) != 0 {
-				return false
-			}
+// CountZeros returns the number of zero elements of the vector
+func (v *Vector) CountZeros() int {
+	zeros := 0
+	for _, val := range v.Values {
+		if val.Sign() == 0 {
+			zeros++
 		}
 	}
+	return zeros
+}
 
-	return true
+// CountNonZeros returns the number of non-zero elements of the vector
+func (v *Vector) CountNonZeros() int {
+	return v.Length() - v.CountZeros()
 }
 
-// Get returns the value at the specified position
-func (m *Matrix) Get(row, col int) *big.Int {
-	return new(big.Int).Set(m.Values[row][col])
+// Density returns the fraction of non-zero elements in [0, 1]. An empty vector has density 0.
+func (v *Vector) Density() float64 {
+	if v.Length() == 0 {
+		return 0
+	}
+	return float64(v.CountNonZeros()) / float64(v.Length())
 }
 
-// Set sets the value at the specified position
-func (m *Matrix) Set(row, col int, value *big.Int) {
-	m.Values[row][col] = new(big.Int).Mod(value, m.Modulus)
+// ZeroInPlace sets every element to zero, reusing the existing *big.Int values
+func (v *Vector) ZeroInPlace() {
+	for _, val := range v.Values {
+		val.SetInt64(0)
+	}
 }
 
-// Transpose returns the transpose of the matrix
-func (m *Matrix) Transpose() (Matrix, error) {
-	if m.Rows > ParallelStart || m.Cols > ParallelStart {
-		return m.ParallelTranspose()
+// Fill sets every element to value mod Q, reusing the existing *big.Int values
+func (v *Vector) Fill(value *big.Int) {
+	reduced := new(big.Int).Mod(value, v.Modulus)
+	for _, val := range v.Values {
+		val.Set(reduced)
 	}
-	result := NewMatrix(m.Cols, m.Rows, m.Modulus)
+}
 
-	for i := 0; i < m.Rows; i++ {
-		for j := 0; j < m.Cols; j++ {
-			result.Values[j][i] = new(big.Int).Set(m.Values[i][j])
+// SetAll replaces every element with the matching entry of values reduced mod Q. values must
+// have v's length and no nil entries; otherwise v is left unchanged.
+func (v *Vector) SetAll(values []*big.Int) error {
+	if len(values) != v.Length() {
+		return fmt.Errorf("%w: got %d values, expected %d", ErrInvalidDimensions, len(values), v.Length())
+	}
+	for i, val := range values {
+		if val == nil {
+			return fmt.Errorf("%w: value %d is nil", ErrInvalidDimensions, i)
 		}
 	}
 
-	return result, nil
+	for i, val := range values {
+		v.Values[i] = new(big.Int).Mod(val, v.Modulus)
+	}
+	return nil
 }
 
-func (m *Matrix) ParallelTranspose() (Matrix, error) {
-	result := NewMatrix(m.Cols, m.Rows, m.Modulus)
-
-	rowsPerWorker := max(1, m.Rows/runtime.NumCPU())
+// modulusTagFlag is set in the top bit of a length header that is followed by a modulus tag
+const modulusTagFlag = 1 << 31
 
-	var wg sync.WaitGroup
-	for startRow := 0; startRow < m.Rows; startRow += rowsPerWorker {
-		wg.Add(1)
-		endRow := min(m.Rows, startRow+rowsPerWorker)
+// modulusTagSize is the length of the truncated modulus hash in tagged encodings
+const modulusTagSize = 4
 
-		go func(startRow, endRow int) {
-			defer wg.Done()
+// modulusTag returns the first modulusTagSize bytes of SHA3-256 over the big-endian modulus
+func modulusTag(modulus *big.Int) []byte {
+	digest := sha3.Sum256(modulus.Bytes())
+	return digest[:modulusTagSize]
+}
+
+// checkModulusTag compares tag with the tag of modulus
+func checkModulusTag(tag []byte, modulus *big.Int) error {
+	if !bytes.Equal(tag, modulusTag(modulus)) {
+		return fmt.Errorf("%w: encoded for a different modulus", ErrDeserializationError)
+	}
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The encoding is
+// length (4 bytes, top bit set) | modulus tag (4 bytes) | fixed-width big-endian elements,
+// so decoding under another modulus fails instead of producing plausible values.
+func (v *Vector) MarshalBinary() ([]byte, error) {
+	return v.AppendBinary(make([]byte, 0, v.EncodedSize()))
+}
+
+// AppendBinary appends the MarshalBinary encoding of the vector to dst
+func (v *Vector) AppendBinary(dst []byte) ([]byte, error) {
+	start := len(dst)
+	dst, err := v.AppendBinaryRaw(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flag the length header and insert the tag after it
+	dst[start] |= modulusTagFlag >> 24
+	return slices.Insert(dst, start+4, modulusTag(v.Modulus)...), nil
+}
+
+// MarshalBinaryRaw encodes the vector as length (4 bytes) | fixed-width big-endian elements,
+// without a modulus tag. It is meant for fixed layouts, such as ciphertexts, where the modulus
+// is implied by the parameter set.
+func (v *Vector) MarshalBinaryRaw() ([]byte, error) {
+	return v.AppendBinaryRaw(make([]byte, 0, v.RawEncodedSize()))
+}
+
+// AppendBinaryRaw appends the MarshalBinaryRaw encoding of the vector to dst
+func (v *Vector) AppendBinaryRaw(dst []byte) ([]byte, error) {
+	elementSize := (v.Modulus.BitLen() + 7) / 8 // Number of bytes needed to represent each element
+	start := len(dst)
+	dst = slices.Grow(dst, v.RawEncodedSize())[:start+v.RawEncodedSize()]
+	buf := dst[start:]
+	clear(buf)
+
+	// Write the length
+	binary.BigEndian.PutUint32(buf[:4], uint32(v.Length()))
+
+	// Write each element, left-padded with zeros
+	for i, val := range v.Values {
+		offset := 4 + i*elementSize
+		if (val.BitLen()+7)/8 > elementSize {
+			return nil, fmt.Errorf("%w: element too large", ErrSerializationError)
+		}
+		val.FillBytes(buf[offset : offset+elementSize])
+	}
+
+	return dst, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. It decodes both the
+// tagged MarshalBinary encoding, rejecting a tag that does not match the receiver's modulus,
+// and the untagged MarshalBinaryRaw encoding. Untagged input must be exactly the size the
+// receiver's modulus implies and hold only reduced elements, so stripping the tag does not
+// let an encoding under another modulus through.
+func (v *Vector) UnmarshalBinary(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("%w: data too short", ErrDeserializationError)
+	}
+	header := binary.BigEndian.Uint32(data[:4])
+	if header&modulusTagFlag == 0 {
+		if err := checkUntaggedElements(int(header), data[4:], v.Modulus); err != nil {
+			return err
+		}
+		return v.unmarshalElements(int(header), data[4:])
+	}
+	if len(data) < 4+modulusTagSize {
+		return fmt.Errorf("%w: data too short", ErrDeserializationError)
+	}
+	if err := checkModulusTag(data[4:4+modulusTagSize], v.Modulus); err != nil {
+		return err
+	}
+	return v.unmarshalElements(int(header&^modulusTagFlag), data[4+modulusTagSize:])
+}
+
+// UnmarshalBinaryRaw decodes the MarshalBinaryRaw encoding using the receiver's modulus
+func (v *Vector) UnmarshalBinaryRaw(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("%w: data too short", ErrDeserializationError)
+	}
+	header := binary.BigEndian.Uint32(data[:4])
+	if header&modulusTagFlag != 0 {
+		return fmt.Errorf("%w: unexpected modulus tag in raw encoding", ErrDeserializationError)
+	}
+	return v.unmarshalElements(int(header), data[4:])
+}
+
+// checkUntaggedElements checks that data holds exactly count elements of the width modulus
+// implies, each below modulus. It stands in for the modulus tag on untagged encodings.
+func checkUntaggedElements(count int, data []byte, modulus *big.Int) error {
+	elementSize := (modulus.BitLen() + 7) / 8
+	if count < 0 || elementSize == 0 || len(data)%elementSize != 0 || len(data)/elementSize != count {
+		return fmt.Errorf("%w: untagged encoding is %d bytes, expected %d elements of %d bytes",
+			ErrDeserializationError, len(data), count, elementSize)
+	}
+	val := new(big.Int)
+	for offset := 0; offset < len(data); offset += elementSize {
+		if val.SetBytes(data[offset:offset+elementSize]).Cmp(modulus) >= 0 {
+			return fmt.Errorf("%w: untagged element %d is not reduced modulo %v",
+				ErrDeserializationError, offset/elementSize, modulus)
+		}
+	}
+	return nil
+}
+
+// unmarshalElements reads length fixed-width elements from data into the vector, reducing them
+func (v *Vector) unmarshalElements(length int, data []byte) error {
+	// Calculate element size
+	elementSize := (v.Modulus.BitLen() + 7) / 8
+
+	// Verify that the buffer is large enough
+	if len(data) < length*elementSize {
+		return fmt.Errorf("%w: data too short for specified length", ErrDeserializationError)
+	}
+
+	// Resize the vector if necessary
+	if length != v.Length() {
+		v.Values = make([]*big.Int, length)
+		for i := range v.Values {
+			v.Values[i] = new(big.Int)
+		}
+	}
+
+	// Read each element
+	for i := 0; i < length; i++ {
+		offset := i * elementSize
+		v.Values[i] = new(big.Int).SetBytes(data[offset : offset+elementSize])
+		v.Values[i].Mod(v.Values[i], v.Modulus)
+	}
+
+	return nil
+}
+
+// ToBytes encodes the elements of the vector as fixed-width big-endian integers of
+// (Modulus.BitLen()+7)/8 bytes each, with no length header or modulus tag. This is the element
+// layout of MarshalBinaryRaw, for contexts where the length is implied. Elements outside
+// [0, Modulus) are reduced first.
+func (v *Vector) ToBytes() []byte {
+	elementSize := (v.Modulus.BitLen() + 7) / 8
+	out := make([]byte, v.Length()*elementSize)
+	reduced := new(big.Int)
+	for i, val := range v.Values {
+		if val.Sign() < 0 || val.Cmp(v.Modulus) >= 0 {
+			val = reduced.Mod(val, v.Modulus)
+		}
+		val.FillBytes(out[i*elementSize : (i+1)*elementSize])
+	}
+	return out
+}
+
+// VectorFromBytes decodes length elements encoded by ToBytes under modulus. data must be
+// exactly length*(modulus.BitLen()+7)/8 bytes long.
+func VectorFromBytes(data []byte, length int, modulus *big.Int) (*Vector, error) {
+	if modulus == nil || modulus.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: invalid modulus", ErrDeserializationError)
+	}
+	elementSize := (modulus.BitLen() + 7) / 8
+	if length < 0 || len(data) != length*elementSize {
+		return nil, fmt.Errorf("%w: expected %d bytes for %d elements, got %d",
+			ErrDeserializationError, length*elementSize, length, len(data))
+	}
+	v := NewVector(length, modulus)
+	if err := v.unmarshalElements(length, data); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// MarshalHex renders the elements of the vector as space-separated, fixed-width, lower-case
+// hex strings of 2*((Modulus.BitLen()+7)/8) digits each, the hex form of ToBytes
+func (v *Vector) MarshalHex() string {
+	elementSize := (v.Modulus.BitLen() + 7) / 8
+	data := v.ToBytes()
+	var b strings.Builder
+	b.Grow(max(v.Length()*(2*elementSize+1)-1, 0))
+	for i := 0; i < v.Length(); i++ {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(hex.EncodeToString(data[i*elementSize : (i+1)*elementSize]))
+	}
+	return b.String()
+}
+
+// VectorFromHex decodes length elements from the output of MarshalHex. Whitespace between
+// digits is ignored, so the elements may also be concatenated without separators.
+func VectorFromHex(s string, length int, modulus *big.Int) (*Vector, error) {
+	data, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeserializationError, err)
+	}
+	return VectorFromBytes(data, length, modulus)
+}
+
+// canonicalChunk is the number of elements WriteCanonical buffers per Write call
+const canonicalChunk = 64
+
+// WriteCanonical writes the elements of the vector to w as fixed-width big-endian integers of
+// elementSize bytes each, with no length header. Elements are buffered in small chunks so the
+// whole vector is never serialized in memory at once.
+func (v *Vector) WriteCanonical(w io.Writer, elementSize int) error {
+	if elementSize <= 0 {
+		return fmt.Errorf("%w: invalid element size %d", ErrSerializationError, elementSize)
+	}
+
+	buf := make([]byte, min(v.Length(), canonicalChunk)*elementSize)
+	for start := 0; start < v.Length(); start += canonicalChunk {
+		end := min(v.Length(), start+canonicalChunk)
+		chunk := buf[:(end-start)*elementSize]
+		for i, val := range v.Values[start:end] {
+			if val.Sign() < 0 || (val.BitLen()+7)/8 > elementSize {
+				return fmt.Errorf("%w: element too large", ErrSerializationError)
+			}
+			val.FillBytes(chunk[i*elementSize : (i+1)*elementSize])
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EncodedSize returns the size of the encoded vector in bytes
+func (v *Vector) EncodedSize() int {
+	return v.RawEncodedSize() + modulusTagSize
+}
+
+// RawEncodedSize returns the size of the MarshalBinaryRaw encoding of the vector in bytes
+func (v *Vector) RawEncodedSize() int {
+	elementSize := (v.Modulus.BitLen() + 7) / 8
+	return 4 + v.Length()*elementSize
+}
+
+// Equal checks if two matrices are equal
+func (m *Matrix) Equal(other Matrix) bool {
+	if m.Rows != other.Rows || m.Cols != other.Cols {
+		return false
+	}
+
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			if m.Values[i][j].Cmp(other.Values[i][j]) != 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// IsNilOrEmpty reports whether the matrix has no rows, no columns or no backing values
+func (m *Matrix) IsNilOrEmpty() bool {
+	return m.Rows == 0 || m.Cols == 0 || m.Values == nil
+}
+
+// AssertDimensions checks that m is rows x cols and that its Values actually have that shape,
+// so that operations can index them without panicking
+func (m *Matrix) AssertDimensions(rows, cols int) error {
+	if m.Rows != rows || m.Cols != cols {
+		return fmt.Errorf("%w: matrix is %dx%d, expected %dx%d", ErrInvalidDimensions, m.Rows, m.Cols, rows, cols)
+	}
+	if len(m.Values) != rows {
+		return fmt.Errorf("%w: matrix has %d rows of values, expected %d", ErrInvalidDimensions, len(m.Values), rows)
+	}
+	for i, row := range m.Values {
+		if len(row) != cols {
+			return fmt.Errorf("%w: matrix row %d has %d values, expected %d", ErrInvalidDimensions, i, len(row), cols)
+		}
+	}
+	return nil
+}
+
+// checkProduct checks that m and other are well-formed, non-empty and can be multiplied
+func checkProduct(m, other *Matrix) error {
+	if m.IsNilOrEmpty() || other.IsNilOrEmpty() {
+		return ErrInvalidDimensions
+	}
+	if err := m.AssertDimensions(m.Rows, m.Cols); err != nil {
+		return err
+	}
+	return other.AssertDimensions(m.Cols, other.Cols)
+}
+
+// IsSymmetric reports whether the matrix is square and equal to its transpose
+func (m *Matrix) IsSymmetric() bool {
+	if m.Rows != m.Cols {
+		return false
+	}
+
+	for i := 0; i < m.Rows; i++ {
+		for j := i + 1; j < m.Cols; j++ {
+			if m.Values[i][j].Cmp(m.Values[j][i]) != 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// NewDiagonalMatrix creates a square matrix with v on the main diagonal and zeros elsewhere
+func NewDiagonalMatrix(v *Vector) Matrix {
+	result := NewMatrix(v.Length(), v.Length(), v.Modulus)
+	for i, val := range v.Values {
+		result.Values[i][i] = new(big.Int).Mod(val, v.Modulus)
+	}
+	return result
+}
+
+// Diagonal returns the main diagonal as a vector of min(rows, cols) elements
+func (m *Matrix) Diagonal() (*Vector, error) {
+	if m.IsNilOrEmpty() {
+		return nil, ErrInvalidDimensions
+	}
+
+	result := NewVector(min(m.Rows, m.Cols), m.Modulus)
+	for i := range result.Values {
+		result.Values[i].Set(m.Values[i][i])
+	}
+	return result, nil
+}
+
+// Trace returns the sum of the main diagonal of the square matrix m mod Q
+func (m *Matrix) Trace() (*big.Int, error) {
+	if m.Rows != m.Cols {
+		return nil, fmt.Errorf("%w: %dx%d matrix is not square", ErrInvalidDimensions, m.Rows, m.Cols)
+	}
+
+	sum := new(big.Int)
+	for i := 0; i < m.Rows; i++ {
+		sum.Add(sum, m.Values[i][i])
+	}
+	return sum.Mod(sum, m.Modulus), nil
+}
+
+// IsDiagonal reports whether every entry off the main diagonal is zero
+func (m *Matrix) IsDiagonal() bool {
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			if i != j && m.Values[i][j].Sign() != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// UpperTriangular returns a copy of the square matrix m with the entries below the main
+// diagonal set to zero
+func (m *Matrix) UpperTriangular() (Matrix, error) {
+	return m.triangular(func(i, j int) bool { return j >= i })
+}
+
+// LowerTriangular returns a copy of the square matrix m with the entries above the main
+// diagonal set to zero
+func (m *Matrix) LowerTriangular() (Matrix, error) {
+	return m.triangular(func(i, j int) bool { return j <= i })
+}
+
+// triangular copies the entries of the square matrix m for which keep reports true
+func (m *Matrix) triangular(keep func(i, j int) bool) (Matrix, error) {
+	if m.Rows != m.Cols {
+		return Matrix{}, fmt.Errorf("%w: %dx%d matrix is not square", ErrInvalidDimensions, m.Rows, m.Cols)
+	}
+
+	result := NewMatrix(m.Rows, m.Cols, m.Modulus)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			if keep(i, j) {
+				result.Values[i][j].Set(m.Values[i][j])
+			}
+		}
+	}
+	return result, nil
+}
+
+// IsUpperTriangular reports whether every entry below the main diagonal is zero
+func (m *Matrix) IsUpperTriangular() bool {
+	return m.isZeroWhere(func(i, j int) bool { return i > j })
+}
+
+// IsLowerTriangular reports whether every entry above the main diagonal is zero
+func (m *Matrix) IsLowerTriangular() bool {
+	return m.isZeroWhere(func(i, j int) bool { return i < j })
+}
+
+// IsStrictlyUpperTriangular reports whether every entry on or below the main diagonal is zero
+func (m *Matrix) IsStrictlyUpperTriangular() bool {
+	return m.isZeroWhere(func(i, j int) bool { return i >= j })
+}
+
+// IsStrictlyLowerTriangular reports whether every entry on or above the main diagonal is zero
+func (m *Matrix) IsStrictlyLowerTriangular() bool {
+	return m.isZeroWhere(func(i, j int) bool { return i <= j })
+}
+
+// isZeroWhere reports whether every entry at a position selected by at is zero
+func (m *Matrix) isZeroWhere(at func(i, j int) bool) bool {
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			if at(i, j) && m.Values[i][j].Sign() != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// FillDiagonal sets every entry of the main diagonal to v mod Q in-place
+func (m *Matrix) FillDiagonal(v *big.Int) error {
+	if m.IsNilOrEmpty() {
+		return ErrInvalidDimensions
+	}
+
+	for i := 0; i < min(m.Rows, m.Cols); i++ {
+		m.Values[i][i] = new(big.Int).Mod(v, m.Modulus)
+	}
+	return nil
+}
+
+// CirculantFromVector creates the n x n circulant matrix whose first column is v, so that
+// entry (i, j) is v[(i-j) mod n] and each row is the previous row cyclically shifted right.
+// Multiplying it by w computes the product of v and w in Z_Q[x]/(x^n - 1).
+func CirculantFromVector(v *Vector) Matrix {
+	n := v.Length()
+	result := NewMatrix(n, n, v.Modulus)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			result.Values[i][j] = new(big.Int).Mod(v.Values[(i-j+n)%n], v.Modulus)
+		}
+	}
+	return result
+}
+
+// IsCirculant reports whether the matrix is square and each row is the previous row cyclically shifted right
+func (m *Matrix) IsCirculant() bool {
+	if m.IsNilOrEmpty() || m.Rows != m.Cols {
+		return false
+	}
+
+	n := m.Rows
+	for i := 1; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if m.Values[i][j].Cmp(m.Values[i-1][(j-1+n)%n]) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Get returns a copy of the value at the specified position
+func (m *Matrix) Get(row, col int) *big.Int {
+	return new(big.Int).Set(m.Values[row][col])
+}
+
+// ElementAt is Get with bounds checking: it returns a copy of the value at row i, column j, or
+// ErrInvalidDimensions if either index is out of range
+func (m *Matrix) ElementAt(i, j int) (*big.Int, error) {
+	if i < 0 || i >= m.Rows || j < 0 || j >= m.Cols {
+		return nil, fmt.Errorf("%w: position (%d, %d) out of range for a %dx%d matrix", ErrInvalidDimensions, i, j, m.Rows, m.Cols)
+	}
+	return m.Get(i, j), nil
+}
+
+// Set stores a copy of value mod Q at the specified position
+func (m *Matrix) Set(row, col int, value *big.Int) {
+	m.Values[row][col] = new(big.Int).Mod(value, m.Modulus)
+}
+
+// Transpose returns the transpose of the matrix
+func (m *Matrix) Transpose() (Matrix, error) {
+	if m.IsNilOrEmpty() {
+		return Matrix{}, ErrInvalidDimensions
+	}
+	if err := m.AssertDimensions(m.Rows, m.Cols); err != nil {
+		return Matrix{}, err
+	}
+	if m.Rows > ParallelStart || m.Cols > ParallelStart {
+		return m.ParallelTranspose()
+	}
+	result := NewMatrix(m.Cols, m.Rows, m.Modulus)
+
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			result.Values[j][i] = new(big.Int).Set(m.Values[i][j])
+		}
+	}
+
+	return result, nil
+}
+
+func (m *Matrix) ParallelTranspose() (Matrix, error) {
+	if m.IsNilOrEmpty() {
+		return Matrix{}, ErrInvalidDimensions
+	}
+	if err := m.AssertDimensions(m.Rows, m.Cols); err != nil {
+		return Matrix{}, err
+	}
+	result := NewMatrix(m.Cols, m.Rows, m.Modulus)
+
+	rowsPerWorker := max(1, m.Rows/runtime.NumCPU())
+
+	var wg sync.WaitGroup
+	for startRow := 0; startRow < m.Rows; startRow += rowsPerWorker {
+		wg.Add(1)
+		endRow := min(m.Rows, startRow+rowsPerWorker)
+
+		go func(startRow, endRow int) {
+			defer wg.Done()
 
 			for i := startRow; i < endRow; i++ {
 				for j := 0; j < m.Cols; j++ {
 					result.Values[j][i] = new(big.Int).Set(m.Values[i][j])
 				}
 			}
-		}(startRow, endRow)
+		}(startRow, endRow)
+	}
+
+	wg.Wait()
+	return result, nil
+}
+
+// Multiply multiplies two matrices
+func (m *Matrix) Multiply(other Matrix) (Matrix, error) {
+	if err := checkProduct(m, &other); err != nil {
+		return Matrix{}, err
+	}
+
+	result := NewMatrix(m.Rows, other.Cols, m.Modulus)
+	m.multiplyRows(other, result, 0, m.Rows)
+
+	return result, nil
+}
+
+// MultiplyAdd returns m·other + addend. The product is accumulated directly on top of a copy of
+// addend, so no intermediate matrix is allocated.
+func (m *Matrix) MultiplyAdd(other Matrix, addend Matrix) (Matrix, error) {
+	if err := checkProduct(m, &other); err != nil {
+		return Matrix{}, err
+	}
+	if err := addend.AssertDimensions(m.Rows, other.Cols); err != nil {
+		return Matrix{}, err
+	}
+
+	result := NewMatrix(m.Rows, other.Cols, m.Modulus)
+	for i := 0; i < result.Rows; i++ {
+		for j := 0; j < result.Cols; j++ {
+			result.Values[i][j].Set(addend.Values[i][j])
+		}
+	}
+	m.multiplyRows(other, result, 0, m.Rows)
+
+	return result, nil
+}
+
+// TransposedMulMat returns m^T·other without materializing the transpose. Both matrices must
+// have the same number of rows.
+func (m *Matrix) TransposedMulMat(other Matrix) (Matrix, error) {
+	if m.IsNilOrEmpty() || other.IsNilOrEmpty() {
+		return Matrix{}, ErrInvalidDimensions
+	}
+	if err := m.AssertDimensions(m.Rows, m.Cols); err != nil {
+		return Matrix{}, err
+	}
+	if err := other.AssertDimensions(m.Rows, other.Cols); err != nil {
+		return Matrix{}, err
+	}
+
+	result := NewMatrix(m.Cols, other.Cols, m.Modulus)
+	product := new(big.Int)
+	for k := 0; k < m.Rows; k++ {
+		for i, a := range m.Values[k] {
+			if a.Sign() == 0 {
+				continue
+			}
+			row := result.Values[i]
+			for j, b := range other.Values[k] {
+				product.Mul(a, b)
+				row[j].Add(row[j], product)
+			}
+		}
+	}
+	for i := 0; i < result.Rows; i++ {
+		for j := 0; j < result.Cols; j++ {
+			result.Values[i][j].Mod(result.Values[i][j], m.Modulus)
+		}
+	}
+
+	return result, nil
+}
+
+// Gram returns the Gram matrix m^T·m, whose entries are the inner products of the columns of m
+func (m *Matrix) Gram() (Matrix, error) {
+	return m.TransposedMulMat(*m)
+}
+
+// Pow returns m^exp by repeated squaring; m^0 is the identity. A non-square matrix is reported
+// as ErrInvalidDimensions and a negative exponent as ErrInvalidArgument.
+func (m *Matrix) Pow(exp int) (Matrix, error) {
+	if m.Rows != m.Cols {
+		return Matrix{}, ErrInvalidDimensions
+	}
+	if exp < 0 {
+		return Matrix{}, fmt.Errorf("%w: negative exponent %d", ErrInvalidArgument, exp)
+	}
+
+	result := NewMatrix(m.Rows, m.Cols, m.Modulus)
+	for i := 0; i < m.Rows; i++ {
+		result.Values[i][i].SetInt64(1)
+	}
+	base := m.Clone()
+
+	var err error
+	for exp > 0 {
+		if exp&1 == 1 {
+			if result, err = result.Multiply(base); err != nil {
+				return Matrix{}, err
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, err = base.Multiply(base); err != nil {
+				return Matrix{}, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// MultiplyParallel multiplies two matrices, splitting the rows of the result into bands across workers.
+// A non-positive workers count uses runtime.NumCPU().
+func (m *Matrix) MultiplyParallel(other Matrix, workers int) (Matrix, error) {
+	if err := checkProduct(m, &other); err != nil {
+		return Matrix{}, err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	result := NewMatrix(m.Rows, other.Cols, m.Modulus)
+	rowsPerWorker := max(1, (m.Rows+workers-1)/workers)
+
+	var wg sync.WaitGroup
+	for startRow := 0; startRow < m.Rows; startRow += rowsPerWorker {
+		wg.Add(1)
+		endRow := min(m.Rows, startRow+rowsPerWorker)
+
+		go func(startRow, endRow int) {
+			defer wg.Done()
+			m.multiplyRows(other, result, startRow, endRow)
+		}(startRow, endRow)
+	}
+
+	wg.Wait()
+	return result, nil
+}
+
+// multiplyRows computes rows [startRow, endRow) of m*other into result, walking the operands in
+// MultiplyBlockSize tiles and reducing each entry once after all partial products are accumulated
+func (m *Matrix) multiplyRows(other Matrix, result Matrix, startRow, endRow int) {
+	block := max(1, MultiplyBlockSize)
+	product := new(big.Int)
+
+	for ii := startRow; ii < endRow; ii += block {
+		iEnd := min(endRow, ii+block)
+		for kk := 0; kk < m.Cols; kk += block {
+			kEnd := min(m.Cols, kk+block)
+			for jj := 0; jj < other.Cols; jj += block {
+				jEnd := min(other.Cols, jj+block)
+
+				for i := ii; i < iEnd; i++ {
+					row := result.Values[i]
+					for k := kk; k < kEnd; k++ {
+						a := m.Values[i][k]
+						if a.Sign() == 0 {
+							continue
+						}
+						for j := jj; j < jEnd; j++ {
+							product.Mul(a, other.Values[k][j])
+							row[j].Add(row[j], product)
+						}
+					}
+				}
+			}
+		}
+
+		for i := ii; i < iEnd; i++ {
+			for j := 0; j < other.Cols; j++ {
+				result.Values[i][j].Mod(result.Values[i][j], m.Modulus)
+			}
+		}
+	}
+}
+
+// MultiplyVector multiplies a matrix by a vector
+func (m *Matrix) MultiplyVector(v *Vector) (*Vector, error) {
+	if m.IsNilOrEmpty() {
+		return nil, ErrInvalidDimensions
+	}
+	if err := m.AssertDimensions(m.Rows, m.Cols); err != nil {
+		return nil, err
+	}
+	if err := v.AssertLength(m.Cols); err != nil {
+		return nil, err
+	}
+	if m.Cols > ParallelStart {
+		return m.ParallelMultiplyVector(v)
+	}
+
+	result := NewVector(m.Rows, m.Modulus)
+
+	for i := 0; i < m.Rows; i++ {
+		sum := new(big.Int)
+		for j := 0; j < m.Cols; j++ {
+			product := new(big.Int).Mul(m.Values[i][j], v.Values[j])
+			product.Mod(product, m.Modulus)
+			sum.Add(sum, product)
+			sum.Mod(sum, m.Modulus)
+		}
+		result.Values[i] = sum
+	}
+
+	return result, nil
+}
+
+// ParallelMultiplyVector Parallel matrix-vector multiplication
+func (m *Matrix) ParallelMultiplyVector(v *Vector) (*Vector, error) {
+	if m.IsNilOrEmpty() {
+		return nil, ErrInvalidDimensions
+	}
+	if err := m.AssertDimensions(m.Rows, m.Cols); err != nil {
+		return nil, err
+	}
+	if err := v.AssertLength(m.Cols); err != nil {
+		return nil, err
+	}
+
+	result := NewVector(m.Rows, m.Modulus)
+	var wg sync.WaitGroup
+	rowsPerWorker := max(1, m.Rows/runtime.NumCPU())
+
+	for startRow := 0; startRow < m.Rows; startRow += rowsPerWorker {
+		wg.Add(1)
+		endRow := min(m.Rows, startRow+rowsPerWorker)
+
+		go func(startRow, endRow int) {
+			defer wg.Done()
+			for i := startRow; i < endRow; i++ {
+				sum := new(big.Int)
+				for j := 0; j < m.Cols; j++ {
+					product := new(big.Int).Mul(m.Values[i][j], v.Values[j])
+					product.Mod(product, m.Modulus)
+					sum.Add(sum, product)
+					sum.Mod(sum, m.Modulus)
+				}
+				result.Values[i] = sum
+			}
+		}(startRow, endRow)
+	}
+
+	wg.Wait()
+	return result, nil
+}
+
+// MultiplyVectorColMajor returns m^T·v without materializing the transpose. v must have m.Rows
+// elements. Rows of m are walked in storage order and each contributes to every output element,
+// so no element of m is skipped and the work does not depend on its values.
+func (m *Matrix) MultiplyVectorColMajor(v *Vector) (*Vector, error) {
+	if m.IsNilOrEmpty() {
+		return nil, ErrInvalidDimensions
+	}
+	if err := m.AssertDimensions(m.Rows, m.Cols); err != nil {
+		return nil, err
+	}
+	if err := v.AssertLength(m.Rows); err != nil {
+		return nil, err
+	}
+
+	result := NewVector(m.Cols, m.Modulus)
+	if m.Cols <= ParallelStart {
+		m.multiplyColsTransposed(v, result, 0, m.Cols)
+		return result, nil
+	}
+
+	colsPerWorker := max(1, m.Cols/runtime.NumCPU())
+	var wg sync.WaitGroup
+	for startCol := 0; startCol < m.Cols; startCol += colsPerWorker {
+		wg.Add(1)
+		endCol := min(m.Cols, startCol+colsPerWorker)
+
+		go func(startCol, endCol int) {
+			defer wg.Done()
+			m.multiplyColsTransposed(v, result, startCol, endCol)
+		}(startCol, endCol)
+	}
+
+	wg.Wait()
+	return result, nil
+}
+
+// multiplyColsTransposed accumulates elements [startCol, endCol) of m^T·v into result
+func (m *Matrix) multiplyColsTransposed(v *Vector, result *Vector, startCol, endCol int) {
+	acc := result.Values[startCol:endCol]
+	product := new(big.Int)
+	for k := 0; k < m.Rows; k++ {
+		row := m.Values[k][startCol:endCol]
+		for j, a := range row {
+			product.Mul(a, v.Values[k])
+			acc[j].Add(acc[j], product)
+		}
+	}
+	for _, val := range acc {
+		val.Mod(val, m.Modulus)
+	}
+}
+
+// Sum returns the sum of all elements in the matrix
+func (m *Matrix) Sum() *big.Int {
+	sum := new(big.Int)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			sum.Add(sum, m.Values[i][j])
+		}
+	}
+	return sum.Mod(sum, m.Modulus)
+}
+
+// TraceInner returns the Frobenius inner product Tr(m^T·other) = Σ m[i][j]·other[i][j] mod Q,
+// without forming the product
+func (m *Matrix) TraceInner(other Matrix) (*big.Int, error) {
+	if err := other.AssertDimensions(m.Rows, m.Cols); err != nil {
+		return nil, err
+	}
+
+	sum := new(big.Int)
+	product := new(big.Int)
+	for i := 0; i < m.Rows; i++ {
+		for j, val := range m.Values[i] {
+			sum.Add(sum, product.Mul(val, other.Values[i][j]))
+		}
+	}
+	return sum.Mod(sum, m.Modulus), nil
+}
+
+// IsAllZero reports whether every element of the matrix is zero
+func (m *Matrix) IsAllZero() bool {
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			if m.Values[i][j].Sign() != 0 {
+				return false
+			}
+		}
 	}
+	return true
+}
 
-	wg.Wait()
+// CountZeros returns the number of zero elements of the matrix
+func (m *Matrix) CountZeros() int {
+	zeros := 0
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			if m.Values[i][j].Sign() == 0 {
+				zeros++
+			}
+		}
+	}
+	return zeros
+}
+
+// CountNonZeros returns the number of non-zero elements of the matrix
+func (m *Matrix) CountNonZeros() int {
+	return m.Rows*m.Cols - m.CountZeros()
+}
+
+// Density returns the fraction of non-zero elements in [0, 1], which can guide the choice
+// between a sparse and a dense representation. An empty matrix has density 0.
+func (m *Matrix) Density() float64 {
+	if m.Rows == 0 || m.Cols == 0 {
+		return 0
+	}
+	return float64(m.CountNonZeros()) / float64(m.Rows*m.Cols)
+}
+
+// ZeroInPlace sets every element to zero, reusing the existing *big.Int values, so a matrix
+// recycled through a sync.Pool can be reset without allocating
+func (m *Matrix) ZeroInPlace() {
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			m.Values[i][j].SetInt64(0)
+		}
+	}
+}
+
+// Fill sets every element to value mod Q, reusing the existing *big.Int values
+func (m *Matrix) Fill(value *big.Int) {
+	reduced := new(big.Int).Mod(value, m.Modulus)
+	for i := 0; i < m.Rows; i++ {
+		for _, val := range m.Values[i] {
+			val.Set(reduced)
+		}
+	}
+}
+
+// SetAll replaces every element with the matching entry of values reduced mod Q. values must
+// have m's dimensions and no nil entries; otherwise m is left unchanged.
+func (m *Matrix) SetAll(values [][]*big.Int) error {
+	if len(values) != m.Rows {
+		return fmt.Errorf("%w: got %d rows, expected %d", ErrInvalidDimensions, len(values), m.Rows)
+	}
+	for i, row := range values {
+		if len(row) != m.Cols {
+			return fmt.Errorf("%w: row %d has %d values, expected %d", ErrInvalidDimensions, i, len(row), m.Cols)
+		}
+		for j, val := range row {
+			if val == nil {
+				return fmt.Errorf("%w: value (%d, %d) is nil", ErrInvalidDimensions, i, j)
+			}
+		}
+	}
+
+	for i, row := range values {
+		for j, val := range row {
+			m.Values[i][j] = new(big.Int).Mod(val, m.Modulus)
+		}
+	}
+	return nil
+}
+
+// Add returns m + other mod Q
+func (m *Matrix) Add(other Matrix) (Matrix, error) {
+	result := m.Clone()
+	if err := result.AddInPlace(other); err != nil {
+		return Matrix{}, err
+	}
 	return result, nil
 }
 
-// Multiply multiplies two matrices
-func (m *Matrix) Multiply(other Matrix) (Matrix, error) {
-	if m.Cols != other.Rows {
+// Subtract returns m - other mod Q
+func (m *Matrix) Subtract(other Matrix) (Matrix, error) {
+	result := m.Clone()
+	if err := result.SubtractInPlace(other); err != nil {
+		return Matrix{}, err
+	}
+	return result, nil
+}
+
+// AddInPlace sets m to m + other mod Q, reusing the elements of m
+func (m *Matrix) AddInPlace(other Matrix) error {
+	if err := other.AssertDimensions(m.Rows, m.Cols); err != nil {
+		return err
+	}
+
+	for i := 0; i < m.Rows; i++ {
+		for j, val := range m.Values[i] {
+			val.Add(val, other.Values[i][j])
+			reduceInPlace(val, m.Modulus)
+		}
+	}
+	return nil
+}
+
+// SubtractInPlace sets m to m - other mod Q, reusing the elements of m
+func (m *Matrix) SubtractInPlace(other Matrix) error {
+	if err := other.AssertDimensions(m.Rows, m.Cols); err != nil {
+		return err
+	}
+
+	for i := 0; i < m.Rows; i++ {
+		for j, val := range m.Values[i] {
+			val.Sub(val, other.Values[i][j])
+			reduceInPlace(val, m.Modulus)
+		}
+	}
+	return nil
+}
+
+// Row returns a copy of row i as a vector
+func (m *Matrix) Row(i int) *Vector {
+	result := NewVector(m.Cols, m.Modulus)
+	for j := 0; j < m.Cols; j++ {
+		result.Values[j].Set(m.Values[i][j])
+	}
+	return result
+}
+
+// Col returns a copy of column j as a vector
+func (m *Matrix) Col(j int) *Vector {
+	result := NewVector(m.Rows, m.Modulus)
+	for i := 0; i < m.Rows; i++ {
+		result.Values[i].Set(m.Values[i][j])
+	}
+	return result
+}
+
+// VectorView is a read-only view of one row or column of a matrix. It aliases the elements of
+// the matrix instead of copying them, so it observes later writes to the matrix; Clone takes an
+// independent Vector.
+type VectorView struct {
+	m   *Matrix
+	idx int
+	col bool
+}
+
+// RowView returns a view of row i without copying it
+func (m *Matrix) RowView(i int) (VectorView, error) {
+	if i < 0 || i >= m.Rows {
+		return VectorView{}, fmt.Errorf("%w: row %d out of range [0, %d)", ErrInvalidDimensions, i, m.Rows)
+	}
+	return VectorView{m: m, idx: i}, nil
+}
+
+// ColView returns a view of column j without copying it
+func (m *Matrix) ColView(j int) (VectorView, error) {
+	if j < 0 || j >= m.Cols {
+		return VectorView{}, fmt.Errorf("%w: column %d out of range [0, %d)", ErrInvalidDimensions, j, m.Cols)
+	}
+	return VectorView{m: m, idx: j, col: true}, nil
+}
+
+// Length returns the number of elements in the view
+func (vw VectorView) Length() int {
+	if vw.m == nil {
+		return 0
+	}
+	if vw.col {
+		return vw.m.Rows
+	}
+	return vw.m.Cols
+}
+
+// at returns the shared element k of the view
+func (vw VectorView) at(k int) *big.Int {
+	if vw.col {
+		return vw.m.Values[k][vw.idx]
+	}
+	return vw.m.Values[vw.idx][k]
+}
+
+// Get returns a copy of element k of the view
+func (vw VectorView) Get(k int) *big.Int {
+	return new(big.Int).Set(vw.at(k))
+}
+
+// Clone copies the viewed elements into a new vector
+func (vw VectorView) Clone() *Vector {
+	if vw.m == nil {
+		return nil
+	}
+	result := NewVector(vw.Length(), vw.m.Modulus)
+	for k, val := range result.Values {
+		val.Set(vw.at(k))
+	}
+	return result
+}
+
+// DotProduct returns the inner product of the view and other mod Q, reading the matrix in place
+func (vw VectorView) DotProduct(other *Vector) (*big.Int, error) {
+	if vw.m == nil {
+		return nil, ErrInvalidDimensions
+	}
+	if err := other.AssertLength(vw.Length()); err != nil {
+		return nil, err
+	}
+	sum := new(big.Int)
+	product := new(big.Int)
+	for k, val := range other.Values {
+		product.Mul(vw.at(k), val)
+		sum.Add(sum, product)
+	}
+	return sum.Mod(sum, vw.m.Modulus), nil
+}
+
+// SwapRows swaps rows i and j in place by exchanging the row slices, without copying elements
+func (m *Matrix) SwapRows(i, j int) error {
+	if i < 0 || i >= m.Rows || j < 0 || j >= m.Rows {
+		return ErrInvalidDimensions
+	}
+	m.Values[i], m.Values[j] = m.Values[j], m.Values[i]
+	return nil
+}
+
+// SwapCols swaps columns i and j in place, touching every row
+func (m *Matrix) SwapCols(i, j int) error {
+	if i < 0 || i >= m.Cols || j < 0 || j >= m.Cols {
+		return ErrInvalidDimensions
+	}
+	for _, row := range m.Values {
+		row[i], row[j] = row[j], row[i]
+	}
+	return nil
+}
+
+// PermuteRows returns a copy of m whose row i is row perm[i] of m
+func (m *Matrix) PermuteRows(perm []int) (Matrix, error) {
+	if err := checkPermutation(perm, m.Rows); err != nil {
+		return Matrix{}, err
+	}
+	result := NewMatrix(m.Rows, m.Cols, m.Modulus)
+	for i, p := range perm {
+		for j := 0; j < m.Cols; j++ {
+			result.Values[i][j].Set(m.Values[p][j])
+		}
+	}
+	return result, nil
+}
+
+// PermuteCols returns a copy of m whose column j is column perm[j] of m
+func (m *Matrix) PermuteCols(perm []int) (Matrix, error) {
+	if err := checkPermutation(perm, m.Cols); err != nil {
+		return Matrix{}, err
+	}
+	result := NewMatrix(m.Rows, m.Cols, m.Modulus)
+	for i := 0; i < m.Rows; i++ {
+		for j, p := range perm {
+			result.Values[i][j].Set(m.Values[i][p])
+		}
+	}
+	return result, nil
+}
+
+// randomPermutation returns a uniformly random permutation of [0, n) drawn from randSource by Fisher-Yates
+func randomPermutation(n int, randSource io.Reader) ([]int, error) {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := n - 1; i > 0; i-- {
+		j, err := rand(randSource, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, err
+		}
+		k := int(j.Int64())
+		perm[i], perm[k] = perm[k], perm[i]
+	}
+	return perm, nil
+}
+
+// RandomlyPermuteRows returns a copy of m with its rows in a uniformly random order drawn from randSource,
+// which should be a cryptographically secure reader such as crypto/rand.Reader
+func (m *Matrix) RandomlyPermuteRows(randSource io.Reader) (Matrix, error) {
+	perm, err := randomPermutation(m.Rows, randSource)
+	if err != nil {
+		return Matrix{}, err
+	}
+	return m.PermuteRows(perm)
+}
+
+// RandomlyPermuteCols returns a copy of m with its columns in a uniformly random order drawn from randSource
+func (m *Matrix) RandomlyPermuteCols(randSource io.Reader) (Matrix, error) {
+	perm, err := randomPermutation(m.Cols, randSource)
+	if err != nil {
+		return Matrix{}, err
+	}
+	return m.PermuteCols(perm)
+}
+
+// UniqueRows returns a copy of m without repeated rows, keeping the first occurrence of each in
+// its original order. Rows are compared after reduction modulo Q.
+func (m *Matrix) UniqueRows() Matrix {
+	seen := make(map[string]bool, m.Rows)
+	var keep []int
+	for i := 0; i < m.Rows; i++ {
+		key := string(m.Row(i).ToBytes())
+		if !seen[key] {
+			seen[key] = true
+			keep = append(keep, i)
+		}
+	}
+
+	result := NewMatrix(len(keep), m.Cols, m.Modulus)
+	for i, r := range keep {
+		for j := 0; j < m.Cols; j++ {
+			result.Values[i][j].Set(m.Values[r][j])
+		}
+	}
+	return result
+}
+
+// IndexOfRow returns the index of the first row of m equal to v, or -1 if there is none. Rows
+// are compared after reduction modulo Q. A v whose length is not m.Cols is rejected with
+// ErrInvalidDimensions.
+func (m *Matrix) IndexOfRow(v *Vector) (int, error) {
+	if err := v.AssertLength(m.Cols); err != nil {
+		return -1, err
+	}
+
+	want := make([]*big.Int, len(v.Values))
+	for j, val := range v.Values {
+		want[j] = new(big.Int).Mod(val, m.Modulus)
+	}
+	elem := new(big.Int)
+	for i := 0; i < m.Rows; i++ {
+		match := true
+		for j, val := range m.Values[i] {
+			if elem.Mod(val, m.Modulus).Cmp(want[j]) != 0 {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// ContainsRow reports whether some row of m equals v modulo Q
+func (m *Matrix) ContainsRow(v *Vector) bool {
+	i, err := m.IndexOfRow(v)
+	return err == nil && i >= 0
+}
+
+// SubMatrix returns a copy of rows [rowStart, rowEnd) and columns [colStart, colEnd)
+func (m *Matrix) SubMatrix(rowStart, rowEnd, colStart, colEnd int) (Matrix, error) {
+	if rowStart < 0 || rowEnd > m.Rows || rowStart >= rowEnd || colStart < 0 || colEnd > m.Cols || colStart >= colEnd {
 		return Matrix{}, ErrInvalidDimensions
 	}
 
-	result := NewMatrix(m.Rows, other.Cols, m.Modulus)
+	result := NewMatrix(rowEnd-rowStart, colEnd-colStart, m.Modulus)
+	for i := range result.Values {
+		for j := range result.Values[i] {
+			result.Values[i][j].Set(m.Values[rowStart+i][colStart+j])
+		}
+	}
+	return result, nil
+}
+
+// Augment returns the horizontal concatenation [m | other] as a new matrix
+func (m *Matrix) Augment(other Matrix) (Matrix, error) {
+	if m.Rows != other.Rows {
+		return Matrix{}, ErrInvalidDimensions
+	}
 
+	result := NewMatrix(m.Rows, m.Cols+other.Cols, m.Modulus)
 	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			result.Values[i][j].Set(m.Values[i][j])
+		}
 		for j := 0; j < other.Cols; j++ {
-			sum := new(big.Int)
-			for k := 0; k < m.Cols; k++ {
-				product := new(big.Int).Mul(m.Values[i][k], other.Values[k][j])
-				product.Mod(product, m.Modulus)
-				sum.Add(sum, product)
-				sum.Mod(sum, m.Modulus)
+			result.Values[i][m.Cols+j].Mod(other.Values[i][j], m.Modulus)
+		}
+	}
+	return result, nil
+}
+
+// Concat returns the vertical concatenation of m and others as a new matrix. All matrices must
+// have m's column count; their elements are reduced modulo m.Modulus.
+func (m *Matrix) Concat(others ...Matrix) (Matrix, error) {
+	rows := m.Rows
+	for _, o := range others {
+		if o.Cols != m.Cols {
+			return Matrix{}, ErrInvalidDimensions
+		}
+		rows += o.Rows
+	}
+
+	result := NewMatrix(rows, m.Cols, m.Modulus)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			result.Values[i][j].Set(m.Values[i][j])
+		}
+	}
+	offset := m.Rows
+	for _, o := range others {
+		for i := 0; i < o.Rows; i++ {
+			for j := 0; j < o.Cols; j++ {
+				result.Values[offset+i][j].Mod(o.Values[i][j], m.Modulus)
 			}
-			result.Values[i][j] = sum
 		}
+		offset += o.Rows
+	}
+	return result, nil
+}
+
+// BlockDiagonal places blocks along the diagonal of a sum(rows) x sum(cols) matrix, with zeros
+// elsewhere. The result uses the modulus of the first block and reduces the others into it.
+func BlockDiagonal(blocks []Matrix) (Matrix, error) {
+	if len(blocks) == 0 {
+		return Matrix{}, ErrInvalidDimensions
 	}
 
+	rows, cols := 0, 0
+	for _, b := range blocks {
+		rows += b.Rows
+		cols += b.Cols
+	}
+
+	modulus := blocks[0].Modulus
+	result := NewMatrix(rows, cols, modulus)
+	rowOffset, colOffset := 0, 0
+	for _, b := range blocks {
+		for i := 0; i < b.Rows; i++ {
+			for j := 0; j < b.Cols; j++ {
+				result.Values[rowOffset+i][colOffset+j].Mod(b.Values[i][j], modulus)
+			}
+		}
+		rowOffset += b.Rows
+		colOffset += b.Cols
+	}
 	return result, nil
 }
 
-// MultiplyVector multiplies a matrix by a vector
-func (m *Matrix) MultiplyVector(v *Vector) (*Vector, error) {
-	if m.Cols != v.Length() {
-		return nil, ErrInvalidDimensions
+// ForEach calls f for every element in row-major order. v is the live element, not a copy;
+// f must not modify it.
+func (m *Matrix) ForEach(f func(i, j int, v *big.Int)) {
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			f(i, j, m.Values[i][j])
+		}
 	}
-	if m.Cols > ParallelStart {
-		return m.ParallelMultiplyVector(v)
+}
+
+// Reduce folds f over the elements in row-major order, starting from init, and returns the
+// final accumulator. Elements are passed live and must not be modified; init may be.
+func (m *Matrix) Reduce(init *big.Int, f func(acc, v *big.Int) *big.Int) *big.Int {
+	acc := init
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			acc = f(acc, m.Values[i][j])
+		}
+	}
+	return acc
+}
+
+// RowNorms returns a vector whose i-th element is the centered L2 norm squared of row i.
+// Like L2NormSquared the entries are not reduced and may exceed the modulus.
+func (m *Matrix) RowNorms() *Vector {
+	result := NewVector(m.Rows, m.Modulus)
+	square := new(big.Int)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			c := centeredAbs(m.Values[i][j], m.Modulus)
+			result.Values[i].Add(result.Values[i], square.Mul(c, c))
+		}
+	}
+	return result
+}
+
+// ColNorms returns a vector whose j-th element is the centered L2 norm squared of column j.
+// Like L2NormSquared the entries are not reduced and may exceed the modulus.
+func (m *Matrix) ColNorms() *Vector {
+	result := NewVector(m.Cols, m.Modulus)
+	square := new(big.Int)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			c := centeredAbs(m.Values[i][j], m.Modulus)
+			result.Values[j].Add(result.Values[j], square.Mul(c, c))
+		}
+	}
+	return result
+}
+
+// debugHashSize is the number of digest bytes DebugString prints
+const debugHashSize = 8
+
+// contentHash returns a short SHA3-256 digest of the reduced elements of rows, for telling
+// large structures apart in debug output
+func contentHash(rows ...*Vector) string {
+	h := sha3.New256()
+	for _, row := range rows {
+		h.Write(row.ToBytes())
+	}
+	return hex.EncodeToString(h.Sum(nil)[:debugHashSize])
+}
+
+// writeValues writes values separated by spaces
+func writeValues(b *strings.Builder, values []*big.Int) {
+	for i, val := range values {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(val.String())
+	}
+}
+
+// DebugString describes the vector for debugging: its length, the bit length of the modulus,
+// a short content hash and at most maxElems leading values. The output size does not depend
+// on the length of the vector.
+func (v *Vector) DebugString(maxElems int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Vector[%d] q=%d bits hash=%s [", v.Length(), v.Modulus.BitLen(), contentHash(v))
+	shown := min(v.Length(), max(maxElems, 0))
+	writeValues(&b, v.Values[:shown])
+	if shown < v.Length() {
+		fmt.Fprintf(&b, " ... %d more", v.Length()-shown)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// DebugString describes the matrix for debugging: its dimensions, the bit length of the
+// modulus, a short content hash and the top-left corner of at most maxElems values, one row
+// per line. The output size does not depend on the dimensions of the matrix.
+func (m *Matrix) DebugString(maxElems int) string {
+	rows := make([]*Vector, m.Rows)
+	for i := range rows {
+		rows[i] = &Vector{Values: m.Values[i], Modulus: m.Modulus}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Matrix[%dx%d] q=%d bits hash=%s", m.Rows, m.Cols, m.Modulus.BitLen(), contentHash(rows...))
+
+	// Show a corner that is as square as the dimensions allow
+	maxElems = max(maxElems, 0)
+	cols := min(m.Cols, max(int(math.Sqrt(float64(maxElems))), 1))
+	shownRows := 0
+	if cols > 0 {
+		shownRows = min(m.Rows, maxElems/cols)
+	}
+	for i := 0; i < shownRows; i++ {
+		b.WriteString("\n  [")
+		writeValues(&b, m.Values[i][:cols])
+		if cols < m.Cols {
+			fmt.Fprintf(&b, " ... %d more", m.Cols-cols)
+		}
+		b.WriteByte(']')
+	}
+	if shownRows < m.Rows {
+		fmt.Fprintf(&b, "\n  ... %d more rows", m.Rows-shownRows)
+	}
+	return b.String()
+}
+
+// MatrixStats summarizes the centered bit lengths of the elements of a matrix
+type MatrixStats struct {
+	// MinBitLen, MaxBitLen and MeanBitLen are taken over the centered absolute values, so an
+	// element congruent to -x has the bit length of x
+	MinBitLen, MaxBitLen int
+	MeanBitLen           float64
+	// Zeros counts the zero elements
+	Zeros int
+}
+
+// Stats returns the bit length statistics of the elements of the matrix. For a matrix
+// sampled from a discrete Gaussian of parameter sigma, MaxBitLen should stay within a few
+// bits of log2(sigma) rather than approach the bit length of the modulus.
+func (m *Matrix) Stats() MatrixStats {
+	var stats MatrixStats
+	if m.Rows == 0 || m.Cols == 0 {
+		return stats
+	}
+	stats.MinBitLen = m.Modulus.BitLen()
+	total := 0
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			bits := centeredAbs(m.Values[i][j], m.Modulus).BitLen()
+			stats.MinBitLen = min(stats.MinBitLen, bits)
+			stats.MaxBitLen = max(stats.MaxBitLen, bits)
+			total += bits
+			if bits == 0 {
+				stats.Zeros++
+			}
+		}
 	}
+	stats.MeanBitLen = float64(total) / float64(m.Rows*m.Cols)
+	return stats
+}
 
+// RowSums returns a vector whose i-th element is the sum of row i
+func (m *Matrix) RowSums() *Vector {
 	result := NewVector(m.Rows, m.Modulus)
-
 	for i := 0; i < m.Rows; i++ {
 		sum := new(big.Int)
 		for j := 0; j < m.Cols; j++ {
-			product := new(big.Int).Mul(m.Values[i][j], v.Values[j])
-			product.Mod(product, m.Modulus)
-			sum.Add(sum, product)
-			sum.Mod(sum, m.Modulus)
+			sum.Add(sum, m.Values[i][j])
 		}
-		result.Values[i] = sum
+		result.Values[i] = sum.Mod(sum, m.Modulus)
 	}
-
-	return result, nil
+	return result
 }
 
-// ParallelMultiplyVector Parallel matrix-vector multiplication
-func (m *Matrix) ParallelMultiplyVector(v *Vector) (*Vector, error) {
-	if m.Cols != v.Length() {
-		return nil, ErrInvalidDimensions
+// OuterProduct computes the rank-1 matrix v ⊗ w^T with (i,j) = v[i]*w[j]
+func OuterProduct(v, w *Vector) (Matrix, error) {
+	if v == nil || w == nil || v.Length() == 0 || w.Length() == 0 {
+		return Matrix{}, ErrInvalidDimensions
+	}
+	if v.Modulus.Cmp(w.Modulus) != 0 {
+		return Matrix{}, fmt.Errorf("%w: vectors have different moduli", ErrInvalidDimensions)
 	}
 
-	result := NewVector(m.Rows, m.Modulus)
-	var wg sync.WaitGroup
-	rowsPerWorker := max(1, m.Rows/runtime.NumCPU())
-
-	for startRow := 0; startRow < m.Rows; startRow += rowsPerWorker {
-		wg.Add(1)
-		endRow := min(m.Rows, startRow+rowsPerWorker)
-
-		go func(startRow, endRow int) {
-			defer wg.Done()
-			for i := startRow; i < endRow; i++ {
-				sum := new(big.Int)
-				for j := 0; j < m.Cols; j++ {
-					product := new(big.Int).Mul(m.Values[i][j], v.Values[j])
-					product.Mod(product, m.Modulus)
-					sum.Add(sum, product)
-					sum.Mod(sum, m.Modulus)
-				}
-				result.Values[i] = sum
-			}
-		}(startRow, endRow)
+	result := NewMatrix(v.Length(), w.Length(), v.Modulus)
+	for i := range v.Values {
+		for j := range w.Values {
+			product := new(big.Int).Mul(v.Values[i], w.Values[j])
+			result.Values[i][j] = product.Mod(product, v.Modulus)
+		}
 	}
 
-	wg.Wait()
 	return result, nil
 }
 
 // MarshalBinary implements the encoding.BinaryMarshaler interface
 func (m *Matrix) MarshalBinary() ([]byte, error) {
-	// Calculate the size needed for serialization
-	elementSize := (m.Modulus.BitLen() + 7) / 8 // Number of bytes needed to represent each element
-	totalSize := 8 + m.Rows*m.Cols*elementSize  // 8 bytes for dimensions + space for elements
+	return m.AppendBinary(make([]byte, 0, m.EncodedSize()))
+}
 
-	// Create the buffer
-	buf := make([]byte, totalSize)
+// AppendBinary appends the MarshalBinary encoding of the matrix to dst
+func (m *Matrix) AppendBinary(dst []byte) ([]byte, error) {
+	elementSize := (m.Modulus.BitLen() + 7) / 8 // Number of bytes needed to represent each element
+	start := len(dst)
+	dst = slices.Grow(dst, m.EncodedSize())[:start+m.EncodedSize()]
+	buf := dst[start:]
+	clear(buf)
 
 	// Write the dimensions
 	binary.BigEndian.PutUint32(buf[:4], uint32(m.Rows))
 	binary.BigEndian.PutUint32(buf[4:8], uint32(m.Cols))
 
-	// Write each element
+	// Write each element, left-padded with zeros
 	for i := 0; i < m.Rows; i++ {
 		for j := 0; j < m.Cols; j++ {
 			index := i*m.Cols + j
 			offset := 8 + index*elementSize
-			valBytes := m.Values[i][j].Bytes()
-			// Pad with leading zeros if necessary
-			padding := elementSize - len(valBytes)
-			if padding < 0 {
+			val := m.Values[i][j]
+			if (val.BitLen()+7)/8 > elementSize {
 				return nil, fmt.Errorf("%w: element too large", ErrSerializationError)
 			}
-			copy(buf[offset+padding:offset+elementSize], valBytes)
+			val.FillBytes(buf[offset : offset+elementSize])
 		}
 	}
 
-	return buf, nil
+	return dst, nil
 }
 
 // UnmarshalBinary implements the encoding.BinaryUnmarshaler interface
@@ -489,6 +2323,331 @@ func (m *Matrix) EncodedSize() int {
 	return 8 + m.Rows*m.Cols*elementSize
 }
 
+// MarshalCSV encodes the matrix as CSV for export to other tools: one line per row, each
+// element a decimal integer. The modulus is not included.
+func (m *Matrix) MarshalCSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	record := make([]string, m.Cols)
+	for i := 0; i < m.Rows; i++ {
+		for j, val := range m.Values[i] {
+			record[j] = val.String()
+		}
+		if err := w.Write(record); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// MatrixFromCSV decodes a matrix written by MarshalCSV. Every line must have the same number
+// of fields; elements may be any decimal integer, including negative ones, and are reduced
+// modulo modulus.
+func MatrixFromCSV(data []byte, modulus *big.Int) (Matrix, error) {
+	if modulus == nil || modulus.Sign() <= 0 {
+		return Matrix{}, fmt.Errorf("%w: invalid modulus", ErrDeserializationError)
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return Matrix{}, fmt.Errorf("%w: %v", ErrDeserializationError, err)
+	}
+
+	rows, cols := len(records), 0
+	if rows > 0 {
+		cols = len(records[0])
+	}
+	m := NewMatrix(rows, cols, modulus)
+	for i, record := range records {
+		for j, field := range record {
+			if _, ok := m.Values[i][j].SetString(strings.TrimSpace(field), 10); !ok {
+				return Matrix{}, fmt.Errorf("%w: invalid element %q at (%d, %d)", ErrDeserializationError, field, i, j)
+			}
+			m.Values[i][j].Mod(m.Values[i][j], modulus)
+		}
+	}
+	return m, nil
+}
+
+// MarshalHex renders the matrix with one row per line, each in the format of
+// Vector.MarshalHex
+func (m *Matrix) MarshalHex() string {
+	var b strings.Builder
+	for i := 0; i < m.Rows; i++ {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		row := Vector{Values: m.Values[i], Modulus: m.Modulus}
+		b.WriteString(row.MarshalHex())
+	}
+	return b.String()
+}
+
+// MatrixFromHex decodes a rows x cols matrix from the output of Matrix.MarshalHex. Empty
+// lines are ignored.
+func MatrixFromHex(s string, rows, cols int, modulus *big.Int) (Matrix, error) {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if modulus == nil || modulus.Sign() <= 0 {
+		return Matrix{}, fmt.Errorf("%w: invalid modulus", ErrDeserializationError)
+	}
+	if rows < 0 || len(lines) != rows {
+		return Matrix{}, fmt.Errorf("%w: expected %d rows, got %d", ErrDeserializationError, rows, len(lines))
+	}
+
+	m := Matrix{Rows: rows, Cols: cols, Values: make([][]*big.Int, rows), Modulus: new(big.Int).Set(modulus)}
+	for i, line := range lines {
+		row, err := VectorFromHex(line, cols, modulus)
+		if err != nil {
+			return Matrix{}, fmt.Errorf("row %d: %w", i, err)
+		}
+		m.Values[i] = row.Values
+	}
+	return m, nil
+}
+
+// Compact encodings pack every element at exactly Modulus.BitLen() bits, most significant bit
+// first, instead of rounding each element up to a whole number of bytes. The bit width is
+// stored in the header so a decoder can reject data written under a different modulus.
+
+// compactSize returns the number of bytes needed to pack count elements of width bits
+func compactSize(count, width int) int {
+	return (count*width + 7) / 8
+}
+
+// checkedCompactSize is compactSize for a count and width read from an encoding; ok is false if
+// the size does not fit in an int
+func checkedCompactSize(count, width int) (size int, ok bool) {
+	if width > 0 && count > (math.MaxInt-7)/width {
+		return 0, false
+	}
+	return compactSize(count, width), true
+}
+
+// bitWriter packs values MSB-first into a byte slice
+type bitWriter struct {
+	buf  []byte
+	pos  int
+	acc  uint64
+	nacc uint
+}
+
+// writeBits writes the low n bits of v, with n at most 8
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	w.acc = w.acc<<n | v&(1<<n-1)
+	w.nacc += n
+	for w.nacc >= 8 {
+		w.nacc -= 8
+		w.buf[w.pos] = byte(w.acc >> w.nacc)
+		w.pos++
+	}
+}
+
+// flush writes any remaining bits, zero-padded on the right
+func (w *bitWriter) flush() {
+	if w.nacc > 0 {
+		w.buf[w.pos] = byte(w.acc << (8 - w.nacc))
+		w.pos++
+		w.nacc = 0
+	}
+}
+
+// bitReader unpacks values written by bitWriter
+type bitReader struct {
+	buf  []byte
+	pos  int
+	acc  uint64
+	nacc uint
+}
+
+// readBits reads n bits, with n at most 8
+func (r *bitReader) readBits(n uint) uint64 {
+	for r.nacc < n {
+		r.acc = r.acc<<8 | uint64(r.buf[r.pos])
+		r.pos++
+		r.nacc += 8
+	}
+	r.nacc -= n
+	return (r.acc >> r.nacc) & (1<<n - 1)
+}
+
+// writeCompactElement writes val as width bits, using scratch (width rounded up to bytes) as workspace
+func (w *bitWriter) writeCompactElement(val *big.Int, width int, scratch []byte) error {
+	if val.Sign() < 0 || val.BitLen() > width {
+		return fmt.Errorf("%w: element too large", ErrSerializationError)
+	}
+	if width == 0 {
+		return nil
+	}
+	val.FillBytes(scratch)
+	// The leading byte only carries the bits that do not fill a whole byte
+	w.writeBits(uint64(scratch[0]), uint(width-8*(len(scratch)-1)))
+	for _, b := range scratch[1:] {
+		w.writeBits(uint64(b), 8)
+	}
+	return nil
+}
+
+// readCompactElement reads a width-bit element, rejecting values that are not reduced modulo modulus
+func (r *bitReader) readCompactElement(width int, modulus *big.Int, scratch []byte) (*big.Int, error) {
+	if width == 0 {
+		return new(big.Int), nil
+	}
+	scratch[0] = byte(r.readBits(uint(width - 8*(len(scratch)-1))))
+	for i := 1; i < len(scratch); i++ {
+		scratch[i] = byte(r.readBits(8))
+	}
+	val := new(big.Int).SetBytes(scratch)
+	if val.Cmp(modulus) >= 0 {
+		return nil, fmt.Errorf("%w: element is not reduced modulo q", ErrDeserializationError)
+	}
+	return val, nil
+}
+
+// checkPadding reports an error if the bits left after the last element are not all zero
+func (r *bitReader) checkPadding() error {
+	if r.acc&(1<<r.nacc-1) != 0 {
+		return fmt.Errorf("%w: non-zero padding bits", ErrDeserializationError)
+	}
+	return nil
+}
+
+// CompactEncodedSize returns the size of the MarshalBinaryCompact encoding of the vector in bytes
+func (v *Vector) CompactEncodedSize() int {
+	return 6 + compactSize(v.Length(), v.Modulus.BitLen())
+}
+
+// MarshalBinaryCompact encodes the vector as length (4 bytes) | bit width (2 bytes) | packed elements
+func (v *Vector) MarshalBinaryCompact() ([]byte, error) {
+	width := v.Modulus.BitLen()
+	buf := make([]byte, v.CompactEncodedSize())
+	binary.BigEndian.PutUint32(buf[:4], uint32(v.Length()))
+	binary.BigEndian.PutUint16(buf[4:6], uint16(width))
+
+	w := bitWriter{buf: buf[6:]}
+	scratch := make([]byte, (width+7)/8)
+	for _, val := range v.Values {
+		if err := w.writeCompactElement(val, width, scratch); err != nil {
+			return nil, err
+		}
+	}
+	w.flush()
+
+	return buf, nil
+}
+
+// UnmarshalBinaryCompact decodes data produced by MarshalBinaryCompact. The receiver's
+// Modulus must be set and have the bit width recorded in the header. Only the canonical
+// encoding is accepted: exactly the packed size, every element below the modulus and zero
+// padding bits.
+func (v *Vector) UnmarshalBinaryCompact(data []byte) error {
+	if len(data) < 6 {
+		return fmt.Errorf("%w: data too short", ErrDeserializationError)
+	}
+
+	length := int(binary.BigEndian.Uint32(data[:4]))
+	width := int(binary.BigEndian.Uint16(data[4:6]))
+	if width != v.Modulus.BitLen() {
+		return fmt.Errorf("%w: bit width %d does not match modulus (%d bits)", ErrDeserializationError, width, v.Modulus.BitLen())
+	}
+	if size, ok := checkedCompactSize(length, width); !ok || len(data)-6 != size {
+		return fmt.Errorf("%w: data is not the size of the specified length", ErrDeserializationError)
+	}
+
+	values := make([]*big.Int, length)
+	r := bitReader{buf: data[6:]}
+	scratch := make([]byte, (width+7)/8)
+	for i := range values {
+		val, err := r.readCompactElement(width, v.Modulus, scratch)
+		if err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+		values[i] = val
+	}
+	if err := r.checkPadding(); err != nil {
+		return err
+	}
+
+	v.Values = values
+	return nil
+}
+
+// CompactEncodedSize returns the size of the MarshalBinaryCompact encoding of the matrix in bytes
+func (m *Matrix) CompactEncodedSize() int {
+	return 10 + compactSize(m.Rows*m.Cols, m.Modulus.BitLen())
+}
+
+// MarshalBinaryCompact encodes the matrix as rows (4 bytes) | cols (4 bytes) | bit width (2 bytes) | packed elements in row-major order
+func (m *Matrix) MarshalBinaryCompact() ([]byte, error) {
+	width := m.Modulus.BitLen()
+	buf := make([]byte, m.CompactEncodedSize())
+	binary.BigEndian.PutUint32(buf[:4], uint32(m.Rows))
+	binary.BigEndian.PutUint32(buf[4:8], uint32(m.Cols))
+	binary.BigEndian.PutUint16(buf[8:10], uint16(width))
+
+	w := bitWriter{buf: buf[10:]}
+	scratch := make([]byte, (width+7)/8)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			if err := w.writeCompactElement(m.Values[i][j], width, scratch); err != nil {
+				return nil, err
+			}
+		}
+	}
+	w.flush()
+
+	return buf, nil
+}
+
+// UnmarshalBinaryCompact decodes data produced by MarshalBinaryCompact. The receiver's
+// Modulus must be set and have the bit width recorded in the header. Like the vector decoder it
+// only accepts the canonical encoding.
+func (m *Matrix) UnmarshalBinaryCompact(data []byte) error {
+	if len(data) < 10 {
+		return fmt.Errorf("%w: data too short", ErrDeserializationError)
+	}
+
+	rows := int(binary.BigEndian.Uint32(data[:4]))
+	cols := int(binary.BigEndian.Uint32(data[4:8]))
+	width := int(binary.BigEndian.Uint16(data[8:10]))
+	if width != m.Modulus.BitLen() {
+		return fmt.Errorf("%w: bit width %d does not match modulus (%d bits)", ErrDeserializationError, width, m.Modulus.BitLen())
+	}
+	// rows and cols come from the input, so their product can overflow
+	if cols != 0 && rows > math.MaxInt/cols {
+		return fmt.Errorf("%w: %dx%d matrix is too large", ErrDeserializationError, rows, cols)
+	}
+	if size, ok := checkedCompactSize(rows*cols, width); !ok || len(data)-10 != size {
+		return fmt.Errorf("%w: data is not the size of the specified dimensions", ErrDeserializationError)
+	}
+
+	decoded := NewMatrix(rows, cols, m.Modulus)
+	r := bitReader{buf: data[10:]}
+	scratch := make([]byte, (width+7)/8)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			val, err := r.readCompactElement(width, m.Modulus, scratch)
+			if err != nil {
+				return fmt.Errorf("element (%d, %d): %w", i, j, err)
+			}
+			decoded.Values[i][j] = val
+		}
+	}
+	if err := r.checkPadding(); err != nil {
+		return err
+	}
+
+	*m = decoded
+	return nil
+}
+
 // GenerateRandomMatrix creates a new matrix filled with random Values
 func GenerateRandomMatrix(rows, cols int, modulus *big.Int, randSource io.Reader) (Matrix, error) {
 	result := NewMatrix(rows, cols, modulus)
@@ -521,8 +2680,61 @@ func GenerateRandomVector(length int, modulus *big.Int, randSource io.Reader) (*
 	return result, nil
 }
 
+// MinRingDegree is the smallest ring degree accepted by CheckRingParameters
+const MinRingDegree = 16
+
+// MaxModulusBits bounds the modulus: lattigo's NTT requires q < 2^61
+const MaxModulusBits = 61
+
+// CheckRingParameters reports whether degree and modulus define a ring lattigo can build with
+// an NTT: the degree must be a power of two no smaller than MinRingDegree and the modulus must be
+// below 2^MaxModulusBits and congruent to 1 mod 2*degree. The error names the violated condition.
+func CheckRingParameters(degree int, modulus *big.Int) error {
+	if degree < MinRingDegree {
+		return fmt.Errorf("%w: degree %d is less than %d", ErrInvalidRing, degree, MinRingDegree)
+	}
+	if degree&(degree-1) != 0 {
+		return fmt.Errorf("%w: degree %d is not a power of two", ErrInvalidRing, degree)
+	}
+	if modulus == nil || modulus.Sign() <= 0 {
+		return fmt.Errorf("%w: modulus must be positive", ErrInvalidRing)
+	}
+	if modulus.BitLen() > MaxModulusBits {
+		return fmt.Errorf("%w: modulus has %d bits, must be below 2^%d", ErrInvalidRing, modulus.BitLen(), MaxModulusBits)
+	}
+	twoN := big.NewInt(2 * int64(degree))
+	if new(big.Int).Mod(modulus, twoN).Cmp(big.NewInt(1)) != 0 {
+		return fmt.Errorf("%w: modulus %s is not 1 mod 2*degree (%s)", ErrInvalidRing, modulus, twoN)
+	}
+	return nil
+}
+
+// GenerateSampleDVector samples a length-coefficient discrete Gaussian vector expanded from rho.
+// length and modulus are checked with CheckRingParameters before the ring is built.
+// rho keys the PRNG byte for byte, so it must be a fixed-width seed: deriving it with
+// big.Int.Bytes, which drops leading zeros, would make equal seeds of different widths collide
+// and distinct encodings of one seed diverge.
 func GenerateSampleDVector(length int, alpha_ float64, rho []byte, modulus *big.Int) (*Vector, error) {
-	result := NewVector(length, modulus)
+	if err := CheckRingParameters(length, modulus); err != nil {
+		return nil, err
+	}
+	newRing, err := ring.NewRing(length, []uint64{modulus.Uint64()})
+	if err != nil {
+		return nil, err
+	}
+	return GenerateSampleDVectorWithRing(newRing, alpha_, rho, modulus)
+}
+
+// GenerateSampleDVectorWithRing is GenerateSampleDVector over an already constructed ring of degree length.
+// A nil ring or a non-positive modulus is rejected with ErrInvalidRing rather than panicking.
+func GenerateSampleDVectorWithRing(newRing *ring.Ring, alpha_ float64, rho []byte, modulus *big.Int) (*Vector, error) {
+	if newRing == nil {
+		return nil, fmt.Errorf("%w: nil ring", ErrInvalidRing)
+	}
+	if modulus == nil || modulus.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: modulus must be positive", ErrInvalidRing)
+	}
+	result := NewVector(newRing.N(), modulus)
 	p := modulus
 	pFloat, _ := p.Float64()
 	d := ring.DiscreteGaussian{Sigma: alpha_, Bound: pFloat}
@@ -530,10 +2742,6 @@ func GenerateSampleDVector(length int, alpha_ float64, rho []byte, modulus *big.
 	if err != nil {
 		return nil, err
 	}
-	newRing, err := ring.NewRing(length, []uint64{modulus.Uint64()})
-	if err != nil {
-		return nil, err
-	}
 	sampler, err := ring.NewSampler(prng, newRing, d, false)
 	if err != nil {
 		return nil, err
@@ -543,6 +2751,64 @@ func GenerateSampleDVector(length int, alpha_ float64, rho []byte, modulus *big.
 	return result, nil
 }
 
+// SampleDVector is GenerateSampleDVector when length and modulus define a ring lattigo can
+// build. Otherwise, for instance when length is not a power of two, it falls back to
+// NewGaussianVector keyed with rho, which serves any modulus but yields different samples.
+func SampleDVector(length int, sigma float64, rho []byte, modulus *big.Int) (*Vector, error) {
+	if CheckRingParameters(length, modulus) == nil {
+		return GenerateSampleDVector(length, sigma, rho, modulus)
+	}
+	return NewGaussianVector(length, sigma, rho, modulus)
+}
+
+// gaussianSamplerDegree and gaussianSamplerModulus define the ring that drives NewGaussianVector.
+// The modulus is an NTT-friendly prime for this degree, so any target modulus can be served:
+// samples are lifted to their centered representatives before being reduced.
+const (
+	gaussianSamplerDegree         = 4096
+	gaussianSamplerModulus uint64 = 2305843009213317121
+)
+
+// NewGaussianVector samples length elements from the discrete Gaussian with standard deviation
+// sigma centered at zero, reduced modulo modulus. The PRNG is keyed with seed, so equal
+// arguments always produce the same vector.
+func NewGaussianVector(length int, sigma float64, seed []byte, modulus *big.Int) (*Vector, error) {
+	if length < 0 || !(sigma > 0) || modulus == nil || modulus.Cmp(big.NewInt(1)) <= 0 {
+		return nil, fmt.Errorf("%w: invalid Gaussian vector arguments", ErrInvalidDimensions)
+	}
+
+	samplerRing, err := ring.NewRing(gaussianSamplerDegree, []uint64{gaussianSamplerModulus})
+	if err != nil {
+		return nil, err
+	}
+	prng, err := sampling.NewKeyedPRNG(seed)
+	if err != nil {
+		return nil, err
+	}
+	// Cut the tail where samples would wrap around either modulus
+	bound := float64(gaussianSamplerModulus / 2)
+	if b, _ := new(big.Float).SetInt(new(big.Int).Rsh(modulus, 1)).Float64(); b < bound {
+		bound = b
+	}
+	sampler, err := ring.NewSampler(prng, samplerRing, ring.DiscreteGaussian{Sigma: sigma, Bound: bound}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result := NewVector(length, modulus)
+	samplerQ := new(big.Int).SetUint64(gaussianSamplerModulus)
+	coeffs := make([]*big.Int, gaussianSamplerDegree)
+	for start := 0; start < length; start += gaussianSamplerDegree {
+		samplerRing.PolyToBigint(sampler.ReadNew(), 1, coeffs)
+		for i := start; i < min(length, start+gaussianSamplerDegree); i++ {
+			c := centered(coeffs[i-start], samplerQ)
+			result.Values[i] = c.Mod(c, modulus)
+		}
+	}
+
+	return result, nil
+}
+
 // rand generates a random value in the range [0, Modulus-1]
 func rand(randSource io.Reader, modulus *big.Int) (*big.Int, error) {
 	// The number of bytes needed to represent numbers up to Modulus
@@ -571,7 +2837,7 @@ func rand(randSource io.Reader, modulus *big.Int) (*big.Int, error) {
 	}
 }
 
-// MarshalVectorSlice marshals a slice of vectors
+// MarshalVectorSlice marshals a slice of vectors sharing a modulus, tagged with that modulus
 func MarshalVectorSlice(vectors []*Vector) ([]byte, error) {
 	if len(vectors) == 0 {
 		return []byte{0, 0, 0, 0}, nil
@@ -582,14 +2848,14 @@ func MarshalVectorSlice(vectors []*Vector) ([]byte, error) {
 	modulus := vectors[0].Modulus
 
 	// Calculate the size needed for serialization
-	elementSize := (modulus.BitLen() + 7) / 8        // Number of bytes needed to represent each element
-	totalSize := 8 + len(vectors)*vecLen*elementSize // 8 bytes for dimensions + space for elements
+	elementSize := (modulus.BitLen() + 7) / 8                         // Number of bytes needed to represent each element
+	totalSize := 8 + modulusTagSize + len(vectors)*vecLen*elementSize // dimensions, tag and elements
 
 	// Create the buffer
 	buf := bytes.NewBuffer(make([]byte, 0, totalSize))
 
-	// Write the dimensions
-	err := binary.Write(buf, binary.BigEndian, uint32(len(vectors)))
+	// Write the dimensions, flagging the count to announce the modulus tag
+	err := binary.Write(buf, binary.BigEndian, uint32(len(vectors))|modulusTagFlag)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
 	}
@@ -598,6 +2864,7 @@ func MarshalVectorSlice(vectors []*Vector) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
 	}
+	buf.Write(modulusTag(modulus))
 
 	// Write each vector
 	for _, vec := range vectors {
@@ -627,21 +2894,41 @@ func MarshalVectorSlice(vectors []*Vector) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// UnmarshalVectorSlice unmarshals a slice of vectors
+// UnmarshalVectorSlice unmarshals a slice of vectors, checking the modulus tag when present and
+// otherwise the size and range of the untagged elements
 func UnmarshalVectorSlice(data []byte, modulus *big.Int) ([]*Vector, error) {
 	if len(data) < 8 {
 		return nil, fmt.Errorf("%w: data too short", ErrDeserializationError)
 	}
 
-	// Read the dimensions
-	numVecs := int(binary.BigEndian.Uint32(data[:4]))
+	// Read the dimensions; a flagged count is followed by the modulus tag
+	header := binary.BigEndian.Uint32(data[:4])
+	numVecs := int(header &^ modulusTagFlag)
 	vecLen := int(binary.BigEndian.Uint32(data[4:8]))
+	data = data[8:]
+	if header&modulusTagFlag != 0 {
+		if len(data) < modulusTagSize {
+			return nil, fmt.Errorf("%w: data too short", ErrDeserializationError)
+		}
+		if err := checkModulusTag(data[:modulusTagSize], modulus); err != nil {
+			return nil, err
+		}
+		data = data[modulusTagSize:]
+	} else {
+		// Untagged input must fit modulus exactly, as in Vector.UnmarshalBinary
+		if numVecs > 0 && vecLen > math.MaxInt/numVecs {
+			return nil, fmt.Errorf("%w: %dx%d vector slice is too large", ErrDeserializationError, numVecs, vecLen)
+		}
+		if err := checkUntaggedElements(numVecs*vecLen, data, modulus); err != nil {
+			return nil, err
+		}
+	}
 
 	// Calculate element size
 	elementSize := (modulus.BitLen() + 7) / 8
 
 	// Verify that the buffer is large enough
-	if len(data) < 8+numVecs*vecLen*elementSize {
+	if len(data) < numVecs*vecLen*elementSize {
 		return nil, fmt.Errorf("%w: data too short for specified dimensions", ErrDeserializationError)
 	}
 
@@ -654,7 +2941,7 @@ func UnmarshalVectorSlice(data []byte, modulus *big.Int) ([]*Vector, error) {
 
 		for j := 0; j < vecLen; j++ {
 			index := i*vecLen + j
-			offset := 8 + index*elementSize
+			offset := index * elementSize
 			result[i].Values[j] = new(big.Int).SetBytes(data[offset : offset+elementSize])
 			result[i].Values[j].Mod(result[i].Values[j], modulus)
 		}
@@ -663,6 +2950,61 @@ func UnmarshalVectorSlice(data []byte, modulus *big.Int) ([]*Vector, error) {
 	return result, nil
 }
 
+// NewVectorFromPoly returns the coefficients of p as a vector of length r.N(). r must be a ring
+// modulo modulus.
+func NewVectorFromPoly(p ring.Poly, r *ring.Ring, modulus *big.Int) (*Vector, error) {
+	if r.Modulus().Cmp(modulus) != 0 {
+		return nil, fmt.Errorf("%w: ring modulus differs from %v", ErrInvalidDimensions, modulus)
+	}
+	if p.N() != r.N() {
+		return nil, fmt.Errorf("%w: polynomial has degree %d, expected %d", ErrInvalidDimensions, p.N(), r.N())
+	}
+	v := NewVector(r.N(), modulus)
+	r.PolyToBigint(p, 1, v.Values)
+	return v, nil
+}
+
+// ToPoly converts v into a polynomial of r, the inverse of NewVectorFromPoly. r must have
+// degree v.Length() and modulus v.Modulus.
+func (v *Vector) ToPoly(r *ring.Ring) (ring.Poly, error) {
+	if v.Length() != r.N() || r.Modulus().Cmp(v.Modulus) != 0 {
+		return ring.Poly{}, ErrInvalidDimensions
+	}
+	p := r.NewPoly()
+	r.SetCoefficientsBigint(v.Values, p)
+	return p, nil
+}
+
+// NewMatrixFromPolyVec returns the matrix whose row i holds the coefficients of pv[i]. r must be a
+// ring modulo modulus; the matrix has r.N() columns.
+func NewMatrixFromPolyVec(pv []ring.Poly, r *ring.Ring, modulus *big.Int) (Matrix, error) {
+	if r.Modulus().Cmp(modulus) != 0 {
+		return Matrix{}, fmt.Errorf("%w: ring modulus differs from %v", ErrInvalidDimensions, modulus)
+	}
+	m := NewMatrix(len(pv), r.N(), modulus)
+	for i := range pv {
+		if pv[i].N() != r.N() {
+			return Matrix{}, fmt.Errorf("%w: polynomial %d has degree %d, expected %d", ErrInvalidDimensions, i, pv[i].N(), r.N())
+		}
+		r.PolyToBigint(pv[i], 1, m.Values[i])
+	}
+	return m, nil
+}
+
+// ToPolyVec converts each row of m into a polynomial of r, the inverse of NewMatrixFromPolyVec.
+// r must have degree m.Cols and modulus m.Modulus.
+func (m *Matrix) ToPolyVec(r *ring.Ring) ([]ring.Poly, error) {
+	if m.Cols != r.N() || r.Modulus().Cmp(m.Modulus) != 0 {
+		return nil, ErrInvalidDimensions
+	}
+	pv := make([]ring.Poly, m.Rows)
+	for i := range pv {
+		pv[i] = r.NewPoly()
+		r.SetCoefficientsBigint(m.Values[i], pv[i])
+	}
+	return pv, nil
+}
+
 func InitPolyVecWithSampler(n int, sampler ring.Sampler) []ring.Poly {
 	polyVec := make([]ring.Poly, n)
 	for i := range n {
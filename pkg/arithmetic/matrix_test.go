@@ -0,0 +1,2732 @@
+package arithmetic
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v6/ring"
+)
+
+func identityMatrix(n int, modulus *big.Int) Matrix {
+	m := NewMatrix(n, n, modulus)
+	for i := 0; i < n; i++ {
+		m.Values[i][i] = big.NewInt(1)
+	}
+	return m
+}
+
+func TestMatrixIsSymmetric(t *testing.T) {
+	modulus := big.NewInt(17)
+
+	id := identityMatrix(5, modulus)
+	if !id.IsSymmetric() {
+		t.Fatalf("identity matrix should be symmetric")
+	}
+
+	// Build a random symmetric matrix by mirroring the upper triangle
+	sym, err := GenerateRandomMatrix(6, 6, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+	for i := 0; i < sym.Rows; i++ {
+		for j := 0; j < i; j++ {
+			sym.Values[i][j] = new(big.Int).Set(sym.Values[j][i])
+		}
+	}
+	if !sym.IsSymmetric() {
+		t.Fatalf("mirrored matrix should be symmetric")
+	}
+
+	sym.Set(0, 1, new(big.Int).Add(sym.Values[1][0], big.NewInt(1)))
+	if sym.IsSymmetric() {
+		t.Fatalf("perturbed matrix should not be symmetric")
+	}
+
+	rect := NewMatrix(2, 3, modulus)
+	if rect.IsSymmetric() {
+		t.Fatalf("non-square matrix should not be symmetric")
+	}
+}
+
+func TestMatrixIsNilOrEmptyGuards(t *testing.T) {
+	modulus := big.NewInt(17)
+	empty := Matrix{Modulus: modulus}
+	if !empty.IsNilOrEmpty() {
+		t.Fatalf("zero-value matrix should be empty")
+	}
+	noCols := NewMatrix(3, 0, modulus)
+	if !noCols.IsNilOrEmpty() {
+		t.Fatalf("matrix without columns should be empty")
+	}
+	full := NewMatrix(2, 2, modulus)
+	if full.IsNilOrEmpty() {
+		t.Fatalf("2x2 matrix should not be empty")
+	}
+
+	if _, err := empty.Transpose(); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Transpose on empty matrix: got %v, want ErrInvalidDimensions", err)
+	}
+	if _, err := empty.Multiply(full); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Multiply on empty matrix: got %v, want ErrInvalidDimensions", err)
+	}
+	if _, err := full.Multiply(empty); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Multiply by empty matrix: got %v, want ErrInvalidDimensions", err)
+	}
+	if _, err := empty.MultiplyVector(NewVector(0, modulus)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("MultiplyVector on empty matrix: got %v, want ErrInvalidDimensions", err)
+	}
+	if _, err := full.MultiplyVector(nil); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("MultiplyVector with nil vector: got %v, want ErrInvalidDimensions", err)
+	}
+}
+
+func TestCheckedConstructors(t *testing.T) {
+	q := big.NewInt(97)
+	if v, err := NewVectorChecked(3, q); err != nil || !v.Equal(NewVector(3, q)) {
+		t.Fatalf("NewVectorChecked(3, 97) = %v, %v", v, err)
+	}
+	if m, err := NewMatrixChecked(2, 3, q); err != nil || !m.Equal(NewMatrix(2, 3, q)) {
+		t.Fatalf("NewMatrixChecked(2, 3, 97) = %v, %v", m, err)
+	}
+
+	vectors := []struct {
+		name    string
+		length  int
+		modulus *big.Int
+	}{
+		{"zero length", 0, q},
+		{"negative length", -1, q},
+		{"nil modulus", 3, nil},
+		{"modulus one", 3, big.NewInt(1)},
+		{"negative modulus", 3, big.NewInt(-97)},
+	}
+	for _, tt := range vectors {
+		if _, err := NewVectorChecked(tt.length, tt.modulus); !errors.Is(err, ErrInvalidDimensions) {
+			t.Errorf("NewVectorChecked with %s: got %v", tt.name, err)
+		}
+	}
+
+	matrices := []struct {
+		name       string
+		rows, cols int
+		modulus    *big.Int
+	}{
+		{"zero rows", 0, 5, q},
+		{"zero cols", 5, 0, q},
+		{"negative rows", -2, 2, q},
+		{"negative cols", 2, -2, q},
+		{"nil modulus", 2, 2, nil},
+		{"modulus zero", 2, 2, big.NewInt(0)},
+	}
+	for _, tt := range matrices {
+		if _, err := NewMatrixChecked(tt.rows, tt.cols, tt.modulus); !errors.Is(err, ErrInvalidDimensions) {
+			t.Errorf("NewMatrixChecked with %s: got %v", tt.name, err)
+		}
+	}
+}
+
+func TestOuterProduct(t *testing.T) {
+	modulus := big.NewInt(17)
+	const rows, cols = 3, 4
+
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			ei := NewVector(rows, modulus)
+			ei.Set(i, big.NewInt(1))
+			ej := NewVector(cols, modulus)
+			ej.Set(j, big.NewInt(1))
+
+			outer, err := OuterProduct(ei, ej)
+			if err != nil {
+				t.Fatalf("OuterProduct failed: %v", err)
+			}
+			if outer.Rows != rows || outer.Cols != cols {
+				t.Fatalf("unexpected shape %dx%d", outer.Rows, outer.Cols)
+			}
+			for r := 0; r < rows; r++ {
+				for c := 0; c < cols; c++ {
+					want := int64(0)
+					if r == i && c == j {
+						want = 1
+					}
+					if outer.Values[r][c].Int64() != want {
+						t.Fatalf("e_%d ⊗ e_%d at (%d,%d): got %v, want %d", i, j, r, c, outer.Values[r][c], want)
+					}
+				}
+			}
+		}
+	}
+
+	v, err := GenerateRandomVector(rows, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomVector failed: %v", err)
+	}
+	ones := NewVector(cols, modulus)
+	for j := 0; j < cols; j++ {
+		ones.Set(j, big.NewInt(1))
+	}
+	outer, err := OuterProduct(v, ones)
+	if err != nil {
+		t.Fatalf("OuterProduct failed: %v", err)
+	}
+	want, _ := v.ScalarMultiply(big.NewInt(cols))
+	if !outer.RowSums().Equal(want) {
+		t.Fatalf("RowSums of v ⊗ 1 should equal v scaled by %d", cols)
+	}
+
+	if _, err := OuterProduct(v, NewVector(cols, big.NewInt(19))); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("OuterProduct with mismatched moduli: got %v", err)
+	}
+}
+
+// naiveMultiply is the textbook triple loop used as a reference for the tiled implementation
+func naiveMultiply(a, b Matrix) Matrix {
+	result := NewMatrix(a.Rows, b.Cols, a.Modulus)
+	for i := 0; i < a.Rows; i++ {
+		for j := 0; j < b.Cols; j++ {
+			sum := new(big.Int)
+			for k := 0; k < a.Cols; k++ {
+				sum.Add(sum, new(big.Int).Mul(a.Values[i][k], b.Values[k][j]))
+			}
+			result.Values[i][j] = sum.Mod(sum, a.Modulus)
+		}
+	}
+	return result
+}
+
+func TestMatrixMultiplyMatchesNaive(t *testing.T) {
+	modulus := big.NewInt(1<<31 - 1)
+	shapes := [][3]int{
+		{1, 1, 1}, {1, 7, 1}, {7, 1, 7}, {1, 40, 3}, {40, 1, 5},
+		{5, 3, 1}, {33, 65, 17}, {64, 64, 64}, {70, 31, 45},
+	}
+
+	oldBlock := MultiplyBlockSize
+	defer func() { MultiplyBlockSize = oldBlock }()
+
+	for _, block := range []int{1, 4, 32} {
+		MultiplyBlockSize = block
+		for _, shape := range shapes {
+			a, err := GenerateRandomMatrix(shape[0], shape[1], modulus, crand.Reader)
+			if err != nil {
+				t.Fatalf("GenerateRandomMatrix failed: %v", err)
+			}
+			b, err := GenerateRandomMatrix(shape[1], shape[2], modulus, crand.Reader)
+			if err != nil {
+				t.Fatalf("GenerateRandomMatrix failed: %v", err)
+			}
+			want := naiveMultiply(a, b)
+
+			got, err := a.Multiply(b)
+			if err != nil {
+				t.Fatalf("Multiply failed: %v", err)
+			}
+			if !got.Equal(want) {
+				t.Fatalf("block %d shape %v: Multiply differs from naive result", block, shape)
+			}
+
+			for _, workers := range []int{0, 1, 3} {
+				got, err := a.MultiplyParallel(b, workers)
+				if err != nil {
+					t.Fatalf("MultiplyParallel failed: %v", err)
+				}
+				if !got.Equal(want) {
+					t.Fatalf("block %d shape %v workers %d: MultiplyParallel differs from naive result", block, shape, workers)
+				}
+			}
+		}
+	}
+
+	a := NewMatrix(2, 3, modulus)
+	if _, err := a.MultiplyParallel(NewMatrix(2, 3, modulus), 2); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("MultiplyParallel with mismatched shapes: got %v", err)
+	}
+}
+
+func TestMatrixMultiplyAdd(t *testing.T) {
+	modulus := big.NewInt(17)
+	a, _ := GenerateRandomMatrix(3, 5, modulus, crand.Reader)
+	b, _ := GenerateRandomMatrix(5, 4, modulus, crand.Reader)
+	c, _ := GenerateRandomMatrix(3, 4, modulus, crand.Reader)
+
+	got, err := a.MultiplyAdd(b, c)
+	if err != nil {
+		t.Fatalf("MultiplyAdd failed: %v", err)
+	}
+	want, _ := a.Multiply(b)
+	for i := 0; i < want.Rows; i++ {
+		for j := 0; j < want.Cols; j++ {
+			want.Values[i][j].Add(want.Values[i][j], c.Values[i][j])
+			want.Values[i][j].Mod(want.Values[i][j], modulus)
+		}
+	}
+	if !got.Equal(want) {
+		t.Fatalf("MultiplyAdd differs from Multiply followed by addition")
+	}
+
+	before := c.Clone()
+	a.MultiplyAdd(b, c)
+	if !c.Equal(before) {
+		t.Fatalf("MultiplyAdd modified the addend")
+	}
+
+	if _, err := a.MultiplyAdd(c, c); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("incompatible factors: got %v", err)
+	}
+	if _, err := a.MultiplyAdd(b, NewMatrix(4, 3, modulus)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("incompatible addend: got %v", err)
+	}
+}
+
+// BenchmarkMatrixMultiplyAdd compares MultiplyAdd with Multiply followed by an element-wise
+// addition into a fresh matrix; run with -benchmem to see the saved allocation
+func BenchmarkMatrixMultiplyAdd(b *testing.B) {
+	modulus := new(big.Int).Lsh(big.NewInt(1), 61)
+	modulus.Sub(modulus, big.NewInt(1))
+	const n, lambda = 128, 16
+
+	x, _ := GenerateRandomMatrix(lambda, n, modulus, crand.Reader)
+	y, _ := GenerateRandomMatrix(n, lambda, modulus, crand.Reader)
+	z, _ := GenerateRandomMatrix(lambda, lambda, modulus, crand.Reader)
+
+	b.Run("MultiplyThenAdd", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			product, _ := x.Multiply(y)
+			sum := NewMatrix(product.Rows, product.Cols, modulus)
+			for r := range sum.Values {
+				for c := range sum.Values[r] {
+					sum.Values[r][c].Add(product.Values[r][c], z.Values[r][c])
+					sum.Values[r][c].Mod(sum.Values[r][c], modulus)
+				}
+			}
+		}
+	})
+	b.Run("MultiplyAdd", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := x.MultiplyAdd(y, z); err != nil {
+				b.Fatalf("MultiplyAdd failed: %v", err)
+			}
+		}
+	})
+}
+
+// BenchmarkMatrixMultiply multiplies lambda x n by n x lambda matrices using the Security64 sizes (n = 512, lambda = 64)
+func BenchmarkMatrixMultiply(b *testing.B) {
+	modulus := new(big.Int).Lsh(big.NewInt(1), 61)
+	modulus.Sub(modulus, big.NewInt(1))
+	const n, lambda = 512, 64
+
+	x, err := GenerateRandomMatrix(lambda, n, modulus, crand.Reader)
+	if err != nil {
+		b.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+	y, err := GenerateRandomMatrix(n, lambda, modulus, crand.Reader)
+	if err != nil {
+		b.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+
+	b.Run("Naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			naiveMultiply(x, y)
+		}
+	})
+	b.Run("Tiled", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := x.Multiply(y); err != nil {
+				b.Fatalf("Multiply failed: %v", err)
+			}
+		}
+	})
+	b.Run("Parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := x.MultiplyParallel(y, 0); err != nil {
+				b.Fatalf("MultiplyParallel failed: %v", err)
+			}
+		}
+	})
+}
+
+func TestVectorL2NormSquared(t *testing.T) {
+	modulus := big.NewInt(17)
+	v := NewVector(5, modulus)
+	for i, x := range []int64{1, 16, 8, 9, 0} {
+		v.Set(i, big.NewInt(x))
+	}
+
+	// Centered representatives are 1, 1, 8, 8, 0, so the norm is 1 + 1 + 64 + 64 = 130 (not reduced mod 17)
+	if got := v.L2NormSquared(); got.Int64() != 130 {
+		t.Fatalf("L2NormSquared: got %v, want 130", got)
+	}
+	if got := NewVector(3, modulus).L2NormSquared(); got.Sign() != 0 {
+		t.Fatalf("L2NormSquared of zero vector: got %v, want 0", got)
+	}
+}
+
+func TestMatrixRowColNorms(t *testing.T) {
+	modulus := big.NewInt(97)
+	m, err := GenerateRandomMatrix(5, 7, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+
+	rowNorms := m.RowNorms()
+	if rowNorms.Length() != m.Rows {
+		t.Fatalf("RowNorms length: got %d, want %d", rowNorms.Length(), m.Rows)
+	}
+	for i := 0; i < m.Rows; i++ {
+		if want := m.Row(i).L2NormSquared(); rowNorms.Values[i].Cmp(want) != 0 {
+			t.Fatalf("RowNorms[%d]: got %v, want %v", i, rowNorms.Values[i], want)
+		}
+	}
+
+	colNorms := m.ColNorms()
+	if colNorms.Length() != m.Cols {
+		t.Fatalf("ColNorms length: got %d, want %d", colNorms.Length(), m.Cols)
+	}
+	for j := 0; j < m.Cols; j++ {
+		if want := m.Col(j).L2NormSquared(); colNorms.Values[j].Cmp(want) != 0 {
+			t.Fatalf("ColNorms[%d]: got %v, want %v", j, colNorms.Values[j], want)
+		}
+	}
+}
+
+func TestVectorStatistics(t *testing.T) {
+	modulus := big.NewInt(101)
+	v, err := GenerateRandomVector(64, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomVector failed: %v", err)
+	}
+
+	minC, maxC, l2sq := v.Statistics()
+	if minC.Cmp(v.Min()) != 0 {
+		t.Fatalf("Statistics min %v differs from Min %v", minC, v.Min())
+	}
+	if maxC.Cmp(v.Max()) != 0 {
+		t.Fatalf("Statistics max %v differs from Max %v", maxC, v.Max())
+	}
+	if l2sq.Cmp(v.L2NormSquared()) != 0 {
+		t.Fatalf("Statistics l2sq %v differs from L2NormSquared %v", l2sq, v.L2NormSquared())
+	}
+
+	small := NewVector(3, modulus)
+	for i, x := range []int64{3, 100, 50} {
+		small.Set(i, big.NewInt(x))
+	}
+	minC, maxC, l2sq = small.Statistics()
+	if minC.Int64() != -1 || maxC.Int64() != 50 || l2sq.Int64() != 9+1+2500 {
+		t.Fatalf("Statistics of [3, -1, 50]: got (%v, %v, %v)", minC, maxC, l2sq)
+	}
+}
+
+func TestVectorMeanVariance(t *testing.T) {
+	modulus := big.NewInt(101)
+	ratio := func(num, den *big.Int) string { return num.String() + "/" + den.String() }
+
+	// n copies of c, including a c that centers to a negative value
+	for _, c := range []int64{7, 90} {
+		v := NewVector(10, modulus)
+		v.Fill(big.NewInt(c))
+		want := centered(big.NewInt(c), modulus).String() + "/1"
+		if got := ratio(v.Mean()); got != want {
+			t.Errorf("Mean of 10 copies of %d = %s, want %s", c, got, want)
+		}
+		if got := ratio(v.Variance()); got != "0/1" {
+			t.Errorf("Variance of 10 copies of %d = %s, want 0/1", c, got)
+		}
+	}
+
+	// [c, Q-c, c, Q-c, ...] is symmetric about zero, so every deviation is c
+	symmetric := NewVector(8, modulus)
+	for i := range symmetric.Values {
+		if i%2 == 0 {
+			symmetric.Set(i, big.NewInt(12))
+		} else {
+			symmetric.Set(i, big.NewInt(101-12))
+		}
+	}
+	if got := ratio(symmetric.Mean()); got != "0/1" {
+		t.Errorf("Mean of a symmetric vector = %s, want 0/1", got)
+	}
+	if got := ratio(symmetric.Variance()); got != "144/1" {
+		t.Errorf("Variance of a symmetric vector = %s, want 144/1", got)
+	}
+
+	// [1, 2, -1]: mean 2/3, variance ((1/3)^2 + (4/3)^2 + (5/3)^2) / 3 = 14/9
+	small := NewVector(3, modulus)
+	for i, x := range []int64{1, 2, 100} {
+		small.Set(i, big.NewInt(x))
+	}
+	if got := ratio(small.Mean()); got != "2/3" {
+		t.Errorf("Mean of [1, 2, -1] = %s, want 2/3", got)
+	}
+	if got := ratio(small.Variance()); got != "14/9" {
+		t.Errorf("Variance of [1, 2, -1] = %s, want 14/9", got)
+	}
+
+	empty := NewVector(0, modulus)
+	if ratio(empty.Mean()) != "0/1" || ratio(empty.Variance()) != "0/1" {
+		t.Errorf("Mean and Variance of an empty vector should be 0/1")
+	}
+}
+
+func TestMatrixDiagonal(t *testing.T) {
+	modulus := big.NewInt(17)
+	v, err := GenerateRandomVector(6, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomVector failed: %v", err)
+	}
+
+	d := NewDiagonalMatrix(v)
+	if !d.IsDiagonal() {
+		t.Fatalf("NewDiagonalMatrix result should be diagonal")
+	}
+	diag, err := d.Diagonal()
+	if err != nil {
+		t.Fatalf("Diagonal failed: %v", err)
+	}
+	if !diag.Equal(v) {
+		t.Fatalf("Diagonal did not round-trip NewDiagonalMatrix")
+	}
+
+	zero := NewMatrix(4, 4, modulus)
+	if err := zero.FillDiagonal(big.NewInt(1)); err != nil {
+		t.Fatalf("FillDiagonal failed: %v", err)
+	}
+	if !zero.Equal(identityMatrix(4, modulus)) {
+		t.Fatalf("FillDiagonal(1) on the zero matrix should give the identity")
+	}
+
+	rect := NewMatrix(2, 5, modulus)
+	rect.Set(1, 3, big.NewInt(2))
+	if rect.IsDiagonal() {
+		t.Fatalf("matrix with an off-diagonal entry should not be diagonal")
+	}
+	if diag, _ := rect.Diagonal(); diag.Length() != 2 {
+		t.Fatalf("Diagonal of a 2x5 matrix should have 2 elements, got %d", diag.Length())
+	}
+
+	empty := Matrix{Modulus: modulus}
+	if _, err := empty.Diagonal(); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Diagonal on empty matrix: got %v", err)
+	}
+	if err := empty.FillDiagonal(big.NewInt(1)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("FillDiagonal on empty matrix: got %v", err)
+	}
+}
+
+func TestCloneAndAliasing(t *testing.T) {
+	modulus := big.NewInt(97)
+	m, err := GenerateRandomMatrix(4, 3, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+	v, err := GenerateRandomVector(3, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomVector failed: %v", err)
+	}
+	mOrig := m.Clone()
+	vOrig := v.Clone()
+	if !mOrig.Equal(m) || !vOrig.Equal(v) {
+		t.Fatalf("Clone should produce equal copies")
+	}
+
+	// Mutate every result in place through its shared pointers
+	bump := func(x *big.Int) { x.Add(x, big.NewInt(1)) }
+	bumpMatrix := func(r Matrix) {
+		for i := range r.Values {
+			for j := range r.Values[i] {
+				bump(r.Values[i][j])
+			}
+		}
+	}
+	bumpVector := func(r *Vector) {
+		for i := range r.Values {
+			bump(r.Values[i])
+		}
+	}
+
+	clone := m.Clone()
+	bumpMatrix(clone)
+	vClone := v.Clone()
+	bumpVector(vClone)
+	bump(m.Get(0, 0))
+	bump(v.Get(0))
+	tr, _ := m.Transpose()
+	bumpMatrix(tr)
+	bumpVector(m.Row(1))
+	bumpVector(m.Col(2))
+	diag, _ := m.Diagonal()
+	bumpVector(diag)
+	mv, _ := m.MultiplyVector(v)
+	bumpVector(mv)
+	sum, _ := v.Add(v)
+	bumpVector(sum)
+
+	stored := big.NewInt(5)
+	m.Set(3, 0, stored)
+	mOrig.Set(3, 0, stored)
+	bump(stored)
+
+	if !m.Equal(mOrig) {
+		t.Fatalf("mutating derived values changed the original matrix")
+	}
+	if !v.Equal(vOrig) {
+		t.Fatalf("mutating derived values changed the original vector")
+	}
+}
+
+func TestMatrixSumAndIsAllZero(t *testing.T) {
+	modulus := big.NewInt(7)
+	const rows, cols = 4, 5
+	m := NewMatrix(rows, cols, modulus)
+	if !m.IsAllZero() {
+		t.Fatalf("new matrix should be all zero")
+	}
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			m.Set(i, j, big.NewInt(1))
+		}
+	}
+
+	if got, want := m.Sum().Int64(), int64(rows*cols%7); got != want {
+		t.Fatalf("Sum: got %d, want %d", got, want)
+	}
+	if m.IsAllZero() {
+		t.Fatalf("all-ones matrix should not be all zero")
+	}
+	m.ZeroInPlace()
+	if !m.IsAllZero() {
+		t.Fatalf("matrix should be all zero after ZeroInPlace")
+	}
+
+	v := m.Row(0)
+	v.Set(2, big.NewInt(3))
+	if v.IsAllZero() {
+		t.Fatalf("vector with a non-zero element should not be all zero")
+	}
+	v.ZeroInPlace()
+	if !v.IsAllZero() {
+		t.Fatalf("vector should be all zero after ZeroInPlace")
+	}
+}
+
+func TestZeroInPlaceReuse(t *testing.T) {
+	modulus := big.NewInt(7681)
+	m, _ := GenerateRandomMatrix(4, 6, modulus, crand.Reader)
+	elem := m.Values[2][3]
+	m.ZeroInPlace()
+	if !m.IsAllZero() || m.Values[2][3] != elem {
+		t.Fatalf("ZeroInPlace should zero the existing elements instead of replacing them")
+	}
+
+	// A zeroed matrix behaves like a new one
+	other, _ := GenerateRandomMatrix(4, 6, modulus, crand.Reader)
+	if err := m.AddInPlace(other); err != nil || !m.Equal(other) {
+		t.Fatalf("0 + other should equal other: %v", err)
+	}
+	v, _ := GenerateRandomVector(6, modulus, crand.Reader)
+	want, _ := other.MultiplyVector(v)
+	if got, err := m.MultiplyVector(v); err != nil || !got.Equal(want) {
+		t.Fatalf("MultiplyVector after ZeroInPlace differs: %v", err)
+	}
+
+	v.ZeroInPlace()
+	w, _ := GenerateRandomVector(6, modulus, crand.Reader)
+	if got, err := v.Add(w); err != nil || !got.Equal(w) {
+		t.Fatalf("0 + w should equal w: %v", err)
+	}
+}
+
+func BenchmarkZeroInPlace(b *testing.B) {
+	modulus := new(big.Int).Lsh(big.NewInt(1), 61)
+	modulus.Sub(modulus, big.NewInt(1))
+	src, _ := GenerateRandomMatrix(64, 128, modulus, crand.Reader)
+
+	b.Run("NewMatrix", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := NewMatrix(src.Rows, src.Cols, modulus)
+			m.AddInPlace(src)
+		}
+	})
+	b.Run("ZeroInPlace", func(b *testing.B) {
+		b.ReportAllocs()
+		m := src.Clone()
+		for i := 0; i < b.N; i++ {
+			m.ZeroInPlace()
+			m.AddInPlace(src)
+		}
+	})
+}
+
+func TestCompactEncodingRoundTrip(t *testing.T) {
+	for _, bits := range []int{2, 7, 8, 9, 24, 61, 64, 100} {
+		modulus := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+		modulus.Sub(modulus, big.NewInt(1))
+
+		m, err := GenerateRandomMatrix(5, 7, modulus, crand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateRandomMatrix failed: %v", err)
+		}
+		m.Set(0, 0, new(big.Int).Sub(modulus, big.NewInt(1)))
+		data, err := m.MarshalBinaryCompact()
+		if err != nil {
+			t.Fatalf("%d bits: MarshalBinaryCompact failed: %v", bits, err)
+		}
+		if len(data) != m.CompactEncodedSize() || len(data) != 10+(35*modulus.BitLen()+7)/8 {
+			t.Fatalf("%d bits: compact matrix is %d bytes", bits, len(data))
+		}
+		decoded := Matrix{Modulus: modulus}
+		if err := decoded.UnmarshalBinaryCompact(data); err != nil {
+			t.Fatalf("%d bits: UnmarshalBinaryCompact failed: %v", bits, err)
+		}
+		if !decoded.Equal(m) {
+			t.Fatalf("%d bits: matrix did not round-trip", bits)
+		}
+
+		v, err := GenerateRandomVector(13, modulus, crand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateRandomVector failed: %v", err)
+		}
+		vData, err := v.MarshalBinaryCompact()
+		if err != nil {
+			t.Fatalf("%d bits: MarshalBinaryCompact failed: %v", bits, err)
+		}
+		if len(vData) != v.CompactEncodedSize() {
+			t.Fatalf("%d bits: compact vector is %d bytes, expected %d", bits, len(vData), v.CompactEncodedSize())
+		}
+		decodedV := &Vector{Modulus: modulus}
+		if err := decodedV.UnmarshalBinaryCompact(vData); err != nil {
+			t.Fatalf("%d bits: UnmarshalBinaryCompact failed: %v", bits, err)
+		}
+		if !decodedV.Equal(v) {
+			t.Fatalf("%d bits: vector did not round-trip", bits)
+		}
+
+		if err := decodedV.UnmarshalBinaryCompact(vData[:len(vData)-1]); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("%d bits: truncated vector: got %v", bits, err)
+		}
+		other := &Vector{Modulus: new(big.Int).Lsh(modulus, 1)}
+		if err := other.UnmarshalBinaryCompact(vData); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("%d bits: mismatched bit width: got %v", bits, err)
+		}
+	}
+}
+
+func TestCompactEncodingCanonical(t *testing.T) {
+	// Three 61-bit elements take 183 bits, leaving one padding bit in the last byte
+	modulus := big.NewInt(1<<61 - 1)
+	v := NewVector(3, modulus)
+	v.Set(1, big.NewInt(12345))
+	vData, err := v.MarshalBinaryCompact()
+	if err != nil {
+		t.Fatalf("MarshalBinaryCompact failed: %v", err)
+	}
+	m := NewMatrix(1, 3, modulus)
+	m.Set(0, 1, big.NewInt(12345))
+	mData, err := m.MarshalBinaryCompact()
+	if err != nil {
+		t.Fatalf("MarshalBinaryCompact failed: %v", err)
+	}
+
+	for name, encoded := range map[string]struct {
+		data   []byte
+		header int
+	}{"vector": {vData, 6}, "matrix": {mData, 10}} {
+		cases := map[string][]byte{
+			"trailing byte": append(append([]byte(nil), encoded.data...), 0),
+			"padding bit":   append([]byte(nil), encoded.data...),
+			"unreduced":     append([]byte(nil), encoded.data...),
+		}
+		cases["padding bit"][len(encoded.data)-1] |= 0x01
+		// Set all 61 bits of the first element, which encodes q itself
+		unreduced := cases["unreduced"][encoded.header:]
+		for i := 0; i < 7; i++ {
+			unreduced[i] = 0xFF
+		}
+		unreduced[7] |= 0xF8
+
+		for c, data := range cases {
+			var err error
+			if name == "vector" {
+				got := v.Clone()
+				err = got.UnmarshalBinaryCompact(data)
+				if !got.Equal(v) {
+					t.Fatalf("%s, %s: receiver modified on error", name, c)
+				}
+			} else {
+				got := m.Clone()
+				err = got.UnmarshalBinaryCompact(data)
+				if !got.Equal(m) {
+					t.Fatalf("%s, %s: receiver modified on error", name, c)
+				}
+			}
+			if !errors.Is(err, ErrDeserializationError) {
+				t.Fatalf("%s, %s: got %v", name, c, err)
+			}
+		}
+	}
+}
+
+func TestCompactEncodingHugeDimensions(t *testing.T) {
+	modulus := big.NewInt(1<<61 - 1)
+	// Dimensions whose product, or the product's size in bits, overflows an int must be
+	// rejected before anything is allocated for them
+	for _, dims := range [][2]uint32{{0xFFFFFFFF, 0xFFFFFFFF}, {1 << 28, 1 << 28}, {1 << 31, 1 << 30}} {
+		data := make([]byte, 10+64)
+		binary.BigEndian.PutUint32(data[0:4], dims[0])
+		binary.BigEndian.PutUint32(data[4:8], dims[1])
+		binary.BigEndian.PutUint16(data[8:10], uint16(modulus.BitLen()))
+		m := Matrix{Modulus: modulus}
+		if err := m.UnmarshalBinaryCompact(data); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("%dx%d matrix: got %v", dims[0], dims[1], err)
+		}
+	}
+
+	if _, ok := checkedCompactSize(math.MaxInt/8, 61); ok {
+		t.Fatalf("checkedCompactSize should report an overflowing size")
+	}
+	if size, ok := checkedCompactSize(3, 61); !ok || size != 23 {
+		t.Fatalf("checkedCompactSize(3, 61) = %d, %v", size, ok)
+	}
+}
+
+// BenchmarkMatrixMarshalCompact compares the byte-aligned and compact encodings of an n x m
+// matrix at the Security16 sizes (n = 128, m = 8192, 61-bit modulus)
+func BenchmarkMatrixMarshalCompact(b *testing.B) {
+	modulus, _ := new(big.Int).SetString("2305843009213317121", 10)
+	mat, err := GenerateRandomMatrix(128, 8192, modulus, crand.Reader)
+	if err != nil {
+		b.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+
+	b.Run("Aligned", func(b *testing.B) {
+		b.ReportMetric(float64(mat.EncodedSize()), "bytes")
+		for i := 0; i < b.N; i++ {
+			if _, err := mat.MarshalBinary(); err != nil {
+				b.Fatalf("MarshalBinary failed: %v", err)
+			}
+		}
+	})
+	b.Run("Compact", func(b *testing.B) {
+		b.ReportMetric(float64(mat.CompactEncodedSize()), "bytes")
+		for i := 0; i < b.N; i++ {
+			if _, err := mat.MarshalBinaryCompact(); err != nil {
+				b.Fatalf("MarshalBinaryCompact failed: %v", err)
+			}
+		}
+	})
+}
+
+func TestCirculant(t *testing.T) {
+	modulus := big.NewInt(17)
+	v := &Vector{Values: []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)}, Modulus: modulus}
+	w := &Vector{Values: []*big.Int{big.NewInt(5), big.NewInt(6), big.NewInt(7), big.NewInt(8)}, Modulus: modulus}
+
+	c := CirculantFromVector(v)
+	if !c.IsCirculant() {
+		t.Fatalf("CirculantFromVector result is not circulant")
+	}
+	if col := c.Col(0); !col.Equal(v) {
+		t.Fatalf("first column is not v")
+	}
+
+	// (1 + 2x + 3x^2 + 4x^3)(5 + 6x + 7x^2 + 8x^3) mod (x^4 - 1) = 66 + 68x + 66x^2 + 60x^3,
+	// which reduces to 15 + 0x + 15x^2 + 9x^3 modulo 17
+	got, err := c.MultiplyVector(w)
+	if err != nil {
+		t.Fatalf("MultiplyVector failed: %v", err)
+	}
+	want := &Vector{Values: []*big.Int{big.NewInt(15), big.NewInt(0), big.NewInt(15), big.NewInt(9)}, Modulus: modulus}
+	if !got.Equal(want) {
+		t.Fatalf("circulant product %v, want %v", got.Values, want.Values)
+	}
+
+	c.Set(1, 2, big.NewInt(9))
+	if c.IsCirculant() {
+		t.Fatalf("modified matrix should not be circulant")
+	}
+	rect := NewMatrix(2, 3, modulus)
+	if rect.IsCirculant() {
+		t.Fatalf("non-square matrix should not be circulant")
+	}
+}
+
+func TestCyclicShift(t *testing.T) {
+	modulus := big.NewInt(97)
+	v, err := GenerateRandomVector(7, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomVector failed: %v", err)
+	}
+	n := v.Length()
+
+	if !v.CyclicShift(0).Equal(v) || !v.CyclicShift(n).Equal(v) || !v.CyclicShift(-n).Equal(v) {
+		t.Fatalf("shifting by a multiple of n should be the identity")
+	}
+	shifted := v.CyclicShift(2)
+	for i := 0; i < n; i++ {
+		if shifted.Values[(i+2)%n].Cmp(v.Values[i]) != 0 {
+			t.Fatalf("CyclicShift(2) moved element %d to the wrong place", i)
+		}
+	}
+	if !v.CyclicShift(-1).Equal(v.CyclicShift(n - 1)) {
+		t.Fatalf("negative offsets should shift the other way")
+	}
+	for _, a := range []int{-9, -3, 0, 1, 4, 11} {
+		for _, b := range []int{-5, 0, 2, 6, 15} {
+			if !v.CyclicShift(a).CyclicShift(b).Equal(v.CyclicShift(a + b)) {
+				t.Fatalf("CyclicShift(%d).CyclicShift(%d) != CyclicShift(%d)", a, b, a+b)
+			}
+		}
+	}
+
+	orig := v.Clone()
+	shifted.Values[2].Add(shifted.Values[2], big.NewInt(1))
+	if !v.Equal(orig) {
+		t.Fatalf("CyclicShift result aliases the input")
+	}
+
+	c := CirculantFromVector(v)
+	if !c.Col(0).CyclicShift(3).Equal(c.Col(3)) {
+		t.Fatalf("circulant columns should be cyclic shifts of the first column")
+	}
+}
+
+func TestWriteCanonical(t *testing.T) {
+	modulus := big.NewInt(70000)
+	v, err := GenerateRandomVector(150, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomVector failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := v.WriteCanonical(&buf, 3); err != nil {
+		t.Fatalf("WriteCanonical failed: %v", err)
+	}
+	encoded, _ := v.MarshalBinaryRaw()
+	if !bytes.Equal(buf.Bytes(), encoded[4:]) {
+		t.Fatalf("WriteCanonical does not match the MarshalBinary element encoding")
+	}
+
+	buf.Reset()
+	if err := v.WriteCanonical(&buf, 4); err != nil || buf.Len() != 4*v.Length() {
+		t.Fatalf("WriteCanonical with wider elements wrote %d bytes: %v", buf.Len(), err)
+	}
+	if err := v.WriteCanonical(&buf, 2); !errors.Is(err, ErrSerializationError) {
+		t.Fatalf("WriteCanonical with too narrow elements: got %v", err)
+	}
+}
+
+func TestNewGaussianVector(t *testing.T) {
+	const length, sigma = 100000, 3.2
+	modulus := big.NewInt(1<<31 - 1)
+	seed := []byte("gaussian vector test seed")
+
+	v, err := NewGaussianVector(length, sigma, seed, modulus)
+	if err != nil {
+		t.Fatalf("NewGaussianVector failed: %v", err)
+	}
+	if v.Length() != length {
+		t.Fatalf("got %d elements, want %d", v.Length(), length)
+	}
+
+	var sum, sumSq float64
+	for _, val := range v.Values {
+		c := float64(centered(val, modulus).Int64())
+		sum += c
+		sumSq += c * c
+	}
+	mean := sum / length
+	std := math.Sqrt(sumSq/length - mean*mean)
+	if math.Abs(mean) > 0.1*sigma {
+		t.Fatalf("empirical mean %f is not within 0.1*sigma of zero", mean)
+	}
+	if math.Abs(std-sigma) > 0.2*sigma {
+		t.Fatalf("empirical standard deviation %f is not within 20%% of %f", std, sigma)
+	}
+
+	again, err := NewGaussianVector(length, sigma, seed, modulus)
+	if err != nil || !again.Equal(v) {
+		t.Fatalf("the same seed should give the same vector")
+	}
+	if _, err := NewGaussianVector(8, 0, seed, modulus); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("zero sigma: got %v", err)
+	}
+}
+
+func TestAugmentAndSubMatrix(t *testing.T) {
+	modulus := big.NewInt(97)
+	m, err := GenerateRandomMatrix(3, 4, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+
+	aug, err := m.Augment(m)
+	if err != nil {
+		t.Fatalf("Augment failed: %v", err)
+	}
+	if aug.Rows != m.Rows || aug.Cols != 2*m.Cols {
+		t.Fatalf("augmented matrix is %dx%d", aug.Rows, aug.Cols)
+	}
+	left, err := aug.SubMatrix(0, m.Rows, 0, m.Cols)
+	if err != nil || !left.Equal(m) {
+		t.Fatalf("left half does not equal m: %v", err)
+	}
+	right, err := aug.SubMatrix(0, m.Rows, m.Cols, 2*m.Cols)
+	if err != nil || !right.Equal(m) {
+		t.Fatalf("right half does not equal m: %v", err)
+	}
+
+	orig := m.Clone()
+	aug.Values[0][0].Add(aug.Values[0][0], big.NewInt(1))
+	aug.Values[0][m.Cols].Add(aug.Values[0][m.Cols], big.NewInt(1))
+	if !m.Equal(orig) || !left.Equal(orig) {
+		t.Fatalf("Augment or SubMatrix result aliases its input")
+	}
+
+	other := NewMatrix(2, 4, modulus)
+	if _, err := m.Augment(other); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Augment with different row counts: got %v", err)
+	}
+	if _, err := m.SubMatrix(0, 4, 0, 1); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("SubMatrix out of range: got %v", err)
+	}
+}
+
+func TestMatrixPow(t *testing.T) {
+	modulus := big.NewInt(17)
+	m, err := GenerateRandomMatrix(3, 3, modulus, crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+
+	p0, err := m.Pow(0)
+	if err != nil || !p0.Equal(identityMatrix(3, modulus)) {
+		t.Fatalf("m^0 is not the identity: %v", err)
+	}
+	p1, err := m.Pow(1)
+	if err != nil || !p1.Equal(m) {
+		t.Fatalf("m^1 != m: %v", err)
+	}
+	m2, _ := m.Multiply(m)
+	p2, err := m.Pow(2)
+	if err != nil || !p2.Equal(m2) {
+		t.Fatalf("m^2 != m*m: %v", err)
+	}
+	m3, _ := m2.Multiply(m)
+	p3, err := m.Pow(3)
+	if err != nil || !p3.Equal(m3) {
+		t.Fatalf("m^3 != m*m*m: %v", err)
+	}
+	m13 := identityMatrix(3, modulus)
+	for i := 0; i < 13; i++ {
+		m13, _ = m13.Multiply(m)
+	}
+	if p13, err := m.Pow(13); err != nil || !p13.Equal(m13) {
+		t.Fatalf("m^13 does not match repeated multiplication: %v", err)
+	}
+
+	rect := NewMatrix(2, 3, modulus)
+	if _, err := rect.Pow(2); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Pow of a non-square matrix: got %v", err)
+	}
+	if _, err := m.Pow(-1); !errors.Is(err, ErrInvalidArgument) || errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Pow with a negative exponent: got %v", err)
+	}
+}
+
+func TestBlockDiagonal(t *testing.T) {
+	modulus := big.NewInt(17)
+	n := 4
+	bd, err := BlockDiagonal([]Matrix{identityMatrix(n, modulus), identityMatrix(n, modulus)})
+	if err != nil {
+		t.Fatalf("BlockDiagonal failed: %v", err)
+	}
+	if !bd.Equal(identityMatrix(2*n, modulus)) {
+		t.Fatalf("two identity blocks do not form the identity")
+	}
+
+	a, _ := GenerateRandomMatrix(2, 3, modulus, crand.Reader)
+	b, _ := GenerateRandomMatrix(3, 1, modulus, crand.Reader)
+	bd, err = BlockDiagonal([]Matrix{a, b})
+	if err != nil {
+		t.Fatalf("BlockDiagonal failed: %v", err)
+	}
+	if bd.Rows != 5 || bd.Cols != 4 {
+		t.Fatalf("got %dx%d, expected 5x4", bd.Rows, bd.Cols)
+	}
+	if top, _ := bd.SubMatrix(0, 2, 0, 3); !top.Equal(a) {
+		t.Fatalf("first block not on the diagonal")
+	}
+	if bottom, _ := bd.SubMatrix(2, 5, 3, 4); !bottom.Equal(b) {
+		t.Fatalf("second block not on the diagonal")
+	}
+	for _, r := range [][4]int{{0, 2, 3, 4}, {2, 5, 0, 3}} {
+		off, _ := bd.SubMatrix(r[0], r[1], r[2], r[3])
+		if !off.Equal(NewMatrix(off.Rows, off.Cols, modulus)) {
+			t.Fatalf("off-block area %v is not zero", r)
+		}
+	}
+
+	if _, err := BlockDiagonal(nil); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("empty input: got %v", err)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	modulus := big.NewInt(17)
+	m, _ := GenerateRandomMatrix(3, 4, modulus, crand.Reader)
+
+	count := 0
+	m.ForEach(func(i, j int, v *big.Int) {
+		if i != count/m.Cols || j != count%m.Cols {
+			t.Fatalf("visit %d at (%d, %d), expected row-major order", count, i, j)
+		}
+		if v.Cmp(m.Values[i][j]) != 0 {
+			t.Fatalf("value at (%d, %d) does not match", i, j)
+		}
+		count++
+	})
+	if count != m.Rows*m.Cols {
+		t.Fatalf("visited %d elements, expected %d", count, m.Rows*m.Cols)
+	}
+
+	v, _ := GenerateRandomVector(5, modulus, crand.Reader)
+	count = 0
+	v.ForEach(func(i int, x *big.Int) {
+		if i != count || x.Cmp(v.Values[i]) != 0 {
+			t.Fatalf("visit %d got index %d", count, i)
+		}
+		count++
+	})
+	if count != v.Length() {
+		t.Fatalf("visited %d elements, expected %d", count, v.Length())
+	}
+}
+
+func TestReduce(t *testing.T) {
+	modulus := big.NewInt(97)
+	add := func(acc, v *big.Int) *big.Int {
+		acc.Add(acc, v)
+		return acc.Mod(acc, modulus)
+	}
+
+	v, _ := GenerateRandomVector(20, modulus, crand.Reader)
+	if got := v.Reduce(new(big.Int), add); got.Cmp(v.Sum()) != 0 {
+		t.Fatalf("v.Reduce(add) = %v, Sum = %v", got, v.Sum())
+	}
+
+	m, _ := GenerateRandomMatrix(4, 6, modulus, crand.Reader)
+	if got := m.Reduce(new(big.Int), add); got.Cmp(m.Sum()) != 0 {
+		t.Fatalf("m.Reduce(add) = %v, Sum = %v", got, m.Sum())
+	}
+
+	minimum := func(acc, v *big.Int) *big.Int {
+		if v.Cmp(acc) < 0 {
+			return acc.Set(v)
+		}
+		return acc
+	}
+	want := new(big.Int).Set(v.Values[0])
+	for _, x := range v.Values {
+		if x.Cmp(want) < 0 {
+			want.Set(x)
+		}
+	}
+	if got := v.Reduce(new(big.Int).Set(modulus), minimum); got.Cmp(want) != 0 {
+		t.Fatalf("v.Reduce(min) = %v, expected %v", got, want)
+	}
+
+	// The elements themselves are left untouched
+	before := v.Clone()
+	v.Reduce(new(big.Int), add)
+	if !v.Equal(before) {
+		t.Fatalf("Reduce modified the vector")
+	}
+}
+
+func TestVectorAXPY(t *testing.T) {
+	modulus := big.NewInt(7681)
+	for i := 0; i < 1000; i++ {
+		v, _ := GenerateRandomVector(8, modulus, crand.Reader)
+		x, _ := GenerateRandomVector(8, modulus, crand.Reader)
+		scalar, _ := crand.Int(crand.Reader, modulus)
+
+		got, err := v.AXPY(scalar, x)
+		if err != nil {
+			t.Fatalf("AXPY failed: %v", err)
+		}
+		scaled, _ := x.ScalarMultiply(scalar)
+		want, _ := scaled.Add(v)
+		if !got.Equal(want) {
+			t.Fatalf("AXPY(%v) differs from ScalarMultiply then Add", scalar)
+		}
+	}
+
+	v, _ := GenerateRandomVector(8, modulus, crand.Reader)
+	if _, err := v.AXPY(big.NewInt(3), NewVector(7, modulus)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("AXPY with mismatched lengths: got %v", err)
+	}
+}
+
+func BenchmarkVectorAXPY(b *testing.B) {
+	modulus := new(big.Int).Lsh(big.NewInt(1), 61)
+	modulus.Sub(modulus, big.NewInt(1))
+	v, _ := GenerateRandomVector(8192, modulus, crand.Reader)
+	x, _ := GenerateRandomVector(8192, modulus, crand.Reader)
+	scalar, _ := crand.Int(crand.Reader, modulus)
+
+	b.Run("ScalarMultiplyThenAdd", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			scaled, _ := x.ScalarMultiply(scalar)
+			scaled.Add(v)
+		}
+	})
+	b.Run("AXPY", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			v.AXPY(scalar, x)
+		}
+	})
+}
+
+func TestVectorModulusTag(t *testing.T) {
+	q1 := big.NewInt(7681)
+	q2 := big.NewInt(12289) // same element width as q1
+	v, _ := GenerateRandomVector(10, q1, crand.Reader)
+
+	data, err := v.MarshalBinary()
+	if err != nil || len(data) != v.EncodedSize() || v.EncodedSize() != v.RawEncodedSize()+4 {
+		t.Fatalf("MarshalBinary produced %d bytes (EncodedSize %d): %v", len(data), v.EncodedSize(), err)
+	}
+	decoded := NewVector(0, q1)
+	if err := decoded.UnmarshalBinary(data); err != nil || !decoded.Equal(v) {
+		t.Fatalf("tagged round trip failed: %v", err)
+	}
+
+	// Decoding under another modulus is rejected, for vectors and vector slices
+	if err := NewVector(0, q2).UnmarshalBinary(data); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("cross-modulus decode: got %v", err)
+	}
+	slice, err := MarshalVectorSlice([]*Vector{v, v})
+	if err != nil {
+		t.Fatalf("MarshalVectorSlice failed: %v", err)
+	}
+	if got, err := UnmarshalVectorSlice(slice, q1); err != nil || len(got) != 2 || !got[1].Equal(v) {
+		t.Fatalf("slice round trip failed: %v", err)
+	}
+	if _, err := UnmarshalVectorSlice(slice, q2); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("cross-modulus slice decode: got %v", err)
+	}
+
+	// The raw encoding carries no tag: UnmarshalBinary still accepts it, UnmarshalBinaryRaw
+	// rejects a tagged input
+	raw, _ := v.MarshalBinaryRaw()
+	if len(raw) != v.RawEncodedSize() {
+		t.Fatalf("raw encoding is %d bytes, expected %d", len(raw), v.RawEncodedSize())
+	}
+	decoded = NewVector(0, q1)
+	if err := decoded.UnmarshalBinary(raw); err != nil || !decoded.Equal(v) {
+		t.Fatalf("UnmarshalBinary of raw encoding failed: %v", err)
+	}
+	if err := NewVector(0, q1).UnmarshalBinaryRaw(data); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("UnmarshalBinaryRaw of tagged encoding: got %v", err)
+	}
+
+	// A corrupted tag is detected
+	data[5] ^= 1
+	if err := NewVector(0, q1).UnmarshalBinary(data); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("corrupted tag: got %v", err)
+	}
+
+	// Stripping the tag does not bypass the check: untagged input must match the width of the
+	// receiver's modulus and hold only reduced elements
+	wide := NewVector(10, q2)
+	wide.Set(3, big.NewInt(12000)) // >= q1
+	stripped, _ := wide.MarshalBinaryRaw()
+	if err := NewVector(0, q1).UnmarshalBinary(stripped); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("untagged cross-modulus decode: got %v", err)
+	}
+	if err := NewVector(0, big.NewInt(1<<20)).UnmarshalBinary(stripped); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("untagged decode under a wider modulus: got %v", err)
+	}
+	if err := NewVector(0, q2).UnmarshalBinary(append(stripped, 0, 0)); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("untagged decode with trailing data: got %v", err)
+	}
+	strippedSlice := append(binary.BigEndian.AppendUint32(nil, 1), stripped...)
+	if _, err := UnmarshalVectorSlice(strippedSlice, q1); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("untagged cross-modulus slice decode: got %v", err)
+	}
+	if got, err := UnmarshalVectorSlice(strippedSlice, q2); err != nil || len(got) != 1 || !got[0].Equal(wide) {
+		t.Fatalf("untagged slice decode under the right modulus failed: %v", err)
+	}
+}
+
+func TestVectorToBytes(t *testing.T) {
+	q := big.NewInt(1 << 20) // 3-byte elements
+	v := NewVector(5, q)
+	for i, val := range v.Values {
+		val.SetInt64(int64(i*200003) % q.Int64())
+	}
+
+	data := v.ToBytes()
+	marshaled, _ := v.MarshalBinary()
+	raw, _ := v.MarshalBinaryRaw()
+	// MarshalBinary adds the length header and modulus tag, 4 bytes each
+	if len(data) != 5*3 || len(marshaled) != len(data)+8 {
+		t.Fatalf("ToBytes is %d bytes, MarshalBinary %d", len(data), len(marshaled))
+	}
+	if !bytes.Equal(raw[4:], data) {
+		t.Fatalf("ToBytes differs from the elements of MarshalBinaryRaw")
+	}
+
+	decoded, err := VectorFromBytes(data, 5, q)
+	if err != nil || !decoded.Equal(v) {
+		t.Fatalf("round trip failed: %v", err)
+	}
+	empty, err := VectorFromBytes(nil, 0, q)
+	if err != nil || empty.Length() != 0 {
+		t.Fatalf("empty round trip failed: %v", err)
+	}
+
+	// Unreduced elements are encoded modulo q
+	unreduced := v.Clone()
+	unreduced.Values[0].Sub(unreduced.Values[0], q)
+	if decoded, _ := VectorFromBytes(unreduced.ToBytes(), 5, q); !decoded.Equal(v) {
+		t.Fatalf("unreduced element was not reduced")
+	}
+
+	for _, tc := range []struct {
+		data   []byte
+		length int
+	}{{data[:14], 5}, {append(data, 0), 5}, {data, 4}, {data, -1}} {
+		if _, err := VectorFromBytes(tc.data, tc.length, q); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("VectorFromBytes(%d bytes, %d): got %v", len(tc.data), tc.length, err)
+		}
+	}
+	if _, err := VectorFromBytes(data, 5, nil); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("VectorFromBytes with nil modulus: got %v", err)
+	}
+}
+
+func TestDebugString(t *testing.T) {
+	q := big.NewInt(2305843009213317121)
+
+	// Output stays bounded however large the structure is
+	huge := NewMatrix(1024, 1024, q)
+	huge.Fill(new(big.Int).Sub(q, big.NewInt(1)))
+	out := huge.DebugString(16)
+	if len(out) > 512 || !strings.HasPrefix(out, "Matrix[1024x1024] q=61 bits hash=") {
+		t.Fatalf("unexpected matrix debug string (%d bytes):\n%s", len(out), out)
+	}
+	if n := strings.Count(out, "2305843009213317120"); n != 16 {
+		t.Fatalf("expected a 4x4 corner, found %d values:\n%s", n, out)
+	}
+	long := NewVector(1<<20, q)
+	if out := long.DebugString(8); len(out) > 256 || !strings.Contains(out, "... 1048568 more") {
+		t.Fatalf("unexpected vector debug string (%d bytes): %s", len(out), out)
+	}
+
+	// Small structures are printed in full and the hash tracks the contents
+	v, _ := FromBigInts([]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)}, q, false)
+	before := v.DebugString(10)
+	if !strings.HasSuffix(before, "[1 2 3]") {
+		t.Fatalf("unexpected vector debug string: %s", before)
+	}
+	v.Values[2].SetInt64(4)
+	if after := v.DebugString(10); after[:len(after)-5] == before[:len(before)-5] {
+		t.Fatalf("content hash did not change: %s", after)
+	}
+	if out := v.DebugString(0); !strings.HasSuffix(out, "[ ... 3 more]") {
+		t.Fatalf("unexpected vector debug string: %s", out)
+	}
+	small := NewMatrix(2, 3, q)
+	if out := small.DebugString(100); strings.Count(out, "\n") != 2 || strings.Contains(out, "more") {
+		t.Fatalf("unexpected matrix debug string:\n%s", out)
+	}
+}
+
+func TestMatrixStats(t *testing.T) {
+	q := big.NewInt(2305843009213317121)
+	const rows, cols, sigma = 16, 256, 3.2
+	m := NewMatrix(rows, cols, q)
+	for i := range rows {
+		row, err := NewGaussianVector(cols, sigma, []byte{byte(i)}, q)
+		if err != nil {
+			t.Fatalf("NewGaussianVector failed: %v", err)
+		}
+		m.Values[i] = row.Values
+	}
+
+	// Centered Gaussian samples stay small even though negative ones are stored near q
+	stats := m.Stats()
+	if stats.MaxBitLen > 6 || stats.MinBitLen != 0 || stats.Zeros == 0 || stats.MeanBitLen < 1 || stats.MeanBitLen > 3 {
+		t.Fatalf("unexpected Gaussian stats %+v", stats)
+	}
+
+	m.Set(0, 0, new(big.Int).Rsh(q, 1))
+	if stats := m.Stats(); stats.MaxBitLen != 60 {
+		t.Fatalf("expected a 60-bit maximum, got %+v", stats)
+	}
+	empty := NewMatrix(0, 0, q)
+	if stats := empty.Stats(); stats != (MatrixStats{}) {
+		t.Fatalf("expected zero stats for an empty matrix, got %+v", stats)
+	}
+}
+
+func TestDensity(t *testing.T) {
+	q := big.NewInt(97)
+	m := NewMatrix(4, 6, q)
+	if d := m.Density(); d != 0 || m.CountZeros() != 24 || m.CountNonZeros() != 0 {
+		t.Fatalf("all-zero matrix: density %v, %d zeros", d, m.CountZeros())
+	}
+
+	// Every other element non-zero, including residues near q
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			if (i+j)%2 == 0 {
+				m.Set(i, j, big.NewInt(int64(96-j)))
+			}
+		}
+	}
+	if d := m.Density(); math.Abs(d-0.5) > 1e-9 || m.CountNonZeros() != 12 {
+		t.Fatalf("half-filled matrix: density %v, %d non-zeros", d, m.CountNonZeros())
+	}
+
+	m.Fill(big.NewInt(5))
+	if d := m.Density(); d != 1 || m.CountZeros() != 0 {
+		t.Fatalf("full matrix: density %v", d)
+	}
+	empty := NewMatrix(0, 0, q)
+	if d := empty.Density(); d != 0 {
+		t.Fatalf("empty matrix: density %v", d)
+	}
+
+	v := NewVector(4, q)
+	v.Values[1].SetInt64(3)
+	if d := v.Density(); d != 0.25 || v.CountZeros() != 3 || v.CountNonZeros() != 1 {
+		t.Fatalf("vector: density %v, %d zeros", d, v.CountZeros())
+	}
+	if d := NewVector(0, q).Density(); d != 0 {
+		t.Fatalf("empty vector: density %v", d)
+	}
+}
+
+func TestGram(t *testing.T) {
+	q := big.NewInt(97)
+	ones := NewVector(4, q)
+	ones.Fill(big.NewInt(1))
+	identity := NewDiagonalMatrix(ones)
+	g, err := identity.Gram()
+	if err != nil || !g.Equal(identity) {
+		t.Fatalf("Gram of the identity should be the identity: %v", err)
+	}
+
+	for trial := 0; trial < 8; trial++ {
+		m, err := GenerateRandomMatrix(5+trial, 3+trial%3, q, crand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateRandomMatrix failed: %v", err)
+		}
+		g, err := m.Gram()
+		if err != nil {
+			t.Fatalf("Gram failed: %v", err)
+		}
+		if g.Rows != m.Cols || !g.IsSymmetric() {
+			t.Fatalf("Gram of a %dx%d matrix is not a symmetric %dx%d matrix", m.Rows, m.Cols, m.Cols, m.Cols)
+		}
+		mt, _ := m.Transpose()
+		want, _ := mt.Multiply(m)
+		if !g.Equal(want) {
+			t.Fatalf("Gram differs from Transpose().Multiply")
+		}
+	}
+
+	a := NewMatrix(3, 2, q)
+	b := NewMatrix(4, 2, q)
+	if _, err := a.TransposedMulMat(b); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("TransposedMulMat with mismatched rows: got %v", err)
+	}
+}
+
+// BenchmarkGram compares TransposedMulMat with transposing first and then multiplying
+func BenchmarkGram(b *testing.B) {
+	modulus := new(big.Int).Lsh(big.NewInt(1), 61)
+	modulus.Sub(modulus, big.NewInt(1))
+	m, err := GenerateRandomMatrix(512, 64, modulus, crand.Reader)
+	if err != nil {
+		b.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+
+	b.Run("TransposedMulMat", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := m.TransposedMulMat(m); err != nil {
+				b.Fatalf("TransposedMulMat failed: %v", err)
+			}
+		}
+	})
+	b.Run("TransposeMultiply", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mt, _ := m.Transpose()
+			if _, err := mt.Multiply(m); err != nil {
+				b.Fatalf("Multiply failed: %v", err)
+			}
+		}
+	})
+}
+
+func TestMultiplyVectorColMajor(t *testing.T) {
+	q := big.NewInt(2305843009213317121)
+	shapes := [][2]int{{1, 1}, {3, 7}, {7, 3}, {ParallelStart + 5, 2}, {4, ParallelStart + 9}, {64, 33}}
+	for _, shape := range shapes {
+		m, err := GenerateRandomMatrix(shape[0], shape[1], q, crand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateRandomMatrix failed: %v", err)
+		}
+		v, _ := GenerateRandomVector(shape[0], q, crand.Reader)
+		got, err := m.MultiplyVectorColMajor(v)
+		if err != nil {
+			t.Fatalf("%dx%d: MultiplyVectorColMajor failed: %v", shape[0], shape[1], err)
+		}
+		mt, _ := m.Transpose()
+		want, _ := mt.MultiplyVector(v)
+		if !got.Equal(want) {
+			t.Fatalf("%dx%d: MultiplyVectorColMajor differs from Transpose().MultiplyVector", shape[0], shape[1])
+		}
+	}
+
+	m := NewMatrix(3, 2, q)
+	if _, err := m.MultiplyVectorColMajor(NewVector(2, q)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("MultiplyVectorColMajor with a vector of length Cols: got %v", err)
+	}
+}
+
+func TestRowColView(t *testing.T) {
+	q := big.NewInt(97)
+	m, _ := GenerateRandomMatrix(4, 6, q, crand.Reader)
+	v, _ := GenerateRandomVector(6, q, crand.Reader)
+	w, _ := GenerateRandomVector(4, q, crand.Reader)
+
+	row, err := m.RowView(2)
+	if err != nil || row.Length() != 6 || !row.Clone().Equal(m.Row(2)) {
+		t.Fatalf("RowView(2) differs from Row(2): %v", err)
+	}
+	col, err := m.ColView(5)
+	if err != nil || col.Length() != 4 || !col.Clone().Equal(m.Col(5)) {
+		t.Fatalf("ColView(5) differs from Col(5): %v", err)
+	}
+	want, _ := m.Row(2).DotProduct(v)
+	if got, _ := row.DotProduct(v); got.Cmp(want) != 0 {
+		t.Fatalf("RowView.DotProduct differs from Vector.DotProduct")
+	}
+	want, _ = m.Col(5).DotProduct(w)
+	if got, _ := col.DotProduct(w); got.Cmp(want) != 0 {
+		t.Fatalf("ColView.DotProduct differs from Vector.DotProduct")
+	}
+
+	// Views alias the matrix, but Get and Clone hand out copies
+	m.Set(2, 5, big.NewInt(42))
+	if row.Get(5).Int64() != 42 || col.Get(2).Int64() != 42 {
+		t.Fatalf("views do not observe writes to the matrix")
+	}
+	row.Get(5).SetInt64(1)
+	row.Clone().Values[5].SetInt64(1)
+	if m.Get(2, 5).Int64() != 42 {
+		t.Fatalf("mutating a copy from a view changed the matrix")
+	}
+
+	for _, bad := range []int{-1, 4} {
+		if _, err := m.RowView(bad); !errors.Is(err, ErrInvalidDimensions) {
+			t.Errorf("RowView(%d): got %v", bad, err)
+		}
+	}
+	for _, bad := range []int{-1, 6} {
+		if _, err := m.ColView(bad); !errors.Is(err, ErrInvalidDimensions) {
+			t.Errorf("ColView(%d): got %v", bad, err)
+		}
+	}
+	if _, err := row.DotProduct(w); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("RowView.DotProduct with a mismatched length: got %v", err)
+	}
+}
+
+// BenchmarkMultiplyVectorColMajor compares the column-major product with transposing first
+func BenchmarkMultiplyVectorColMajor(b *testing.B) {
+	modulus := new(big.Int).Lsh(big.NewInt(1), 61)
+	modulus.Sub(modulus, big.NewInt(1))
+	m, err := GenerateRandomMatrix(1024, 256, modulus, crand.Reader)
+	if err != nil {
+		b.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+	v, _ := GenerateRandomVector(1024, modulus, crand.Reader)
+
+	b.Run("ColMajor", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := m.MultiplyVectorColMajor(v); err != nil {
+				b.Fatalf("MultiplyVectorColMajor failed: %v", err)
+			}
+		}
+	})
+	b.Run("TransposeMultiply", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mt, _ := m.Transpose()
+			if _, err := mt.MultiplyVector(v); err != nil {
+				b.Fatalf("MultiplyVector failed: %v", err)
+			}
+		}
+	})
+	mt, _ := m.Transpose()
+	b.Run("PretransposedMultiply", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := mt.MultiplyVector(v); err != nil {
+				b.Fatalf("MultiplyVector failed: %v", err)
+			}
+		}
+	})
+}
+
+func TestElementAt(t *testing.T) {
+	q := big.NewInt(97)
+	v, _ := GenerateRandomVector(5, q, crand.Reader)
+	got, err := v.ElementAt(4)
+	if err != nil || got.Cmp(v.Values[4]) != 0 {
+		t.Fatalf("Vector.ElementAt(4) = %v, %v; want %v", got, err, v.Values[4])
+	}
+	got.Add(got, big.NewInt(1))
+	if got.Cmp(v.Values[4]) == 0 {
+		t.Fatalf("Vector.ElementAt returned a shared element")
+	}
+	for _, i := range []int{-1, 5} {
+		if _, err := v.ElementAt(i); !errors.Is(err, ErrInvalidDimensions) {
+			t.Errorf("Vector.ElementAt(%d): got %v", i, err)
+		}
+	}
+
+	m, _ := GenerateRandomMatrix(3, 4, q, crand.Reader)
+	got, err = m.ElementAt(2, 3)
+	if err != nil || got.Cmp(m.Values[2][3]) != 0 {
+		t.Fatalf("Matrix.ElementAt(2, 3) = %v, %v; want %v", got, err, m.Values[2][3])
+	}
+	for _, pos := range [][2]int{{-1, 0}, {0, -1}, {3, 0}, {0, 4}} {
+		if _, err := m.ElementAt(pos[0], pos[1]); !errors.Is(err, ErrInvalidDimensions) {
+			t.Errorf("Matrix.ElementAt(%d, %d): got %v", pos[0], pos[1], err)
+		}
+	}
+}
+
+func TestMatrixCSV(t *testing.T) {
+	q := big.NewInt(2305843009213317121)
+	qMinus1 := new(big.Int).Sub(q, big.NewInt(1))
+	m, _ := FromBigIntRows([][]*big.Int{
+		{big.NewInt(0), big.NewInt(1), big.NewInt(2)},
+		{big.NewInt(42), qMinus1, big.NewInt(7)},
+		{big.NewInt(1 << 40), big.NewInt(3), big.NewInt(9)},
+	}, q, false)
+
+	data, err := m.MarshalCSV()
+	if err != nil {
+		t.Fatalf("MarshalCSV failed: %v", err)
+	}
+	if want := "0,1,2\n42,2305843009213317120,7\n1099511627776,3,9\n"; string(data) != want {
+		t.Fatalf("MarshalCSV = %q, want %q", data, want)
+	}
+	decoded, err := MatrixFromCSV(data, q)
+	if err != nil {
+		t.Fatalf("MatrixFromCSV failed: %v", err)
+	}
+	if decoded.Rows != 3 || decoded.Cols != 3 {
+		t.Fatalf("decoded a %dx%d matrix", decoded.Rows, decoded.Cols)
+	}
+	for i := range 3 {
+		for j := range 3 {
+			if decoded.Get(i, j).Cmp(m.Get(i, j)) != 0 {
+				t.Fatalf("element (%d, %d) = %v, want %v", i, j, decoded.Get(i, j), m.Get(i, j))
+			}
+		}
+	}
+
+	// Centered values exported by other tools are reduced
+	if got, err := MatrixFromCSV([]byte("-1, 5\n"), q); err != nil || got.Get(0, 0).Cmp(qMinus1) != 0 {
+		t.Fatalf("negative element was not reduced: %v", err)
+	}
+	for _, bad := range []string{"1,2\n3\n", "1,x\n", "1,\n"} {
+		if _, err := MatrixFromCSV([]byte(bad), q); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("MatrixFromCSV(%q): got %v", bad, err)
+		}
+	}
+}
+
+func TestHexEncoding(t *testing.T) {
+	// The modulus and lattice dimension of the Security16 parameter set
+	q := big.NewInt(2305843009213317121)
+	const n = 128
+	v, err := NewGaussianVector(n, 1e12, []byte("hex"), q)
+	if err != nil {
+		t.Fatalf("NewGaussianVector failed: %v", err)
+	}
+	v.Values[0].Sub(q, big.NewInt(1))
+	v.Values[1].SetInt64(0)
+
+	s := v.MarshalHex()
+	fields := strings.Fields(s)
+	if len(fields) != n || len(s) != n*17-1 {
+		t.Fatalf("MarshalHex produced %d fields in %d characters", len(fields), len(s))
+	}
+	if fields[0] != "1ffffffffffa4000" || fields[1] != "0000000000000000" {
+		t.Fatalf("unexpected leading elements %s %s", fields[0], fields[1])
+	}
+	for i, field := range fields {
+		val, ok := new(big.Int).SetString(field, 16)
+		if !ok || val.Cmp(v.Values[i]) != 0 {
+			t.Fatalf("field %d = %s, want %x", i, field, v.Values[i])
+		}
+	}
+
+	decoded, err := VectorFromHex(s, n, q)
+	if err != nil || !decoded.Equal(v) {
+		t.Fatalf("round trip failed: %v", err)
+	}
+	if decoded, err := VectorFromHex(strings.Join(fields, ""), n, q); err != nil || !decoded.Equal(v) {
+		t.Fatalf("round trip without separators failed: %v", err)
+	}
+	for _, bad := range []string{s[:len(s)-1], s + " 00", "zz" + s[2:]} {
+		if _, err := VectorFromHex(bad, n, q); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("VectorFromHex of malformed input: got %v", err)
+		}
+	}
+
+	m := NewMatrix(4, n, q)
+	for i := range m.Values {
+		row, _ := NewGaussianVector(n, 1e12, []byte{byte(i)}, q)
+		m.Values[i] = row.Values
+	}
+	ms := m.MarshalHex()
+	if strings.Count(ms, "\n") != 3 || strings.Split(ms, "\n")[2] != m.Row(2).MarshalHex() {
+		t.Fatalf("matrix rows are not newline-separated vector encodings")
+	}
+	dm, err := MatrixFromHex(ms+"\n", 4, n, q)
+	if err != nil || !dm.Equal(m) {
+		t.Fatalf("matrix round trip failed: %v", err)
+	}
+	if _, err := MatrixFromHex(ms, 3, n, q); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("MatrixFromHex with the wrong row count: got %v", err)
+	}
+}
+
+func TestGenerateSampleDVectorDeterminism(t *testing.T) {
+	q := big.NewInt(2305843009213317121)
+	const n = 1024
+	rho := make([]byte, 32)
+	rho[0], rho[31] = 0, 7 // a leading zero byte that big.Int.Bytes would drop
+
+	a, err := GenerateSampleDVector(n, 3.2, rho, q)
+	if err != nil {
+		t.Fatalf("GenerateSampleDVector failed: %v", err)
+	}
+	b, _ := GenerateSampleDVector(n, 3.2, rho, q)
+	if !a.Equal(b) {
+		t.Fatalf("equal seeds produced different vectors")
+	}
+
+	// The encapsulator builds a ring per call and the decapsulator reuses one; both must agree
+	r, err := ring.NewRing(n, []uint64{q.Uint64()})
+	if err != nil {
+		t.Fatalf("NewRing failed: %v", err)
+	}
+	withRing, err := GenerateSampleDVectorWithRing(r, 3.2, rho, q)
+	if err != nil || !withRing.Equal(a) {
+		t.Fatalf("GenerateSampleDVectorWithRing differs from GenerateSampleDVector: %v", err)
+	}
+
+	// rho is used at its full width: dropping the leading zero changes the samples
+	stripped := new(big.Int).SetBytes(rho).Bytes()
+	if len(stripped) == len(rho) {
+		t.Fatalf("test seed has no leading zero")
+	}
+	if c, _ := GenerateSampleDVector(n, 3.2, stripped, q); c.Equal(a) {
+		t.Fatalf("a seed without its leading zero produced the same vector")
+	}
+	if stats := (&Matrix{Rows: 1, Cols: n, Values: [][]*big.Int{a.Values}, Modulus: q}).Stats(); stats.MaxBitLen > 6 {
+		t.Fatalf("samples are not centered near zero: %+v", stats)
+	}
+}
+
+func TestTriangular(t *testing.T) {
+	q := big.NewInt(97)
+	m := NewMatrix(4, 4, q)
+	for i := range 4 {
+		for j := range 4 {
+			m.Set(i, j, big.NewInt(int64(10*i+j+1)))
+		}
+	}
+
+	upper, err := m.UpperTriangular()
+	if err != nil {
+		t.Fatalf("UpperTriangular failed: %v", err)
+	}
+	lower, err := m.LowerTriangular()
+	if err != nil {
+		t.Fatalf("LowerTriangular failed: %v", err)
+	}
+	for i := range 4 {
+		for j := range 4 {
+			if (j < i) != (upper.Get(i, j).Sign() == 0) || (j > i) != (lower.Get(i, j).Sign() == 0) {
+				t.Fatalf("wrong triangular entry at (%d, %d): upper %v, lower %v", i, j, upper.Get(i, j), lower.Get(i, j))
+			}
+		}
+	}
+
+	// U + L - diag(m) = m
+	diagonal, _ := m.Diagonal()
+	sum, err := upper.Add(lower)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	recombined, err := sum.Subtract(NewDiagonalMatrix(diagonal))
+	if err != nil || !recombined.Equal(m) {
+		t.Fatalf("U + L - D does not give back m: %v", err)
+	}
+	if m.Get(3, 0).Int64() != 31 {
+		t.Fatalf("UpperTriangular modified its receiver")
+	}
+
+	rect := NewMatrix(2, 3, q)
+	if _, err := rect.UpperTriangular(); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("UpperTriangular of a 2x3 matrix: got %v", err)
+	}
+	if _, err := rect.LowerTriangular(); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("LowerTriangular of a 2x3 matrix: got %v", err)
+	}
+	if _, err := m.Add(rect); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Add with mismatched dimensions: got %v", err)
+	}
+}
+
+func TestTriangularPredicates(t *testing.T) {
+	q := big.NewInt(97)
+	zero := NewMatrix(3, 3, q)
+	if !zero.IsUpperTriangular() || !zero.IsLowerTriangular() ||
+		!zero.IsStrictlyUpperTriangular() || !zero.IsStrictlyLowerTriangular() {
+		t.Fatalf("the zero matrix should satisfy every triangular predicate")
+	}
+
+	m, _ := GenerateRandomMatrix(4, 4, q, crand.Reader)
+	m.FillDiagonal(big.NewInt(1))
+	m.Set(3, 0, big.NewInt(5))
+	m.Set(0, 3, big.NewInt(5))
+	upper, _ := m.UpperTriangular()
+	lower, _ := m.LowerTriangular()
+	if !upper.IsUpperTriangular() || upper.IsLowerTriangular() || upper.IsStrictlyUpperTriangular() {
+		t.Fatalf("UpperTriangular output has the wrong shape")
+	}
+	if !lower.IsLowerTriangular() || lower.IsUpperTriangular() || lower.IsStrictlyLowerTriangular() {
+		t.Fatalf("LowerTriangular output has the wrong shape")
+	}
+
+	// Clearing the diagonal makes them strict
+	upper.FillDiagonal(big.NewInt(0))
+	lower.FillDiagonal(big.NewInt(0))
+	if !upper.IsStrictlyUpperTriangular() || !lower.IsStrictlyLowerTriangular() {
+		t.Fatalf("triangular matrices with a zero diagonal should be strictly triangular")
+	}
+	if m.IsUpperTriangular() || m.IsLowerTriangular() {
+		t.Fatalf("a full matrix is not triangular")
+	}
+}
+
+func TestTraceInner(t *testing.T) {
+	q := big.NewInt(17)
+	for n := 1; n <= 5; n++ {
+		a, _ := GenerateRandomMatrix(n, n, q, crand.Reader)
+		b, _ := GenerateRandomMatrix(n, n, q, crand.Reader)
+		got, err := a.TraceInner(b)
+		if err != nil {
+			t.Fatalf("TraceInner failed: %v", err)
+		}
+		bt, _ := b.Transpose()
+		product, _ := a.Multiply(bt)
+		want, err := product.Trace()
+		if err != nil || got.Cmp(want) != 0 {
+			t.Fatalf("%dx%d: TraceInner = %v, Tr(A·B^T) = %v (%v)", n, n, got, want, err)
+		}
+	}
+
+	rect := NewMatrix(2, 3, q)
+	rect.Fill(big.NewInt(4))
+	if got, err := rect.TraceInner(rect); err != nil || got.Int64() != 6*16%17 {
+		t.Fatalf("TraceInner of a 2x3 matrix = %v, %v", got, err)
+	}
+	if _, err := rect.TraceInner(NewMatrix(3, 2, q)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("TraceInner with mismatched shapes: got %v", err)
+	}
+	if _, err := rect.Trace(); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Trace of a 2x3 matrix: got %v", err)
+	}
+}
+
+// negacyclicMultiply multiplies a and b in Z_Q[x]/(x^n + 1) by schoolbook multiplication
+func negacyclicMultiply(a, b *Vector) *Vector {
+	n := a.Length()
+	result := NewVector(n, a.Modulus)
+	product := new(big.Int)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			product.Mul(a.Values[i], b.Values[j])
+			k := i + j
+			if k >= n {
+				k -= n
+				product.Neg(product)
+			}
+			result.Values[k].Add(result.Values[k], product)
+			result.Values[k].Mod(result.Values[k], a.Modulus)
+		}
+	}
+	return result
+}
+
+func TestVectorNegaCyclicShift(t *testing.T) {
+	modulus := big.NewInt(17)
+	v := &Vector{Values: []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3), big.NewInt(4)}, Modulus: modulus}
+
+	// Worked by hand for 1 + 2x + 3x^2 + 4x^3 with x^4 = -1 mod 17
+	cases := []struct {
+		offset int
+		want   []int64
+	}{
+		{0, []int64{1, 2, 3, 4}},
+		{1, []int64{13, 1, 2, 3}},    // -4 + x + 2x^2 + 3x^3
+		{2, []int64{14, 13, 1, 2}},   // -3 - 4x + x^2 + 2x^3
+		{4, []int64{16, 15, 14, 13}}, // -v
+		{8, []int64{1, 2, 3, 4}},
+		{-1, []int64{2, 3, 4, 16}}, // x^-1 = -x^3
+	}
+	for _, c := range cases {
+		got := v.NegaCyclicShift(c.offset)
+		for i, w := range c.want {
+			if got.Values[i].Int64() != w {
+				t.Fatalf("offset %d: got %v, expected %v", c.offset, got.Values, c.want)
+			}
+		}
+
+		// Agrees with multiplying by the monomial x^offset
+		monomial := NewVector(4, modulus)
+		k := ((c.offset % 8) + 8) % 8
+		if k < 4 {
+			monomial.Values[k].SetInt64(1)
+		} else {
+			monomial.Values[k-4].SetInt64(16)
+		}
+		if !got.Equal(negacyclicMultiply(v, monomial)) {
+			t.Fatalf("offset %d: NegaCyclicShift differs from multiplication by x^%d", c.offset, c.offset)
+		}
+	}
+
+	r, _ := GenerateRandomVector(16, big.NewInt(7681), crand.Reader)
+	for offset := -40; offset <= 40; offset++ {
+		monomial := NewVector(16, r.Modulus)
+		k := ((offset % 32) + 32) % 32
+		if k < 16 {
+			monomial.Values[k].SetInt64(1)
+		} else {
+			monomial.Values[k-16].SetInt64(7680)
+		}
+		if !r.NegaCyclicShift(offset).Equal(negacyclicMultiply(r, monomial)) {
+			t.Fatalf("offset %d: NegaCyclicShift differs from polynomial multiplication", offset)
+		}
+	}
+}
+
+func TestMatrixConcat(t *testing.T) {
+	modulus := big.NewInt(97)
+	blocks := make([]Matrix, 3)
+	for b := range blocks {
+		blocks[b] = NewMatrix(3, 4, modulus)
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 4; j++ {
+				blocks[b].Values[i][j].SetInt64(int64(b*100 + i*10 + j))
+			}
+		}
+	}
+
+	m, err := blocks[0].Concat(blocks[1], blocks[2])
+	if err != nil {
+		t.Fatalf("Concat failed: %v", err)
+	}
+	if m.Rows != 9 || m.Cols != 4 {
+		t.Fatalf("concatenated matrix is %dx%d, expected 9x4", m.Rows, m.Cols)
+	}
+	// Block boundaries at rows 2|3 and 5|6; values from later blocks are reduced mod 97
+	cases := []struct {
+		row, col int
+		want     int64
+	}{
+		{0, 0, 0},
+		{2, 3, 23},
+		{3, 0, 100 % 97},
+		{3, 3, 103 % 97},
+		{5, 3, 123 % 97},
+		{6, 0, 200 % 97},
+		{8, 3, 223 % 97},
+	}
+	for _, c := range cases {
+		if got := m.Get(c.row, c.col).Int64(); got != c.want {
+			t.Fatalf("element (%d,%d): got %d, expected %d", c.row, c.col, got, c.want)
+		}
+	}
+
+	m.Values[0][0].SetInt64(1)
+	if blocks[0].Values[0][0].Sign() != 0 {
+		t.Fatalf("Concat result aliases its receiver")
+	}
+
+	if single, err := blocks[0].Concat(); err != nil || !single.Equal(blocks[0]) {
+		t.Fatalf("Concat with no others: %v", err)
+	}
+	if _, err := blocks[0].Concat(blocks[1], NewMatrix(3, 5, modulus)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("column mismatch: got %v", err)
+	}
+}
+
+func TestMatrixSwapRowsCols(t *testing.T) {
+	modulus := big.NewInt(97)
+	m := NewMatrix(5, 4, modulus)
+	m.ForEach(func(i, j int, v *big.Int) { v.SetInt64(int64(i*10 + j)) })
+	orig := m.Clone()
+
+	// Adjacent rows, then distant rows; row slices are exchanged, not copied
+	row1, row4 := m.Values[1], m.Values[4]
+	if err := m.SwapRows(0, 1); err != nil {
+		t.Fatalf("SwapRows failed: %v", err)
+	}
+	if err := m.SwapRows(0, 4); err != nil {
+		t.Fatalf("SwapRows failed: %v", err)
+	}
+	if &m.Values[4][0] != &row1[0] || &m.Values[0][0] != &row4[0] {
+		t.Fatalf("SwapRows copied row elements")
+	}
+	for j := 0; j < 4; j++ {
+		if m.Get(0, j).Int64() != int64(40+j) || m.Get(1, j).Int64() != int64(j) || m.Get(4, j).Int64() != int64(10+j) {
+			t.Fatalf("unexpected rows after swaps: %v", m.Values)
+		}
+	}
+
+	m = orig.Clone()
+	if err := m.SwapRows(2, 2); err != nil || !m.Equal(orig) {
+		t.Fatalf("swapping a row with itself changed the matrix: %v", err)
+	}
+	if err := m.SwapCols(1, 1); err != nil || !m.Equal(orig) {
+		t.Fatalf("swapping a column with itself changed the matrix: %v", err)
+	}
+
+	if err := m.SwapCols(0, 3); err != nil {
+		t.Fatalf("SwapCols failed: %v", err)
+	}
+	if err := m.SwapCols(1, 2); err != nil {
+		t.Fatalf("SwapCols failed: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 4; j++ {
+			if m.Get(i, j).Int64() != int64(i*10+3-j) {
+				t.Fatalf("element (%d,%d): got %v, expected %d", i, j, m.Get(i, j), i*10+3-j)
+			}
+		}
+	}
+
+	for _, idx := range [][2]int{{-1, 0}, {0, 5}, {5, 5}} {
+		if err := m.SwapRows(idx[0], idx[1]); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("SwapRows(%d, %d): got %v", idx[0], idx[1], err)
+		}
+	}
+	for _, idx := range [][2]int{{-1, 0}, {0, 4}} {
+		if err := m.SwapCols(idx[0], idx[1]); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("SwapCols(%d, %d): got %v", idx[0], idx[1], err)
+		}
+	}
+}
+
+func TestVectorPadTruncate(t *testing.T) {
+	modulus := big.NewInt(97)
+	v, _ := GenerateRandomVector(5, modulus, crand.Reader)
+
+	padded, err := v.Pad(8, big.NewInt(0))
+	if err != nil {
+		t.Fatalf("Pad failed: %v", err)
+	}
+	if padded.Length() != 8 {
+		t.Fatalf("padded length %d, expected 8", padded.Length())
+	}
+	back, err := padded.Truncate(v.Length())
+	if err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if !back.Equal(v) {
+		t.Fatalf("Pad then Truncate does not round-trip")
+	}
+
+	// The fill value is reduced, and results do not alias their input
+	padded, _ = v.Pad(7, big.NewInt(-1))
+	if padded.Get(5).Int64() != 96 || padded.Get(6).Int64() != 96 {
+		t.Fatalf("fill value not reduced: %v", padded.Values)
+	}
+	padded.Values[0].SetInt64(1000)
+	back.Values[1].SetInt64(1000)
+	if v.Get(0).Int64() == 1000 || v.Get(1).Int64() == 1000 {
+		t.Fatalf("Pad or Truncate result aliases its input")
+	}
+
+	if same, err := v.Pad(5, big.NewInt(1)); err != nil || !same.Equal(v) {
+		t.Fatalf("Pad to the same length: %v", err)
+	}
+	if empty, err := v.Truncate(0); err != nil || empty.Length() != 0 {
+		t.Fatalf("Truncate to zero: %v", err)
+	}
+	if _, err := v.Pad(4, big.NewInt(0)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Pad to a shorter length: got %v", err)
+	}
+	if _, err := v.Truncate(6); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Truncate to a longer length: got %v", err)
+	}
+	if _, err := v.Truncate(-1); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Truncate to a negative length: got %v", err)
+	}
+}
+
+// inversePermutation returns q with q[perm[i]] = i
+func inversePermutation(perm []int) []int {
+	inv := make([]int, len(perm))
+	for i, p := range perm {
+		inv[p] = i
+	}
+	return inv
+}
+
+func TestPermute(t *testing.T) {
+	modulus := big.NewInt(97)
+	perm := []int{3, 0, 4, 1, 2}
+	inv := inversePermutation(perm)
+
+	v, _ := GenerateRandomVector(5, modulus, crand.Reader)
+	pv, err := v.Permute(perm)
+	if err != nil {
+		t.Fatalf("Permute failed: %v", err)
+	}
+	for i, p := range perm {
+		if pv.Get(i).Cmp(v.Get(p)) != 0 {
+			t.Fatalf("element %d: got %v, expected v[%d] = %v", i, pv.Get(i), p, v.Get(p))
+		}
+	}
+	if back, err := pv.Permute(inv); err != nil || !back.Equal(v) {
+		t.Fatalf("inverse permutation does not recover the vector: %v", err)
+	}
+
+	m, _ := GenerateRandomMatrix(5, 5, modulus, crand.Reader)
+	rows, err := m.PermuteRows(perm)
+	if err != nil {
+		t.Fatalf("PermuteRows failed: %v", err)
+	}
+	cols, err := m.PermuteCols(perm)
+	if err != nil {
+		t.Fatalf("PermuteCols failed: %v", err)
+	}
+	for i, p := range perm {
+		if !rows.Row(i).Equal(m.Row(p)) || !cols.Col(i).Equal(m.Col(p)) {
+			t.Fatalf("row or column %d is not %d of the original", i, p)
+		}
+	}
+	if back, err := rows.PermuteRows(inv); err != nil || !back.Equal(m) {
+		t.Fatalf("inverse row permutation does not recover the matrix: %v", err)
+	}
+	if back, err := cols.PermuteCols(inv); err != nil || !back.Equal(m) {
+		t.Fatalf("inverse column permutation does not recover the matrix: %v", err)
+	}
+
+	for _, bad := range [][]int{{0, 1, 2, 3}, {0, 1, 2, 3, 3}, {0, 1, 2, 3, 5}, {-1, 1, 2, 3, 4}} {
+		if _, err := v.Permute(bad); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("Permute(%v): got %v", bad, err)
+		}
+		if _, err := m.PermuteRows(bad); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("PermuteRows(%v): got %v", bad, err)
+		}
+		if _, err := m.PermuteCols(bad); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("PermuteCols(%v): got %v", bad, err)
+		}
+	}
+}
+
+func TestAssertDimensions(t *testing.T) {
+	modulus := big.NewInt(97)
+	good := NewMatrix(3, 4, modulus)
+	if err := good.AssertDimensions(3, 4); err != nil {
+		t.Fatalf("AssertDimensions on a well-formed matrix: %v", err)
+	}
+	if err := good.AssertDimensions(4, 3); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("AssertDimensions with the wrong shape: got %v", err)
+	}
+	v := NewVector(4, modulus)
+	if err := v.AssertLength(4); err != nil {
+		t.Fatalf("AssertLength on a well-formed vector: %v", err)
+	}
+	var nilVec *Vector
+	if err := nilVec.AssertLength(0); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("AssertLength on nil: got %v", err)
+	}
+
+	// Matrices whose declared shape disagrees with their values
+	ragged := NewMatrix(3, 4, modulus)
+	ragged.Values[1] = ragged.Values[1][:2]
+	short := NewMatrix(3, 4, modulus)
+	short.Rows = 4
+	malformed := map[string]Matrix{"ragged": ragged, "short": short}
+
+	for name, m := range malformed {
+		if err := m.AssertDimensions(m.Rows, m.Cols); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s: AssertDimensions: got %v", name, err)
+		}
+		other := NewMatrix(4, 2, modulus)
+		if _, err := m.Multiply(other); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s: Multiply: got %v", name, err)
+		}
+		if _, err := other.Multiply(m); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s: Multiply as right operand: got %v", name, err)
+		}
+		if _, err := m.MultiplyParallel(other, 2); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s: MultiplyParallel: got %v", name, err)
+		}
+		if _, err := good.MultiplyAdd(NewMatrix(4, 4, modulus), m); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s: MultiplyAdd with malformed addend: got %v", name, err)
+		}
+		if _, err := m.MultiplyVector(v); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s: MultiplyVector: got %v", name, err)
+		}
+		if _, err := m.ParallelMultiplyVector(v); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s: ParallelMultiplyVector: got %v", name, err)
+		}
+		if _, err := m.Transpose(); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s: Transpose: got %v", name, err)
+		}
+		if _, err := m.ParallelTranspose(); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s: ParallelTranspose: got %v", name, err)
+		}
+	}
+
+	if _, err := good.MultiplyVector(nil); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("MultiplyVector(nil): got %v", err)
+	}
+	for name, op := range map[string]func(*Vector) error{
+		"Add":        func(o *Vector) error { _, err := v.Add(o); return err },
+		"Subtract":   func(o *Vector) error { _, err := v.Subtract(o); return err },
+		"DotProduct": func(o *Vector) error { _, err := v.DotProduct(o); return err },
+		"AXPY":       func(o *Vector) error { _, err := v.AXPY(big.NewInt(1), o); return err },
+	} {
+		if err := op(nil); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s(nil): got %v", name, err)
+		}
+		if err := op(NewVector(3, modulus)); !errors.Is(err, ErrInvalidDimensions) {
+			t.Fatalf("%s with a shorter vector: got %v", name, err)
+		}
+	}
+}
+
+func TestCheckRingParameters(t *testing.T) {
+	q := big.NewInt(12289) // 12289 = 1 + 3·2^12
+	if err := CheckRingParameters(1024, q); err != nil {
+		t.Fatalf("CheckRingParameters(1024, 12289) failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		degree  int
+		modulus *big.Int
+	}{
+		{"degree not a power of two", 1000, q},
+		{"degree below minimum", 8, big.NewInt(17)},
+		{"modulus too large", 1024, new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 62), big.NewInt(1))},
+		{"modulus not 1 mod 2n", 1024, big.NewInt(12289 + 2)},
+		{"nil modulus", 1024, nil},
+	}
+	for _, tt := range tests {
+		if err := CheckRingParameters(tt.degree, tt.modulus); !errors.Is(err, ErrInvalidRing) {
+			t.Errorf("%s: expected ErrInvalidRing, got %v", tt.name, err)
+		}
+		if _, err := GenerateSampleDVector(tt.degree, 3.2, make([]byte, 32), tt.modulus); !errors.Is(err, ErrInvalidRing) {
+			t.Errorf("%s: GenerateSampleDVector expected ErrInvalidRing, got %v", tt.name, err)
+		}
+	}
+
+	r, err := ring.NewRing(1024, []uint64{q.Uint64()})
+	if err != nil {
+		t.Fatalf("NewRing failed: %v", err)
+	}
+	if _, err := GenerateSampleDVectorWithRing(nil, 3.2, make([]byte, 32), q); !errors.Is(err, ErrInvalidRing) {
+		t.Errorf("GenerateSampleDVectorWithRing(nil ring): expected ErrInvalidRing, got %v", err)
+	}
+	if _, err := GenerateSampleDVectorWithRing(r, 3.2, make([]byte, 32), nil); !errors.Is(err, ErrInvalidRing) {
+		t.Errorf("GenerateSampleDVectorWithRing(nil modulus): expected ErrInvalidRing, got %v", err)
+	}
+}
+
+func TestAddSubtractInPlace(t *testing.T) {
+	modulus := big.NewInt(7681)
+	v, _ := GenerateRandomVector(32, modulus, crand.Reader)
+	w, _ := GenerateRandomVector(32, modulus, crand.Reader)
+
+	wantSum, _ := v.Add(w)
+	wantDiff, _ := v.Subtract(w)
+	got := v.Clone()
+	if err := got.AddInPlace(w); err != nil || !got.Equal(wantSum) {
+		t.Fatalf("Vector.AddInPlace differs from Add: %v", err)
+	}
+	got = v.Clone()
+	if err := got.SubtractInPlace(w); err != nil || !got.Equal(wantDiff) {
+		t.Fatalf("Vector.SubtractInPlace differs from Subtract: %v", err)
+	}
+	if err := got.AddInPlace(NewVector(31, modulus)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Vector.AddInPlace with mismatched lengths: got %v", err)
+	}
+	if err := got.SubtractInPlace(NewVector(33, modulus)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Vector.SubtractInPlace with mismatched lengths: got %v", err)
+	}
+
+	// Adding a vector to itself doubles it
+	got = v.Clone()
+	wantDouble, _ := v.Add(v)
+	if err := got.AddInPlace(got); err != nil || !got.Equal(wantDouble) {
+		t.Fatalf("Vector.AddInPlace of itself differs from Add: %v", err)
+	}
+
+	// Unreduced inputs are still reduced
+	u := NewVector(2, modulus)
+	u.Values[0].SetInt64(3 * 7681)
+	u.Values[1].SetInt64(-20000)
+	wantSum, _ = u.Add(u)
+	got = u.Clone()
+	if err := got.AddInPlace(u); err != nil || !got.Equal(wantSum) {
+		t.Fatalf("Vector.AddInPlace of unreduced values = %v, want %v", got.Values, wantSum.Values)
+	}
+
+	a, _ := GenerateRandomMatrix(5, 7, modulus, crand.Reader)
+	b, _ := GenerateRandomMatrix(5, 7, modulus, crand.Reader)
+	sum, diff := a.Clone(), a.Clone()
+	if err := sum.AddInPlace(b); err != nil {
+		t.Fatalf("Matrix.AddInPlace failed: %v", err)
+	}
+	if err := diff.SubtractInPlace(b); err != nil {
+		t.Fatalf("Matrix.SubtractInPlace failed: %v", err)
+	}
+	for i := 0; i < a.Rows; i++ {
+		wantSum, _ := a.Row(i).Add(b.Row(i))
+		wantDiff, _ := a.Row(i).Subtract(b.Row(i))
+		if !sum.Row(i).Equal(wantSum) || !diff.Row(i).Equal(wantDiff) {
+			t.Fatalf("row %d of the in-place results differs from Vector.Add/Subtract", i)
+		}
+	}
+	if err := sum.AddInPlace(NewMatrix(7, 5, modulus)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Matrix.AddInPlace with mismatched dimensions: got %v", err)
+	}
+	if err := diff.SubtractInPlace(NewMatrix(5, 6, modulus)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Matrix.SubtractInPlace with mismatched dimensions: got %v", err)
+	}
+}
+
+func BenchmarkAddInPlace(b *testing.B) {
+	modulus := new(big.Int).Lsh(big.NewInt(1), 61)
+	modulus.Sub(modulus, big.NewInt(1))
+	v, _ := GenerateRandomVector(8192, modulus, crand.Reader)
+	w, _ := GenerateRandomVector(8192, modulus, crand.Reader)
+	m, _ := GenerateRandomMatrix(64, 128, modulus, crand.Reader)
+	other, _ := GenerateRandomMatrix(64, 128, modulus, crand.Reader)
+
+	b.Run("Vector/Add", func(b *testing.B) {
+		b.ReportAllocs()
+		acc := v.Clone()
+		for i := 0; i < b.N; i++ {
+			acc, _ = acc.Add(w)
+		}
+	})
+	b.Run("Vector/AddInPlace", func(b *testing.B) {
+		b.ReportAllocs()
+		acc := v.Clone()
+		for i := 0; i < b.N; i++ {
+			acc.AddInPlace(w)
+		}
+	})
+	b.Run("Matrix/RowAdd", func(b *testing.B) {
+		b.ReportAllocs()
+		acc := m.Clone()
+		for i := 0; i < b.N; i++ {
+			for r := 0; r < acc.Rows; r++ {
+				row, _ := acc.Row(r).Add(other.Row(r))
+				acc.Values[r] = row.Values
+			}
+		}
+	})
+	b.Run("Matrix/AddInPlace", func(b *testing.B) {
+		b.ReportAllocs()
+		acc := m.Clone()
+		for i := 0; i < b.N; i++ {
+			acc.AddInPlace(other)
+		}
+	})
+}
+
+func TestBigIntConversions(t *testing.T) {
+	modulus := big.NewInt(7681)
+	m, _ := GenerateRandomMatrix(3, 4, modulus, crand.Reader)
+
+	for _, share := range []bool{false, true} {
+		rows := m.RawRows(share)
+		got, err := FromBigIntRows(rows, modulus, share)
+		if err != nil || !got.Equal(m) {
+			t.Fatalf("share=%v: matrix round trip failed: %v", share, err)
+		}
+		// Only a shared conversion aliases the source elements
+		if aliased := got.Values[1][2] == rows[1][2]; aliased != share {
+			t.Fatalf("share=%v: FromBigIntRows aliasing is %v", share, aliased)
+		}
+		if aliased := m.Values[1][2] == rows[1][2]; aliased != share {
+			t.Fatalf("share=%v: RawRows aliasing is %v", share, aliased)
+		}
+
+		v := m.Row(0)
+		values := v.RawValues(share)
+		gotV, err := FromBigInts(values, modulus, share)
+		if err != nil || !gotV.Equal(v) {
+			t.Fatalf("share=%v: vector round trip failed: %v", share, err)
+		}
+		if aliased := v.Values[0] == values[0]; aliased != share {
+			t.Fatalf("share=%v: RawValues aliasing is %v", share, aliased)
+		}
+		if aliased := gotV.Values[0] == values[0]; aliased != share {
+			t.Fatalf("share=%v: FromBigInts aliasing is %v", share, aliased)
+		}
+	}
+
+	if _, err := FromBigIntRows([][]*big.Int{{big.NewInt(1)}, {}}, modulus, true); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("ragged rows: got %v", err)
+	}
+	if _, err := FromBigIntRows([][]*big.Int{{nil}}, modulus, false); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("nil element: got %v", err)
+	}
+	if _, err := FromBigInts([]*big.Int{big.NewInt(1), nil}, modulus, false); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("nil vector element: got %v", err)
+	}
+	if empty, err := FromBigIntRows(nil, modulus, false); err != nil || empty.Rows != 0 || empty.Cols != 0 {
+		t.Fatalf("empty rows: got %dx%d, %v", empty.Rows, empty.Cols, err)
+	}
+}
+
+func TestFill(t *testing.T) {
+	modulus := big.NewInt(7681)
+	m, _ := GenerateRandomMatrix(3, 5, modulus, crand.Reader)
+	m.Fill(big.NewInt(0))
+	if !m.IsAllZero() {
+		t.Fatalf("Fill(0) should produce an all-zero matrix")
+	}
+	m.Fill(big.NewInt(9))
+	m.Fill(m.Modulus)
+	if !m.IsAllZero() {
+		t.Fatalf("Fill(Q) should produce an all-zero matrix")
+	}
+	m.Fill(big.NewInt(-1))
+	if got := m.Get(2, 4); got.Int64() != 7680 {
+		t.Fatalf("Fill(-1) stored %v, want 7680", got)
+	}
+
+	// all-ones times 5 is all-fives
+	m.Fill(big.NewInt(1))
+	five := NewMatrix(5, 5, modulus)
+	if err := five.FillDiagonal(big.NewInt(5)); err != nil {
+		t.Fatalf("FillDiagonal failed: %v", err)
+	}
+	product, err := m.Multiply(five)
+	if err != nil {
+		t.Fatalf("Multiply failed: %v", err)
+	}
+	product.ForEach(func(i, j int, v *big.Int) {
+		if v.Int64() != 5 {
+			t.Fatalf("element (%d, %d) = %v, want 5", i, j, v)
+		}
+	})
+
+	v, _ := GenerateRandomVector(8, modulus, crand.Reader)
+	v.Fill(modulus)
+	if !v.IsAllZero() {
+		t.Fatalf("Vector.Fill(Q) should produce an all-zero vector")
+	}
+	v.Fill(big.NewInt(1))
+	scaled, _ := v.ScalarMultiply(big.NewInt(5))
+	for i, val := range scaled.Values {
+		if val.Int64() != 5 {
+			t.Fatalf("element %d = %v, want 5", i, val)
+		}
+	}
+}
+
+func TestSetAll(t *testing.T) {
+	modulus := big.NewInt(97)
+	values := []*big.Int{big.NewInt(5), big.NewInt(97), big.NewInt(200), big.NewInt(-1)}
+
+	v, _ := GenerateRandomVector(len(values), modulus, crand.Reader)
+	if err := v.SetAll(values); err != nil {
+		t.Fatalf("SetAll failed: %v", err)
+	}
+	want := NewVector(len(values), modulus)
+	for i, val := range values {
+		want.Set(i, val)
+	}
+	if !v.Equal(want) {
+		t.Fatalf("SetAll = %v, want %v", v.Values, want.Values)
+	}
+	for i, expected := range []int64{5, 0, 6, 96} {
+		if v.Values[i].Int64() != expected {
+			t.Fatalf("element %d = %v, want %d", i, v.Values[i], expected)
+		}
+	}
+
+	// The input is copied, not aliased
+	values[0].SetInt64(7)
+	if v.Values[0].Int64() != 5 {
+		t.Fatalf("SetAll should copy its input")
+	}
+
+	before := v.Clone()
+	if err := v.SetAll(values[:3]); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("SetAll with the wrong length: got %v", err)
+	}
+	if err := v.SetAll([]*big.Int{big.NewInt(1), nil, big.NewInt(2), big.NewInt(3)}); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("SetAll with a nil value: got %v", err)
+	}
+	if !v.Equal(before) {
+		t.Fatalf("a failed SetAll should leave the vector unchanged")
+	}
+
+	m, _ := GenerateRandomMatrix(2, 3, modulus, crand.Reader)
+	rows := [][]*big.Int{
+		{big.NewInt(1), big.NewInt(98), big.NewInt(-2)},
+		{big.NewInt(0), big.NewInt(194), big.NewInt(50)},
+	}
+	if err := m.SetAll(rows); err != nil {
+		t.Fatalf("Matrix.SetAll failed: %v", err)
+	}
+	wantM := NewMatrix(2, 3, modulus)
+	for i, row := range rows {
+		for j, val := range row {
+			wantM.Set(i, j, val)
+		}
+	}
+	if !m.Equal(wantM) {
+		t.Fatalf("Matrix.SetAll = %v, want %v", m.Values, wantM.Values)
+	}
+
+	beforeM := m.Clone()
+	if err := m.SetAll(rows[:1]); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Matrix.SetAll with too few rows: got %v", err)
+	}
+	if err := m.SetAll([][]*big.Int{rows[0], rows[1][:2]}); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("Matrix.SetAll with a short row: got %v", err)
+	}
+	if !m.Equal(beforeM) {
+		t.Fatalf("a failed Matrix.SetAll should leave the matrix unchanged")
+	}
+}
+
+func TestRandomlyPermute(t *testing.T) {
+	modulus := big.NewInt(1 << 30)
+	m, _ := GenerateRandomMatrix(16, 12, modulus, crand.Reader)
+
+	multiset := func(vectors []*Vector) map[string]int {
+		counts := make(map[string]int)
+		for _, v := range vectors {
+			counts[v.MarshalHex()]++
+		}
+		return counts
+	}
+	rowsOf := func(x Matrix) []*Vector {
+		rows := make([]*Vector, x.Rows)
+		for i := range rows {
+			rows[i] = x.Row(i)
+		}
+		return rows
+	}
+	colsOf := func(x Matrix) []*Vector {
+		cols := make([]*Vector, x.Cols)
+		for j := range cols {
+			cols[j] = x.Col(j)
+		}
+		return cols
+	}
+	sameMultiset := func(a, b map[string]int) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for k, n := range a {
+			if b[k] != n {
+				return false
+			}
+		}
+		return true
+	}
+
+	p1, err := m.RandomlyPermuteRows(crand.Reader)
+	if err != nil {
+		t.Fatalf("RandomlyPermuteRows failed: %v", err)
+	}
+	p2, err := m.RandomlyPermuteRows(crand.Reader)
+	if err != nil {
+		t.Fatalf("RandomlyPermuteRows failed: %v", err)
+	}
+	if !sameMultiset(multiset(rowsOf(p1)), multiset(rowsOf(m))) || !sameMultiset(multiset(rowsOf(p2)), multiset(rowsOf(m))) {
+		t.Fatalf("RandomlyPermuteRows changed the multiset of rows")
+	}
+	// 16! orderings: two independent shuffles agree with negligible probability
+	if p1.Equal(p2) {
+		t.Fatalf("two row permutations produced the same ordering")
+	}
+
+	c1, err := m.RandomlyPermuteCols(crand.Reader)
+	if err != nil {
+		t.Fatalf("RandomlyPermuteCols failed: %v", err)
+	}
+	c2, _ := m.RandomlyPermuteCols(crand.Reader)
+	if !sameMultiset(multiset(colsOf(c1)), multiset(colsOf(m))) {
+		t.Fatalf("RandomlyPermuteCols changed the multiset of columns")
+	}
+	if c1.Equal(c2) {
+		t.Fatalf("two column permutations produced the same ordering")
+	}
+
+	if _, err := m.RandomlyPermuteRows(bytes.NewReader(nil)); err == nil {
+		t.Fatalf("RandomlyPermuteRows should fail when the reader is exhausted")
+	}
+}
+
+func TestVectorSort(t *testing.T) {
+	modulus := big.NewInt(97)
+	v, err := NewGaussianVector(200, 3.2, []byte("sort"), modulus)
+	if err != nil {
+		t.Fatalf("NewGaussianVector failed: %v", err)
+	}
+	original := v.Clone()
+
+	sorted := v.Sort()
+	if !v.Equal(original) {
+		t.Fatalf("Sort modified its receiver")
+	}
+	for i := 1; i < sorted.Length(); i++ {
+		prev, cur := sorted.Get(i-1), sorted.Get(i)
+		if c := centeredAbs(prev, modulus).Cmp(centeredAbs(cur, modulus)); c > 0 || c == 0 && centered(prev, modulus).Cmp(centered(cur, modulus)) > 0 {
+			t.Fatalf("elements %d and %d are out of order: %v, %v", i-1, i, prev, cur)
+		}
+	}
+	if again := sorted.Sort(); !again.Equal(sorted) {
+		t.Fatalf("sorting twice changed the result")
+	}
+
+	histogram := func(x *Vector) map[int64]int {
+		counts := make(map[int64]int)
+		for _, val := range x.Values {
+			counts[val.Int64()]++
+		}
+		return counts
+	}
+	want, got := histogram(v), histogram(sorted)
+	if len(want) != len(got) {
+		t.Fatalf("Sort changed the set of values")
+	}
+	for val, n := range want {
+		if got[val] != n {
+			t.Fatalf("value %d appears %d times after sorting, %d before", val, got[val], n)
+		}
+	}
+
+	small, wantOrder := NewVector(6, modulus), NewVector(6, modulus)
+	for i, x := range []int64{96, 1, 0, 48, 49, 95} {
+		small.Set(i, big.NewInt(x))
+	}
+	for i, x := range []int64{0, 96, 1, 95, 49, 48} {
+		wantOrder.Set(i, big.NewInt(x))
+	}
+	if got := small.Sort(); !got.Equal(wantOrder) {
+		t.Fatalf("Sort = %v, want %v", got.Values, wantOrder.Values)
+	}
+}
+
+func TestUniqueRows(t *testing.T) {
+	modulus := big.NewInt(97)
+	base, _ := GenerateRandomMatrix(4, 6, modulus, crand.Reader)
+
+	// Rows 0, 1, 2, 3 followed by copies of 1, 0, 1; the last copy of row 3 is unreduced
+	order := []int{0, 1, 1, 2, 0, 3, 1, 3}
+	m := NewMatrix(len(order), base.Cols, modulus)
+	for i, r := range order {
+		for j := 0; j < base.Cols; j++ {
+			m.Values[i][j].Set(base.Values[r][j])
+		}
+	}
+	for j := 0; j < m.Cols; j++ {
+		m.Values[7][j].Add(m.Values[7][j], modulus)
+	}
+
+	unique := m.UniqueRows()
+	if unique.Rows != m.Rows-4 || unique.Cols != m.Cols {
+		t.Fatalf("UniqueRows gave %dx%d, want %dx%d", unique.Rows, unique.Cols, m.Rows-4, m.Cols)
+	}
+	if !unique.Equal(base) {
+		t.Fatalf("unique rows are not in order of first appearance: %v", unique.Values)
+	}
+
+	distinct, _ := GenerateRandomMatrix(8, 8, big.NewInt(1<<40), crand.Reader)
+	if got := distinct.UniqueRows(); !got.Equal(distinct) {
+		t.Fatalf("UniqueRows changed a matrix with distinct rows")
+	}
+}
+
+func TestIndexOfRow(t *testing.T) {
+	modulus := big.NewInt(1 << 40)
+	m, _ := GenerateRandomMatrix(5, 6, modulus, crand.Reader)
+	for _, i := range []int{0, 2, 4} {
+		got, err := m.IndexOfRow(m.Row(i))
+		if err != nil || got != i {
+			t.Fatalf("IndexOfRow(row %d) = %d, %v", i, got, err)
+		}
+		if !m.ContainsRow(m.Row(i)) {
+			t.Fatalf("ContainsRow(row %d) = false", i)
+		}
+	}
+
+	// The first of two equal rows wins, and unreduced vectors still match
+	dup := m.Clone()
+	for j := 0; j < dup.Cols; j++ {
+		dup.Values[3][j].Set(dup.Values[1][j])
+	}
+	unreduced := dup.Row(1)
+	unreduced.Values[0].Add(unreduced.Values[0], modulus)
+	if got, err := dup.IndexOfRow(unreduced); err != nil || got != 1 {
+		t.Fatalf("IndexOfRow(repeated row 1) = %d, %v; want 1", got, err)
+	}
+
+	absent := m.Row(2)
+	absent.Values[5].Add(absent.Values[5], big.NewInt(1))
+	absent.Values[5].Mod(absent.Values[5], modulus)
+	if got, err := m.IndexOfRow(absent); err != nil || got != -1 {
+		t.Fatalf("IndexOfRow(absent row) = %d, %v; want -1", got, err)
+	}
+	if m.ContainsRow(absent) {
+		t.Fatalf("ContainsRow(absent row) = true")
+	}
+
+	if got, err := m.IndexOfRow(NewVector(5, modulus)); !errors.Is(err, ErrInvalidDimensions) || got != -1 {
+		t.Fatalf("IndexOfRow with a mismatched length = %d, %v", got, err)
+	}
+	if m.ContainsRow(NewVector(5, modulus)) {
+		t.Fatalf("ContainsRow with a mismatched length = true")
+	}
+}
@@ -0,0 +1,301 @@
+package arithmetic
+
+import (
+	"math/big"
+	"math/bits"
+	"runtime"
+	"sync"
+
+	"github.com/tuneinsight/lattigo/v6/ring"
+)
+
+// PolyMatrix is a matrix over Z_q whose rows are stored as ring.Poly of degree Cols.
+// Products only use coefficient-wise ring operations, so a row can be combined with a vector
+// of the same length without leaving the ring representation; this is how key generation
+// computes A·Zb^T. The modulus must be below 2^MaxModulusBits.
+//
+// When Cols and the modulus define a ring lattigo can build (see CheckRingParameters), the
+// operations run on Ring. Otherwise Ring is nil and the same operations fall back to plain
+// word arithmetic, which is slower but gives identical results.
+type PolyMatrix struct {
+	Rows, Cols int
+	Ring       *ring.Ring
+	Values     []ring.Poly
+	// q is the modulus when Ring is nil
+	q uint64
+}
+
+// NewPolyMatrix creates a zero rows x cols matrix over a fresh ring of degree cols, or over
+// the fallback arithmetic if lattigo cannot build that ring
+func NewPolyMatrix(rows, cols int, modulus *big.Int) (*PolyMatrix, error) {
+	if rows < 0 || cols <= 0 || modulus == nil || modulus.Cmp(big.NewInt(1)) <= 0 || modulus.BitLen() > MaxModulusBits {
+		return nil, ErrInvalidDimensions
+	}
+	if CheckRingParameters(cols, modulus) == nil {
+		if r, err := ring.NewRing(cols, []uint64{modulus.Uint64()}); err == nil {
+			return NewPolyMatrixWithRing(rows, r), nil
+		}
+	}
+
+	p := &PolyMatrix{Rows: rows, Cols: cols, Values: make([]ring.Poly, rows), q: modulus.Uint64()}
+	for i := range p.Values {
+		p.Values[i] = p.newPoly()
+	}
+	return p, nil
+}
+
+// NewPolyMatrixWithRing creates a zero matrix with the given number of rows over r; the
+// column count is the ring degree
+func NewPolyMatrixWithRing(rows int, r *ring.Ring) *PolyMatrix {
+	values := make([]ring.Poly, rows)
+	for i := range values {
+		values[i] = r.NewPoly()
+	}
+	return &PolyMatrix{
+		Rows:   rows,
+		Cols:   r.N(),
+		Ring:   r,
+		Values: values,
+	}
+}
+
+// PolyMatrixFromRows wraps existing polynomials as the rows of a matrix over r without copying
+func PolyMatrixFromRows(rows []ring.Poly, r *ring.Ring) *PolyMatrix {
+	return &PolyMatrix{
+		Rows:   len(rows),
+		Cols:   r.N(),
+		Ring:   r,
+		Values: rows,
+	}
+}
+
+// PolyMatrixFromMatrix converts m, reducing every element modulo m.Modulus
+func PolyMatrixFromMatrix(m Matrix) (*PolyMatrix, error) {
+	p, err := NewPolyMatrix(m.Rows, m.Cols, m.Modulus)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < m.Rows; i++ {
+		p.setCoefficients(m.Values[i], p.Values[i])
+	}
+	return p, nil
+}
+
+// Accelerated reports whether p runs on a lattigo ring rather than the fallback arithmetic
+func (p *PolyMatrix) Accelerated() bool {
+	return p.Ring != nil
+}
+
+// Modulus returns a copy of the modulus of the underlying ring
+func (p *PolyMatrix) Modulus() *big.Int {
+	return new(big.Int).SetUint64(p.modulus())
+}
+
+// modulus returns the modulus as a word
+func (p *PolyMatrix) modulus() uint64 {
+	if p.Ring != nil {
+		return p.Ring.Modulus().Uint64()
+	}
+	return p.q
+}
+
+// newPoly returns a zero polynomial of degree Cols
+func (p *PolyMatrix) newPoly() ring.Poly {
+	if p.Ring != nil {
+		return p.Ring.NewPoly()
+	}
+	return ring.NewPoly(p.Cols, 0)
+}
+
+// newMatrix returns a zero matrix with the given number of rows over the same ring as p
+func (p *PolyMatrix) newMatrix(rows int) *PolyMatrix {
+	if p.Ring != nil {
+		return NewPolyMatrixWithRing(rows, p.Ring)
+	}
+	result := &PolyMatrix{Rows: rows, Cols: p.Cols, Values: make([]ring.Poly, rows), q: p.q}
+	for i := range result.Values {
+		result.Values[i] = result.newPoly()
+	}
+	return result
+}
+
+// setCoefficients sets the coefficients of poly to values reduced modulo the modulus
+func (p *PolyMatrix) setCoefficients(values []*big.Int, poly ring.Poly) {
+	if p.Ring != nil {
+		p.Ring.SetCoefficientsBigint(values, poly)
+		return
+	}
+	q := new(big.Int).SetUint64(p.q)
+	reduced := new(big.Int)
+	for i, val := range values {
+		poly.Coeffs[0][i] = reduced.Mod(val, q).Uint64()
+	}
+}
+
+// mulMod returns a·b mod q
+func mulMod(a, b, q uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, q)
+}
+
+// mulCoeffs sets out to the coefficient-wise product of a and b
+func (p *PolyMatrix) mulCoeffs(a, b, out ring.Poly) {
+	if p.Ring != nil {
+		p.Ring.MulCoeffsBarrett(a, b, out)
+		return
+	}
+	for i, c := range a.Coeffs[0] {
+		out.Coeffs[0][i] = mulMod(c, b.Coeffs[0][i], p.q)
+	}
+}
+
+// mulScalarThenAdd adds scalar·a to acc coefficient-wise
+func (p *PolyMatrix) mulScalarThenAdd(a ring.Poly, scalar uint64, acc ring.Poly) {
+	if p.Ring != nil {
+		p.Ring.MulScalarThenAdd(a, scalar, acc)
+		return
+	}
+	for i, c := range a.Coeffs[0] {
+		sum := acc.Coeffs[0][i] + mulMod(c, scalar, p.q)
+		if sum >= p.q {
+			sum -= p.q
+		}
+		acc.Coeffs[0][i] = sum
+	}
+}
+
+// ToMatrix materializes p as a big.Int matrix
+func (p *PolyMatrix) ToMatrix() Matrix {
+	m := NewMatrix(p.Rows, p.Cols, p.Modulus())
+	for i := 0; i < p.Rows; i++ {
+		coeffs := p.Values[i].Coeffs[0]
+		for j := 0; j < p.Cols; j++ {
+			m.Values[i][j].SetUint64(coeffs[j])
+		}
+	}
+	return m
+}
+
+// Equal reports whether p and other have the same dimensions, modulus and entries
+func (p *PolyMatrix) Equal(other *PolyMatrix) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	if p.Rows != other.Rows || p.Cols != other.Cols || p.modulus() != other.modulus() {
+		return false
+	}
+	for i := 0; i < p.Rows; i++ {
+		if !p.Values[i].Equal(&other.Values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// vectorPoly converts v into a polynomial of p's ring
+func (p *PolyMatrix) vectorPoly(v *Vector) ring.Poly {
+	poly := p.newPoly()
+	p.setCoefficients(v.Values, poly)
+	return poly
+}
+
+// sumMod returns the sum of coeffs modulo q; every coefficient must already be below q < 2^63
+func sumMod(coeffs []uint64, q uint64) uint64 {
+	var acc uint64
+	for _, c := range coeffs {
+		acc += c
+		if acc >= q {
+			acc -= q
+		}
+	}
+	return acc
+}
+
+// MulVec computes p·v. Each entry is the sum of the coefficient-wise product of a row with v.
+func (p *PolyMatrix) MulVec(v *Vector) (*Vector, error) {
+	if v.Length() != p.Cols {
+		return nil, ErrInvalidDimensions
+	}
+	q := p.modulus()
+	vPoly := p.vectorPoly(v)
+	tmp := p.newPoly()
+
+	result := NewVector(p.Rows, p.Modulus())
+	for i := 0; i < p.Rows; i++ {
+		p.mulCoeffs(p.Values[i], vPoly, tmp)
+		result.Values[i].SetUint64(sumMod(tmp.Coeffs[0], q))
+	}
+	return result, nil
+}
+
+// MulVecTransposed computes p^T·v as the linear combination Σ v[i]·row_i, without transposing p
+func (p *PolyMatrix) MulVecTransposed(v *Vector) (*Vector, error) {
+	if v.Length() != p.Rows {
+		return nil, ErrInvalidDimensions
+	}
+	modulus := p.Modulus()
+	acc := p.newPoly()
+	scalar := new(big.Int)
+	for i := 0; i < p.Rows; i++ {
+		p.mulScalarThenAdd(p.Values[i], scalar.Mod(v.Values[i], modulus).Uint64(), acc)
+	}
+
+	result := NewVector(p.Cols, modulus)
+	for j, c := range acc.Coeffs[0] {
+		result.Values[j].SetUint64(c)
+	}
+	return result, nil
+}
+
+// MulMatTransposed computes p·other^T, where other shares p's ring. This is the natural product
+// when the right-hand matrix is already held by columns, as Zb^T is during key generation.
+func (p *PolyMatrix) MulMatTransposed(other *PolyMatrix) (Matrix, error) {
+	if other.Cols != p.Cols || other.modulus() != p.modulus() {
+		return Matrix{}, ErrInvalidDimensions
+	}
+	q := p.modulus()
+	result := NewMatrix(p.Rows, other.Rows, p.Modulus())
+	if p.Rows == 0 {
+		return result, nil
+	}
+
+	workers := min(p.Rows, runtime.NumCPU())
+	rowsPerWorker := (p.Rows + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < p.Rows; start += rowsPerWorker {
+		end := min(p.Rows, start+rowsPerWorker)
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			tmp := p.newPoly()
+			for i := start; i < end; i++ {
+				for j := 0; j < other.Rows; j++ {
+					p.mulCoeffs(p.Values[i], other.Values[j], tmp)
+					result.Values[i][j].SetUint64(sumMod(tmp.Coeffs[0], q))
+				}
+			}
+		}(start, end)
+	}
+	wg.Wait()
+	return result, nil
+}
+
+// MulMat computes p·other. The columns of other are first gathered into rows of p's ring.
+func (p *PolyMatrix) MulMat(other Matrix) (Matrix, error) {
+	if other.Rows != p.Cols {
+		return Matrix{}, ErrInvalidDimensions
+	}
+	modulus := p.Modulus()
+	if other.Modulus.Cmp(modulus) != 0 {
+		return Matrix{}, ErrInvalidDimensions
+	}
+	otherT := p.newMatrix(other.Cols)
+	col := make([]*big.Int, other.Rows)
+	for j := 0; j < other.Cols; j++ {
+		for i := 0; i < other.Rows; i++ {
+			col[i] = other.Values[i][j]
+		}
+		p.setCoefficients(col, otherT.Values[j])
+	}
+	return p.MulMatTransposed(otherT)
+}
@@ -0,0 +1,229 @@
+package arithmetic
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/tuneinsight/lattigo/v6/ring"
+	"github.com/tuneinsight/lattigo/v6/utils/sampling"
+)
+
+// polyTestModulus is NTT-friendly for every power-of-two degree up to 2^13
+var polyTestModulus, _ = new(big.Int).SetString("2305843009213317121", 10)
+
+func TestPolyMatrixConversion(t *testing.T) {
+	m, _ := GenerateRandomMatrix(8, 32, polyTestModulus, crand.Reader)
+	p, err := PolyMatrixFromMatrix(m)
+	if err != nil {
+		t.Fatalf("PolyMatrixFromMatrix failed: %v", err)
+	}
+	if p.Rows != 8 || p.Cols != 32 || p.Modulus().Cmp(polyTestModulus) != 0 {
+		t.Fatalf("got %dx%d mod %v", p.Rows, p.Cols, p.Modulus())
+	}
+	if back := p.ToMatrix(); !back.Equal(m) {
+		t.Fatalf("ToMatrix does not round-trip")
+	}
+
+	again, _ := PolyMatrixFromMatrix(m)
+	if !p.Equal(again) {
+		t.Fatalf("equal matrices compare different")
+	}
+	again.Values[3].Coeffs[0][5]++
+	if p.Equal(again) {
+		t.Fatalf("different matrices compare equal")
+	}
+
+	if !p.Accelerated() {
+		t.Fatalf("an NTT-friendly shape should use a lattigo ring")
+	}
+
+	// Shapes lattigo rejects fall back to word arithmetic; moduli above 61 bits are refused
+	fallback, err := PolyMatrixFromMatrix(NewMatrix(4, 12, polyTestModulus))
+	if err != nil || fallback.Accelerated() || fallback.Cols != 12 {
+		t.Fatalf("non power-of-two columns should fall back: %v", err)
+	}
+	tooLarge := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 62), big.NewInt(1))
+	if _, err := PolyMatrixFromMatrix(NewMatrix(4, 16, tooLarge)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("62-bit modulus: got %v", err)
+	}
+}
+
+// polyFallbackModulus is a 61-bit prime that is not 1 mod 2*cols for any power-of-two cols
+var polyFallbackModulus, _ = new(big.Int).SetString("2305843009213693951", 10)
+
+func TestPolyMatrixMatchesBigInt(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		rows, cols  int
+		modulus     *big.Int
+		accelerated bool
+	}{
+		{"ring", 16, 64, polyTestModulus, true},
+		{"fallback degree", 16, 24, polyTestModulus, false},
+		{"fallback modulus", 16, 64, polyFallbackModulus, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			testPolyMatrixMatchesBigInt(t, tt.rows, tt.cols, tt.modulus, tt.accelerated)
+		})
+	}
+}
+
+func testPolyMatrixMatchesBigInt(t *testing.T, rows, cols int, modulus *big.Int, accelerated bool) {
+	m, _ := GenerateRandomMatrix(rows, cols, modulus, crand.Reader)
+	p, err := PolyMatrixFromMatrix(m)
+	if err != nil {
+		t.Fatalf("PolyMatrixFromMatrix failed: %v", err)
+	}
+	if p.Accelerated() != accelerated {
+		t.Fatalf("Accelerated() = %v, want %v", p.Accelerated(), accelerated)
+	}
+
+	v, _ := GenerateRandomVector(cols, modulus, crand.Reader)
+	got, err := p.MulVec(v)
+	if err != nil {
+		t.Fatalf("MulVec failed: %v", err)
+	}
+	want, _ := m.MultiplyVector(v)
+	if !got.Equal(want) {
+		t.Fatalf("MulVec differs from MultiplyVector")
+	}
+
+	s, _ := GenerateRandomVector(rows, modulus, crand.Reader)
+	got, err = p.MulVecTransposed(s)
+	if err != nil {
+		t.Fatalf("MulVecTransposed failed: %v", err)
+	}
+	mt, _ := m.Transpose()
+	want, _ = mt.MultiplyVector(s)
+	if !got.Equal(want) {
+		t.Fatalf("MulVecTransposed differs from Transpose().MultiplyVector")
+	}
+
+	other, _ := GenerateRandomMatrix(cols, 8, modulus, crand.Reader)
+	prod, err := p.MulMat(other)
+	if err != nil {
+		t.Fatalf("MulMat failed: %v", err)
+	}
+	wantProd, _ := m.Multiply(other)
+	if !prod.Equal(wantProd) {
+		t.Fatalf("MulMat differs from Multiply")
+	}
+
+	otherT, _ := other.Transpose()
+	otherTPoly, _ := PolyMatrixFromMatrix(otherT)
+	prod, err = p.MulMatTransposed(otherTPoly)
+	if err != nil {
+		t.Fatalf("MulMatTransposed failed: %v", err)
+	}
+	if !prod.Equal(wantProd) {
+		t.Fatalf("MulMatTransposed differs from Multiply")
+	}
+
+	if _, err := p.MulVec(s); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("MulVec with wrong length: got %v", err)
+	}
+	if _, err := p.MulVecTransposed(v); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("MulVecTransposed with wrong length: got %v", err)
+	}
+	if _, err := p.MulMat(m); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("MulMat with wrong dimensions: got %v", err)
+	}
+}
+
+func BenchmarkPolyMatrixMulVecTransposed(b *testing.B) {
+	m, _ := GenerateRandomMatrix(64, 1024, polyTestModulus, crand.Reader)
+	p, _ := PolyMatrixFromMatrix(m)
+	s, _ := GenerateRandomVector(64, polyTestModulus, crand.Reader)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.MulVecTransposed(s)
+	}
+}
+
+func TestMatrixPolyVecRoundTrip(t *testing.T) {
+	r, err := ring.NewRing(64, []uint64{polyTestModulus.Uint64()})
+	if err != nil {
+		t.Fatalf("NewRing failed: %v", err)
+	}
+	prng, _ := sampling.NewPRNG()
+	pv := InitPolyVecWithSampler(5, ring.NewUniformSampler(prng, r))
+
+	m, err := NewMatrixFromPolyVec(pv, r, polyTestModulus)
+	if err != nil {
+		t.Fatalf("NewMatrixFromPolyVec failed: %v", err)
+	}
+	if m.Rows != 5 || m.Cols != 64 {
+		t.Fatalf("matrix is %dx%d, expected 5x64", m.Rows, m.Cols)
+	}
+	for i := range pv {
+		for j, c := range pv[i].Coeffs[0] {
+			if !m.Values[i][j].IsUint64() || m.Values[i][j].Uint64() != c {
+				t.Fatalf("element (%d,%d): got %v, expected %d", i, j, m.Values[i][j], c)
+			}
+		}
+	}
+
+	back, err := m.ToPolyVec(r)
+	if err != nil {
+		t.Fatalf("ToPolyVec failed: %v", err)
+	}
+	for i := range pv {
+		if !back[i].Equal(&pv[i]) {
+			t.Fatalf("row %d does not round-trip", i)
+		}
+	}
+
+	if _, err := NewMatrixFromPolyVec(pv, r, big.NewInt(97)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("modulus mismatch: got %v", err)
+	}
+	wide := NewMatrix(2, 32, polyTestModulus)
+	if _, err := wide.ToPolyVec(r); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("column mismatch: got %v", err)
+	}
+}
+
+func TestVectorPolyRoundTrip(t *testing.T) {
+	r, err := ring.NewRing(16, []uint64{polyTestModulus.Uint64()})
+	if err != nil {
+		t.Fatalf("NewRing failed: %v", err)
+	}
+	q := polyTestModulus.Uint64()
+	p := r.NewPoly()
+	for i := range p.Coeffs[0] {
+		p.Coeffs[0][i] = uint64(i*i + 1)
+	}
+	p.Coeffs[0][15] = q - 1
+
+	v, err := NewVectorFromPoly(p, r, polyTestModulus)
+	if err != nil {
+		t.Fatalf("NewVectorFromPoly failed: %v", err)
+	}
+	if v.Length() != 16 {
+		t.Fatalf("vector has length %d, expected 16", v.Length())
+	}
+	for i := 0; i < 15; i++ {
+		if got := v.Get(i).Int64(); got != int64(i*i+1) {
+			t.Fatalf("coefficient %d: got %d, expected %d", i, got, i*i+1)
+		}
+	}
+	if want := new(big.Int).Sub(polyTestModulus, big.NewInt(1)); v.Get(15).Cmp(want) != 0 {
+		t.Fatalf("coefficient 15: got %v, expected %v", v.Get(15), want)
+	}
+
+	back, err := v.ToPoly(r)
+	if err != nil {
+		t.Fatalf("ToPoly failed: %v", err)
+	}
+	if !back.Equal(&p) {
+		t.Fatalf("polynomial does not round-trip")
+	}
+
+	if _, err := NewVectorFromPoly(p, r, big.NewInt(97)); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("modulus mismatch: got %v", err)
+	}
+	if _, err := NewVector(8, polyTestModulus).ToPoly(r); !errors.Is(err, ErrInvalidDimensions) {
+		t.Fatalf("length mismatch: got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+// Package bitsio converts between packed bytes, unpacked bit slices and vectors of small values.
+//
+// Unless a function says otherwise the bit order is LSB-first: bit i of a packed byte string is
+// bit i%8 of byte i/8, where bit 0 is the least significant bit of the byte.
+package bitsio
+
+import (
+	"math/big"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/arithmetic"
+)
+
+// UnpackBits returns the first n bits of src, one per byte, in LSB-first order: bit i is
+// (src[i/8] >> (i%8)) & 1. It returns nil if src holds fewer than n bits.
+func UnpackBits(src []byte, n int) []byte {
+	if n < 0 || len(src)*8 < n {
+		return nil
+	}
+	bits := make([]byte, n)
+	for i := range bits {
+		bits[i] = (src[i/8] >> (i % 8)) & 1
+	}
+	return bits
+}
+
+// PackBits is the inverse of UnpackBits: bit i of the result is the low bit of bits[i], in
+// LSB-first order. The last byte is zero-padded in its high bits.
+func PackBits(bits []byte) []byte {
+	dst := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		dst[i/8] |= (b & 1) << (i % 8)
+	}
+	return dst
+}
+
+// BitsToVector groups unpacked bits into len(bits)/width values of width bits each, least
+// significant bit first: element i is the sum of bits[i*width+j] << j. Trailing bits that do not
+// fill an element are ignored. The values are reduced modulo modulus. It returns nil if width
+// is not positive.
+func BitsToVector(bits []byte, width int, modulus *big.Int) *arithmetic.Vector {
+	if width <= 0 {
+		return nil
+	}
+	result := arithmetic.NewVector(len(bits)/width, modulus)
+	for i, val := range result.Values {
+		for j := width - 1; j >= 0; j-- {
+			val.Lsh(val, 1)
+			if bits[i*width+j]&1 == 1 {
+				val.SetBit(val, 0, 1)
+			}
+		}
+		val.Mod(val, modulus)
+	}
+	return result
+}
+
+// VectorToBits is the inverse of BitsToVector: it writes the low width bits of each element,
+// least significant bit first. Negative elements are written as their value modulo 2^width.
+func VectorToBits(v *arithmetic.Vector, width int) []byte {
+	if width <= 0 {
+		return nil
+	}
+	bits := make([]byte, v.Length()*width)
+	for i, val := range v.Values {
+		for j := 0; j < width; j++ {
+			bits[i*width+j] = byte(val.Bit(j))
+		}
+	}
+	return bits
+}
+
+// UnpackChunked decodes n values of width bits from src in the order used to expand the secret
+// vector s from a seed. The bits of element i are the stream bits i*width to (i+1)*width-1 in
+// LSB-first order, but they are consumed in per-byte chunks and every chunk is shifted in
+// below the previous ones: when an element starts at bit offset o of byte k and continues into
+// byte k+1, its value is (src[k] >> o) << (width-(8-o)) | (src[k+1] & (1<<(width-(8-o))-1)).
+// Within a single byte this is the same as BitsToVector(UnpackBits(src, n*width), width,
+// modulus). The values are reduced modulo modulus. It returns nil if src holds fewer than
+// n*width bits or width is not positive.
+func UnpackChunked(src []byte, n, width int, modulus *big.Int) *arithmetic.Vector {
+	if width <= 0 || n < 0 || len(src)*8 < n*width {
+		return nil
+	}
+	result := arithmetic.NewVector(n, modulus)
+	for i, val := range result.Values {
+		startBit := i * width
+		offset := startBit % 8
+		for k, remaining := startBit/8, width; remaining > 0; k++ {
+			chunk := min(8-offset, remaining)
+			val.Lsh(val, uint(chunk))
+			val.Or(val, big.NewInt(int64((src[k]>>offset)&(1<<chunk-1))))
+			remaining -= chunk
+			offset = 0
+		}
+		val.Mod(val, modulus)
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package bitsio
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"testing/quick"
+)
+
+func TestUnpackPackRoundTrip(t *testing.T) {
+	// PackBits(UnpackBits(src)) == src
+	packUnpack := func(src []byte) bool {
+		return bytes.Equal(PackBits(UnpackBits(src, len(src)*8)), src)
+	}
+	if err := quick.Check(packUnpack, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// UnpackBits(PackBits(bits)) == bits for 0/1 entries of any length
+	unpackPack := func(raw []byte) bool {
+		bits := make([]byte, len(raw))
+		for i, b := range raw {
+			bits[i] = b & 1
+		}
+		return bytes.Equal(UnpackBits(PackBits(bits), len(bits)), bits)
+	}
+	if err := quick.Check(unpackPack, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBitOrder(t *testing.T) {
+	bits := UnpackBits([]byte{0x01, 0x80}, 16)
+	for i, b := range bits {
+		want := byte(0)
+		if i == 0 || i == 15 {
+			want = 1
+		}
+		if b != want {
+			t.Fatalf("bit %d = %d, want %d", i, b, want)
+		}
+	}
+	if got := PackBits([]byte{1, 0, 1}); !bytes.Equal(got, []byte{0x05}) {
+		t.Fatalf("PackBits(1, 0, 1) = %x, want 05", got)
+	}
+	if UnpackBits([]byte{0xFF}, 9) != nil {
+		t.Fatalf("UnpackBits should fail when src is too short")
+	}
+
+	// 0b110 in LSB-first order is 3
+	v := BitsToVector([]byte{1, 1, 0}, 3, big.NewInt(8))
+	if v.Length() != 1 || v.Values[0].Int64() != 3 {
+		t.Fatalf("BitsToVector(1, 1, 0) = %v, want [3]", v.Values)
+	}
+}
+
+func TestVectorBitsRoundTrip(t *testing.T) {
+	roundTrip := func(src []byte, w uint8) bool {
+		width := int(w%16) + 1
+		bits := UnpackBits(src, len(src)*8/width*width)
+		modulus := new(big.Int).Lsh(big.NewInt(1), uint(width))
+		v := BitsToVector(bits, width, modulus)
+		return v.Length() == len(bits)/width && bytes.Equal(VectorToBits(v, width), bits)
+	}
+	if err := quick.Check(roundTrip, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// unpackChunkedReference is the decoder expandSeed used before this package existed
+func unpackChunkedReference(data []byte, length, bitsPerValue int, modulus *big.Int) []*big.Int {
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bitsPerValue)), big.NewInt(1))
+	result := make([]*big.Int, length)
+	for i := 0; i < length; i++ {
+		startBit := i * bitsPerValue
+		bitOffset := startBit % 8
+		value := big.NewInt(0)
+		bitsRemaining := bitsPerValue
+		for j := startBit / 8; bitsRemaining > 0 && j < len(data); j++ {
+			bitsToRead := min(8-bitOffset, bitsRemaining)
+			extracted := (data[j] >> bitOffset) & byte((1<<bitsToRead)-1)
+			value.Lsh(value, uint(bitsToRead))
+			value.Or(value, big.NewInt(int64(extracted)))
+			bitsRemaining -= bitsToRead
+			bitOffset = 0
+		}
+		value.And(value, mask)
+		result[i] = value.Mod(value, modulus)
+	}
+	return result
+}
+
+func TestUnpackChunkedMatchesReference(t *testing.T) {
+	matches := func(src []byte, w uint8) bool {
+		width := int(w%12) + 1
+		n := len(src) * 8 / width
+		modulus := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(width)), big.NewInt(1))
+		if width == 1 {
+			modulus = big.NewInt(2)
+		}
+		got := UnpackChunked(src, n, width, modulus)
+		want := unpackChunkedReference(src, n, width, modulus)
+		if got.Length() != n {
+			return false
+		}
+		for i := range want {
+			if got.Values[i].Cmp(want[i]) != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(matches, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Widths that divide 8 never straddle a byte, so the chunked order is plain LSB-first
+	src := []byte{0x1B, 0xE4, 0x72}
+	for _, width := range []int{1, 2, 4, 8} {
+		modulus := new(big.Int).Lsh(big.NewInt(1), uint(width))
+		got := UnpackChunked(src, len(src)*8/width, width, modulus)
+		if !got.Equal(BitsToVector(UnpackBits(src, len(src)*8), width, modulus)) {
+			t.Fatalf("width %d: UnpackChunked differs from BitsToVector", width)
+		}
+	}
+	if UnpackChunked(src, 5, 5, big.NewInt(31)) != nil {
+		t.Fatalf("UnpackChunked should fail when src is too short")
+	}
+}
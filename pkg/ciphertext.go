@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// Range is a byte range within an encoded ciphertext
+type Range struct {
+	Offset int
+	Length int
+}
+
+// End returns the offset one past the last byte of the range
+func (r Range) End() int {
+	return r.Offset + r.Length
+}
+
+// CiphertextLayout describes where each component lives in the encoding c0 || c1 || x || hatH0 || hatH1
+type CiphertextLayout struct {
+	C0, C1, X, HatH0, HatH1 Range
+}
+
+// Size returns the total length of a ciphertext with this layout
+func (l CiphertextLayout) Size() int {
+	return l.HatH1.End()
+}
+
+// ciphertextLayout computes the layout produced by constructCiphertext for the given dimensions.
+// With compressionBits > 0, hatH0 and hatH1 are stored as vectors modulo 2^compressionBits.
+func ciphertextLayout(m, lambda int, modulus *big.Int, compressionBits int) CiphertextLayout {
+	elementSize := (modulus.BitLen() + 7) / 8
+	hatHElementSize := elementSize
+	if compressionBits > 0 {
+		hatHElementSize = (compressionModulus(compressionBits).BitLen() + 7) / 8
+	}
+	cbSize := lambda / 8
+	xSize := 4 + m*elementSize
+	hatHSize := 4 + lambda*hatHElementSize
+
+	var l CiphertextLayout
+	l.C0 = Range{Offset: 0, Length: cbSize}
+	l.C1 = Range{Offset: l.C0.End(), Length: cbSize}
+	l.X = Range{Offset: l.C1.End(), Length: xSize}
+	l.HatH0 = Range{Offset: l.X.End(), Length: hatHSize}
+	l.HatH1 = Range{Offset: l.HatH0.End(), Length: hatHSize}
+	return l
+}
+
+// CiphertextLayout returns the offsets and lengths of the ciphertext components for these parameters
+func (p Parameters) CiphertextLayout() CiphertextLayout {
+	return ciphertextLayout(p.LatticeParams.M, p.LatticeParams.Lambda, p.LatticeParams.Q, p.GaussianParams.CompressionBits)
+}
+
+// CiphertextC0 returns the c0 component of ct without copying
+func (p Parameters) CiphertextC0(ct []byte) ([]byte, error) {
+	return p.ciphertextComponent(ct, "c0", p.CiphertextLayout().C0)
+}
+
+// CiphertextC1 returns the c1 component of ct without copying
+func (p Parameters) CiphertextC1(ct []byte) ([]byte, error) {
+	return p.ciphertextComponent(ct, "c1", p.CiphertextLayout().C1)
+}
+
+// CiphertextX returns the encoded x component of ct without copying
+func (p Parameters) CiphertextX(ct []byte) ([]byte, error) {
+	return p.ciphertextComponent(ct, "x", p.CiphertextLayout().X)
+}
+
+// CiphertextHatH0 returns the encoded hatH0 component of ct without copying
+func (p Parameters) CiphertextHatH0(ct []byte) ([]byte, error) {
+	return p.ciphertextComponent(ct, "hatH0", p.CiphertextLayout().HatH0)
+}
+
+// CiphertextHatH1 returns the encoded hatH1 component of ct without copying
+func (p Parameters) CiphertextHatH1(ct []byte) ([]byte, error) {
+	return p.ciphertextComponent(ct, "hatH1", p.CiphertextLayout().HatH1)
+}
+
+// ciphertextComponent checks that ct has the expected total length and slices out r
+func (p Parameters) ciphertextComponent(ct []byte, name string, r Range) ([]byte, error) {
+	if size := p.CiphertextLayout().Size(); len(ct) != size {
+		return nil, fmt.Errorf("%w: ciphertext is %d bytes, expected %d", ErrInvalidCiphertext, len(ct), size)
+	}
+	if r.Offset < 0 || r.End() > len(ct) {
+		return nil, fmt.Errorf("%w: %s out of range", ErrInvalidCiphertext, name)
+	}
+	return ct[r.Offset:r.End():r.End()], nil
+}
+
+// ValidateCiphertext performs structural checks on ct without any cryptographic work: the total
+// length, the component ranges and the length headers of the embedded vectors
+func (kem *OwChCCAKEM) ValidateCiphertext(ct []byte) error {
+	layout := kem.Params.CiphertextLayout()
+	tagSize := 0
+	if kem.keyConfirmation {
+		tagSize = ConfirmationTagSize
+	}
+	if size := kem.CiphertextSize(); len(ct) != size || layout.Size()+tagSize != size {
+		return fmt.Errorf("%w: ciphertext is %d bytes, expected %d", ErrInvalidCiphertext, len(ct), size)
+	}
+
+	vectors := []struct {
+		name   string
+		r      Range
+		length int
+	}{
+		{"x", layout.X, kem.Params.LatticeParams.M},
+		{"hatH0", layout.HatH0, kem.Params.LatticeParams.Lambda},
+		{"hatH1", layout.HatH1, kem.Params.LatticeParams.Lambda},
+	}
+	for _, v := range vectors {
+		if v.r.Offset < layout.C1.End() || v.r.Length < 4 || v.r.End() > len(ct) {
+			return fmt.Errorf("%w: %s range [%d, %d) out of bounds", ErrInvalidCiphertext, v.name, v.r.Offset, v.r.End())
+		}
+		if got := int(binary.BigEndian.Uint32(ct[v.r.Offset : v.r.Offset+4])); got != v.length {
+			return fmt.Errorf("%w: %s at offset %d has length header %d, expected %d", ErrInvalidCiphertext, v.name, v.r.Offset, got, v.length)
+		}
+	}
+
+	return nil
+}
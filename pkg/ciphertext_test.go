@@ -0,0 +1,112 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/arithmetic"
+)
+
+func TestCiphertextLayoutMatchesConstruction(t *testing.T) {
+	for _, name := range ListParameterSets() {
+		params, err := GetParameterSet(name)
+		if err != nil {
+			t.Fatalf("GetParameterSet failed: %v", err)
+		}
+		t.Run(name, func(t *testing.T) {
+			m := params.LatticeParams.M
+			lambda := params.LatticeParams.Lambda
+			modulus := params.LatticeParams.Q
+
+			c0 := make([]byte, lambda/8)
+			c1 := make([]byte, lambda/8)
+			rand.Read(c0)
+			rand.Read(c1)
+			x, _ := arithmetic.GenerateRandomVector(m, modulus, rand.Reader)
+			hatH0, _ := arithmetic.GenerateRandomVector(lambda, modulus, rand.Reader)
+			hatH1, _ := arithmetic.GenerateRandomVector(lambda, modulus, rand.Reader)
+
+			ct, err := constructCiphertext(params.KeyParams.CiphertextSize, 0, c0, c1, x, hatH0, hatH1)
+			if err != nil {
+				t.Fatalf("constructCiphertext failed: %v", err)
+			}
+
+			layout := params.CiphertextLayout()
+			if layout.Size() != len(ct) || layout.Size() != params.CiphertextSize() {
+				t.Fatalf("layout size %d, ciphertext %d, CiphertextSize %d", layout.Size(), len(ct), params.CiphertextSize())
+			}
+
+			xBytes, _ := x.MarshalBinaryRaw()
+			hatH0Bytes, _ := hatH0.MarshalBinaryRaw()
+			hatH1Bytes, _ := hatH1.MarshalBinaryRaw()
+			components := []struct {
+				name   string
+				get    func([]byte) ([]byte, error)
+				r      Range
+				expect []byte
+			}{
+				{"c0", params.CiphertextC0, layout.C0, c0},
+				{"c1", params.CiphertextC1, layout.C1, c1},
+				{"x", params.CiphertextX, layout.X, xBytes},
+				{"hatH0", params.CiphertextHatH0, layout.HatH0, hatH0Bytes},
+				{"hatH1", params.CiphertextHatH1, layout.HatH1, hatH1Bytes},
+			}
+			for _, c := range components {
+				got, err := c.get(ct)
+				if err != nil {
+					t.Fatalf("%s accessor failed: %v", c.name, err)
+				}
+				if !bytes.Equal(got, c.expect) || !bytes.Equal(ct[c.r.Offset:c.r.End()], c.expect) {
+					t.Fatalf("%s does not match the constructed ciphertext", c.name)
+				}
+				if _, err := c.get(ct[:len(ct)-1]); !errors.Is(err, ErrInvalidCiphertext) {
+					t.Fatalf("%s accessor on truncated ciphertext: got %v", c.name, err)
+				}
+			}
+
+			pc0, pc1, px, ph0, ph1, err := parseCiphertext(ct, m, lambda, modulus, 0)
+			if err != nil {
+				t.Fatalf("parseCiphertext failed: %v", err)
+			}
+			if !bytes.Equal(pc0, c0) || !bytes.Equal(pc1, c1) || !px.Equal(x) || !ph0.Equal(hatH0) || !ph1.Equal(hatH1) {
+				t.Fatalf("parseCiphertext did not recover the components")
+			}
+		})
+	}
+}
+
+func TestValidateCiphertext(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, _, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	ct, _, err := kem.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+
+	if err := kem.ValidateCiphertext(ct); err != nil {
+		t.Fatalf("ValidateCiphertext rejected a valid ciphertext: %v", err)
+	}
+	if err := kem.ValidateCiphertext(nil); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("zero-length ciphertext: got %v", err)
+	}
+	if err := kem.ValidateCiphertext(ct[:len(ct)-1]); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("too-short ciphertext: got %v", err)
+	}
+	if err := kem.ValidateCiphertext(append(append([]byte(nil), ct...), 0)); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("too-long ciphertext: got %v", err)
+	}
+
+	layout := kem.Params.CiphertextLayout()
+	for _, r := range []Range{layout.X, layout.HatH0, layout.HatH1} {
+		bad := append([]byte(nil), ct...)
+		bad[r.Offset+3]++
+		if err := kem.ValidateCiphertext(bad); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Fatalf("wrong embedded dimension at offset %d: got %v", r.Offset, err)
+		}
+	}
+}
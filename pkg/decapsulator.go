@@ -0,0 +1,284 @@
+package pkg
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"sync"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/arithmetic"
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/subtleops"
+	"github.com/tuneinsight/lattigo/v6/ring"
+)
+
+// Decapsulator holds the values derived from a private key that every decapsulation reuses.
+// It shares the matrices of the key rather than copying them, so the key must not be modified,
+// for example by one of its unmarshaling methods, once the Decapsulator has been built. As long
+// as it is not, the Decapsulator is safe for concurrent use.
+type Decapsulator struct {
+	params Parameters
+	sk     *PrivateKey
+	pRing  *ring.Ring             // nil when the parameters use the pure-Go fallback
+	zb     arithmetic.Matrix      // Zb, multiplied transposed in place
+	a      *arithmetic.PolyMatrix // A, multiplied transposed in ring form
+	ub     arithmetic.Matrix      // U_b, multiplied transposed in place
+	unb    arithmetic.Matrix      // U_{1-b}, multiplied transposed in place
+
+	keyConfirmation bool // expect a key confirmation tag, see OwChCCAKEM.WithKeyConfirmation
+
+	digestOnce sync.Once
+	pkDigest   []byte
+}
+
+// NewDecapsulator validates the private key, selects U_b and U_{1-b} and precomputes the ring
+func (sk *PrivateKey) NewDecapsulator(opts ...Option) (*Decapsulator, error) {
+	if err := sk.Validate(); err != nil {
+		return nil, err
+	}
+
+	d, err := newDecapsulator(sk.Pk.Parameters(), sk)
+	if err != nil {
+		return nil, err
+	}
+	d.keyConfirmation = newOptions(opts).keyConfirmation
+	return d, nil
+}
+
+// newDecapsulator builds the precomputed state for decapsulating under params
+func newDecapsulator(params Parameters, sk *PrivateKey) (*Decapsulator, error) {
+	pk := sk.Pk
+
+	pRing, err := newParamsRing(params)
+	if err != nil {
+		return nil, err
+	}
+
+	ub, unb := pk.u1, pk.u0
+	if !sk.b {
+		ub, unb = pk.u0, pk.u1
+	}
+
+	return &Decapsulator{
+		params: params,
+		sk:     sk,
+		pRing:  pRing,
+		zb:     sk.zb,
+		a:      pk.a,
+		ub:     ub,
+		unb:    unb,
+	}, nil
+}
+
+// PublicKeyDigest returns the SHA3-256 digest of the serialized public key. It is computed on
+// first use, since it hashes the whole encoding.
+func (d *Decapsulator) PublicKeyDigest() []byte {
+	d.digestOnce.Do(func() {
+		// The key was validated by NewDecapsulator, so it serializes
+		d.pkDigest, _ = d.sk.Pk.Fingerprint()
+	})
+	return append([]byte(nil), d.pkDigest...)
+}
+
+// Decapsulate recovers the shared key from a ciphertext
+func (d *Decapsulator) Decapsulate(ciphertext []byte) (sharedKey []byte, err error) {
+	return d.DecapsulateContext(context.Background(), ciphertext)
+}
+
+// DecapsulateContext is Decapsulate for callers that label their goroutines for pprof. While
+// profiling is enabled, the phase labels are added to the labels of ctx. The goroutine is left
+// with the labels it had on entry; see EnableProfiling.
+func (d *Decapsulator) DecapsulateContext(ctx context.Context, ciphertext []byte) (sharedKey []byte, err error) {
+	return d.decapsulate(ctx, ciphertext, d.keyConfirmation)
+}
+
+// decapsulate is DecapsulateContext, expecting a key confirmation tag if confirm is set
+func (d *Decapsulator) decapsulate(ctx context.Context, ciphertext []byte, confirm bool) (sharedKey []byte, err error) {
+	sk := d.sk
+	defer enterPhases().restore()
+
+	// Get parameter values
+	n := d.params.LatticeParams.N
+	m := d.params.LatticeParams.M
+	lambda := d.params.LatticeParams.Lambda
+	logEta := d.params.GaussianParams.LogEta
+	modulus := d.params.LatticeParams.Q
+	alphaPrime := d.params.GaussianParams.AlphaPrime
+	sharedKeySize := d.params.KeyParams.SharedKeySize
+	compressionBits := d.params.GaussianParams.CompressionBits
+
+	// Split off the key confirmation tag
+	var tag []byte
+	if confirm {
+		if len(ciphertext) < ConfirmationTagSize {
+			return nil, fmt.Errorf("failed to parse ciphertext: %w: missing key confirmation tag", ErrInvalidCiphertext)
+		}
+		ciphertext, tag = ciphertext[:len(ciphertext)-ConfirmationTagSize], ciphertext[len(ciphertext)-ConfirmationTagSize:]
+	}
+
+	// Parse ciphertext
+	setPhase(ctx, phaseSerialization)
+	c0, c1, x, hatH0, hatH1, err := parseCiphertext(ciphertext, m, lambda, modulus, compressionBits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ciphertext: %w", err)
+	}
+
+	// Determine which components to use based on the b flag
+	var hatHb, hatHnb *arithmetic.Vector
+	var hb, hnb *arithmetic.Vector
+	var cb, cnb []byte
+	branch := 0
+
+	if sk.b {
+		hatHb, hatHnb = hatH1, hatH0
+		cb, cnb = c1, c0
+		branch = 1
+	} else {
+		hatHb, hatHnb = hatH0, hatH1
+		cb, cnb = c0, c1
+	}
+
+	// Calculate Zb^T*x
+	setPhase(ctx, phaseMatVec)
+	zbtx, err := d.zb.MultiplyVectorColMajor(x)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute Zb^T*x: %w", err)
+	}
+
+	// Calculate hatHb - Zb^T*x
+	diff, err := hatHb.Subtract(zbtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute hatHb - Zb^T*x: %w", err)
+	}
+
+	// Round to get hb'. roundVector neither divides nor branches on the secret values and is the
+	// only rounding applied to secret data.
+	hbPrime, err := roundVector(diff, modulus)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate hatKb = H(x, hatHb, hb')
+	setPhase(ctx, phaseHash)
+	hatKb, err := hash3(branch, x, hatHb, hbPrime, lambda/8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute hatKb: %w", err)
+	}
+
+	// Recover r = cb ⊕ hatKb
+	r := make([]byte, lambda/8)
+	if err := subtleops.XORBytes(r, cb, hatKb); err != nil {
+		return nil, fmt.Errorf("failed to recover r: %w", err)
+	}
+
+	// Check the key confirmation tag before any re-encryption work. It only verifies if r was
+	// recovered with the right key from the ciphertext as it was sent.
+	if confirm && subtle.ConstantTimeCompare(tag, confirmationTag(r, ciphertext)) != 1 {
+		return nil, ErrKeyConfirmationFailed
+	}
+
+	// Expand r to get s, rho, h0, h1
+	setPhase(ctx, phaseSeedExpansion)
+	s, rho, h0, h1 := expandSeed(r, n, lambda, logEta)
+	s.Modulus = modulus
+
+	// Determine which h values to use
+	if sk.b {
+		hb, hnb = h1, h0
+	} else {
+		hb, hnb = h0, h1
+	}
+
+	// Calculate hatHnb' = Unb^T*s + hnb*⌊q/2⌋
+	setPhase(ctx, phaseMatVec)
+	unbts, err := d.unb.MultiplyVectorColMajor(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute Unb^T*s: %w", err)
+	}
+
+	hatHnbPrime, err := computeHatH(unbts, hnb, modulus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute hatHnb': %w", err)
+	}
+	hatHnbPrime = applyCompression(hatHnbPrime, compressionBits, modulus)
+
+	// Calculate hatKnb = H(x, hatHnb', hnb)
+	setPhase(ctx, phaseHash)
+	hatKnb, err := hash3(1-branch, x, hatHnbPrime, hnb, lambda/8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute hatKnb: %w", err)
+	}
+
+	setPhase(ctx, phaseSeedExpansion)
+	var e *arithmetic.Vector
+	if d.pRing != nil {
+		e, err = arithmetic.GenerateSampleDVectorWithRing(d.pRing, alphaPrime, rho, modulus)
+	} else {
+		e, err = arithmetic.SampleDVector(m, alphaPrime, rho, modulus)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample error vector: %w", err)
+	}
+
+	// Calculate x' = A^T*s + e
+	setPhase(ctx, phaseMatVec)
+	ats, err := d.a.MulVecTransposed(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute A^T*s: %w", err)
+	}
+
+	xPrime, err := ats.Add(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute x' = A^T*s + e: %w", err)
+	}
+
+	// Calculate hatHb' = Ub^T*s + hb*⌊q/2⌋, the b-side component the ciphertext should carry
+	ubts, err := d.ub.MultiplyVectorColMajor(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute Ub^T*s: %w", err)
+	}
+
+	hatHbPrime, err := computeHatH(ubts, hb, modulus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute hatHb': %w", err)
+	}
+	hatHbPrime = applyCompression(hatHbPrime, compressionBits, modulus)
+
+	// Verify that hatKnb ⊕ r = cnb
+	cnbCalculated := make([]byte, lambda/8)
+	if err := subtleops.XORBytes(cnbCalculated, hatKnb, r); err != nil {
+		return nil, fmt.Errorf("failed to compute hatKnb ⊕ r: %w", err)
+	}
+
+	// Accumulate every re-encryption check without branching and reject once at the end:
+	// x' = x, hatKnb ⊕ r = cnb, hb' = hb, hatHb' = hatHb and hatHnb' = hatHnb
+	// The vector comparisons encode both sides, so they are attributed to serialization
+	setPhase(ctx, phaseSerialization)
+	ok := subtle.ConstantTimeCompare(cnb, cnbCalculated)
+	ok &= vectorsEqualConstantTime(x, xPrime)
+	ok &= vectorsEqualConstantTime(hbPrime, hb)
+	ok &= vectorsEqualConstantTime(hatHbPrime, hatHb)
+	ok &= vectorsEqualConstantTime(hatHnbPrime, hatHnb)
+	if ok != 1 {
+		return nil, ErrDecapsulationFailed
+	}
+
+	// Use r as the shared secret (possibly with key derivation)
+	setPhase(ctx, phaseHash)
+	if confirm {
+		return confirmedKDF(r, sharedKeySize), nil
+	}
+	sharedKey = kdf(r, sharedKeySize)
+
+	return sharedKey, nil
+}
+
+// vectorsEqualConstantTime returns 1 if a and b have the same encoding and 0 otherwise, in time
+// that depends only on the encoded lengths
+func vectorsEqualConstantTime(a, b *arithmetic.Vector) int {
+	aBytes, errA := a.MarshalBinary()
+	bBytes, errB := b.MarshalBinary()
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return subtle.ConstantTimeCompare(aBytes, bBytes)
+}
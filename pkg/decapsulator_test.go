@@ -0,0 +1,187 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/arithmetic"
+)
+
+func TestDecapsulatorMatchesKEM(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	d, err := sk.NewDecapsulator()
+	if err != nil {
+		t.Fatalf("NewDecapsulator failed: %v", err)
+	}
+	if len(d.PublicKeyDigest()) != 32 {
+		t.Fatalf("unexpected public key digest length %d", len(d.PublicKeyDigest()))
+	}
+
+	ct, ss, err := kem.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+	ss1, err := d.Decapsulate(ct)
+	if err != nil {
+		t.Fatalf("Decapsulator.Decapsulate failed: %v", err)
+	}
+	ss2, err := kem.Decapsulate(sk, ct)
+	if err != nil {
+		t.Fatalf("Decapsulate failed: %v", err)
+	}
+	if !bytes.Equal(ss, ss1) || !bytes.Equal(ss1, ss2) {
+		t.Fatalf("shared keys do not match")
+	}
+
+	modified := append([]byte(nil), ct...)
+	modified[0] ^= 0xFF
+	if _, err := d.Decapsulate(modified); err == nil {
+		t.Fatalf("Decapsulator should reject a modified ciphertext")
+	}
+	if _, err := d.Decapsulate(ct[:len(ct)-1]); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("truncated ciphertext error mismatch: %v", err)
+	}
+}
+
+func TestDecapsulatorRejectsInvalidKey(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	_, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	broken := &PrivateKey{Pk: sk.Pk, b: sk.b}
+	if _, err := broken.NewDecapsulator(); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("NewDecapsulator with missing Zb: got %v, want ErrInvalidPrivateKey", err)
+	}
+
+	var nilKey *PrivateKey
+	if _, err := nilKey.NewDecapsulator(); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("NewDecapsulator on nil key: got %v, want ErrInvalidPrivateKey", err)
+	}
+}
+
+func TestDecapsulatorConcurrent(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	d, err := sk.NewDecapsulator()
+	if err != nil {
+		t.Fatalf("NewDecapsulator failed: %v", err)
+	}
+
+	const count = 8
+	cts := make([][]byte, count)
+	sss := make([][]byte, count)
+	for i := range cts {
+		cts[i], sss[i], err = kem.Encapsulate(pk)
+		if err != nil {
+			t.Fatalf("Encapsulate failed: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ss, err := d.Decapsulate(cts[i])
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if !bytes.Equal(ss, sss[i]) {
+				errs[i] = errors.New("shared key mismatch")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("decapsulation %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestDecapsulateRejectsPerturbedComponents(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	d, err := sk.NewDecapsulator()
+	if err != nil {
+		t.Fatalf("NewDecapsulator failed: %v", err)
+	}
+	ct, _, err := kem.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+
+	// Flip the lowest bit of the last byte of each component; for the vectors that is the low
+	// bit of the last coefficient, which leaves the rounding of hatHb unchanged
+	layout := kem.Params.CiphertextLayout()
+	components := []struct {
+		name string
+		r    Range
+	}{
+		{"c0", layout.C0},
+		{"c1", layout.C1},
+		{"x", layout.X},
+		{"hatH0", layout.HatH0},
+		{"hatH1", layout.HatH1},
+	}
+	for _, c := range components {
+		perturbed := append([]byte(nil), ct...)
+		perturbed[c.r.End()-1] ^= 1
+		if _, err := d.Decapsulate(perturbed); !errors.Is(err, ErrDecapsulationFailed) {
+			t.Fatalf("perturbed %s: got %v, want ErrDecapsulationFailed", c.name, err)
+		}
+	}
+}
+
+// TestVectorsEqualConstantTime checks the comparison behind the decapsulator's accumulated
+// re-encryption check: a difference in the first, middle or last element yields the same 0
+// result, and one mismatching comparison rejects however many others match
+func TestVectorsEqualConstantTime(t *testing.T) {
+	q := GetDefaultParameterSet().LatticeParams.Q
+	a := arithmetic.NewVector(33, q)
+	for i := 0; i < a.Length(); i++ {
+		v, _ := rand.Int(rand.Reader, q)
+		a.Set(i, v)
+	}
+	if vectorsEqualConstantTime(a, a.Clone()) != 1 {
+		t.Fatalf("equal vectors reported different")
+	}
+
+	for _, i := range []int{0, a.Length() / 2, a.Length() - 1} {
+		b := a.Clone()
+		b.Set(i, new(big.Int).Mod(new(big.Int).Add(a.Get(i), big.NewInt(1)), q))
+		if got := vectorsEqualConstantTime(a, b); got != 0 {
+			t.Fatalf("difference at element %d: got %d", i, got)
+		}
+		ok := 1
+		ok &= vectorsEqualConstantTime(a, a.Clone())
+		ok &= vectorsEqualConstantTime(a, b)
+		ok &= vectorsEqualConstantTime(b, b.Clone())
+		if ok != 0 {
+			t.Fatalf("difference at element %d was not kept by the accumulator", i)
+		}
+	}
+
+	if vectorsEqualConstantTime(a, arithmetic.NewVector(32, q)) != 0 {
+		t.Fatalf("vectors of different lengths reported equal")
+	}
+}
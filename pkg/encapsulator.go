@@ -0,0 +1,216 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/arithmetic"
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/subtleops"
+)
+
+// Encapsulator holds the values derived from a public key that every encapsulation reuses.
+// Like Decapsulator it shares the key's matrices, so the key must not be modified once the
+// Encapsulator has been built; as long as it is not, the Encapsulator is safe for concurrent use.
+type Encapsulator struct {
+	params Parameters
+	pk     *PublicKey
+	a      *arithmetic.PolyMatrix // A, multiplied transposed in ring form
+	u0     arithmetic.Matrix      // U0, multiplied transposed in place
+	u1     arithmetic.Matrix      // U1, multiplied transposed in place
+
+	keyConfirmation bool // append a key confirmation tag, see OwChCCAKEM.WithKeyConfirmation
+}
+
+// Option configures NewEncapsulator and NewDecapsulator
+type Option func(*options)
+
+type options struct {
+	keyConfirmation bool
+}
+
+// WithKeyConfirmation makes an Encapsulator append, and a Decapsulator expect, a key
+// confirmation tag; see OwChCCAKEM.WithKeyConfirmation
+func WithKeyConfirmation(enable bool) Option {
+	return func(o *options) {
+		o.keyConfirmation = enable
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// NewEncapsulator validates the public key and precomputes the state shared by every encapsulation
+func (pk *PublicKey) NewEncapsulator(opts ...Option) (*Encapsulator, error) {
+	if err := pk.Validate(); err != nil {
+		return nil, err
+	}
+	enc, err := newEncapsulator(pk.Parameters(), pk)
+	if err != nil {
+		return nil, err
+	}
+	enc.keyConfirmation = newOptions(opts).keyConfirmation
+	return enc, nil
+}
+
+// newEncapsulator builds the precomputed state for encapsulating under params
+func newEncapsulator(params Parameters, pk *PublicKey) (*Encapsulator, error) {
+	if err := params.checkRing(); err != nil {
+		return nil, err
+	}
+	if pk.a == nil {
+		return nil, ErrInvalidPublicKey
+	}
+
+	return &Encapsulator{
+		params: params,
+		pk:     pk,
+		a:      pk.a,
+		u0:     pk.u0,
+		u1:     pk.u1,
+	}, nil
+}
+
+// PublicKey returns the public key the encapsulator was built from
+func (enc *Encapsulator) PublicKey() *PublicKey {
+	return enc.pk
+}
+
+// Encapsulate generates a shared key and encapsulates it, drawing the seed from crypto/rand
+func (enc *Encapsulator) Encapsulate() (ciphertext, sharedKey []byte, err error) {
+	return enc.EncapsulateFrom(rand.Reader)
+}
+
+// EncapsulateFrom is Encapsulate with the seed r drawn from randSource; a nil randSource means crypto/rand
+func (enc *Encapsulator) EncapsulateFrom(randSource io.Reader) (ciphertext, sharedKey []byte, err error) {
+	return enc.EncapsulateContext(context.Background(), randSource)
+}
+
+// EncapsulateContext is EncapsulateFrom for callers that label their goroutines for pprof. While
+// profiling is enabled, the phase labels are added to the labels of ctx. The goroutine is left
+// with the labels it had on entry; see EnableProfiling.
+func (enc *Encapsulator) EncapsulateContext(ctx context.Context, randSource io.Reader) (ciphertext, sharedKey []byte, err error) {
+	if randSource == nil {
+		randSource = rand.Reader
+	}
+	defer enterPhases().restore()
+
+	// Get parameter values
+	n := enc.params.LatticeParams.N
+	m := enc.params.LatticeParams.M
+	lambda := enc.params.LatticeParams.Lambda
+	modulus := enc.params.LatticeParams.Q
+	alphaPrime := enc.params.GaussianParams.AlphaPrime
+	logEta := enc.params.GaussianParams.LogEta
+	sharedKeySize := enc.params.KeyParams.SharedKeySize
+
+	// Generate random seed r
+	r := make([]byte, lambda/8)
+	if _, err = io.ReadFull(randSource, r); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate random seed: %w", err)
+	}
+
+	// Expand r to get s, rho, h0, h1 using G function
+	setPhase(ctx, phaseSeedExpansion)
+	s, rho, h0, h1 := expandSeed(r, n, lambda, logEta)
+	s.Modulus = modulus
+
+	e, err := arithmetic.SampleDVector(m, alphaPrime, rho, modulus)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sample error vector: %w", err)
+	}
+
+	// Correctness relies on ‖e‖ staying close to α'·√m; see errorNormBound for the margin
+	if e.L2NormSquared().Cmp(errorNormBound(alphaPrime, m)) > 0 {
+		slog.Warn("owchcca: sampled error vector exceeds the expected L2 norm bound", "params", enc.params.Name)
+	}
+
+	// Calculate x = A^T*s + e
+	setPhase(ctx, phaseMatVec)
+	ats, err := enc.a.MulVecTransposed(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute A^T*s: %w", err)
+	}
+
+	x, err := ats.Add(e)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute x = A^T*s + e: %w", err)
+	}
+
+	// Calculate hatH0 = U0^T*s + h0*⌊q/2⌋
+	u0ts, err := enc.u0.MultiplyVectorColMajor(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute U0^T*s: %w", err)
+	}
+
+	hatH0, err := computeHatH(u0ts, h0, modulus)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute hatH0: %w", err)
+	}
+
+	// Calculate hatH1 = U1^T*s + h1*⌊q/2⌋
+	u1ts, err := enc.u1.MultiplyVectorColMajor(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute U1^T*s: %w", err)
+	}
+
+	hatH1, err := computeHatH(u1ts, h1, modulus)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute hatH1: %w", err)
+	}
+
+	// Reduce hatH0 and hatH1 to the precision they are transmitted with, so that the
+	// decapsulator hashes the same values
+	compressionBits := enc.params.GaussianParams.CompressionBits
+	hatH0 = applyCompression(hatH0, compressionBits, modulus)
+	hatH1 = applyCompression(hatH1, compressionBits, modulus)
+
+	// Calculate hatK0 = H(x, hatH0, h0)
+	setPhase(ctx, phaseHash)
+	hatK0, err := hash3(0, x, hatH0, h0, lambda/8)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute hatK0: %w", err)
+	}
+
+	// Calculate hatK1 = H(x, hatH1, h1)
+	hatK1, err := hash3(1, x, hatH1, h1, lambda/8)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute hatK1: %w", err)
+	}
+
+	// Calculate c0 = hatK0 ⊕ r
+	c0 := make([]byte, lambda/8)
+	if err := subtleops.XORBytes(c0, hatK0, r); err != nil {
+		return nil, nil, fmt.Errorf("failed to compute c0: %w", err)
+	}
+
+	// Calculate c1 = hatK1 ⊕ r
+	c1 := make([]byte, lambda/8)
+	if err := subtleops.XORBytes(c1, hatK1, r); err != nil {
+		return nil, nil, fmt.Errorf("failed to compute c1: %w", err)
+	}
+
+	// Construct ciphertext: c0 || c1 || x || hatH0 || hatH1
+	setPhase(ctx, phaseSerialization)
+	ciphertext, err = constructCiphertext(enc.params.KeyParams.CiphertextSize, compressionBits, c0, c1, x, hatH0, hatH1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to construct ciphertext: %w", err)
+	}
+
+	// Use r as the shared secret (possibly with key derivation)
+	setPhase(ctx, phaseHash)
+	if enc.keyConfirmation {
+		ciphertext = append(ciphertext, confirmationTag(r, ciphertext)...)
+		return ciphertext, confirmedKDF(r, sharedKeySize), nil
+	}
+	sharedKey = kdf(r, sharedKeySize)
+
+	return ciphertext, sharedKey, nil
+}
@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestEncapsulatorMatchesKEM(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	enc, err := pk.NewEncapsulator()
+	if err != nil {
+		t.Fatalf("NewEncapsulator failed: %v", err)
+	}
+	if enc.PublicKey() != pk {
+		t.Fatalf("PublicKey returned a different key")
+	}
+
+	// The same seed gives the same ciphertext through either path
+	ct1, ss1, err := enc.EncapsulateFrom(seededReader("encapsulator"))
+	if err != nil {
+		t.Fatalf("Encapsulator.EncapsulateFrom failed: %v", err)
+	}
+	ct2, ss2, err := kem.EncapsulateFrom(pk, seededReader("encapsulator"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+	if !bytes.Equal(ct1, ct2) || !bytes.Equal(ss1, ss2) {
+		t.Fatalf("Encapsulator and KEM disagree")
+	}
+
+	ct, ss, err := enc.Encapsulate()
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+	got, err := kem.Decapsulate(sk, ct)
+	if err != nil || !bytes.Equal(got, ss) {
+		t.Fatalf("Decapsulate does not recover the shared key: %v", err)
+	}
+}
+
+func TestEncapsulatorRejectsInvalidKey(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, _, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	broken := &PublicKey{Params: pk.Params, a: pk.a, u0: pk.u0}
+	if _, err := broken.NewEncapsulator(); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Fatalf("NewEncapsulator with missing U1: got %v, want ErrInvalidPublicKey", err)
+	}
+
+	var nilKey *PublicKey
+	if _, err := nilKey.NewEncapsulator(); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Fatalf("NewEncapsulator on nil key: got %v, want ErrInvalidPublicKey", err)
+	}
+}
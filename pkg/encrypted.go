@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ErrDecryptionFailed is returned by ImportEncryptedPrivateKey when the AEAD check fails, which
+// happens both for a wrong passphrase and for a modified encryption
+var ErrDecryptionFailed = errors.New("owchcca: private key decryption failed")
+
+// encryptedKeyMagic starts every encrypted private key
+var encryptedKeyMagic = [4]byte{'O', 'W', 'E', 'K'}
+
+// encryptedKeyVersion is the version of the encrypted private key format
+const encryptedKeyVersion byte = 1
+
+// kdfArgon2id identifies Argon2id key derivation in the encrypted key header
+const kdfArgon2id byte = 1
+
+const (
+	encryptedKeySaltSize = 16
+	// encryptedKeyHeaderSize: magic (4) | version (1) | KDF (1) | time (4) | memory in KiB (4) |
+	// threads (1) | salt (16) | nonce (12)
+	encryptedKeyHeaderSize = 4 + 1 + 1 + 4 + 4 + 1 + encryptedKeySaltSize + chacha20poly1305.NonceSize
+)
+
+// argon2Params are the Argon2id cost parameters carried in an encrypted key header
+type argon2Params struct {
+	time    uint32
+	memory  uint32 // KiB
+	threads uint8
+}
+
+// defaultArgon2Params follow the second recommended option of RFC 9106
+var defaultArgon2Params = argon2Params{time: 3, memory: 64 * 1024, threads: 4}
+
+// maxArgon2Params bound the cost an imported header can demand, since the header is read
+// before anything is authenticated: at most 10 passes over 1 GiB with 16 lanes
+var maxArgon2Params = argon2Params{time: 10, memory: 1024 * 1024, threads: 16}
+
+// check rejects parameters that Argon2id does not accept or that exceed maxArgon2Params
+func (p argon2Params) check() error {
+	if p.time == 0 || p.time > maxArgon2Params.time ||
+		p.threads == 0 || p.threads > maxArgon2Params.threads ||
+		p.memory < 8*uint32(p.threads) || p.memory > maxArgon2Params.memory {
+		return fmt.Errorf("%w: Argon2id parameters t=%d m=%d p=%d out of range",
+			ErrDeserializationError, p.time, p.memory, p.threads)
+	}
+	return nil
+}
+
+func (p argon2Params) deriveKey(passphrase, salt []byte) []byte {
+	return argon2.IDKey(passphrase, salt, p.time, p.memory, p.threads, chacha20poly1305.KeySize)
+}
+
+// ExportEncryptedPrivateKey encrypts the self-contained encoding of sk (see
+// PrivateKey.MarshalWithHeader) under a key derived from passphrase with Argon2id, using
+// ChaCha20-Poly1305. The salt, nonce and Argon2id parameters are stored in a header that is
+// authenticated along with the key.
+func ExportEncryptedPrivateKey(sk *PrivateKey, passphrase []byte) ([]byte, error) {
+	var salt [encryptedKeySaltSize]byte
+	var nonce [chacha20poly1305.NonceSize]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return exportEncryptedPrivateKey(sk, passphrase, defaultArgon2Params, salt[:], nonce[:])
+}
+
+// exportEncryptedPrivateKey is ExportEncryptedPrivateKey with explicit parameters, salt and nonce
+func exportEncryptedPrivateKey(sk *PrivateKey, passphrase []byte, params argon2Params, salt, nonce []byte) ([]byte, error) {
+	plaintext, err := sk.MarshalWithHeader()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(plaintext)
+
+	header := make([]byte, 0, encryptedKeyHeaderSize)
+	header = append(header, encryptedKeyMagic[:]...)
+	header = append(header, encryptedKeyVersion, kdfArgon2id)
+	header = binary.BigEndian.AppendUint32(header, params.time)
+	header = binary.BigEndian.AppendUint32(header, params.memory)
+	header = append(header, params.threads)
+	header = append(header, salt...)
+	header = append(header, nonce...)
+
+	key := params.deriveKey(passphrase, salt)
+	defer clear(key)
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(header), len(header)+len(plaintext)+aead.Overhead())
+	copy(out, header)
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// ImportEncryptedPrivateKey decrypts a private key produced by ExportEncryptedPrivateKey.
+// A malformed header is reported as ErrDeserializationError; a wrong passphrase and a modified
+// encryption both return ErrDecryptionFailed.
+func ImportEncryptedPrivateKey(data, passphrase []byte) (*PrivateKey, error) {
+	params, salt, nonce, err := parseEncryptedKeyHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key := params.deriveKey(passphrase, salt)
+	defer clear(key)
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, data[encryptedKeyHeaderSize:], data[:encryptedKeyHeaderSize])
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	defer clear(plaintext)
+
+	return ParsePrivateKeyWithHeader(plaintext)
+}
+
+// parseEncryptedKeyHeader checks the header of an encrypted private key and returns its
+// Argon2id parameters, salt and nonce. It runs before any key derivation, so that a header
+// cannot make the import spend more than maxArgon2Params allows.
+func parseEncryptedKeyHeader(data []byte) (params argon2Params, salt, nonce []byte, err error) {
+	if len(data) < encryptedKeyHeaderSize+chacha20poly1305.Overhead {
+		return params, nil, nil, fmt.Errorf("%w: encrypted private key too short", ErrDeserializationError)
+	}
+	if [4]byte(data[:4]) != encryptedKeyMagic {
+		return params, nil, nil, fmt.Errorf("%w: not an encrypted private key", ErrDeserializationError)
+	}
+	if data[4] != encryptedKeyVersion {
+		return params, nil, nil, fmt.Errorf("%w: unsupported encrypted key version %d", ErrDeserializationError, data[4])
+	}
+	if data[5] != kdfArgon2id {
+		return params, nil, nil, fmt.Errorf("%w: unsupported key derivation function %d", ErrDeserializationError, data[5])
+	}
+
+	params = argon2Params{
+		time:    binary.BigEndian.Uint32(data[6:10]),
+		memory:  binary.BigEndian.Uint32(data[10:14]),
+		threads: data[14],
+	}
+	if err := params.check(); err != nil {
+		return params, nil, nil, err
+	}
+	salt = data[15 : 15+encryptedKeySaltSize]
+	nonce = data[15+encryptedKeySaltSize : encryptedKeyHeaderSize]
+	return params, salt, nonce, nil
+}
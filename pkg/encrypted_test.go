@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// testArgon2Params keep the tests fast; they are far below what ExportEncryptedPrivateKey uses
+var testArgon2Params = argon2Params{time: 1, memory: 64, threads: 1}
+
+func TestEncryptedPrivateKeyRoundTrip(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	_, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	passphrase := []byte("correct horse battery staple")
+
+	data, err := ExportEncryptedPrivateKey(sk, passphrase)
+	if err != nil {
+		t.Fatalf("ExportEncryptedPrivateKey failed: %v", err)
+	}
+	got, err := ImportEncryptedPrivateKey(data, passphrase)
+	if err != nil {
+		t.Fatalf("ImportEncryptedPrivateKey failed: %v", err)
+	}
+	if !got.Equal(sk) {
+		t.Fatalf("imported key differs from the exported key")
+	}
+
+	data, err = exportEncryptedPrivateKey(sk, passphrase, testArgon2Params, make([]byte, encryptedKeySaltSize), make([]byte, 12))
+	if err != nil {
+		t.Fatalf("exportEncryptedPrivateKey failed: %v", err)
+	}
+
+	if _, err := ImportEncryptedPrivateKey(data, []byte("wrong")); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("wrong passphrase: got %v", err)
+	}
+	for _, offset := range []int{15, encryptedKeyHeaderSize - 1, encryptedKeyHeaderSize + 100, len(data) - 1} {
+		modified := bytes.Clone(data)
+		modified[offset] ^= 1
+		if _, err := ImportEncryptedPrivateKey(modified, passphrase); !errors.Is(err, ErrDecryptionFailed) {
+			t.Fatalf("modified byte %d: got %v", offset, err)
+		}
+	}
+
+	malformed := map[string]func([]byte){
+		"magic":   func(d []byte) { d[0] = 'X' },
+		"version": func(d []byte) { d[4] = 99 },
+		"kdf":     func(d []byte) { d[5] = 99 },
+		"time":    func(d []byte) { binary.BigEndian.PutUint32(d[6:10], 0) },
+		"memory":  func(d []byte) { binary.BigEndian.PutUint32(d[10:14], 1<<30) },
+		"threads": func(d []byte) { d[14] = 0 },
+	}
+	for name, mutate := range malformed {
+		modified := bytes.Clone(data)
+		mutate(modified)
+		if _, err := ImportEncryptedPrivateKey(modified, passphrase); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("malformed %s: got %v", name, err)
+		}
+	}
+	if _, err := ImportEncryptedPrivateKey(data[:encryptedKeyHeaderSize], passphrase); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("truncated: got %v", err)
+	}
+}
+
+func TestEncryptedPrivateKeyArgon2Limits(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	_, sk, err := kem.GenerateKeyPair(seededReader("argon2 limits"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	data, err := exportEncryptedPrivateKey(sk, []byte("pass"), testArgon2Params, make([]byte, encryptedKeySaltSize), make([]byte, 12))
+	if err != nil {
+		t.Fatalf("exportEncryptedPrivateKey failed: %v", err)
+	}
+	withParams := func(p argon2Params) []byte {
+		d := bytes.Clone(data)
+		binary.BigEndian.PutUint32(d[6:10], p.time)
+		binary.BigEndian.PutUint32(d[10:14], p.memory)
+		d[14] = p.threads
+		return d
+	}
+
+	if err := defaultArgon2Params.check(); err != nil {
+		t.Fatalf("the export parameters exceed the import limits: %v", err)
+	}
+	limit := maxArgon2Params
+	for name, p := range map[string]argon2Params{
+		"time":    {time: limit.time + 1, memory: 1024, threads: 1},
+		"memory":  {time: 1, memory: limit.memory + 1, threads: 1},
+		"threads": {time: 1, memory: 1024, threads: limit.threads + 1},
+	} {
+		// The header is rejected by parseEncryptedKeyHeader, which ImportEncryptedPrivateKey
+		// calls before deriving a key; a derivation would have failed with ErrDecryptionFailed
+		if _, _, _, err := parseEncryptedKeyHeader(withParams(p)); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("%s just over the limit: parseEncryptedKeyHeader returned %v", name, err)
+		}
+		if _, err := ImportEncryptedPrivateKey(withParams(p), []byte("pass")); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("%s just over the limit: got %v", name, err)
+		}
+	}
+
+	// At the time and thread limits the header is accepted and the altered header fails the AEAD check
+	atLimit := argon2Params{time: limit.time, memory: 8 * uint32(limit.threads), threads: limit.threads}
+	if _, err := ImportEncryptedPrivateKey(withParams(atLimit), []byte("pass")); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("parameters at the limits: got %v", err)
+	}
+}
+
+func TestEncryptedPrivateKeyVector(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	_, sk, err := kem.GenerateKeyPair(seededReader("encrypted private key"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	nonce, _ := hex.DecodeString("101112131415161718191a1b")
+	passphrase := []byte("correct horse battery staple")
+
+	data, err := exportEncryptedPrivateKey(sk, passphrase, testArgon2Params, salt, nonce)
+	if err != nil {
+		t.Fatalf("exportEncryptedPrivateKey failed: %v", err)
+	}
+
+	const wantHeader = "4f57454b" + "01" + "01" + "00000001" + "00000040" + "01" +
+		"000102030405060708090a0b0c0d0e0f" + "101112131415161718191a1b"
+	if got := hex.EncodeToString(data[:encryptedKeyHeaderSize]); got != wantHeader {
+		t.Fatalf("header: got %s, expected %s", got, wantHeader)
+	}
+	const wantDigest = "ec8f4264537e5a1d08daf6658ad7a17f52f3a3c21574c6ce035223f39b6d9855"
+	if got := digestHex(data); got != wantDigest {
+		t.Fatalf("encrypted key digest: got %s, expected %s", got, wantDigest)
+	}
+
+	got, err := ImportEncryptedPrivateKey(data, passphrase)
+	if err != nil || !got.Equal(sk) {
+		t.Fatalf("vector does not decrypt to the key: %v", err)
+	}
+}
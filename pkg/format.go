@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/sha3"
+)
+
+// fingerprintSize is the number of fingerprint bytes shown when a key is formatted
+const fingerprintSize = 8
+
+var (
+	_ fmt.Stringer   = Parameters{}
+	_ fmt.GoStringer = Parameters{}
+	_ fmt.Stringer   = (*PublicKey)(nil)
+	_ fmt.GoStringer = (*PublicKey)(nil)
+	_ fmt.Formatter  = PrivateKey{}
+)
+
+// String summarizes the parameter set: name, dimensions, modulus size and encoding sizes
+func (p Parameters) String() string {
+	return fmt.Sprintf("%s (n=%d, m=%d, λ=%d, q: %d bits, pk: %d bytes, sk: %d bytes, ct: %d bytes)",
+		p.Name, p.LatticeParams.N, p.LatticeParams.M, p.LatticeParams.Lambda, p.modulusBits(),
+		p.KeyParams.PublicKeySize, p.KeyParams.PrivateKeySize, p.KeyParams.CiphertextSize)
+}
+
+// GoString is like String in %#v form
+func (p Parameters) GoString() string {
+	return fmt.Sprintf("pkg.Parameters{Name: %q, N: %d, M: %d, Lambda: %d, QBits: %d}",
+		p.Name, p.LatticeParams.N, p.LatticeParams.M, p.LatticeParams.Lambda, p.modulusBits())
+}
+
+// modulusBits returns the bit length of Q, or 0 if it is unset
+func (p Parameters) modulusBits() int {
+	if p.LatticeParams.Q == nil {
+		return 0
+	}
+	return p.LatticeParams.Q.BitLen()
+}
+
+// keyDigest caches the fingerprint of a public key. A fresh one is attached whenever the key's
+// matrices are set, and copies of the key share it, so a key is serialized and hashed at most
+// once. The digest covers only the matrices; the encoded size is checked against Params on
+// every call, as Bytes does.
+type keyDigest struct {
+	once sync.Once
+	sum  [32]byte
+	size int
+	err  error
+}
+
+// Fingerprint returns the SHA3-256 digest of the public key encoding
+func (pk *PublicKey) Fingerprint() ([]byte, error) {
+	if pk == nil {
+		return nil, ErrInvalidPublicKey
+	}
+	d := pk.digest
+	if d == nil {
+		d = new(keyDigest)
+	}
+	d.once.Do(func() {
+		var data []byte
+		if data, d.err = pk.appendMatrices(nil); d.err == nil {
+			d.sum, d.size = sha3.Sum256(data), len(data)
+		}
+	})
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := pk.checkEncodedSize(d.size); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), d.sum[:]...), nil
+}
+
+// shortFingerprint returns the leading bytes of the fingerprint in hex, for display
+func (pk *PublicKey) shortFingerprint() string {
+	fp, err := pk.Fingerprint()
+	if err != nil {
+		return "invalid"
+	}
+	return hex.EncodeToString(fp[:fingerprintSize])
+}
+
+// String identifies the key by parameter set and fingerprint instead of printing its matrices
+func (pk *PublicKey) String() string {
+	if pk == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s public key %s (%d bytes)", pk.Params.Name, pk.shortFingerprint(), pk.Params.KeyParams.PublicKeySize)
+}
+
+// GoString is like String in %#v form
+func (pk *PublicKey) GoString() string {
+	if pk == nil {
+		return "(*pkg.PublicKey)(nil)"
+	}
+	return fmt.Sprintf("&pkg.PublicKey{Params: %q, Fingerprint: %q, Size: %d}", pk.Params.Name, pk.shortFingerprint(), pk.Params.KeyParams.PublicKeySize)
+}
+
+// String identifies the private key by the fingerprint of its public key. Zb and b are never printed.
+func (sk PrivateKey) String() string {
+	if sk.Pk == nil {
+		return "private key (redacted)"
+	}
+	return fmt.Sprintf("%s private key for %s (redacted)", sk.Pk.Params.Name, sk.Pk.shortFingerprint())
+}
+
+// GoString is like String in %#v form
+func (sk PrivateKey) GoString() string {
+	name, fp := "", "invalid"
+	if sk.Pk != nil {
+		name, fp = sk.Pk.Params.Name, sk.Pk.shortFingerprint()
+	}
+	return fmt.Sprintf("&pkg.PrivateKey{Params: %q, Fingerprint: %q, Zb: <redacted>}", name, fp)
+}
+
+// Format implements fmt.Formatter so that no verb or flag prints the secret fields: %#v uses
+// GoString and every other verb String. The methods on private keys use value receivers so
+// that keys embedded by value are redacted too.
+func (sk PrivateKey) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		fmt.Fprint(f, sk.GoString())
+		return
+	}
+	fmt.Fprint(f, sk.String())
+}
@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/sha3"
+)
+
+func TestFormatRedactsPrivateKey(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	fingerprint := pk.shortFingerprint()
+
+	// Collect the printable forms of Zb elements long enough not to occur by chance
+	var secrets []string
+	sk.zb.ForEach(func(i, j int, v *big.Int) {
+		for _, s := range []string{v.String(), v.Text(16)} {
+			if len(s) >= 6 {
+				secrets = append(secrets, s)
+			}
+		}
+	})
+	if len(secrets) == 0 {
+		t.Fatalf("Zb has no element large enough to search for")
+	}
+
+	embedded := struct{ Key PrivateKey }{*sk}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%X", "%d"} {
+		for _, arg := range []any{sk, *sk, embedded} {
+			out := fmt.Sprintf(format, arg)
+			if len(out) > 200 {
+				t.Fatalf("%s of %T is %d bytes long", format, arg, len(out))
+			}
+			for _, s := range secrets {
+				if strings.Contains(out, s) {
+					t.Fatalf("%s of %T contains the Zb element %s: %s", format, arg, s, out)
+				}
+			}
+			if !strings.Contains(out, fingerprint) {
+				t.Fatalf("%s of %T does not contain the fingerprint: %s", format, arg, out)
+			}
+		}
+	}
+
+	want := fmt.Sprintf("%s public key %s (%d bytes)", kem.Params.Name, fingerprint, kem.Params.KeyParams.PublicKeySize)
+	if out := fmt.Sprintf("%v", pk); out != want {
+		t.Fatalf("unexpected public key format: %s", out)
+	}
+	if out := fmt.Sprintf("%#v", pk); !strings.HasPrefix(out, "&pkg.PublicKey{") {
+		t.Fatalf("unexpected public key GoString: %s", out)
+	}
+	if out := fmt.Sprintf("%v", kem.Params); !strings.Contains(out, "n=128, m=8192, λ=16, q: 61 bits") {
+		t.Fatalf("unexpected parameters format: %s", out)
+	}
+	if out := fmt.Sprintf("%#v", kem.Params); !strings.Contains(out, `Name: "OWChCCA-16"`) {
+		t.Fatalf("unexpected parameters GoString: %s", out)
+	}
+}
+
+func TestFormatDistinguishesKeys(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk1, sk1, err := kem.GenerateKeyPair(seededReader("format key 1"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	pk2, sk2, err := kem.GenerateKeyPair(seededReader("format key 2"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	for _, format := range []string{"%v", "%#v"} {
+		if fmt.Sprintf(format, pk1) == fmt.Sprintf(format, pk2) {
+			t.Fatalf("%s formats two public keys the same: %s", format, fmt.Sprintf(format, pk1))
+		}
+		if fmt.Sprintf(format, sk1) == fmt.Sprintf(format, sk2) {
+			t.Fatalf("%s formats two private keys the same: %s", format, fmt.Sprintf(format, sk1))
+		}
+	}
+
+	// The cached fingerprint is the digest of the encoding and follows the key when it is
+	// overwritten by unmarshaling
+	data, _ := pk2.Bytes()
+	want := sha3.Sum256(data)
+	reused := pk1
+	if err := reused.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	if got, err := reused.Fingerprint(); err != nil || !bytes.Equal(got, want[:]) {
+		t.Fatalf("Fingerprint after UnmarshalBinary: got %x, %v, want %x", got, err, want)
+	}
+	if fmt.Sprint(reused) != fmt.Sprint(pk2) {
+		t.Fatalf("overwritten key formats as %s, want %s", reused, pk2)
+	}
+}
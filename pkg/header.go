@@ -0,0 +1,175 @@
+package pkg
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// FormatVersion is the version byte written at the start of headered encodings. It is also
+// absorbed by hash3 and kdf, so it changes whenever ciphertexts or shared keys would.
+// Version 2 built h0, h1 and hb' as binary vectors; version 1 built them modulo 1, so every
+// bit was 0. Version 3 added length and domain prefixes to hash3 and kdf. Version 4 separated
+// the two branches of hash3 and derived its λ/8 output bytes from SHAKE256. Version 5 derived
+// the seed expansion G from SHAKE256 with a domain label instead of chaining SHA3-256 into SHA3-512.
+const FormatVersion byte = 5
+
+// HeaderSize is the length of the header: version (1 byte) | kind (1 byte) | parameter set ID (2 bytes, big-endian)
+const HeaderSize = 4
+
+// EncodingKind identifies the object that follows a header
+type EncodingKind byte
+
+const (
+	// KindPublicKey marks an encoded public key
+	KindPublicKey EncodingKind = 1
+	// KindPrivateKey marks an encoded private key, which embeds its public key
+	KindPrivateKey EncodingKind = 2
+	// KindPrivateKeySeed marks a private key stored as its key generation seed, see MarshalSeed
+	KindPrivateKeySeed EncodingKind = 3
+)
+
+// appendHeader appends the header for an object of the given kind under params
+func appendHeader(dst []byte, kind EncodingKind, params Parameters) ([]byte, error) {
+	if params.ID() == 0 {
+		return nil, fmt.Errorf("%w: parameter set %s has no registered ID", ErrSerializationError, params.Name)
+	}
+	dst = append(dst, FormatVersion, byte(kind))
+	return binary.BigEndian.AppendUint16(dst, params.ID()), nil
+}
+
+// parseHeader checks the header at the start of data and returns the parameter set it names
+func parseHeader(data []byte, kind EncodingKind) (Parameters, error) {
+	if len(data) < HeaderSize {
+		return Parameters{}, fmt.Errorf("%w: header too short", ErrDeserializationError)
+	}
+	if data[0] != FormatVersion {
+		return Parameters{}, fmt.Errorf("%w: unsupported format version %d", ErrDeserializationError, data[0])
+	}
+	if EncodingKind(data[1]) != kind {
+		return Parameters{}, fmt.Errorf("%w: encoding kind %d, expected %d", ErrDeserializationError, data[1], kind)
+	}
+	params, err := ParameterSetByID(binary.BigEndian.Uint16(data[2:HeaderSize]))
+	if err != nil {
+		return Parameters{}, fmt.Errorf("%w: %v", ErrDeserializationError, err)
+	}
+	return params, nil
+}
+
+// isHeadered reports whether data starts with a current-version header for kind. Bare encodings
+// start with a big-endian matrix dimension, whose first byte is 0 for every supported size.
+func isHeadered(data []byte, kind EncodingKind) bool {
+	return len(data) >= HeaderSize && data[0] == FormatVersion && EncodingKind(data[1]) == kind
+}
+
+// unmarshalWithoutParams decodes a public key into a receiver that carries no parameters, from
+// either the headered encoding or a bare encoding whose length identifies a registered set
+func (pk *PublicKey) unmarshalWithoutParams(data []byte) error {
+	if isHeadered(data, KindPublicKey) {
+		parsed, err := ParsePublicKeyWithHeader(data)
+		if err != nil {
+			return err
+		}
+		*pk = *parsed
+		return nil
+	}
+
+	params, err := InferParameterSet(data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDeserializationError, err)
+	}
+	pk.Params = params
+	return pk.UnmarshalBinary(data)
+}
+
+// unmarshalWithoutPublicKey decodes a private key into a receiver without a public key, from
+// either the headered encoding or a bare encoding whose length identifies a registered set
+func (sk *PrivateKey) unmarshalWithoutPublicKey(data []byte) error {
+	if isHeadered(data, KindPrivateKey) {
+		parsed, err := ParsePrivateKeyWithHeader(data)
+		if err != nil {
+			return err
+		}
+		*sk = *parsed
+		return nil
+	}
+
+	params, err := InferFromPrivateKey(data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDeserializationError, err)
+	}
+	sk.Pk = &PublicKey{Params: params}
+	return sk.UnmarshalBinary(data)
+}
+
+// MarshalWithHeader returns the public key encoding prefixed with a header carrying the parameter set ID
+func (pk *PublicKey) MarshalWithHeader() ([]byte, error) {
+	if pk == nil {
+		return nil, ErrInvalidPublicKey
+	}
+	buf, err := appendHeader(make([]byte, 0, HeaderSize+pk.Params.KeyParams.PublicKeySize), KindPublicKey, pk.Params)
+	if err != nil {
+		return nil, err
+	}
+	return pk.appendBinary(buf)
+}
+
+// ParsePublicKeyWithHeader parses a public key produced by MarshalWithHeader, resolving its parameters from the registry
+func ParsePublicKeyWithHeader(data []byte) (*PublicKey, error) {
+	params, err := parseHeader(data, KindPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	pk := &PublicKey{Params: params}
+	if err := pk.UnmarshalBinaryStrict(data[HeaderSize:]); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// ParsePublicKeyAuto parses a public key in either the headered encoding or the bare encoding
+// of a legacy blob. The parameters of a bare encoding are inferred from its length with
+// InferParameterSet, which fails when the length is ambiguous.
+func ParsePublicKeyAuto(data []byte) (*PublicKey, error) {
+	if isHeadered(data, KindPublicKey) {
+		return ParsePublicKeyWithHeader(data)
+	}
+	params, err := InferParameterSet(data)
+	if err != nil {
+		return nil, err
+	}
+	pk := &PublicKey{Params: params}
+	if err := pk.UnmarshalBinaryStrict(data); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+// MarshalWithHeader returns the private key encoding prefixed with a header carrying the parameter set ID.
+// The result is self-contained: it can be parsed without the public key.
+func (sk *PrivateKey) MarshalWithHeader() ([]byte, error) {
+	if sk == nil || sk.Pk == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	skBytes, err := sk.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := appendHeader(make([]byte, 0, HeaderSize+len(skBytes)), KindPrivateKey, sk.Pk.Params)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, skBytes...), nil
+}
+
+// ParsePrivateKeyWithHeader parses a private key produced by MarshalWithHeader, including its public key
+func ParsePrivateKeyWithHeader(data []byte) (*PrivateKey, error) {
+	params, err := parseHeader(data, KindPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	sk := &PrivateKey{Pk: &PublicKey{Params: params}}
+	if err := sk.UnmarshalBinaryStrict(data[HeaderSize:]); err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
@@ -0,0 +1,211 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHeaderedKeyRoundTrip(t *testing.T) {
+	params := GetDefaultParameterSet()
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	pkData, err := pk.MarshalWithHeader()
+	if err != nil {
+		t.Fatalf("MarshalWithHeader failed: %v", err)
+	}
+	if len(pkData) != HeaderSize+params.KeyParams.PublicKeySize {
+		t.Fatalf("headered public key is %d bytes", len(pkData))
+	}
+	pk2, err := ParsePublicKeyWithHeader(pkData)
+	if err != nil {
+		t.Fatalf("ParsePublicKeyWithHeader failed: %v", err)
+	}
+	if !pk2.Equal(pk) || pk2.Params.ID() != params.ID() {
+		t.Fatalf("public key did not round-trip through the header")
+	}
+
+	skData, err := sk.MarshalWithHeader()
+	if err != nil {
+		t.Fatalf("MarshalWithHeader failed: %v", err)
+	}
+	sk2, err := ParsePrivateKeyWithHeader(skData)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyWithHeader failed: %v", err)
+	}
+	if !sk2.Equal(sk) {
+		t.Fatalf("private key did not round-trip through the header")
+	}
+
+	if _, err := ParsePrivateKeyWithHeader(pkData); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("parsing a public key as a private key: got %v", err)
+	}
+	badVersion := append([]byte(nil), pkData...)
+	badVersion[0] = FormatVersion + 1
+	if _, err := ParsePublicKeyWithHeader(badVersion); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("unsupported version: got %v", err)
+	}
+	unknownID := append([]byte(nil), pkData...)
+	unknownID[2], unknownID[3] = 0xFF, 0xFE
+	if _, err := ParsePublicKeyWithHeader(unknownID); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("unknown parameter ID: got %v", err)
+	}
+
+	noID := *pk
+	noID.Params.id = 0
+	if _, err := noID.MarshalWithHeader(); !errors.Is(err, ErrSerializationError) {
+		t.Fatalf("MarshalWithHeader without an ID: got %v", err)
+	}
+}
+
+func TestKeyBinaryMarshalerRoundTrip(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	pkData, err := pk.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	var pk2 PublicKey
+	if err := pk2.UnmarshalBinary(pkData); err != nil {
+		t.Fatalf("UnmarshalBinary into a zero public key failed: %v", err)
+	}
+	if !pk2.Equal(pk) || pk2.Params.Name != pk.Params.Name {
+		t.Fatalf("public key did not round-trip")
+	}
+
+	skData, err := sk.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	var sk2 PrivateKey
+	if err := sk2.UnmarshalBinary(skData); err != nil {
+		t.Fatalf("UnmarshalBinary into a zero private key failed: %v", err)
+	}
+	if !sk2.Equal(sk) {
+		t.Fatalf("private key did not round-trip")
+	}
+
+	headered, err := sk.MarshalWithHeader()
+	if err != nil {
+		t.Fatalf("MarshalWithHeader failed: %v", err)
+	}
+	var sk3 PrivateKey
+	if err := sk3.UnmarshalBinary(headered); err != nil || !sk3.Equal(sk) {
+		t.Fatalf("UnmarshalBinary of the headered encoding failed: %v", err)
+	}
+
+	var sk4 PrivateKey
+	if err := sk4.UnmarshalBinary(skData[:len(skData)-1]); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("UnmarshalBinary of a truncated key: got %v", err)
+	}
+}
+
+func TestKeyGobRoundTrip(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(struct{ Pk, Sk any }{pk, sk}); err != nil {
+		t.Fatalf("gob encode failed: %v", err)
+	}
+	var decoded struct{ Pk, Sk any }
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatalf("gob decode failed: %v", err)
+	}
+
+	pk2, ok := decoded.Pk.(*PublicKey)
+	if !ok || !pk2.Equal(pk) {
+		t.Fatalf("public key did not round-trip through gob")
+	}
+	sk2, ok := decoded.Sk.(*PrivateKey)
+	if !ok || !sk2.Equal(sk) {
+		t.Fatalf("private key did not round-trip through gob")
+	}
+}
+
+func TestInferParameterSet(t *testing.T) {
+	params := GetDefaultParameterSet()
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(seededReader("infer parameter set"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	pkData, _ := pk.MarshalBinary()
+	skData, _ := sk.Bytes()
+	ct, _, err := kem.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+
+	// Unique matches
+	for name, infer := range map[string]func() (Parameters, error){
+		"public key":  func() (Parameters, error) { return InferParameterSet(pkData) },
+		"private key": func() (Parameters, error) { return InferFromPrivateKey(skData) },
+		"ciphertext":  func() (Parameters, error) { return InferFromCiphertext(ct) },
+	} {
+		if got, err := infer(); err != nil || got.Name != params.Name {
+			t.Fatalf("%s: inferred %q, %v", name, got.Name, err)
+		}
+	}
+	legacy, err := ParsePublicKeyAuto(pkData)
+	if err != nil || !legacy.Equal(pk) {
+		t.Fatalf("ParsePublicKeyAuto of a bare key: %v", err)
+	}
+	headered, _ := pk.MarshalWithHeader()
+	if parsed, err := ParsePublicKeyAuto(headered); err != nil || !parsed.Equal(pk) {
+		t.Fatalf("ParsePublicKeyAuto of a headered key: %v", err)
+	}
+
+	// No match
+	if _, err := InferParameterSet(pkData[1:]); !errors.Is(err, ErrParameterInference) {
+		t.Fatalf("InferParameterSet of a truncated key: got %v", err)
+	}
+	if _, err := InferFromCiphertext(nil); !errors.Is(err, ErrParameterInference) {
+		t.Fatalf("InferFromCiphertext of an empty ciphertext: got %v", err)
+	}
+
+	// A second set with the same sizes makes every length ambiguous
+	twin := params
+	twin.Name = "OWChCCA-infer-twin"
+	if err := RegisterParameterSetWithID(twin, 0); err != nil {
+		t.Fatalf("RegisterParameterSetWithID failed: %v", err)
+	}
+	t.Cleanup(func() {
+		globalRegistry.mu.Lock()
+		delete(globalRegistry.paramSets, twin.Name)
+		globalRegistry.mu.Unlock()
+	})
+	_, err = InferParameterSet(pkData)
+	if !errors.Is(err, ErrParameterInference) || !strings.Contains(err.Error(), params.Name+", "+twin.Name) {
+		t.Fatalf("ambiguous InferParameterSet: got %v", err)
+	}
+	if _, err := ParsePublicKeyAuto(pkData); !errors.Is(err, ErrParameterInference) {
+		t.Fatalf("ambiguous ParsePublicKeyAuto: got %v", err)
+	}
+	for name, decode := range map[string]func() error{
+		"PublicKey.UnmarshalBinary":  func() error { return new(PublicKey).UnmarshalBinary(pkData) },
+		"PrivateKey.UnmarshalBinary": func() error { return new(PrivateKey).UnmarshalBinary(skData) },
+		"NewLazyPublicKeyFromBytes":  func() error { _, err := NewLazyPublicKeyFromBytes(pkData); return err },
+	} {
+		if err := decode(); !errors.Is(err, ErrParameterInference) || !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("ambiguous %s: got %v", name, err)
+		}
+	}
+	if parsed, err := ParsePublicKeyAuto(headered); err != nil || !parsed.Equal(pk) {
+		t.Fatalf("headered keys are unaffected by ambiguity: %v", err)
+	}
+}
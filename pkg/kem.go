@@ -1,17 +1,22 @@
 package pkg
 
 import (
-	"bytes"
 	"crypto/rand"
 	"crypto/subtle"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
+	"math/bits"
 	"runtime"
 	"sync"
 
 	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/arithmetic"
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/bitsio"
 	"github.com/tuneinsight/lattigo/v6/ring"
 	"github.com/tuneinsight/lattigo/v6/utils/sampling"
 
@@ -25,30 +30,98 @@ var (
 	ErrInvalidCiphertext    = errors.New("owchcca: invalid ciphertext")
 	ErrDecapsulationFailed  = errors.New("owchcca: decapsulation failed")
 	ErrParameterValidation  = errors.New("owchcca: parameter validation failed")
+	ErrParameterInference   = errors.New("owchcca: cannot infer parameter set")
+	ErrToyParameters        = errors.New("owchcca: toy parameter set")
 	ErrInvalidRandomSource  = errors.New("owchcca: invalid random source")
 	ErrInvalidSharedParams  = errors.New("owchcca: invalid shared parameters")
 	ErrSerializationError   = errors.New("owchcca: serialization error")
 	ErrDeserializationError = errors.New("owchcca: deserialization error")
+	// ErrKeyConfirmationFailed wraps ErrDecapsulationFailed for a ciphertext whose key
+	// confirmation tag does not verify under the recovered seed
+	ErrKeyConfirmationFailed = fmt.Errorf("%w: key confirmation failed", ErrDecapsulationFailed)
 )
 
-// OwChCCAKEM implements the KEM interface
+// OwChCCAKEM implements the KEM interface.
+// It holds no mutable state: the With methods return modified copies, so an OwChCCAKEM is safe
+// for concurrent use by multiple goroutines.
 type OwChCCAKEM struct {
 	Params Parameters
+	// allowToy permits key generation with toy parameter sets
+	allowToy bool
+	// keyConfirmation appends a key confirmation tag to ciphertexts
+	keyConfirmation bool
 }
 
-// PublicKey represents an OW-ChCCA-KEM public key
+// WithAllowToyParameters returns a copy of the KEM that does or does not generate keys with
+// toy parameter sets (see SecurityClassToy). By default GenerateKeyPair refuses them.
+func (kem OwChCCAKEM) WithAllowToyParameters(allow bool) OwChCCAKEM {
+	kem.allowToy = allow
+	return kem
+}
+
+// checkToyParameters returns ErrToyParameters if kem.Params is a toy set the KEM does not allow
+func (kem *OwChCCAKEM) checkToyParameters() error {
+	if kem.Params.SecurityClass == SecurityClassToy && !kem.allowToy {
+		return fmt.Errorf("%w: %s must be explicitly allowed with WithAllowToyParameters", ErrToyParameters, kem.Params.Name)
+	}
+	return nil
+}
+
+// WithKeyConfirmation returns a copy of the KEM that does or does not use key confirmation.
+// With key confirmation, Encapsulate appends a ConfirmationTagSize-byte tag over the
+// ciphertext under a key derived from r. Decapsulate checks the tag as soon as it has recovered
+// r, before the re-encryption checks, and fails with ErrKeyConfirmationFailed if it does not
+// verify: the ciphertext was encapsulated to another key, or it or its tag was altered in
+// transit. A ciphertext whose tag verifies but that fails re-encryption was malformed by its
+// sender and is reported as ErrDecapsulationFailed. Both sides must agree:
+// the ciphertexts are ConfirmationTagSize bytes longer and the shared keys are derived under a
+// different label, so the two modes never interoperate.
+func (kem OwChCCAKEM) WithKeyConfirmation(enable bool) OwChCCAKEM {
+	kem.keyConfirmation = enable
+	return kem
+}
+
+// PublicKey represents an OW-ChCCA-KEM public key.
+// A is kept in ring form and only materialized as big.Int values for serialization.
+//
+// A public key is immutable once GenerateKeyPair, NewPublicKey or an unmarshaling method has
+// returned it, and is then safe for concurrent use: any number of goroutines may encapsulate to
+// it, serialize it or build Encapsulators from it. UnmarshalBinary, UnmarshalBinaryStrict and
+// ReadFrom overwrite the key and must not run concurrently with any other use of it.
 type PublicKey struct {
 	Params Parameters
 	u0     arithmetic.Matrix
 	u1     arithmetic.Matrix
-	a      arithmetic.Matrix
+	a      *arithmetic.PolyMatrix
+	digest *keyDigest // fingerprint cache, see Fingerprint
 }
 
-// KEMPrivateKey represents an OW-ChCCA-KEM private key
+// PrivateKey represents an OW-ChCCA-KEM private key.
+// Like PublicKey it is immutable after construction and safe for concurrent decapsulation; only
+// the unmarshaling methods modify it.
 type PrivateKey struct {
-	Pk *PublicKey
-	zb arithmetic.Matrix
-	b  bool // Flag indicating which matrix contains the authentic data
+	Pk   *PublicKey
+	zb   arithmetic.Matrix
+	b    bool   // Flag indicating which matrix contains the authentic data
+	seed []byte // Key generation seed for keys from DeriveKeyPair, nil otherwise
+}
+
+var (
+	_ encoding.BinaryMarshaler   = (*PublicKey)(nil)
+	_ encoding.BinaryUnmarshaler = (*PublicKey)(nil)
+	_ encoding.BinaryMarshaler   = (*PrivateKey)(nil)
+	_ encoding.BinaryUnmarshaler = (*PrivateKey)(nil)
+)
+
+// Register the key types so they can be gob-encoded through interface values
+func init() {
+	gob.Register(&PublicKey{})
+	gob.Register(&PrivateKey{})
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface and is equivalent to Bytes
+func (pk *PublicKey) MarshalBinary() ([]byte, error) {
+	return pk.Bytes()
 }
 
 // Bytes returns the serialized form of the public key
@@ -56,35 +129,52 @@ func (pk *PublicKey) Bytes() ([]byte, error) {
 	if pk == nil {
 		return nil, ErrInvalidPublicKey
 	}
-	var buf bytes.Buffer
+	return pk.appendBinary(make([]byte, 0, pk.Params.KeyParams.PublicKeySize))
+}
 
-	// Write matrix A
-	aBytes, err := pk.a.MarshalBinary()
+// appendBinary appends A || U0 || U1 to dst and checks the result against the declared public key size
+func (pk *PublicKey) appendBinary(dst []byte) ([]byte, error) {
+	start := len(dst)
+	dst, err := pk.appendMatrices(dst)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
+		return nil, err
 	}
-	if _, err = buf.Write(aBytes); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
+	if err := pk.checkEncodedSize(len(dst) - start); err != nil {
+		return nil, err
 	}
+	return dst, nil
+}
 
-	// Write matrices U0 and U1
-	u0Bytes, err := pk.u0.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
+// checkEncodedSize reports an encoding of the given length that disagrees with the public key size
+func (pk *PublicKey) checkEncodedSize(got int) error {
+	if want := pk.Params.KeyParams.PublicKeySize; got != want {
+		return fmt.Errorf("%w: public key is %d bytes, expected %d", ErrSerializationError, got, want)
+	}
+	return nil
+}
+
+// appendMatrices appends A || U0 || U1 to dst. Unlike appendBinary it does not look at Params.
+func (pk *PublicKey) appendMatrices(dst []byte) ([]byte, error) {
+	var err error
+
+	// Write matrix A
+	if pk.a == nil {
+		return nil, fmt.Errorf("%w: matrix A is missing", ErrSerializationError)
 	}
-	if _, err = buf.Write(u0Bytes); err != nil {
+	a := pk.a.ToMatrix()
+	if dst, err = a.AppendBinary(dst); err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
 	}
 
-	u1Bytes, err := pk.u1.MarshalBinary()
-	if err != nil {
+	// Write matrices U0 and U1
+	if dst, err = pk.u0.AppendBinary(dst); err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
 	}
-	if _, err = buf.Write(u1Bytes); err != nil {
+	if dst, err = pk.u1.AppendBinary(dst); err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
 	}
 
-	return buf.Bytes(), nil
+	return dst, nil
 }
 
 // Parameters return the parameters used by this public key
@@ -105,15 +195,76 @@ func (pk *PublicKey) Equal(other *PublicKey) bool {
 	}
 
 	// Compare matrices
-	if !pk.u0.Equal(otherPK.u0) || !pk.u1.Equal(otherPK.u1) || !pk.a.Equal(otherPK.a) {
+	if !pk.u0.Equal(otherPK.u0) || !pk.u1.Equal(otherPK.u1) || pk.a == nil || !pk.a.Equal(otherPK.a) {
 		return false
 	}
 
 	return true
 }
 
-// UnmarshalBinary deserializes a public key
+// Validate checks that the parameters are valid and the matrices have the dimensions they require
+func (pk *PublicKey) Validate() error {
+	if pk == nil {
+		return ErrInvalidPublicKey
+	}
+	if err := pk.Params.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+
+	n := pk.Params.LatticeParams.N
+	m := pk.Params.LatticeParams.M
+	lambda := pk.Params.LatticeParams.Lambda
+	modulus := pk.Params.LatticeParams.Q
+
+	if err := checkPolyMatrixShape(pk.a, n, m, modulus); err != nil {
+		return fmt.Errorf("%w: matrix A: %v", ErrInvalidPublicKey, err)
+	}
+	if err := checkMatrixShape(pk.u0, n, lambda, modulus); err != nil {
+		return fmt.Errorf("%w: matrix U0: %v", ErrInvalidPublicKey, err)
+	}
+	if err := checkMatrixShape(pk.u1, n, lambda, modulus); err != nil {
+		return fmt.Errorf("%w: matrix U1: %v", ErrInvalidPublicKey, err)
+	}
+
+	return nil
+}
+
+// checkMatrixShape checks that mat is a rows x cols matrix over modulus
+func checkMatrixShape(mat arithmetic.Matrix, rows, cols int, modulus *big.Int) error {
+	if mat.Rows != rows || mat.Cols != cols || len(mat.Values) != rows {
+		return fmt.Errorf("expected %dx%d, got %dx%d", rows, cols, mat.Rows, mat.Cols)
+	}
+	if mat.Modulus == nil || mat.Modulus.Cmp(modulus) != 0 {
+		return fmt.Errorf("modulus mismatch")
+	}
+	for i := range mat.Values {
+		if len(mat.Values[i]) != cols {
+			return fmt.Errorf("row %d has %d columns, expected %d", i, len(mat.Values[i]), cols)
+		}
+	}
+	return nil
+}
+
+// checkPolyMatrixShape checks that mat is a rows x cols matrix over modulus
+func checkPolyMatrixShape(mat *arithmetic.PolyMatrix, rows, cols int, modulus *big.Int) error {
+	if mat == nil {
+		return fmt.Errorf("missing")
+	}
+	if mat.Rows != rows || mat.Cols != cols || len(mat.Values) != rows {
+		return fmt.Errorf("expected %dx%d, got %dx%d", rows, cols, mat.Rows, mat.Cols)
+	}
+	if mat.Modulus().Cmp(modulus) != 0 {
+		return fmt.Errorf("modulus mismatch")
+	}
+	return nil
+}
+
+// UnmarshalBinary deserializes a public key. If the receiver has no parameters, they are taken
+// from a MarshalWithHeader header or, for a bare encoding, from the registered set of that size.
 func (pk *PublicKey) UnmarshalBinary(data []byte) error {
+	if pk.Params.LatticeParams.Q == nil {
+		return pk.unmarshalWithoutParams(data)
+	}
 	if len(data) < pk.Params.KeyParams.PublicKeySize {
 		return fmt.Errorf("%w: insufficient data", ErrDeserializationError)
 	}
@@ -133,19 +284,32 @@ func (pk *PublicKey) UnmarshalBinary(data []byte) error {
 	}
 
 	// Parse A matrix
-	pk.a = arithmetic.NewMatrix(n, m, modulus)
-	if err := pk.a.UnmarshalBinary(data[:aSize]); err != nil {
+	a, err := arithmetic.NewMatrixChecked(n, m, modulus)
+	if err != nil {
 		return fmt.Errorf("%w: %v", ErrDeserializationError, err)
 	}
+	if err := a.UnmarshalBinary(data[:aSize]); err != nil {
+		return fmt.Errorf("%w: %v", ErrDeserializationError, err)
+	}
+	aPoly, err := arithmetic.PolyMatrixFromMatrix(a)
+	if err != nil {
+		return fmt.Errorf("%w: matrix A: %v", ErrDeserializationError, err)
+	}
+	pk.digest = new(keyDigest)
+	pk.a = aPoly
 
 	// Parse U0 matrix
-	pk.u0 = arithmetic.NewMatrix(n, lambda, modulus)
+	if pk.u0, err = arithmetic.NewMatrixChecked(n, lambda, modulus); err != nil {
+		return fmt.Errorf("%w: %v", ErrDeserializationError, err)
+	}
 	if err := pk.u0.UnmarshalBinary(data[aSize : aSize+uSize]); err != nil {
 		return fmt.Errorf("%w: %v", ErrDeserializationError, err)
 	}
 
 	// Parse U1 matrix
-	pk.u1 = arithmetic.NewMatrix(n, lambda, modulus)
+	if pk.u1, err = arithmetic.NewMatrixChecked(n, lambda, modulus); err != nil {
+		return fmt.Errorf("%w: %v", ErrDeserializationError, err)
+	}
 	if err := pk.u1.UnmarshalBinary(data[aSize+uSize : aSize+2*uSize]); err != nil {
 		return fmt.Errorf("%w: %v", ErrDeserializationError, err)
 	}
@@ -153,31 +317,93 @@ func (pk *PublicKey) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// UnmarshalBinaryStrict deserializes a public key like UnmarshalBinary, but rejects input that is not
+// exactly PublicKeySize bytes, matrix headers that disagree with the parameters, and unreduced elements
+func (pk *PublicKey) UnmarshalBinaryStrict(data []byte) error {
+	if size := pk.Params.KeyParams.PublicKeySize; len(data) != size {
+		return fmt.Errorf("%w: public key is %d bytes, expected %d", ErrDeserializationError, len(data), size)
+	}
+
+	n := pk.Params.LatticeParams.N
+	m := pk.Params.LatticeParams.M
+	lambda := pk.Params.LatticeParams.Lambda
+	modulus := pk.Params.LatticeParams.Q
+
+	a, offset, err := decodeMatrixStrict(data, 0, "A", n, m, modulus)
+	if err != nil {
+		return err
+	}
+	u0, offset, err := decodeMatrixStrict(data, offset, "U0", n, lambda, modulus)
+	if err != nil {
+		return err
+	}
+	u1, _, err := decodeMatrixStrict(data, offset, "U1", n, lambda, modulus)
+	if err != nil {
+		return err
+	}
+	aPoly, err := arithmetic.PolyMatrixFromMatrix(a)
+	if err != nil {
+		return fmt.Errorf("%w: matrix A: %v", ErrDeserializationError, err)
+	}
+
+	pk.a, pk.u0, pk.u1, pk.digest = aPoly, u0, u1, new(keyDigest)
+	return nil
+}
+
+// decodeMatrixStrict decodes the rows x cols matrix encoded at data[offset:], returning the offset just past it
+func decodeMatrixStrict(data []byte, offset int, name string, rows, cols int, modulus *big.Int) (arithmetic.Matrix, int, error) {
+	mat, err := arithmetic.NewMatrixChecked(rows, cols, modulus)
+	if err != nil {
+		return arithmetic.Matrix{}, 0, fmt.Errorf("%w: matrix %s: %v", ErrDeserializationError, name, err)
+	}
+	elementSize := (modulus.BitLen() + 7) / 8
+	size := 8 + rows*cols*elementSize
+	if len(data) < offset+size {
+		return arithmetic.Matrix{}, 0, fmt.Errorf("%w: matrix %s at offset %d: truncated", ErrDeserializationError, name, offset)
+	}
+
+	gotRows := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+	gotCols := int(binary.BigEndian.Uint32(data[offset+4 : offset+8]))
+	if gotRows != rows || gotCols != cols {
+		return arithmetic.Matrix{}, 0, fmt.Errorf("%w: matrix %s at offset %d: dimension header %dx%d, expected %dx%d",
+			ErrDeserializationError, name, offset, gotRows, gotCols, rows, cols)
+	}
+
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			pos := offset + 8 + (i*cols+j)*elementSize
+			val := mat.Values[i][j].SetBytes(data[pos : pos+elementSize])
+			if val.Cmp(modulus) >= 0 {
+				return arithmetic.Matrix{}, 0, fmt.Errorf("%w: matrix %s element (%d,%d) at offset %d is not reduced mod q",
+					ErrDeserializationError, name, i, j, pos)
+			}
+		}
+	}
+
+	return mat, offset + size, nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface and is equivalent to Bytes
+func (sk *PrivateKey) MarshalBinary() ([]byte, error) {
+	return sk.Bytes()
+}
+
 // Bytes returns the serialized form of the private key
 func (sk *PrivateKey) Bytes() ([]byte, error) {
 	if sk == nil || sk.Pk == nil {
 		return nil, ErrInvalidPrivateKey
 	}
-	var buf bytes.Buffer
+	size := sk.Pk.Params.KeyParams.PrivateKeySize
+	buf := make([]byte, 0, size)
 
 	// Write public key
-	pkBytes, err := sk.Pk.Bytes()
+	buf, err := sk.Pk.appendBinary(buf)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
-	}
-	if len(pkBytes) != sk.Pk.Params.KeyParams.PublicKeySize {
-		return nil, fmt.Errorf("%w: invalid public key size", ErrSerializationError)
-	}
-	if _, err = buf.Write(pkBytes); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
+		return nil, err
 	}
 
 	// Write Zb matrix
-	zbBytes, err := sk.zb.MarshalBinary()
-	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
-	}
-	if _, err = buf.Write(zbBytes); err != nil {
+	if buf, err = sk.zb.AppendBinary(buf); err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
 	}
 
@@ -186,11 +412,13 @@ func (sk *PrivateKey) Bytes() ([]byte, error) {
 	if sk.b {
 		bFlag = 1
 	}
-	if err := buf.WriteByte(bFlag); err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
+	buf = append(buf, bFlag)
+
+	if len(buf) != size {
+		return nil, fmt.Errorf("%w: private key is %d bytes, expected %d", ErrSerializationError, len(buf), size)
 	}
 
-	return buf.Bytes(), nil
+	return buf, nil
 }
 
 // Public returns the public key corresponding to this private key
@@ -219,11 +447,127 @@ func (sk *PrivateKey) Equal(other *PrivateKey) bool {
 	return sk.Pk.Equal(otherSK.Pk)
 }
 
-// UnmarshalBinary deserializes a private key
-func (sk *PrivateKey) UnmarshalBinary(data []byte) error {
+// Validate checks the public key and that Zb has the dimensions required by the parameters
+func (sk *PrivateKey) Validate() error {
 	if sk == nil || sk.Pk == nil {
 		return ErrInvalidPrivateKey
 	}
+	if err := sk.Pk.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+
+	params := sk.Pk.Parameters()
+	m := params.LatticeParams.M
+	lambda := params.LatticeParams.Lambda
+	if err := checkMatrixShape(sk.zb, m, lambda, params.LatticeParams.Q); err != nil {
+		return fmt.Errorf("%w: matrix Zb: %v", ErrInvalidPrivateKey, err)
+	}
+
+	return nil
+}
+
+// NewPublicKey builds a public key under kem.Params from the matrices A (n x m), U0 and U1
+// (n x λ), which must be over Q. The matrices are copied and their elements reduced mod Q.
+// It is meant for tests and research; GenerateKeyPair is the way to create real keys.
+func (kem *OwChCCAKEM) NewPublicKey(a, u0, u1 arithmetic.Matrix) (*PublicKey, error) {
+	if err := kem.Params.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	n := kem.Params.LatticeParams.N
+	m := kem.Params.LatticeParams.M
+	lambda := kem.Params.LatticeParams.Lambda
+	modulus := kem.Params.LatticeParams.Q
+
+	if err := checkMatrixShape(a, n, m, modulus); err != nil {
+		return nil, fmt.Errorf("%w: matrix A: %v", ErrInvalidPublicKey, err)
+	}
+	pa, err := arithmetic.PolyMatrixFromMatrix(a)
+	if err != nil {
+		return nil, fmt.Errorf("%w: matrix A: %v", ErrInvalidPublicKey, err)
+	}
+
+	u0c, err := copyKeyMatrix(u0, n, lambda, modulus)
+	if err != nil {
+		return nil, fmt.Errorf("%w: matrix U0: %v", ErrInvalidPublicKey, err)
+	}
+	u1c, err := copyKeyMatrix(u1, n, lambda, modulus)
+	if err != nil {
+		return nil, fmt.Errorf("%w: matrix U1: %v", ErrInvalidPublicKey, err)
+	}
+	pk := &PublicKey{Params: kem.Params, a: pa, u0: u0c, u1: u1c, digest: new(keyDigest)}
+	return pk, nil
+}
+
+// NewPrivateKey builds a private key for pk from Zb (m x λ over Q) and b, which says whether
+// Zb is the trapdoor of U1 rather than U0. Zb is copied and reduced mod Q. Only the shapes are
+// checked; use CheckKeyPair to verify that A·Zb = U_b.
+func (kem *OwChCCAKEM) NewPrivateKey(pk *PublicKey, zb arithmetic.Matrix, b bool) (*PrivateKey, error) {
+	if err := pk.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrivateKey, err)
+	}
+	if pk.Params.Name != kem.Params.Name {
+		return nil, fmt.Errorf("%w: public key is for %s, not %s", ErrInvalidPrivateKey, pk.Params.Name, kem.Params.Name)
+	}
+	m := kem.Params.LatticeParams.M
+	lambda := kem.Params.LatticeParams.Lambda
+	modulus := kem.Params.LatticeParams.Q
+
+	zbc, err := copyKeyMatrix(zb, m, lambda, modulus)
+	if err != nil {
+		return nil, fmt.Errorf("%w: matrix Zb: %v", ErrInvalidPrivateKey, err)
+	}
+	sk := &PrivateKey{Pk: pk, zb: zbc, b: b}
+	return sk, nil
+}
+
+// copyKeyMatrix checks that src is a rows x cols matrix over modulus and returns a reduced copy
+func copyKeyMatrix(src arithmetic.Matrix, rows, cols int, modulus *big.Int) (arithmetic.Matrix, error) {
+	if err := checkMatrixShape(src, rows, cols, modulus); err != nil {
+		return arithmetic.Matrix{}, err
+	}
+	dst, err := arithmetic.NewMatrixChecked(rows, cols, modulus)
+	if err != nil {
+		return arithmetic.Matrix{}, err
+	}
+	if err := dst.SetAll(src.Values); err != nil {
+		return arithmetic.Matrix{}, err
+	}
+	return dst, nil
+}
+
+// CheckKeyPair reports whether sk is the private key of pk: sk must be valid, carry pk as its
+// public key, and satisfy A·Zb = U_b
+func CheckKeyPair(pk *PublicKey, sk *PrivateKey) error {
+	if err := sk.Validate(); err != nil {
+		return err
+	}
+	if !sk.Pk.Equal(pk) {
+		return fmt.Errorf("%w: private key belongs to a different public key", ErrInvalidPrivateKey)
+	}
+
+	aZb, err := pk.a.MulMat(sk.zb)
+	if err != nil {
+		return fmt.Errorf("%w: failed to compute A*Zb: %v", ErrInvalidPrivateKey, err)
+	}
+	ub := pk.u0
+	if sk.b {
+		ub = pk.u1
+	}
+	if !aZb.Equal(ub) {
+		return fmt.Errorf("%w: A*Zb does not match U_b", ErrInvalidPrivateKey)
+	}
+	return nil
+}
+
+// UnmarshalBinary deserializes a private key. If the receiver has no public key, the parameters
+// are taken from a MarshalWithHeader header or, for a bare encoding, from the registered set of that size.
+func (sk *PrivateKey) UnmarshalBinary(data []byte) error {
+	if sk == nil {
+		return ErrInvalidPrivateKey
+	}
+	if sk.Pk == nil {
+		return sk.unmarshalWithoutPublicKey(data)
+	}
 	// Get parameters from public key
 	params := sk.Pk.Parameters()
 	m := params.LatticeParams.M
@@ -246,17 +590,83 @@ func (sk *PrivateKey) UnmarshalBinary(data []byte) error {
 	}
 
 	// Parse Zb matrix
-	sk.zb = arithmetic.NewMatrix(m, lambda, modulus)
+	zb, err := arithmetic.NewMatrixChecked(m, lambda, modulus)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDeserializationError, err)
+	}
+	sk.zb = zb
 	if err := sk.zb.UnmarshalBinary(data[pkSize : pkSize+zbSize]); err != nil {
 		return fmt.Errorf("%w: %v", ErrDeserializationError, err)
 	}
 
 	// Parse b flag
 	sk.b = data[pkSize+zbSize] == 1
+	sk.seed = nil
+
+	return nil
+}
+
+// UnmarshalBinaryStrict deserializes a private key like UnmarshalBinary, but rejects input that is not
+// exactly PrivateKeySize bytes, non-canonical matrix encodings and b flags other than 0 or 1.
+// If sk.Pk already holds a key, the embedded public key must match it; sk.Pk is never modified.
+// The receiver is only changed once the whole input has been accepted.
+func (sk *PrivateKey) UnmarshalBinaryStrict(data []byte) error {
+	if sk == nil || sk.Pk == nil {
+		return ErrInvalidPrivateKey
+	}
+	params := sk.Pk.Parameters()
+	if size := params.KeyParams.PrivateKeySize; len(data) != size {
+		return fmt.Errorf("%w: private key is %d bytes, expected %d", ErrDeserializationError, len(data), size)
+	}
+
+	pkSize := params.KeyParams.PublicKeySize
+	pk := &PublicKey{Params: params}
+	if err := pk.UnmarshalBinaryStrict(data[:pkSize]); err != nil {
+		return err
+	}
+
+	zb, offset, err := decodeMatrixStrict(data, pkSize, "Zb", params.LatticeParams.M, params.LatticeParams.Lambda, params.LatticeParams.Q)
+	if err != nil {
+		return err
+	}
+
+	bFlag := data[offset]
+	if bFlag > 1 {
+		return fmt.Errorf("%w: b flag at offset %d is %d, expected 0 or 1", ErrDeserializationError, offset, bFlag)
+	}
+
+	if pk, err = matchPublicKey(sk.Pk, pk); err != nil {
+		return err
+	}
 
+	sk.Pk = pk
+	sk.zb = zb
+	sk.b = bFlag == 1
+	sk.seed = nil
 	return nil
 }
 
+// matchPublicKey returns the public key a strictly decoded private key should carry: decoded if
+// supplied holds no matrices yet, otherwise supplied itself, after checking in constant time that
+// it encodes to the same bytes as decoded. supplied is never modified.
+func matchPublicKey(supplied, decoded *PublicKey) (*PublicKey, error) {
+	if supplied.a == nil {
+		return decoded, nil
+	}
+	expected, err := supplied.appendMatrices(nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeserializationError, err)
+	}
+	embedded, err := decoded.appendMatrices(nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDeserializationError, err)
+	}
+	if subtle.ConstantTimeCompare(expected, embedded) != 1 {
+		return nil, fmt.Errorf("%w: embedded public key does not match the supplied one", ErrDeserializationError)
+	}
+	return supplied, nil
+}
+
 // PublicKeySize returns the size in bytes of encoded public keys
 func (kem *OwChCCAKEM) PublicKeySize() int {
 	return kem.Params.KeyParams.PublicKeySize
@@ -267,8 +677,11 @@ func (kem *OwChCCAKEM) PrivateKeySize() int {
 	return kem.Params.KeyParams.PrivateKeySize
 }
 
-// CiphertextSize returns the size in bytes of ciphertexts
+// CiphertextSize returns the size in bytes of ciphertexts, including the key confirmation tag if enabled
 func (kem *OwChCCAKEM) CiphertextSize() int {
+	if kem.keyConfirmation {
+		return kem.Params.KeyParams.CiphertextSize + ConfirmationTagSize
+	}
 	return kem.Params.KeyParams.CiphertextSize
 }
 
@@ -282,25 +695,59 @@ func (kem *OwChCCAKEM) GenerateKeyPair(randSource io.Reader) (*PublicKey, *Priva
 	if randSource == nil {
 		randSource = rand.Reader
 	}
+	randSource = &fullReader{r: randSource}
 
-	// Validate parameters
+	// Validate parameters; this includes the ring pre-flight, so NewRing below cannot fail on q
 	if err := kem.Params.Validate(); err != nil {
 		return nil, nil, err
 	}
+	if err := kem.checkToyParameters(); err != nil {
+		return nil, nil, err
+	}
+
+	pRing, err := newParamsRing(kem.Params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kem.generateKeyPair(randSource, pRing)
+}
+
+// newParamsRing builds the ring of degree m modulo q. It returns a nil ring when lattigo cannot
+// build one for the parameters, in which case the pure-Go fallback paths are used.
+func newParamsRing(params Parameters) (*ring.Ring, error) {
+	if arithmetic.CheckRingParameters(params.LatticeParams.M, params.LatticeParams.Q) != nil {
+		return nil, nil
+	}
+	pRing, err := ring.NewRing(params.LatticeParams.M, []uint64{params.LatticeParams.Q.Uint64()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ring: %w", err)
+	}
+	return pRing, nil
+}
 
+// generateKeyPair generates a key pair over pRing, which must be the ring of degree m modulo q,
+// or with the pure-Go fallback if pRing is nil.
+// The parameters must already be validated and randSource must fill every read.
+func (kem *OwChCCAKEM) generateKeyPair(randSource io.Reader, pRing *ring.Ring) (*PublicKey, *PrivateKey, error) {
 	// Get parameter values
 	n := kem.Params.LatticeParams.N
 	m := kem.Params.LatticeParams.M
 	lambda := kem.Params.LatticeParams.Lambda
 	modulus := kem.Params.LatticeParams.Q
 	alpha := kem.Params.GaussianParams.Alpha
-	pRing, err := ring.NewRing(m, []uint64{modulus.Uint64()})
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create ring: %w", err)
-	}
 
 	// Generate the shared matrix A.
-	polyVecA, a, err := parallelCalculatePolyVecAWithAFromReader(n, m, modulus, randSource, pRing)
+	var polyVecA []ring.Poly
+	var a *arithmetic.PolyMatrix
+	var err error
+	if pRing != nil {
+		polyVecA, err = parallelSamplePolyVecAFromReader(n, randSource, pRing)
+		if err == nil {
+			a = arithmetic.PolyMatrixFromRows(polyVecA, pRing)
+		}
+	} else {
+		a, err = sampleAFallback(n, m, modulus, randSource)
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to sample matrix A: %w", err)
 	}
@@ -309,6 +756,7 @@ func (kem *OwChCCAKEM) GenerateKeyPair(randSource io.Reader) (*PublicKey, *Priva
 	pk := &PublicKey{
 		Params: kem.Params,
 		a:      a,
+		digest: new(keyDigest),
 	}
 
 	sk := &PrivateKey{
@@ -322,17 +770,27 @@ func (kem *OwChCCAKEM) GenerateKeyPair(randSource io.Reader) (*PublicKey, *Priva
 	}
 	sk.b = bByte[0]&1 == 1
 
-	// Sample error matrix Zb from Gaussian distribution.
-	polyVecZbT, zb, err := parallelCalculatePolyVecZbTWithZbFromReader(m, lambda, modulus, alpha, randSource, pRing)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to sample Zb: %w", err)
-	}
-	sk.zb = zb
-
-	// Calculate A*Zb^T.
-	aZb, err := ParallelCalculateAZb(polyVecA, polyVecZbT, n, m, lambda, modulus, pRing)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to calculate A*Zb^T: %w", err)
+	// Sample error matrix Zb from Gaussian distribution and calculate A*Zb^T.
+	var aZb arithmetic.Matrix
+	if pRing != nil {
+		polyVecZbT, zb, err := parallelCalculatePolyVecZbTWithZbFromReader(m, lambda, modulus, alpha, randSource, pRing)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to sample Zb: %w", err)
+		}
+		sk.zb = zb
+		aZb, err = ParallelCalculateAZb(polyVecA, polyVecZbT, n, m, lambda, modulus, pRing)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to calculate A*Zb^T: %w", err)
+		}
+	} else {
+		sk.zb, err = sampleZbFallback(m, lambda, modulus, alpha, randSource)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to sample Zb: %w", err)
+		}
+		aZb, err = a.MulMat(sk.zb)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to calculate A*Zb^T: %w", err)
+		}
 	}
 
 	// Generate a random matrix Zq
@@ -353,14 +811,50 @@ func (kem *OwChCCAKEM) GenerateKeyPair(randSource io.Reader) (*PublicKey, *Priva
 	return pk, sk, nil
 }
 
+// maxRandomRetries is the number of consecutive failed reads fullReader tolerates before giving up
+const maxRandomRetries = 3
+
+// fullReader adapts an arbitrary io.Reader so that every Read fills its buffer completely.
+// Short reads are continued, and up to maxRandomRetries consecutive errors or empty reads are
+// retried; anything more persistent, and io.EOF, is reported as ErrInvalidRandomSource.
+type fullReader struct {
+	r io.Reader
+}
+
+func (f *fullReader) Read(p []byte) (int, error) {
+	n, failures := 0, 0
+	for n < len(p) {
+		k, err := f.r.Read(p[n:])
+		n += k
+		switch {
+		case k > 0:
+			failures = 0
+		case err == io.EOF:
+			return n, fmt.Errorf("%w: %v", ErrInvalidRandomSource, err)
+		default:
+			failures++
+		}
+		if failures > maxRandomRetries {
+			if err == nil {
+				err = io.ErrNoProgress
+			}
+			return n, fmt.Errorf("%w: %v", ErrInvalidRandomSource, err)
+		}
+	}
+	return n, nil
+}
+
+// samplingChunks is the number of independently seeded chunks key generation splits its
+// sampling into. It is fixed rather than derived from the CPU count so that a seeded random
+// source produces the same key pair on every machine.
+const samplingChunks = 16
+
+// workerRanges splits [0, total) into at most samplingChunks contiguous ranges, one per worker
 func workerRanges(total int) [][2]int {
 	if total <= 0 {
 		return nil
 	}
-	workers := runtime.NumCPU()
-	if workers > total {
-		workers = total
-	}
+	workers := min(samplingChunks, total)
 	chunkSize := max(1, (total+workers-1)/workers)
 	ranges := make([][2]int, 0, workers)
 	for start := 0; start < total; start += chunkSize {
@@ -382,20 +876,49 @@ func readWorkerSeeds(randSource io.Reader, workers int) ([][]byte, error) {
 	return seeds, nil
 }
 
-func parallelCalculatePolyVecAWithAFromReader(n, m int, modulus *big.Int, randSource io.Reader, pRing *ring.Ring) ([]ring.Poly, arithmetic.Matrix, error) {
-	polyVecA := make([]ring.Poly, n)
-	a := arithmetic.NewMatrix(n, m, modulus)
-	ranges := workerRanges(n)
-	seeds, err := readWorkerSeeds(randSource, len(ranges))
+// sampleAFallback samples A uniformly with the pure-Go sampler, for parameters without a ring
+func sampleAFallback(n, m int, modulus *big.Int, randSource io.Reader) (*arithmetic.PolyMatrix, error) {
+	a, err := arithmetic.GenerateRandomMatrix(n, m, modulus, randSource)
 	if err != nil {
-		return nil, arithmetic.Matrix{}, err
+		return nil, err
 	}
+	return arithmetic.PolyMatrixFromMatrix(a)
+}
 
-	var wg sync.WaitGroup
-	errChan := make(chan error, 1)
-	for idx, r := range ranges {
-		start, end := r[0], r[1]
-		seed := seeds[idx]
+// sampleZbFallback samples the m x λ matrix Zb with NewGaussianVector keyed from randSource,
+// for parameters without a ring
+func sampleZbFallback(m, lambda int, modulus *big.Int, alpha float64, randSource io.Reader) (arithmetic.Matrix, error) {
+	seeds, err := readWorkerSeeds(randSource, 1)
+	if err != nil {
+		return arithmetic.Matrix{}, err
+	}
+	samples, err := arithmetic.NewGaussianVector(m*lambda, alpha, seeds[0], modulus)
+	if err != nil {
+		return arithmetic.Matrix{}, err
+	}
+	zb, err := arithmetic.NewMatrixChecked(m, lambda, modulus)
+	if err != nil {
+		return arithmetic.Matrix{}, err
+	}
+	for i := range zb.Values {
+		zb.Values[i] = samples.Values[i*lambda : (i+1)*lambda]
+	}
+	return zb, nil
+}
+
+func parallelSamplePolyVecAFromReader(n int, randSource io.Reader, pRing *ring.Ring) ([]ring.Poly, error) {
+	polyVecA := make([]ring.Poly, n)
+	ranges := workerRanges(n)
+	seeds, err := readWorkerSeeds(randSource, len(ranges))
+	if err != nil {
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	errChan := make(chan error, 1)
+	for idx, r := range ranges {
+		start, end := r[0], r[1]
+		seed := seeds[idx]
 		wg.Add(1)
 		go func(start, end int, seed []byte) {
 			defer wg.Done()
@@ -411,7 +934,6 @@ func parallelCalculatePolyVecAWithAFromReader(n, m int, modulus *big.Int, randSo
 			sampler := ring.NewUniformSampler(prng, pRing)
 			for i := start; i < end; i++ {
 				polyVecA[i] = sampler.ReadNew()
-				pRing.PolyToBigint(polyVecA[i], 1, a.Values[i])
 			}
 		}(start, end, seed)
 	}
@@ -419,15 +941,18 @@ func parallelCalculatePolyVecAWithAFromReader(n, m int, modulus *big.Int, randSo
 	wg.Wait()
 	select {
 	case err := <-errChan:
-		return nil, arithmetic.Matrix{}, err
+		return nil, err
 	default:
-		return polyVecA, a, nil
+		return polyVecA, nil
 	}
 }
 
 func parallelCalculatePolyVecZbTWithZbFromReader(m, lambda int, modulus *big.Int, alpha float64, randSource io.Reader, pRing *ring.Ring) ([]ring.Poly, arithmetic.Matrix, error) {
+	zb, err := arithmetic.NewMatrixChecked(m, lambda, modulus)
+	if err != nil {
+		return nil, arithmetic.Matrix{}, err
+	}
 	polyVecZbT := make([]ring.Poly, lambda)
-	zb := arithmetic.NewMatrix(m, lambda, modulus)
 	ranges := workerRanges(lambda)
 	seeds, err := readWorkerSeeds(randSource, len(ranges))
 	if err != nil {
@@ -457,8 +982,14 @@ func parallelCalculatePolyVecZbTWithZbFromReader(m, lambda int, modulus *big.Int
 			sampler := ring.NewGaussianSampler(prng, pRing, gaussian, false)
 			for i := start; i < end; i++ {
 				polyVecZbT[i] = sampler.ReadNew()
-				coeffT := arithmetic.NewVector(m, modulus)
-				pRing.PolyToBigint(polyVecZbT[i], 1, coeffT.Values)
+				coeffT, err := arithmetic.NewVectorFromPoly(polyVecZbT[i], pRing, modulus)
+				if err != nil {
+					select {
+					case errChan <- err:
+					default:
+					}
+					return
+				}
 				for j := 0; j < m; j++ {
 					zb.Values[j][i] = coeffT.Values[j]
 				}
@@ -475,14 +1006,38 @@ func parallelCalculatePolyVecZbTWithZbFromReader(m, lambda int, modulus *big.Int
 	}
 }
 
+// checkSamplerRing checks that pRing is the ring of degree m modulo modulus. A missing ring or
+// one over another modulus is reported as arithmetic.ErrInvalidRing, a ring of another degree
+// as arithmetic.ErrInvalidDimensions.
+func checkSamplerRing(pRing *ring.Ring, m int, modulus *big.Int) error {
+	if pRing == nil {
+		return fmt.Errorf("%w: nil ring", arithmetic.ErrInvalidRing)
+	}
+	if pRing.Modulus().Cmp(modulus) != 0 {
+		return fmt.Errorf("%w: ring modulus %v, expected %v", arithmetic.ErrInvalidRing, pRing.Modulus(), modulus)
+	}
+	if pRing.N() != m {
+		return fmt.Errorf("%w: ring degree %d, expected %d", arithmetic.ErrInvalidDimensions, pRing.N(), m)
+	}
+	return nil
+}
+
 // ParallelCalculatePolyVecAWithA Sample the matrix A in parallel
-func ParallelCalculatePolyVecAWithA(n, m int, modulus *big.Int, sampler ring.Sampler, pRing *ring.Ring) ([]ring.Poly, arithmetic.Matrix) {
-	a := arithmetic.NewMatrix(n, m, modulus)
+// pRing must be the ring of degree m modulo modulus; an error is returned otherwise.
+func ParallelCalculatePolyVecAWithA(n, m int, modulus *big.Int, sampler ring.Sampler, pRing *ring.Ring) ([]ring.Poly, arithmetic.Matrix, error) {
+	if err := checkSamplerRing(pRing, m, modulus); err != nil {
+		return nil, arithmetic.Matrix{}, err
+	}
+	a, err := arithmetic.NewMatrixChecked(n, m, modulus)
+	if err != nil {
+		return nil, arithmetic.Matrix{}, err
+	}
 	polyVecA := make([]ring.Poly, n)
 	rowsPerWorker := max(1, n/runtime.NumCPU())
 
 	var wg sync.WaitGroup
 	var samplerMu sync.Mutex
+	errChan := make(chan error, 1)
 	for startRow := 0; startRow < n; startRow += rowsPerWorker {
 		wg.Add(1)
 		endRow := min(n, startRow+rowsPerWorker)
@@ -493,23 +1048,45 @@ func ParallelCalculatePolyVecAWithA(n, m int, modulus *big.Int, sampler ring.Sam
 				samplerMu.Lock()
 				polyVecA[i] = sampler.ReadNew()
 				samplerMu.Unlock()
-				pRing.PolyToBigint(polyVecA[i], 1, a.Values[i])
 			}
+			rows, err := arithmetic.NewMatrixFromPolyVec(polyVecA[startRow:endRow], pRing, modulus)
+			if err != nil {
+				select {
+				case errChan <- err:
+				default:
+				}
+				return
+			}
+			copy(a.Values[startRow:endRow], rows.Values)
 		}(startRow, endRow)
 	}
+
 	wg.Wait()
-	return polyVecA, a
+	select {
+	case err := <-errChan:
+		return nil, arithmetic.Matrix{}, err
+	default:
+		return polyVecA, a, nil
+	}
 }
 
 // ParallelCalculatePolyVecZbTWithZb Sample the matrix Zb^T in parallel
+// pRing must be the ring of degree m modulo modulus; an error is returned otherwise.
 // TODO: check if swap the loop order will improve the performance, since m > n > lambda
-func ParallelCalculatePolyVecZbTWithZb(m, lambda int, modulus *big.Int, sampler ring.Sampler, pRing *ring.Ring) ([]ring.Poly, arithmetic.Matrix) {
+func ParallelCalculatePolyVecZbTWithZb(m, lambda int, modulus *big.Int, sampler ring.Sampler, pRing *ring.Ring) ([]ring.Poly, arithmetic.Matrix, error) {
+	if err := checkSamplerRing(pRing, m, modulus); err != nil {
+		return nil, arithmetic.Matrix{}, err
+	}
+	zb, err := arithmetic.NewMatrixChecked(m, lambda, modulus)
+	if err != nil {
+		return nil, arithmetic.Matrix{}, err
+	}
 	polyVecZbT := make([]ring.Poly, lambda)
-	zb := arithmetic.NewMatrix(m, lambda, modulus)
 	rowsPerWorker := max(1, lambda/runtime.NumCPU())
 
 	var wg sync.WaitGroup
 	var samplerMu sync.Mutex
+	errChan := make(chan error, 1)
 	for startRow := 0; startRow < lambda; startRow += rowsPerWorker {
 		wg.Add(1)
 		endRow := min(lambda, startRow+rowsPerWorker)
@@ -520,155 +1097,60 @@ func ParallelCalculatePolyVecZbTWithZb(m, lambda int, modulus *big.Int, sampler
 				samplerMu.Lock()
 				polyVecZbT[i] = sampler.ReadNew()
 				samplerMu.Unlock()
-				coeffT := arithmetic.NewVector(m, modulus)
-				pRing.PolyToBigint(polyVecZbT[i], 1, coeffT.Values)
+				coeffT, err := arithmetic.NewVectorFromPoly(polyVecZbT[i], pRing, modulus)
+				if err != nil {
+					select {
+					case errChan <- err:
+					default:
+					}
+					return
+				}
 				for j := 0; j < m; j++ {
 					zb.Values[j][i] = coeffT.Values[j]
 				}
 			}
 		}(startRow, endRow)
 	}
+
 	wg.Wait()
-	return polyVecZbT, zb
+	select {
+	case err := <-errChan:
+		return nil, arithmetic.Matrix{}, err
+	default:
+		return polyVecZbT, zb, nil
+	}
 }
 
 // ParallelCalculateAZb calculates the matrix A*Zb^T in parallel
 func ParallelCalculateAZb(polyVecA []ring.Poly, polyVecZbT []ring.Poly, n, m, lambda int, modulus *big.Int, pRing *ring.Ring) (arithmetic.Matrix, error) {
-	aZb := arithmetic.NewMatrix(n, lambda, modulus)
-	rowsPerWorker := max(1, n/runtime.NumCPU())
-
-	var wg sync.WaitGroup
-	for startRow := 0; startRow < n; startRow += rowsPerWorker {
-		wg.Add(1)
-		endRow := min(n, startRow+rowsPerWorker)
-
-		go func(startRow, endRow int) {
-			defer wg.Done()
-			tmpPoly := pRing.NewPoly()
-
-			for i := startRow; i < endRow; i++ {
-				coeffs := arithmetic.NewVector(m, modulus)
-
-				for j := 0; j < lambda; j++ {
-					// Az[i][j] = row i of A * column j of Zb = Sum(polyVecA[i] * polyVecZbT[j]).
-					pRing.MulCoeffsBarrett(polyVecA[i], polyVecZbT[j], tmpPoly)
-					pRing.PolyToBigint(tmpPoly, 1, coeffs.Values)
-					aZb.Values[i][j] = coeffs.Sum()
-				}
-			}
-		}(startRow, endRow)
+	if err := checkSamplerRing(pRing, m, modulus); err != nil {
+		return arithmetic.Matrix{}, err
 	}
-	wg.Wait()
-	return aZb, nil
+	if len(polyVecA) != n || len(polyVecZbT) != lambda {
+		return arithmetic.Matrix{}, arithmetic.ErrInvalidDimensions
+	}
+	a := arithmetic.PolyMatrixFromRows(polyVecA, pRing)
+	return a.MulMatTransposed(arithmetic.PolyMatrixFromRows(polyVecZbT, pRing))
 }
 
 // Encapsulate generates a shared key and encapsulates it
 func (kem *OwChCCAKEM) Encapsulate(pubKey *PublicKey) (ciphertext, sharedKey []byte, err error) {
+	return kem.EncapsulateFrom(pubKey, rand.Reader)
+}
+
+// EncapsulateFrom is Encapsulate with the seed r drawn from randSource; a nil randSource means crypto/rand
+func (kem *OwChCCAKEM) EncapsulateFrom(pubKey *PublicKey, randSource io.Reader) (ciphertext, sharedKey []byte, err error) {
 	if pubKey == nil {
 		return nil, nil, ErrInvalidPublicKey
 	}
-	pk := pubKey
-
-	// Get parameter values
-	n := kem.Params.LatticeParams.N
-	m := kem.Params.LatticeParams.M
-	lambda := kem.Params.LatticeParams.Lambda
-	modulus := kem.Params.LatticeParams.Q
-	alphaPrime := kem.Params.GaussianParams.AlphaPrime
-	logEta := kem.Params.GaussianParams.LogEta
-	sharedKeySize := kem.Params.KeyParams.SharedKeySize
-
-	// Generate random seed r
-	r := make([]byte, lambda/8)
-	if _, err = io.ReadFull(rand.Reader, r); err != nil {
-		return nil, nil, fmt.Errorf("failed to generate random seed: %w", err)
-	}
-
-	// Expand r to get s, rho, h0, h1 using G function
-	s, rho, h0, h1 := expandSeed(r, n, lambda, logEta)
-	s.Modulus = modulus
-
-	e, err := arithmetic.GenerateSampleDVector(m, alphaPrime, rho, modulus)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to sample error vector: %w", err)
-	}
-
-	// Calculate x = A^T*s + e
-	at, err := pk.a.Transpose()
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to transpose matrix A: %w", err)
-	}
-
-	ats, err := at.MultiplyVector(s)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to compute A^T*s: %w", err)
-	}
 
-	x, err := ats.Add(e)
+	e, err := newEncapsulator(kem.Params, pubKey)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to compute x = A^T*s + e: %w", err)
-	}
-
-	// Calculate hatH0 = U0^T*s + h0*⌊q/2⌋
-	u0t, err := pk.u0.Transpose()
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to transpose matrix U0: %w", err)
-	}
-
-	u0ts, err := u0t.MultiplyVector(s)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to compute U0^T*s: %w", err)
-	}
-
-	hatH0, err := computeHatH(u0ts, h0, modulus)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to compute hatH0: %w", err)
-	}
-
-	// Calculate hatH1 = U1^T*s + h1*⌊q/2⌋
-	u1t, err := pk.u1.Transpose()
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to transpose matrix U1: %w", err)
-	}
-
-	u1ts, err := u1t.MultiplyVector(s)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to compute U1^T*s: %w", err)
-	}
-
-	hatH1, err := computeHatH(u1ts, h1, modulus)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to compute hatH1: %w", err)
-	}
-
-	// Calculate hatK0 = H(x, hatH0, h0)
-	hatK0 := hash3(x, hatH0, h0)[:lambda/8]
-
-	// Calculate hatK1 = H(x, hatH1, h1)
-	hatK1 := hash3(x, hatH1, h1)[:lambda/8]
-
-	// Calculate c0 = hatK0 ⊕ r
-	c0 := make([]byte, lambda/8)
-	for i := range c0 {
-		c0[i] = hatK0[i] ^ r[i]
-	}
-
-	// Calculate c1 = hatK1 ⊕ r
-	c1 := make([]byte, lambda/8)
-	for i := range c1 {
-		c1[i] = hatK1[i] ^ r[i]
-	}
-
-	// Construct ciphertext: c0 || c1 || x || hatH0 || hatH1
-	ciphertext, err = constructCiphertext(c0, c1, x, hatH0, hatH1)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to construct ciphertext: %w", err)
+		return nil, nil, err
 	}
+	e.keyConfirmation = kem.keyConfirmation
 
-	// Use r as the shared secret (possibly with key derivation)
-	sharedKey = kdf(r, sharedKeySize)
-
-	return ciphertext, sharedKey, nil
+	return e.EncapsulateFrom(randSource)
 }
 
 // Decapsulate recovers the shared key from a ciphertext
@@ -676,171 +1158,113 @@ func (kem *OwChCCAKEM) Decapsulate(privKey *PrivateKey, ciphertext []byte) (shar
 	if privKey == nil || privKey.Pk == nil {
 		return nil, ErrInvalidPrivateKey
 	}
-	sk := privKey
-	pk := sk.Pk
-
-	// Get parameter values
-	n := kem.Params.LatticeParams.N
-	m := kem.Params.LatticeParams.M
-	lambda := kem.Params.LatticeParams.Lambda
-	logEta := kem.Params.GaussianParams.LogEta
-	modulus := kem.Params.LatticeParams.Q
-	alphaPrime := kem.Params.GaussianParams.AlphaPrime
-	sharedKeySize := kem.Params.KeyParams.SharedKeySize
-
-	// Parse ciphertext
-	c0, c1, x, hatH0, hatH1, err := parseCiphertext(ciphertext, m, lambda, modulus)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse ciphertext: %w", err)
-	}
-
-	// Determine which components to use based on the b flag
-	var hatHb, hatHnb *arithmetic.Vector
-	var hb, hnb *arithmetic.Vector
-	var cb, cnb []byte
-	var _, unb arithmetic.Matrix
-
-	if sk.b {
-		hatHb, hatHnb = hatH1, hatH0
-		cb, cnb = c1, c0
-		_, unb = pk.u1, pk.u0
-	} else {
-		hatHb, hatHnb = hatH0, hatH1
-		cb, cnb = c0, c1
-		_, unb = pk.u0, pk.u1
-	}
-
-	// Calculate Zb^T*x
-	zbt, err := sk.zb.Transpose()
-	if err != nil {
-		return nil, fmt.Errorf("failed to transpose matrix Zb: %w", err)
-	}
-
-	zbtx, err := zbt.MultiplyVector(x)
-	if err != nil {
-		return nil, fmt.Errorf("failed to compute Zb^T*x: %w", err)
-	}
 
-	// Calculate hatHb - Zb^T*x
-	diff, err := hatHb.Subtract(zbtx)
+	d, err := newDecapsulator(kem.Params, privKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compute hatHb - Zb^T*x: %w", err)
+		return nil, err
 	}
+	d.keyConfirmation = kem.keyConfirmation
 
-	// Round to get hb'
-	hbPrime := roundVector(diff, modulus)
-
-	// Calculate hatKb = H(x, hatHb, hb')
-	hatKb := hash3(x, hatHb, hbPrime)[:lambda/8]
-
-	// Recover r = cb ⊕ hatKb
-	r := make([]byte, lambda/8)
-	for i := range r {
-		r[i] = cb[i] ^ hatKb[i]
-	}
+	return d.Decapsulate(ciphertext)
+}
 
-	// Expand r to get s, rho, h0, h1
-	s, rho, h0, h1 := expandSeed(r, n, lambda, logEta)
-	s.Modulus = modulus
+// DecapsulateBatch decapsulates every ciphertext in cts under privKey. The decapsulation state
+// (the selected U_b and U_{1-b} and the ring) is built once and shared, and the ciphertexts are
+// processed in parallel. sharedKeys[i] and errs[i] belong to cts[i]; errs[i] is nil on success.
+// If privKey is invalid, every entry of errs reports it.
+func (kem *OwChCCAKEM) DecapsulateBatch(privKey *PrivateKey, cts [][]byte) (sharedKeys [][]byte, errs []error) {
+	sharedKeys = make([][]byte, len(cts))
+	errs = make([]error, len(cts))
 
-	// Determine which h values to use
-	if sk.b {
-		hb, hnb = h1, h0
-	} else {
-		hb, hnb = h0, h1
+	var d *Decapsulator
+	err := ErrInvalidPrivateKey
+	if privKey != nil && privKey.Pk != nil {
+		d, err = newDecapsulator(kem.Params, privKey)
 	}
-
-	// Calculate hatHnb' = Unb^T*s + hnb*⌊q/2⌋
-	unbt, err := unb.Transpose()
 	if err != nil {
-		return nil, fmt.Errorf("failed to transpose matrix Unb: %w", err)
+		for i := range errs {
+			errs[i] = err
+		}
+		return sharedKeys, errs
 	}
+	d.keyConfirmation = kem.keyConfirmation
 
-	unbts, err := unbt.MultiplyVector(s)
-	if err != nil {
-		return nil, fmt.Errorf("failed to compute Unb^T*s: %w", err)
-	}
+	perWorker := max(1, len(cts)/runtime.NumCPU())
+	var wg sync.WaitGroup
+	for start := 0; start < len(cts); start += perWorker {
+		wg.Add(1)
+		end := min(len(cts), start+perWorker)
 
-	hatHnbPrime, err := computeHatH(unbts, hnb, modulus)
-	if err != nil {
-		return nil, fmt.Errorf("failed to compute hatHnb': %w", err)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				sharedKeys[i], errs[i] = d.Decapsulate(cts[i])
+			}
+		}(start, end)
 	}
 
-	// Calculate hatKnb = H(x, hatHnb', hnb)
-	hatKnb := hash3(x, hatHnbPrime, hnb)[:lambda/8]
-
-	e, err := arithmetic.GenerateSampleDVector(m, alphaPrime, rho, modulus)
-	if err != nil {
-		return nil, fmt.Errorf("failed to sample error vector: %w", err)
-	}
+	wg.Wait()
+	return sharedKeys, errs
+}
 
-	// Calculate x' = A^T*s + e
-	at, err := pk.a.Transpose()
+// HealthCheck runs a full key generation, encapsulation and decapsulation round trip under
+// kem.Params and reports an error unless both sides agree on the shared key. The keys it
+// generates are discarded, so it also runs for toy parameter sets.
+func (kem *OwChCCAKEM) HealthCheck() error {
+	check := kem.WithAllowToyParameters(true)
+	pk, sk, err := check.GenerateKeyPair(rand.Reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to transpose matrix A: %w", err)
+		return fmt.Errorf("owchcca: health check failed: %w", err)
 	}
+	defer sk.zb.ZeroInPlace()
+	return kem.healthCheck(pk, sk)
+}
 
-	ats, err := at.MultiplyVector(s)
+// healthCheck is the round trip of HealthCheck with the given key pair
+func (kem *OwChCCAKEM) healthCheck(pk *PublicKey, sk *PrivateKey) error {
+	ct, ss, err := kem.Encapsulate(pk)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compute A^T*s: %w", err)
+		return fmt.Errorf("owchcca: health check failed: %w", err)
 	}
-
-	xPrime, err := ats.Add(e)
+	ss2, err := kem.Decapsulate(sk, ct)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compute x' = A^T*s + e: %w", err)
+		return fmt.Errorf("owchcca: health check failed: %w", err)
 	}
-
-	// Verify that x' = x
-	if !x.Equal(xPrime) {
-		return nil, ErrDecapsulationFailed
+	if subtle.ConstantTimeCompare(ss, ss2) != 1 {
+		return fmt.Errorf("owchcca: health check failed: %w", ErrDecapsulationFailed)
 	}
 
-	// Verify that hatKnb ⊕ r = cnb
-	cnbCalculated := make([]byte, lambda/8)
-	for i := range cnbCalculated {
-		cnbCalculated[i] = hatKnb[i] ^ r[i]
-	}
-
-	if subtle.ConstantTimeCompare(cnb, cnbCalculated) != 1 {
-		return nil, ErrDecapsulationFailed
-	}
-
-	// Verify that hb' = hb
-	if !hbPrime.Equal(hb) {
-		return nil, ErrDecapsulationFailed
-	}
+	return nil
+}
 
-	// Verify that hatHnb' = hatHnb
-	if !hatHnbPrime.Equal(hatHnb) {
-		return nil, ErrDecapsulationFailed
-	}
+// gDomain separates the seed expansion G from every other use of SHAKE256
+const gDomain = "OWChCCA-G"
 
-	// Use r as the shared secret (possibly with key derivation)
-	sharedKey = kdf(r, sharedKeySize)
+// gSizes returns the byte lengths of the s, rho, h0 and h1 components of the output of G.
+// s holds n values of logEta+1 bits and h0, h1 hold λ bits each; every length is rounded up to
+// whole bytes.
+func gSizes(n, lambda, logEta int) (sSize, rhoSize, h0Size, h1Size int) {
+	sSize = ceilDiv(n*(logEta+1), 8)
+	rhoSize = ceilDiv(lambda, 8)
+	return sSize, rhoSize, rhoSize, rhoSize
+}
 
-	return sharedKey, nil
+// ceilDiv returns ⌈a/b⌉ for non-negative a and positive b
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
 }
 
-// expandSeed expands a seed into s, rho, h0, h1 using a hash function
+// expandSeed is the function G: it expands a seed into s, rho, h0, h1. The components are
+// read in that order from SHAKE256("OWChCCA-G" || FormatVersion || seed), with the lengths of
+// gSizes.
 func expandSeed(seed []byte, n, lambda, logEta int) (*arithmetic.Vector, []byte, *arithmetic.Vector, *arithmetic.Vector) {
-	// Use SHA3-256 as the hash function
-	h := sha3.New256()
+	h := sha3.NewShake256()
+	h.Write([]byte(gDomain))
+	h.Write([]byte{FormatVersion})
 	h.Write(seed)
-	m := h.Sum(nil)
-
-	// Use SHA3-512 for expansion
-	h = sha3.New512()
-	h.Write(m)
 
-	// Calculate sizes
-	sSize := n * (logEta + 1) / 8
-	rhoSize := lambda / 8
-	h0Size := lambda / 8
-	h1Size := lambda / 8
-
-	// Generate all randomness in one go
-	totalSize := sSize + rhoSize + h0Size + h1Size
-	expandedBytes := make([]byte, totalSize)
+	sSize, rhoSize, h0Size, h1Size := gSizes(n, lambda, logEta)
+	expandedBytes := make([]byte, sSize+rhoSize+h0Size+h1Size)
 	h.Read(expandedBytes)
 
 	// Split into components
@@ -849,116 +1273,84 @@ func expandSeed(seed []byte, n, lambda, logEta int) (*arithmetic.Vector, []byte,
 	h0Bits := expandedBytes[sSize+rhoSize : sSize+rhoSize+h0Size]
 	h1Bits := expandedBytes[sSize+rhoSize+h0Size:]
 
-	// Convert s to a vector
-	s := bytesToVector(sBits, n, logEta+1)
+	// Convert s to a vector of logEta+1 bit values, reduced modulo 2^(logEta+1)-1 as they always were
+	width := logEta + 1
+	sModulus := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(width)), big.NewInt(1))
+	s := bitsio.UnpackChunked(sBits, n, width, sModulus)
 
-	// Convert h0 and h1 to vectors
-	h0 := bytesToBinaryVector(h0Bits, lambda)
-	h1 := bytesToBinaryVector(h1Bits, lambda)
+	// Convert h0 and h1 to binary vectors, LSB-first
+	h0 := bitsio.BitsToVector(bitsio.UnpackBits(h0Bits, lambda), 1, bitModulus)
+	h1 := bitsio.BitsToVector(bitsio.UnpackBits(h1Bits, lambda), 1, bitModulus)
 
 	return s, rho, h0, h1
 }
 
-// bytesToVector converts byte array to a vector, interpreting groups of bits
-func bytesToVector(data []byte, length, bitsPerValue int) *arithmetic.Vector {
-	totalBitsNeeded := length * bitsPerValue
-	if len(data)*8 < totalBitsNeeded {
-		return nil
-	}
-
-	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bitsPerValue)), big.NewInt(1))
-	result := arithmetic.NewVector(length, mask)
-
-	for i := 0; i < length; i++ {
-		startBit := i * bitsPerValue
-		startByte := startBit / 8
-		bitOffset := startBit % 8
-
-		value := big.NewInt(0)
-		bitsRemaining := bitsPerValue
-		currentBitOffset := bitOffset
-
-		// Read bytes until we have all bits for this element
-		for j := startByte; bitsRemaining > 0; j++ {
-			if j >= len(data) {
-				break
-			}
-
-			// Number of bits to read from this byte
-			bitsToRead := 8 - currentBitOffset
-			if bitsToRead > bitsRemaining {
-				bitsToRead = bitsRemaining
-			}
-
-			// Extract bits and add to value
-			byteMask := byte((1 << bitsToRead) - 1)
-			extracted := (data[j] >> currentBitOffset) & byteMask
-
-			value.Lsh(value, uint(bitsToRead))
-			value.Or(value, big.NewInt(int64(extracted)))
-
-			bitsRemaining -= bitsToRead
-			currentBitOffset = 0
+// bitModulus is the modulus of the binary vectors h0, h1 and hb'
+var bitModulus = big.NewInt(2)
+
+// hash3Domain separates hash3 from every other use of SHA3 in the scheme
+const hash3Domain = "OW-ChCCA-KEM-H3"
+
+// hash3BranchLabels separate the hashes of the two ciphertext branches, so hatK0 and hatK1
+// differ even for identical inputs
+var hash3BranchLabels = [2]string{"H0", "H1"}
+
+// hash3 computes H_branch(x, hatH, h) as size bytes of SHAKE256 output. The state absorbs the
+// domain label, format version and branch label, then for each input a one-byte tag, its
+// length (4 bytes), its element size (2 bytes) and its elements in fixed-width big-endian form,
+// streamed without marshaling the vectors. branch is 0 or 1 and size is λ/8, which may exceed
+// the 32 bytes of a SHA3-256 digest. An element that does not fit its element size is an error.
+func hash3(branch int, x, hatH, h *arithmetic.Vector, size int) ([]byte, error) {
+	hash := sha3.NewShake256()
+	hash.Write([]byte(hash3Domain))
+	hash.Write([]byte{FormatVersion})
+	hash.Write([]byte(hash3BranchLabels[branch]))
+
+	for tag, v := range []*arithmetic.Vector{x, hatH, h} {
+		elementSize := (v.Modulus.BitLen() + 7) / 8
+		var prefix [7]byte
+		prefix[0] = byte(tag)
+		binary.BigEndian.PutUint32(prefix[1:5], uint32(v.Length()))
+		binary.BigEndian.PutUint16(prefix[5:7], uint16(elementSize))
+		hash.Write(prefix[:])
+		if err := v.WriteCanonical(&hash, elementSize); err != nil {
+			return nil, fmt.Errorf("hash input %d: %w", tag, err)
 		}
-		value.And(value, mask)
-		result.Set(i, value)
 	}
 
-	return result
+	out := make([]byte, size)
+	hash.Read(out)
+	return out, nil
 }
 
-// bytesToBinaryVector converts byte array to a binary vector (0 or 1 entries)
-func bytesToBinaryVector(data []byte, length int) *arithmetic.Vector {
-	result := arithmetic.NewVector(length, big.NewInt(1))
-	if len(data)*8 < length {
-		return nil
-	}
-
-	for i := 0; i < length; i++ {
-		byteIndex := i / 8
-		bitOffset := i % 8
-
-		bitValue := (data[byteIndex] >> bitOffset) & 1
-		if bitValue == 1 {
-			result.Set(i, big.NewInt(1))
-		} else {
-			result.Set(i, big.NewInt(0))
-		}
-	}
-
+// errorNormTail is the t of the tail bound in errorNormBound: an honestly sampled error vector
+// exceeds the bound with probability at most e^-t
+const errorNormTail = 64
+
+// errorNormBound returns α'²·(m + 2·√(m·t) + 2t) with t = errorNormTail, a bound on the squared
+// L2 norm of the error vector. ‖e‖²/α'² is close to chi-squared with m degrees of freedom, so
+// (α'·√m)² is only its expected value and half of all honest samples exceed it; the
+// Laurent-Massart margin makes an honest e exceed the bound with probability at most e^-t.
+func errorNormBound(alphaPrime float64, m int) *big.Int {
+	t := float64(errorNormTail)
+	factor := float64(m) + 2*math.Sqrt(float64(m)*t) + 2*t
+
+	bound := new(big.Float).SetFloat64(alphaPrime)
+	bound.Mul(bound, bound)
+	bound.Mul(bound, new(big.Float).SetFloat64(factor))
+	result, _ := bound.Int(nil)
 	return result
 }
 
-// hash3 computes H(x, hatH, h)
-func hash3(x, hatH, h *arithmetic.Vector) []byte {
-	// Use SHA3-256 as the hash function
-	hash := sha3.New256()
-
-	// Serialize and write x
-	xBytes, _ := x.MarshalBinary()
-	hash.Write(xBytes)
-
-	// Serialize and write hatH
-	hatHBytes, _ := hatH.MarshalBinary()
-	hash.Write(hatHBytes)
-
-	// Serialize and write h
-	hBytes, _ := h.MarshalBinary()
-	hash.Write(hBytes)
-
-	// Return the hash
-	return hash.Sum(nil)
-}
-
 // computeHatH calculates U^T*s + h*⌊q/2⌋
 func computeHatH(uTs, h *arithmetic.Vector, modulus *big.Int) (*arithmetic.Vector, error) {
 	// Calculate ⌊q/2⌋
 	halfQ := new(big.Int).Rsh(modulus, 1)
 
-	// Scale h by ⌊q/2⌋
-	scaled, err := h.ScalarMultiply(halfQ)
-	if err != nil {
-		return nil, err
+	// Lift the bits of h into Z_q and scale them by ⌊q/2⌋
+	scaled := arithmetic.NewVector(h.Length(), modulus)
+	for i, bit := range h.Values {
+		scaled.Set(i, new(big.Int).Mul(bit, halfQ))
 	}
 
 	// Add to U^T*s
@@ -970,145 +1362,237 @@ func computeHatH(uTs, h *arithmetic.Vector, modulus *big.Int) (*arithmetic.Vecto
 	return result, nil
 }
 
-// roundVector rounds each component of a vector
-func roundVector(v *arithmetic.Vector, modulus *big.Int) *arithmetic.Vector {
-	// Calculate ⌊q/2⌋
-	halfQ := new(big.Int).Rsh(modulus, 1)
+// roundVector rounds each component of a vector to the nearer of 0 and ⌊q/2⌋. The components
+// must already be reduced into [0, q), as the results of Vector arithmetic are. The inputs are
+// secret-derived, so each component is read with wordOf and rounded with roundBit: nothing here
+// divides or branches on the values. The big.Int arithmetic that produces v, and the hashing of
+// the result, are not constant time.
+func roundVector(v *arithmetic.Vector, modulus *big.Int) (*arithmetic.Vector, error) {
+	if modulus.Sign() <= 0 || modulus.BitLen() > 63 {
+		return nil, fmt.Errorf("%w: word-sized rounding needs a modulus below 2^63", ErrParameterValidation)
+	}
+	q := modulus.Uint64()
 
 	// Create result vector
 	length := v.Length()
-	result := arithmetic.NewVector(length, big.NewInt(1))
+	result := arithmetic.NewVector(length, bitModulus)
 
-	// Round each component
+	// Round each component, reading it through a fixed-size word
 	for i := 0; i < length; i++ {
-		val := v.Get(i)
+		result.Values[i].SetUint64(roundBit(wordOf(v.Values[i]), q))
+	}
 
-		// Compute distance to 0 and halfQ
-		distToZero := new(big.Int).Set(val)
-		if distToZero.Cmp(halfQ) > 0 {
-			distToZero.Sub(modulus, distToZero)
-		}
+	return result, nil
+}
 
-		distToHalfQ := new(big.Int).Sub(val, halfQ)
-		if distToHalfQ.Sign() < 0 {
-			distToHalfQ.Neg(distToHalfQ)
-		}
+// wordOf returns x, which must lie in [0, 2^64), as a uint64. The limbs of x are copied into a
+// fixed-size array and combined with shifts, so unlike Mod or FillBytes it does no division and
+// its loop does not depend on the value.
+func wordOf(x *big.Int) uint64 {
+	var limbs [64 / bits.UintSize]big.Word
+	copy(limbs[:], x.Bits())
 
-		// Round to closer value
-		if distToZero.Cmp(distToHalfQ) <= 0 {
-			result.Set(i, big.NewInt(0))
-		} else {
-			result.Set(i, big.NewInt(1))
-		}
+	// With 64-bit limbs there is one limb and the shift is by 0
+	var w uint64
+	for k := len(limbs) - 1; k >= 0; k-- {
+		w = w<<(bits.UintSize%64) | uint64(limbs[k])
 	}
-
-	return result
+	return w
 }
 
-// constructCiphertext constructs the full ciphertext
-func constructCiphertext(c0, c1 []byte, x, hatH0, hatH1 *arithmetic.Vector) ([]byte, error) {
-	var buf bytes.Buffer
+// roundBit returns 1 if x is strictly closer to ⌊q/2⌋ than to 0 modulo q, and 0 otherwise,
+// without branching on x. It requires q < 2^63 and x < q.
+func roundBit(x, q uint64) uint64 {
+	halfQ := q >> 1
 
-	// Write c0
-	if _, err := buf.Write(c0); err != nil {
-		return nil, err
-	}
+	// distToZero = x if x ≤ ⌊q/2⌋, else q - x
+	above := -((halfQ - x) >> 63)
+	distToZero := x&^above | (q-x)&above
 
-	// Write c1
-	if _, err := buf.Write(c1); err != nil {
-		return nil, err
-	}
+	// distToHalfQ = |x - ⌊q/2⌋|
+	diff := x - halfQ
+	neg := diff >> 63
+	distToHalfQ := (diff ^ -neg) + neg
 
-	// Serialize and write x
-	xBytes, err := x.MarshalBinary()
-	if err != nil {
-		return nil, err
-	}
-	if _, err := buf.Write(xBytes); err != nil {
-		return nil, err
-	}
+	// 1 iff distToHalfQ < distToZero
+	return (distToHalfQ - distToZero) >> 63
+}
 
-	// Serialize and write hatH0
-	hatH0Bytes, err := hatH0.MarshalBinary()
-	if err != nil {
-		return nil, err
-	}
-	if _, err := buf.Write(hatH0Bytes); err != nil {
-		return nil, err
+// constructCiphertext constructs the full ciphertext c0 || c1 || x || hatH0 || hatH1 of exactly size bytes,
+// compressing hatH0 and hatH1 to compressionBits bits per coefficient when compressionBits > 0
+func constructCiphertext(size, compressionBits int, c0, c1 []byte, x, hatH0, hatH1 *arithmetic.Vector) ([]byte, error) {
+	buf := make([]byte, 0, size)
+
+	// Write c0 and c1
+	buf = append(buf, c0...)
+	buf = append(buf, c1...)
+
+	if compressionBits > 0 {
+		hatH0 = compressVector(hatH0, compressionBits)
+		hatH1 = compressVector(hatH1, compressionBits)
 	}
 
-	// Serialize and write hatH1
-	hatH1Bytes, err := hatH1.MarshalBinary()
-	if err != nil {
-		return nil, err
+	// Serialize x, hatH0 and hatH1
+	var err error
+	for _, v := range []*arithmetic.Vector{x, hatH0, hatH1} {
+		if buf, err = v.AppendBinaryRaw(buf); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSerializationError, err)
+		}
 	}
-	if _, err := buf.Write(hatH1Bytes); err != nil {
-		return nil, err
+
+	if len(buf) != size {
+		return nil, fmt.Errorf("%w: ciphertext is %d bytes, expected %d", ErrSerializationError, len(buf), size)
 	}
 
-	return buf.Bytes(), nil
+	return buf, nil
 }
 
-// parseCiphertext parses the components of a ciphertext
-func parseCiphertext(ciphertext []byte, m, lambda int, modulus *big.Int) (c0, c1 []byte, x, hatH0, hatH1 *arithmetic.Vector, err error) {
-	if len(ciphertext) < 2*(lambda/8) {
+// parseCiphertext parses the components of a ciphertext, decompressing hatH0 and hatH1 when compressionBits > 0
+func parseCiphertext(ciphertext []byte, m, lambda int, modulus *big.Int, compressionBits int) (c0, c1 []byte, x, hatH0, hatH1 *arithmetic.Vector, err error) {
+	layout := ciphertextLayout(m, lambda, modulus, compressionBits)
+	hatHModulus := modulus
+	if compressionBits > 0 {
+		hatHModulus = compressionModulus(compressionBits)
+	}
+	if len(ciphertext) < layout.C1.End() {
 		return nil, nil, nil, nil, nil, fmt.Errorf("%w: ciphertext too short", ErrInvalidCiphertext)
 	}
 
 	// Read c0 and c1
-	c0 = ciphertext[:lambda/8]
-	c1 = ciphertext[lambda/8 : 2*(lambda/8)]
-
-	// Determine position after c0 and c1
-	pos := 2 * (lambda / 8)
+	c0 = ciphertext[layout.C0.Offset:layout.C0.End()]
+	c1 = ciphertext[layout.C1.Offset:layout.C1.End()]
 
 	// Parse x
-	x = arithmetic.NewVector(m, modulus)
-	xSize := x.EncodedSize()
-	if len(ciphertext) < pos+xSize {
+	if x, err = arithmetic.NewVectorChecked(m, modulus); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	if len(ciphertext) < layout.X.End() {
 		return nil, nil, nil, nil, nil, fmt.Errorf("%w: ciphertext too short for x", ErrInvalidCiphertext)
 	}
-	if err := x.UnmarshalBinary(ciphertext[pos : pos+xSize]); err != nil {
+	if err := x.UnmarshalBinaryRaw(ciphertext[layout.X.Offset:layout.X.End()]); err != nil {
 		return nil, nil, nil, nil, nil, fmt.Errorf("%w: failed to parse x: %v", ErrInvalidCiphertext, err)
 	}
-	pos += xSize
 
 	// Parse hatH0
-	hatH0 = arithmetic.NewVector(lambda, modulus)
-	hSize := hatH0.EncodedSize()
-	if len(ciphertext) < pos+hSize {
+	if hatH0, err = arithmetic.NewVectorChecked(lambda, hatHModulus); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	if len(ciphertext) < layout.HatH0.End() {
 		return nil, nil, nil, nil, nil, fmt.Errorf("%w: ciphertext too short for hatH0", ErrInvalidCiphertext)
 	}
-	if err := hatH0.UnmarshalBinary(ciphertext[pos : pos+hSize]); err != nil {
+	if err := hatH0.UnmarshalBinaryRaw(ciphertext[layout.HatH0.Offset:layout.HatH0.End()]); err != nil {
 		return nil, nil, nil, nil, nil, fmt.Errorf("%w: failed to parse hatH0: %v", ErrInvalidCiphertext, err)
 	}
-	pos += hSize
 
 	// Parse hatH1
-	hatH1 = arithmetic.NewVector(lambda, modulus)
-	if len(ciphertext) < pos+hSize {
+	if hatH1, err = arithmetic.NewVectorChecked(lambda, hatHModulus); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidCiphertext, err)
+	}
+	if len(ciphertext) < layout.HatH1.End() {
 		return nil, nil, nil, nil, nil, fmt.Errorf("%w: ciphertext too short for hatH1", ErrInvalidCiphertext)
 	}
-	if err := hatH1.UnmarshalBinary(ciphertext[pos : pos+hSize]); err != nil {
+	if err := hatH1.UnmarshalBinaryRaw(ciphertext[layout.HatH1.Offset:layout.HatH1.End()]); err != nil {
 		return nil, nil, nil, nil, nil, fmt.Errorf("%w: failed to parse hatH1: %v", ErrInvalidCiphertext, err)
 	}
-	pos += hSize
-	if len(ciphertext) != pos {
+
+	if len(ciphertext) != layout.Size() {
 		return nil, nil, nil, nil, nil, fmt.Errorf("%w: ciphertext has trailing data", ErrInvalidCiphertext)
 	}
 
+	if compressionBits > 0 {
+		hatH0 = decompressVector(hatH0, compressionBits, modulus)
+		hatH1 = decompressVector(hatH1, compressionBits, modulus)
+	}
+
 	return c0, c1, x, hatH0, hatH1, nil
 }
 
+// compressionModulus returns 2^bits, the modulus of compressed coefficients
+func compressionModulus(bits int) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(bits))
+}
+
+// compressVector keeps the top bits of each coefficient: x ↦ ⌊2^bits·x/q⌉ mod 2^bits
+func compressVector(v *arithmetic.Vector, bits int) *arithmetic.Vector {
+	q := v.Modulus
+	halfQ := new(big.Int).Rsh(q, 1)
+	result := arithmetic.NewVector(v.Length(), compressionModulus(bits))
+	for i, val := range v.Values {
+		y := new(big.Int).Lsh(val, uint(bits))
+		y.Add(y, halfQ)
+		y.Quo(y, q)
+		result.Set(i, y)
+	}
+	return result
+}
+
+// decompressVector maps compressed coefficients back to Z_q: y ↦ ⌊q·y/2^bits⌉
+func decompressVector(c *arithmetic.Vector, bits int, modulus *big.Int) *arithmetic.Vector {
+	half := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	result := arithmetic.NewVector(c.Length(), modulus)
+	for i, val := range c.Values {
+		x := new(big.Int).Mul(val, modulus)
+		x.Add(x, half)
+		x.Rsh(x, uint(bits))
+		result.Set(i, x)
+	}
+	return result
+}
+
+// applyCompression returns v rounded to the precision a ciphertext carries: decompress(compress(v)),
+// or v itself when bits is 0
+func applyCompression(v *arithmetic.Vector, bits int, modulus *big.Int) *arithmetic.Vector {
+	if bits == 0 {
+		return v
+	}
+	return decompressVector(compressVector(v, bits), bits, modulus)
+}
+
 // kdf applies a key derivation function to derive the final key
 func kdf(input []byte, outputSize int) []byte {
+	return labeledKDF("OW-ChCCA-KEM-KDF", input, outputSize)
+}
+
+// confirmedKDF derives the final key in key confirmation mode, under a label of its own
+func confirmedKDF(input []byte, outputSize int) []byte {
+	return labeledKDF("OW-ChCCA-KEM-KDF-KC", input, outputSize)
+}
+
+// labeledKDF derives outputSize bytes from input, separated from other uses by label
+func labeledKDF(label string, input []byte, outputSize int) []byte {
 	// Use SHA3-512 for key derivation
 	hash := sha3.New512()
+	hash.Write([]byte(label))
+	hash.Write([]byte{FormatVersion})
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(input)))
+	hash.Write(length[:])
 	hash.Write(input)
-	hash.Write([]byte("OW-ChCCA-KEM-KDF"))
 
 	// For longer keys, we can iterate the hash function
 	output := make([]byte, outputSize)
 	hash.Read(output)
 	return output
 }
+
+// ConfirmationTagSize is the length of the key confirmation tag appended by WithKeyConfirmation
+const ConfirmationTagSize = 16
+
+// confirmationKeySize is the length of the key the confirmation tag is computed under
+const confirmationKeySize = 32
+
+// confirmationTag computes the tag SHAKE256("OW-ChCCA-KEM-KC" || FormatVersion || K_conf || ct),
+// truncated to ConfirmationTagSize bytes, where K_conf is derived from r under its own label
+func confirmationTag(r, ciphertext []byte) []byte {
+	confKey := labeledKDF("OW-ChCCA-KEM-KC-Key", r, confirmationKeySize)
+
+	hash := sha3.NewShake256()
+	hash.Write([]byte("OW-ChCCA-KEM-KC"))
+	hash.Write([]byte{FormatVersion})
+	hash.Write(confKey)
+	hash.Write(ciphertext)
+
+	tag := make([]byte, ConfirmationTagSize)
+	hash.Read(tag)
+	return tag
+}
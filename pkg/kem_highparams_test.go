@@ -0,0 +1,18 @@
+//go:build highparams
+
+package pkg
+
+import "testing"
+
+func TestHealthCheckAllParameterSets(t *testing.T) {
+	for _, name := range ListParameterSets() {
+		params, err := GetParameterSet(name)
+		if err != nil {
+			t.Fatalf("GetParameterSet failed: %v", err)
+		}
+		kem := OwChCCAKEM{Params: params}
+		if err := kem.HealthCheck(); err != nil {
+			t.Fatalf("%s: HealthCheck failed: %v", name, err)
+		}
+	}
+}
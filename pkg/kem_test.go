@@ -2,8 +2,25 @@ package pkg
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"runtime/pprof"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/arithmetic"
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/bitsio"
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/sha3"
+	"github.com/tuneinsight/lattigo/v6/ring"
+	"github.com/tuneinsight/lattigo/v6/utils/sampling"
 )
 
 func BenchmarkOwChCCAKEM_GenerateKeyPair(b *testing.B) {
@@ -11,7 +28,7 @@ func BenchmarkOwChCCAKEM_GenerateKeyPair(b *testing.B) {
 	b.ResetTimer()
 	for _, paramName := range testParams {
 		params, err := GetParameterSet(paramName)
-		kem := OwChCCAKEM{Params: params}
+		kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
 		if err != nil {
 			b.Fatalf("GetParameterSet failed: %v", err)
 		}
@@ -25,7 +42,7 @@ func BenchmarkOwChCCAKEM_GenerateKeyPair(b *testing.B) {
 
 func TestOwChCCAKEM_Decapsulate(t *testing.T) {
 	testParam := GetDefaultParameterSet()
-	kem := OwChCCAKEM{Params: testParam}
+	kem := OwChCCAKEM{Params: testParam}.WithAllowToyParameters(true)
 	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
 	if err != nil {
 		t.Fatalf("GenerateKeyPair failed: %v", err)
@@ -42,3 +59,1294 @@ func TestOwChCCAKEM_Decapsulate(t *testing.T) {
 		t.Fatalf("Decapsulated secret does not match")
 	}
 }
+
+func TestDecapsulateWrongBranch(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	ct, ss, err := kem.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+
+	// With all-zero h vectors hatK0 and hatK1 depended only on public data, so a key with the
+	// wrong b still decapsulated
+	flipped := *sk
+	flipped.b = !sk.b
+	if got, err := kem.Decapsulate(&flipped, ct); err == nil && bytes.Equal(got, ss) {
+		t.Fatalf("Decapsulate succeeded with b flipped")
+	}
+
+	h := bitsio.BitsToVector(bitsio.UnpackBits([]byte{0xff, 0x00}, 16), 1, bitModulus)
+	if h.Modulus.Cmp(bitModulus) != 0 || h.Values[0].Int64() != 1 || h.Values[8].Int64() != 0 {
+		t.Fatalf("h did not decode to a binary vector: %v mod %v", h.Values, h.Modulus)
+	}
+}
+
+func TestErrorNormBound(t *testing.T) {
+	// 3²·(4 + 2·√(4·64) + 2·64) = 9·164
+	if got := errorNormBound(3, 4); got.Int64() != 1476 {
+		t.Fatalf("errorNormBound(3, 4): got %v, want 1476", got)
+	}
+}
+
+func TestErrorNormBoundHonestSamples(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	lp, gp := kem.Params.LatticeParams, kem.Params.GaussianParams
+	bound := errorNormBound(gp.AlphaPrime, lp.M)
+	for i := 0; i < 200; i++ {
+		e, err := arithmetic.SampleDVector(lp.M, gp.AlphaPrime, []byte(fmt.Sprintf("honest e %d", i)), lp.Q)
+		if err != nil {
+			t.Fatalf("SampleDVector failed: %v", err)
+		}
+		if e.L2NormSquared().Cmp(bound) > 0 {
+			t.Fatalf("honest sample %d exceeds the bound: %v > %v", i, e.L2NormSquared(), bound)
+		}
+	}
+
+	// Encapsulate must not warn about honestly sampled error vectors
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	pk, _, err := kem.GenerateKeyPair(seededReader("honest e"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	stream := seededReader("honest e encapsulations")
+	for i := 0; i < 40; i++ {
+		if _, _, err := kem.EncapsulateFrom(pk, stream); err != nil {
+			t.Fatalf("EncapsulateFrom failed: %v", err)
+		}
+	}
+	if logs.Len() != 0 {
+		t.Fatalf("Encapsulate warned on honest samples: %s", logs.String())
+	}
+}
+
+// BenchmarkKeySerialization compares the preallocated Bytes encoding with concatenating per-matrix MarshalBinary output
+func BenchmarkKeySerialization(b *testing.B) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		b.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	b.Run("PublicKey/MarshalConcat", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var buf bytes.Buffer
+			a := pk.a.ToMatrix()
+			for _, mat := range []*arithmetic.Matrix{&a, &pk.u0, &pk.u1} {
+				data, err := mat.MarshalBinary()
+				if err != nil {
+					b.Fatalf("MarshalBinary failed: %v", err)
+				}
+				buf.Write(data)
+			}
+		}
+	})
+	b.Run("PublicKey/Bytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := pk.Bytes(); err != nil {
+				b.Fatalf("Bytes failed: %v", err)
+			}
+		}
+	})
+	b.Run("PrivateKey/Bytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := sk.Bytes(); err != nil {
+				b.Fatalf("Bytes failed: %v", err)
+			}
+		}
+	})
+}
+
+func TestSerializationSizeMismatch(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, _, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	drifted := *pk
+	drifted.Params.KeyParams.PublicKeySize++
+	if _, err := drifted.Bytes(); !errors.Is(err, ErrSerializationError) {
+		t.Fatalf("Bytes with drifted size: got %v, want ErrSerializationError", err)
+	}
+}
+
+func TestCompactMatrixEncodingParameterSets(t *testing.T) {
+	for _, name := range ListParameterSets() {
+		params, err := GetParameterSet(name)
+		if err != nil {
+			t.Fatalf("GetParameterSet failed: %v", err)
+		}
+		lp := params.LatticeParams
+		m, err := arithmetic.GenerateRandomMatrix(lp.Lambda, lp.N, lp.Q, rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateRandomMatrix failed: %v", err)
+		}
+		data, err := m.MarshalBinaryCompact()
+		if err != nil {
+			t.Fatalf("%s: MarshalBinaryCompact failed: %v", name, err)
+		}
+		if len(data) >= m.EncodedSize() {
+			t.Fatalf("%s: compact encoding is %d bytes, aligned is %d", name, len(data), m.EncodedSize())
+		}
+		decoded := arithmetic.Matrix{Modulus: lp.Q}
+		if err := decoded.UnmarshalBinaryCompact(data); err != nil {
+			t.Fatalf("%s: UnmarshalBinaryCompact failed: %v", name, err)
+		}
+		if !decoded.Equal(m) {
+			t.Fatalf("%s: matrix did not round-trip", name)
+		}
+
+		v, err := arithmetic.GenerateRandomVector(lp.M, lp.Q, rand.Reader)
+		if err != nil {
+			t.Fatalf("GenerateRandomVector failed: %v", err)
+		}
+		vData, err := v.MarshalBinaryCompact()
+		if err != nil {
+			t.Fatalf("%s: MarshalBinaryCompact failed: %v", name, err)
+		}
+		decodedV := &arithmetic.Vector{Modulus: lp.Q}
+		if err := decodedV.UnmarshalBinaryCompact(vData); err != nil || !decodedV.Equal(v) {
+			t.Fatalf("%s: vector did not round-trip: %v", name, err)
+		}
+	}
+}
+
+// flakyReader serves bytes from r in chunks of at most chunk bytes and fails the reads whose
+// index is in failAt
+type flakyReader struct {
+	r      io.Reader
+	chunk  int
+	failAt map[int]error
+	reads  int
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	f.reads++
+	if err, ok := f.failAt[f.reads]; ok {
+		return 0, err
+	}
+	if len(p) > f.chunk {
+		p = p[:f.chunk]
+	}
+	return f.r.Read(p)
+}
+
+// errReader fails every read with err
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+
+func seededReader(seed string) io.Reader {
+	h := sha3.NewShake256()
+	h.Write([]byte(seed))
+	return &h
+}
+
+func TestGenerateKeyPairFlakyReader(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	transient := errors.New("transient failure")
+
+	pkRef, skRef, err := kem.GenerateKeyPair(seededReader("flaky"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	flaky := &flakyReader{
+		r:      seededReader("flaky"),
+		chunk:  7,
+		failAt: map[int]error{2: transient, 10: transient, 11: transient},
+	}
+	pk, sk, err := kem.GenerateKeyPair(flaky)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair with short reads and transient errors failed: %v", err)
+	}
+	if !pk.Equal(pkRef) || !sk.Equal(skRef) {
+		t.Fatalf("short reads changed the generated key pair")
+	}
+
+	failing := []struct {
+		name string
+		r    io.Reader
+	}{
+		{"persistent error", errReader{transient}},
+		{"no progress", errReader{nil}},
+		{"eof", bytes.NewReader(make([]byte, 100))},
+		{"late persistent error", &flakyReader{r: seededReader("late"), chunk: 64, failAt: map[int]error{3: transient, 4: transient, 5: transient, 6: transient}}},
+	}
+	for _, tc := range failing {
+		pk, sk, err := kem.GenerateKeyPair(tc.r)
+		if !errors.Is(err, ErrInvalidRandomSource) {
+			t.Fatalf("%s: got %v, want ErrInvalidRandomSource", tc.name, err)
+		}
+		if pk != nil || sk != nil {
+			t.Fatalf("%s: key material returned alongside an error", tc.name)
+		}
+	}
+}
+
+// roundBitReference is the original branching implementation of the rounding step
+func roundBitReference(x, modulus *big.Int) uint64 {
+	halfQ := new(big.Int).Rsh(modulus, 1)
+
+	distToZero := new(big.Int).Set(x)
+	if distToZero.Cmp(halfQ) > 0 {
+		distToZero.Sub(modulus, distToZero)
+	}
+
+	distToHalfQ := new(big.Int).Sub(x, halfQ)
+	if distToHalfQ.Sign() < 0 {
+		distToHalfQ.Neg(distToHalfQ)
+	}
+
+	if distToZero.Cmp(distToHalfQ) <= 0 {
+		return 0
+	}
+	return 1
+}
+
+func TestRoundBitMatchesReference(t *testing.T) {
+	for q := uint64(1); q <= 300; q++ {
+		modulus := new(big.Int).SetUint64(q)
+		for x := uint64(0); x < q; x++ {
+			if got, want := roundBit(x, q), roundBitReference(new(big.Int).SetUint64(x), modulus); got != want {
+				t.Fatalf("roundBit(%d, %d) = %d, want %d", x, q, got, want)
+			}
+		}
+	}
+
+	for _, name := range ListParameterSets() {
+		params, _ := GetParameterSet(name)
+		modulus := params.LatticeParams.Q
+		q := modulus.Uint64()
+		halfQ := q >> 1
+		edges := []uint64{0, 1, halfQ/2 - 1, halfQ / 2, halfQ/2 + 1, halfQ - 1, halfQ, halfQ + 1, q - halfQ/2 - 1, q - halfQ/2, q - 1}
+		for i := 0; i < 1000; i++ {
+			x, _ := rand.Int(rand.Reader, modulus)
+			edges = append(edges, x.Uint64())
+		}
+		for _, x := range edges {
+			if got, want := roundBit(x, q), roundBitReference(new(big.Int).SetUint64(x), modulus); got != want {
+				t.Fatalf("%s: roundBit(%d) = %d, want %d", name, x, got, want)
+			}
+		}
+	}
+
+	if _, err := roundVector(arithmetic.NewVector(1, big.NewInt(1)), new(big.Int).Lsh(big.NewInt(1), 64)); !errors.Is(err, ErrParameterValidation) {
+		t.Fatalf("roundVector with a 65-bit modulus: got %v", err)
+	}
+}
+
+func TestRoundVectorWords(t *testing.T) {
+	for _, x := range []uint64{0, 1, 1<<32 - 1, 1 << 32, 1<<63 - 25, 1<<64 - 1} {
+		if got := wordOf(new(big.Int).SetUint64(x)); got != x {
+			t.Fatalf("wordOf(%d) = %d", x, got)
+		}
+	}
+
+	// roundVector reads reduced components through wordOf and agrees with the reference
+	modulus := GetDefaultParameterSet().LatticeParams.Q
+	v, err := arithmetic.GenerateRandomVector(256, modulus, rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateRandomVector failed: %v", err)
+	}
+	v.Values[0].SetInt64(0)
+	v.Values[1].Sub(modulus, big.NewInt(1))
+	v.Values[2].Rsh(modulus, 1)
+	rounded, err := roundVector(v, modulus)
+	if err != nil {
+		t.Fatalf("roundVector failed: %v", err)
+	}
+	for i, x := range v.Values {
+		if got, want := rounded.Values[i].Uint64(), roundBitReference(x, modulus); got != want {
+			t.Fatalf("roundVector element %d (%v) = %d, want %d", i, x, got, want)
+		}
+	}
+}
+
+func TestHash3AndKDFVectors(t *testing.T) {
+	q := big.NewInt(3329)
+	x := &arithmetic.Vector{Values: []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3328)}, Modulus: q}
+	hatH := &arithmetic.Vector{Values: []*big.Int{big.NewInt(1664), big.NewInt(0)}, Modulus: q}
+	h := &arithmetic.Vector{Values: []*big.Int{big.NewInt(1), big.NewInt(0)}, Modulus: big.NewInt(2)}
+	digest := func(branch int, x, hatH, h *arithmetic.Vector, size int) []byte {
+		t.Helper()
+		out, err := hash3(branch, x, hatH, h, size)
+		if err != nil {
+			t.Fatalf("hash3 failed: %v", err)
+		}
+		return out
+	}
+
+	// SHAKE256("OW-ChCCA-KEM-H3" || 0x05 || "H0" || 00 00000003 0002 0001 0002 0d00 || 01 00000002 0002 0680 0000 || 02 00000002 0001 01 00), 32 bytes
+	if got := hex.EncodeToString(digest(0, x, hatH, h, 32)); got != "3d403c5c7a03497f05883cb3a65c65bd23e44e50d8083a11e5a5f8f83233b720" {
+		t.Fatalf("hash3 = %s", got)
+	}
+	// The same with branch label "H1"
+	if got := hex.EncodeToString(digest(1, x, hatH, h, 32)); got != "a6f7489903d48aeff2b7c5bf6ddd7278fe44c735fce33d3fb2ef80464d18a73c" {
+		t.Fatalf("hash3 = %s", got)
+	}
+	// SHA3-512("OW-ChCCA-KEM-KDF" || 0x05 || 00000010 || "0123456789abcdef") truncated to 32 bytes
+	if got := hex.EncodeToString(kdf([]byte("0123456789abcdef"), 32)); got != "d0f24c30f54dc7692128b53d63d9e01689505fe1ede8edb7c662fd5643a1ef23" {
+		t.Fatalf("kdf = %s", got)
+	}
+
+	// Moving an element across the boundary between inputs must change the digest
+	x2 := &arithmetic.Vector{Values: x.Values[:2], Modulus: q}
+	hatH2 := &arithmetic.Vector{Values: append([]*big.Int{x.Values[2]}, hatH.Values...), Modulus: q}
+	if bytes.Equal(digest(0, x, hatH, h, 32), digest(0, x2, hatH2, h, 32)) {
+		t.Fatalf("hash3 is ambiguous across input boundaries")
+	}
+
+	// Outputs longer than a SHA3-256 digest are supported, for λ > 256
+	long := digest(0, x, hatH, h, 64)
+	if len(long) != 64 || !bytes.Equal(long[:32], digest(0, x, hatH, h, 32)) {
+		t.Fatalf("hash3 with a 64-byte output should extend the 32-byte output")
+	}
+}
+
+func TestHash3RejectsOversizedElements(t *testing.T) {
+	q := big.NewInt(3329)
+	x := &arithmetic.Vector{Values: []*big.Int{big.NewInt(1), big.NewInt(1 << 16)}, Modulus: q}
+	hatH := &arithmetic.Vector{Values: []*big.Int{big.NewInt(1664)}, Modulus: q}
+	h := &arithmetic.Vector{Values: []*big.Int{big.NewInt(1)}, Modulus: big.NewInt(2)}
+	if out, err := hash3(0, x, hatH, h, 32); err == nil {
+		t.Fatalf("hash3 of an element wider than its element size returned %x", out)
+	}
+}
+
+func TestExpandSeedVector(t *testing.T) {
+	// n = 5 values of logEta+1 = 3 bits take 15 bits and λ = 12, so every length rounds up to
+	// 2 bytes: SHAKE256("OWChCCA-G" || 0x05 || "seed") = 98d1 | 13a1 | 135d | 248c
+	if s, rho, h0, h1 := gSizes(5, 12, 2); s != 2 || rho != 2 || h0 != 2 || h1 != 2 {
+		t.Fatalf("gSizes(5, 12, 2) = %d, %d, %d, %d", s, rho, h0, h1)
+	}
+	s, rho, h0, h1 := expandSeed([]byte("seed"), 5, 12, 2)
+
+	sModulus := big.NewInt(7)
+	if want := bitsio.UnpackChunked([]byte{0x98, 0xd1}, 5, 3, sModulus); !s.Equal(want) {
+		t.Fatalf("s = %v, expected %v", s.Values, want.Values)
+	}
+	if got := hex.EncodeToString(rho); got != "13a1" {
+		t.Fatalf("rho = %s, expected 13a1", got)
+	}
+	for name, c := range map[string]struct {
+		got  *arithmetic.Vector
+		want []byte
+	}{"h0": {h0, []byte{0x13, 0x5d}}, "h1": {h1, []byte{0x24, 0x8c}}} {
+		want := bitsio.BitsToVector(bitsio.UnpackBits(c.want, 12), 1, bitModulus)
+		if c.got.Length() != 12 || !c.got.Equal(want) {
+			t.Fatalf("%s = %v, expected %v", name, c.got.Values, want.Values)
+		}
+	}
+
+	// Encapsulate and Decapsulate both split the seed with expandSeed; check the component
+	// lengths they rely on for every registered set
+	for _, name := range ListParameterSets() {
+		params, err := GetParameterSet(name)
+		if err != nil {
+			t.Fatalf("GetParameterSet(%s) failed: %v", name, err)
+		}
+		lp, gp := params.LatticeParams, params.GaussianParams
+		sSize, rhoSize, _, _ := gSizes(lp.N, lp.Lambda, gp.LogEta)
+		s, rho, h0, h1 := expandSeed(make([]byte, lp.Lambda/8), lp.N, lp.Lambda, gp.LogEta)
+		if sSize*8 < lp.N*(gp.LogEta+1) || len(rho) != rhoSize || s.Length() != lp.N || h0.Length() != lp.Lambda || h1.Length() != lp.Lambda {
+			t.Fatalf("%s: unexpected component lengths", name)
+		}
+	}
+}
+
+func TestBranchSwapDetected(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(seededReader("branch swap"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	ct, _, err := kem.EncapsulateFrom(pk, seededReader("branch swap ct"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+
+	// Exchange the components of the two branches: (c0, hatH0) <-> (c1, hatH1)
+	lp, gp := kem.Params.LatticeParams, kem.Params.GaussianParams
+	c0, c1, x, hatH0, hatH1, err := parseCiphertext(ct, lp.M, lp.Lambda, lp.Q, gp.CompressionBits)
+	if err != nil {
+		t.Fatalf("parseCiphertext failed: %v", err)
+	}
+	swapped, err := constructCiphertext(len(ct), gp.CompressionBits, c1, c0, x, hatH1, hatH0)
+	if err != nil {
+		t.Fatalf("constructCiphertext failed: %v", err)
+	}
+	if _, err := kem.Decapsulate(sk, swapped); !errors.Is(err, ErrDecapsulationFailed) {
+		t.Fatalf("Decapsulate of a branch-swapped ciphertext: expected ErrDecapsulationFailed, got %v", err)
+	}
+
+	// Identical inputs hash differently in the two branches
+	k0, err0 := hash3(0, x, hatH0, x, lp.Lambda/8)
+	k1, err1 := hash3(1, x, hatH0, x, lp.Lambda/8)
+	if err0 != nil || err1 != nil {
+		t.Fatalf("hash3 failed: %v, %v", err0, err1)
+	}
+	if bytes.Equal(k0, k1) {
+		t.Fatalf("hash3 does not separate the branches")
+	}
+}
+
+// hash3Marshal hashes the same inputs by marshaling each vector first, as hash3 used to
+func hash3Marshal(x, hatH, h *arithmetic.Vector) []byte {
+	hash := sha3.New256()
+	for _, v := range []*arithmetic.Vector{x, hatH, h} {
+		data, _ := v.MarshalBinary()
+		hash.Write(data)
+	}
+	return hash.Sum(nil)
+}
+
+// BenchmarkHash3 compares the allocations of streaming hash3 with marshaling the inputs at the
+// Security64 sizes (m = 32768, lambda = 64)
+func BenchmarkHash3(b *testing.B) {
+	params, err := GetParameterSet("OWChCCA-64")
+	if err != nil {
+		b.Fatalf("GetParameterSet failed: %v", err)
+	}
+	lp := params.LatticeParams
+	x, _ := arithmetic.GenerateRandomVector(lp.M, lp.Q, rand.Reader)
+	hatH, _ := arithmetic.GenerateRandomVector(lp.Lambda, lp.Q, rand.Reader)
+	h := arithmetic.NewVector(lp.Lambda, bitModulus)
+
+	b.Run("Streaming", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := hash3(0, x, hatH, h, lp.Lambda/8); err != nil {
+				b.Fatalf("hash3 failed: %v", err)
+			}
+		}
+	})
+	b.Run("Marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			hash3Marshal(x, hatH, h)
+		}
+	})
+}
+
+func TestCiphertextCompression(t *testing.T) {
+	params := GetDefaultParameterSet()
+	modulus := params.LatticeParams.Q
+	q := modulus.Uint64()
+	halfQ := q >> 1
+
+	for _, bits := range []int{4, 8, 12, 20} {
+		v, _ := arithmetic.GenerateRandomVector(1000, modulus, rand.Reader)
+		c := compressVector(v, bits)
+		if !compressVector(decompressVector(c, bits, modulus), bits).Equal(c) {
+			t.Fatalf("%d bits: compress(decompress(y)) != y", bits)
+		}
+		// |x - decompress(compress(x))| ≤ q/2^(bits+1) + 1
+		bound := new(big.Int).Rsh(modulus, uint(bits+1))
+		bound.Add(bound, big.NewInt(1))
+		diff, _ := v.Subtract(applyCompression(v, bits, modulus))
+		for _, d := range diff.Values {
+			abs := new(big.Int).Set(d)
+			if abs.Cmp(new(big.Int).Rsh(modulus, 1)) > 0 {
+				abs.Sub(modulus, abs)
+			}
+			if abs.Cmp(bound) > 0 {
+				t.Fatalf("%d bits: compression error %v exceeds %v", bits, abs, bound)
+			}
+		}
+	}
+
+	// Randomized rounding cycles: hatH = u + h·⌊q/2⌋ + e with |e| ≤ q/8 must still round to h
+	// after compression, as in decapsulation where u is recovered as Zb^T·x
+	const trials, bits = 5000, 8
+	failures := 0
+	for i := 0; i < trials; i++ {
+		u, _ := rand.Int(rand.Reader, modulus)
+		e, _ := rand.Int(rand.Reader, new(big.Int).SetUint64(q/4+1))
+		e.Sub(e, new(big.Int).SetUint64(q/8))
+		var hBit [1]byte
+		rand.Read(hBit[:])
+		h := uint64(hBit[0] & 1)
+
+		hatH := new(big.Int).Add(u, e)
+		hatH.Add(hatH, new(big.Int).SetUint64(h*halfQ))
+		hatH.Mod(hatH, modulus)
+		vec := &arithmetic.Vector{Values: []*big.Int{hatH}, Modulus: modulus}
+		received := applyCompression(vec, bits, modulus).Values[0]
+		received.Sub(received, u).Mod(received, modulus)
+		if roundBit(received.Uint64(), q) != h {
+			failures++
+		}
+	}
+	if failures != 0 {
+		t.Fatalf("%d of %d rounding cycles failed with %d-bit compression", failures, trials, bits)
+	}
+
+	compressed := params
+	compressed.GaussianParams.CompressionBits = bits
+	if err := compressed.Validate(); err == nil {
+		t.Fatalf("Validate should reject a stale ciphertext size")
+	}
+	compressed.KeyParams.CiphertextSize = compressed.CiphertextSize()
+	if err := compressed.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if compressed.CiphertextSize() >= params.CiphertextSize() {
+		t.Fatalf("compression did not shrink the ciphertext: %d vs %d bytes", compressed.CiphertextSize(), params.CiphertextSize())
+	}
+	tooMany := compressed
+	tooMany.GaussianParams.CompressionBits = modulus.BitLen() - 1
+	if err := tooMany.Validate(); err == nil {
+		t.Fatalf("Validate should reject %d compression bits", tooMany.GaussianParams.CompressionBits)
+	}
+
+	if testing.Short() {
+		t.Skip("skipping full KEM cycles in short mode")
+	}
+	kem := OwChCCAKEM{Params: compressed}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		ct, ss, err := kem.Encapsulate(pk)
+		if err != nil {
+			t.Fatalf("Encapsulate failed: %v", err)
+		}
+		if len(ct) != compressed.CiphertextSize() {
+			t.Fatalf("ciphertext is %d bytes, expected %d", len(ct), compressed.CiphertextSize())
+		}
+		if err := kem.ValidateCiphertext(ct); err != nil {
+			t.Fatalf("ValidateCiphertext failed: %v", err)
+		}
+		ss2, err := kem.Decapsulate(sk, ct)
+		if err != nil {
+			t.Fatalf("Decapsulate failed: %v", err)
+		}
+		if !bytes.Equal(ss, ss2) {
+			t.Fatalf("shared keys do not match under compression")
+		}
+	}
+}
+
+// TestHealthCheck covers the default parameter set; TestHealthCheckAllParameterSets (highparams) covers the rest
+func TestHealthCheck(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	if err := kem.HealthCheck(); err != nil {
+		t.Fatalf("HealthCheck failed: %v", err)
+	}
+
+	// Flipping b makes the key decapsulate with the wrong half of the public key
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	if err := kem.healthCheck(pk, sk); err != nil {
+		t.Fatalf("healthCheck failed: %v", err)
+	}
+	sk.b = !sk.b
+	if err := kem.healthCheck(pk, sk); err == nil {
+		t.Fatalf("healthCheck should fail with a corrupted private key")
+	}
+}
+
+func TestPublicKeyRingFormMatchesBigInt(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	modulus := kem.Params.LatticeParams.Q
+	a := pk.a.ToMatrix()
+
+	// U_b = A·Zb^T computed in ring form must match the big.Int product
+	ub := pk.u0
+	if sk.b {
+		ub = pk.u1
+	}
+	want, err := a.Multiply(sk.zb)
+	if err != nil {
+		t.Fatalf("Multiply failed: %v", err)
+	}
+	if !ub.Equal(want) {
+		t.Fatalf("A·Zb^T differs from the big.Int product")
+	}
+
+	// A^T·s, as used by encapsulation and decapsulation
+	s, _ := arithmetic.GenerateRandomVector(kem.Params.LatticeParams.N, modulus, rand.Reader)
+	got, err := pk.a.MulVecTransposed(s)
+	if err != nil {
+		t.Fatalf("MulVecTransposed failed: %v", err)
+	}
+	at, _ := a.Transpose()
+	wantVec, _ := at.MultiplyVector(s)
+	if !got.Equal(wantVec) {
+		t.Fatalf("A^T·s differs from the big.Int product")
+	}
+
+	// Both decoders rebuild the ring form
+	pkBytes, _ := pk.Bytes()
+	for name, unmarshal := range map[string]func(*PublicKey, []byte) error{
+		"UnmarshalBinary":       (*PublicKey).UnmarshalBinary,
+		"UnmarshalBinaryStrict": (*PublicKey).UnmarshalBinaryStrict,
+	} {
+		parsed := &PublicKey{Params: kem.Params}
+		if err := unmarshal(parsed, pkBytes); err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		if !parsed.Equal(pk) || !parsed.a.Equal(pk.a) {
+			t.Fatalf("%s did not restore A", name)
+		}
+	}
+}
+
+// BenchmarkEncapsulatePhases times each labelled phase of Encapsulate on its own
+func BenchmarkEncapsulatePhases(b *testing.B) {
+	params := GetDefaultParameterSet()
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	pk, _, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		b.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	lp, gp := params.LatticeParams, params.GaussianParams
+
+	r := make([]byte, lp.Lambda/8)
+	rand.Read(r)
+	s, rho, h0, h1 := expandSeed(r, lp.N, lp.Lambda, gp.LogEta)
+	s.Modulus = lp.Q
+	x, _ := pk.a.MulVecTransposed(s)
+	u0ts, _ := pk.u0.MultiplyVectorColMajor(s)
+	hatH0, _ := computeHatH(u0ts, h0, lp.Q)
+	u1ts, _ := pk.u1.MultiplyVectorColMajor(s)
+	hatH1, _ := computeHatH(u1ts, h1, lp.Q)
+
+	b.Run(phaseSeedExpansion, func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			expandSeed(r, lp.N, lp.Lambda, gp.LogEta)
+			arithmetic.GenerateSampleDVector(lp.M, gp.AlphaPrime, rho, lp.Q)
+		}
+	})
+	b.Run(phaseMatVec, func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pk.a.MulVecTransposed(s)
+			pk.u0.MultiplyVectorColMajor(s)
+			pk.u1.MultiplyVectorColMajor(s)
+		}
+	})
+	b.Run(phaseHash, func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := hash3(0, x, hatH0, h0, lp.Lambda/8); err != nil {
+				b.Fatalf("hash3 failed: %v", err)
+			}
+			if _, err := hash3(1, x, hatH1, h1, lp.Lambda/8); err != nil {
+				b.Fatalf("hash3 failed: %v", err)
+			}
+		}
+	})
+	b.Run(phaseSerialization, func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			constructCiphertext(params.KeyParams.CiphertextSize, gp.CompressionBits, r, r, x, hatH0, hatH1)
+		}
+	})
+}
+
+// BenchmarkProfiling measures the cost of the phase labels. With profiling disabled setPhase
+// should be indistinguishable from the empty baseline.
+func BenchmarkProfiling(b *testing.B) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		b.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	ct, _, err := kem.Encapsulate(pk)
+	if err != nil {
+		b.Fatalf("Encapsulate failed: %v", err)
+	}
+	defer EnableProfiling(false)
+
+	b.Run("Phase/Baseline", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+		}
+	})
+	for _, enabled := range []bool{false, true} {
+		name := "Disabled"
+		if enabled {
+			name = "Enabled"
+		}
+		EnableProfiling(enabled)
+		b.Run("Phase/"+name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				scope := enterPhases()
+				setPhase(context.Background(), phaseHash)
+				scope.restore()
+			}
+		})
+		b.Run("Encapsulate/"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				kem.Encapsulate(pk)
+			}
+		})
+		b.Run("Decapsulate/"+name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				kem.Decapsulate(sk, ct)
+			}
+		})
+	}
+}
+
+// goroutineLabels returns the goroutine profile, which lists the labels of every goroutine
+func goroutineLabels(t *testing.T) string {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		t.Fatalf("writing goroutine profile failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestProfilingPhases(t *testing.T) {
+	label := `"` + phaseLabel + `":"` + phaseMatVec + `"`
+
+	setPhase(context.Background(), phaseMatVec)
+	if strings.Contains(goroutineLabels(t), label) {
+		t.Fatalf("phase labelled while profiling is disabled")
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		scope := enterPhases()
+		setPhase(context.Background(), phaseMatVec)
+		scope.restore()
+	}); allocs != 0 {
+		t.Fatalf("disabled phase labels allocate %v times", allocs)
+	}
+
+	EnableProfiling(true)
+	defer EnableProfiling(false)
+	scope := enterPhases()
+	setPhase(context.Background(), phaseMatVec)
+	if !strings.Contains(goroutineLabels(t), label) {
+		t.Fatalf("phase not labelled while profiling is enabled")
+	}
+	scope.restore()
+	if strings.Contains(goroutineLabels(t), label) {
+		t.Fatalf("phase label not cleared")
+	}
+
+	// A labelled round trip still agrees and leaves no label behind
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	ct, ss, err := kem.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+	ss2, err := kem.Decapsulate(sk, ct)
+	if err != nil || !bytes.Equal(ss, ss2) {
+		t.Fatalf("labelled round trip failed: %v", err)
+	}
+	if strings.Contains(goroutineLabels(t), phaseLabel) {
+		t.Fatalf("phase label left on the goroutine")
+	}
+
+	// Labels the caller set are kept alongside the phase and restored afterwards
+	callerLabel := `"caller":"profiling-test"`
+	pprof.Do(context.Background(), pprof.Labels("caller", "profiling-test"), func(ctx context.Context) {
+		scope := enterPhases()
+		setPhase(ctx, phaseMatVec)
+		if labels := goroutineLabels(t); !strings.Contains(labels, callerLabel) || !strings.Contains(labels, label) {
+			t.Fatalf("phase did not extend the caller's labels")
+		}
+		scope.restore()
+		if labels := goroutineLabels(t); !strings.Contains(labels, callerLabel) || strings.Contains(labels, phaseLabel) {
+			t.Fatalf("restore did not give back the caller's labels")
+		}
+
+		// The entry points without a context label their phases on their own and still give
+		// the caller's labels back
+		ct, ss, err := kem.Encapsulate(pk)
+		if err != nil {
+			t.Fatalf("Encapsulate failed: %v", err)
+		}
+		if labels := goroutineLabels(t); !strings.Contains(labels, callerLabel) || strings.Contains(labels, phaseLabel) {
+			t.Fatalf("Encapsulate did not restore the caller's labels: %s", labels)
+		}
+		if ss2, err := kem.Decapsulate(sk, ct); err != nil || !bytes.Equal(ss, ss2) {
+			t.Fatalf("Decapsulate failed: %v", err)
+		}
+		if labels := goroutineLabels(t); !strings.Contains(labels, callerLabel) || strings.Contains(labels, phaseLabel) {
+			t.Fatalf("Decapsulate did not restore the caller's labels: %s", labels)
+		}
+
+		enc, err := pk.NewEncapsulator()
+		if err != nil {
+			t.Fatalf("NewEncapsulator failed: %v", err)
+		}
+		dec, err := sk.NewDecapsulator()
+		if err != nil {
+			t.Fatalf("NewDecapsulator failed: %v", err)
+		}
+		ct, ss, err = enc.EncapsulateContext(ctx, nil)
+		if err != nil {
+			t.Fatalf("EncapsulateContext failed: %v", err)
+		}
+		if ss2, err := dec.DecapsulateContext(ctx, ct); err != nil || !bytes.Equal(ss, ss2) {
+			t.Fatalf("DecapsulateContext failed: %v", err)
+		}
+		if labels := goroutineLabels(t); !strings.Contains(labels, callerLabel) || strings.Contains(labels, phaseLabel) {
+			t.Fatalf("labelled calls did not restore the caller's labels")
+		}
+	})
+}
+
+func TestNewPublicKeyPrivateKey(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	lp := kem.Params.LatticeParams
+	stream := seededReader("structural key")
+
+	// A key pair with a binary trapdoor: U0 = A·Z0 and U1 uniform
+	a, err := arithmetic.GenerateRandomMatrix(lp.N, lp.M, lp.Q, stream)
+	if err != nil {
+		t.Fatalf("GenerateRandomMatrix failed: %v", err)
+	}
+	z0, _ := arithmetic.GenerateRandomMatrix(lp.M, lp.Lambda, big.NewInt(2), stream)
+	z0.Modulus = lp.Q
+	pa, err := arithmetic.PolyMatrixFromMatrix(a)
+	if err != nil {
+		t.Fatalf("PolyMatrixFromMatrix failed: %v", err)
+	}
+	u0, err := pa.MulMat(z0)
+	if err != nil {
+		t.Fatalf("MulMat failed: %v", err)
+	}
+	u1, _ := arithmetic.GenerateRandomMatrix(lp.N, lp.Lambda, lp.Q, stream)
+
+	pk, err := kem.NewPublicKey(a, u0, u1)
+	if err != nil {
+		t.Fatalf("NewPublicKey failed: %v", err)
+	}
+	sk, err := kem.NewPrivateKey(pk, z0, false)
+	if err != nil {
+		t.Fatalf("NewPrivateKey failed: %v", err)
+	}
+	if err := CheckKeyPair(pk, sk); err != nil {
+		t.Fatalf("CheckKeyPair failed: %v", err)
+	}
+
+	ct, ss, err := kem.EncapsulateFrom(pk, seededReader("structural key ct"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+	got, err := kem.Decapsulate(sk, ct)
+	if err != nil || !bytes.Equal(got, ss) {
+		t.Fatalf("Decapsulate does not recover the shared key: %v", err)
+	}
+
+	// The matrices are copied
+	u1.Values[0][0].Add(u1.Values[0][0], big.NewInt(1))
+	if pk.u1.Values[0][0].Cmp(u1.Values[0][0]) == 0 {
+		t.Fatalf("NewPublicKey should copy U1")
+	}
+
+	if _, err := kem.NewPublicKey(u0, u0, u1); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Fatalf("NewPublicKey with an n x λ matrix A: got %v", err)
+	}
+	if _, err := kem.NewPublicKey(a, u0, z0); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Fatalf("NewPublicKey with an m x λ matrix U1: got %v", err)
+	}
+	if _, err := kem.NewPrivateKey(pk, u0, true); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("NewPrivateKey with an n x λ matrix Zb: got %v", err)
+	}
+	if _, err := kem.NewPrivateKey(nil, z0, true); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("NewPrivateKey with a nil public key: got %v", err)
+	}
+
+	// The wrong trapdoor bit is caught by CheckKeyPair
+	wrong, err := kem.NewPrivateKey(pk, z0, true)
+	if err != nil {
+		t.Fatalf("NewPrivateKey failed: %v", err)
+	}
+	if err := CheckKeyPair(pk, wrong); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("CheckKeyPair with the wrong b: got %v", err)
+	}
+}
+
+func TestNonNTTParameters(t *testing.T) {
+	// m = 24 is not a power of two and 2^61 - 1 is not 1 mod 2m, so lattigo cannot build the ring
+	q, _ := new(big.Int).SetString("2305843009213693951", 10)
+	params := buildParameters(Security16, 24, q.BitLen(), q)
+	params.Name = "OWChCCA-16-non-ntt-test"
+	params.id = 0
+	if params.NTTAccelerated {
+		t.Fatalf("m = 24 should not be NTT accelerated")
+	}
+	if err := params.Validate(); err != nil {
+		t.Fatalf("Validate rejected a set that only lacks NTT support: %v", err)
+	}
+
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(seededReader("non-NTT key"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	if pk.a.Accelerated() {
+		t.Fatalf("A should use the fallback arithmetic")
+	}
+	if err := sk.Validate(); err != nil {
+		t.Fatalf("generated private key is invalid: %v", err)
+	}
+	for i := range 4 {
+		ct, ss, err := kem.EncapsulateFrom(pk, seededReader(fmt.Sprintf("non-NTT encapsulation %d", i)))
+		if err != nil {
+			t.Fatalf("Encapsulate failed: %v", err)
+		}
+		got, err := kem.Decapsulate(sk, ct)
+		if err != nil || !bytes.Equal(got, ss) {
+			t.Fatalf("Decapsulate failed: %v", err)
+		}
+	}
+
+	// Keys survive serialization, which rebuilds A without a ring
+	data, err := pk.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+	decoded := &PublicKey{Params: params}
+	if err := decoded.UnmarshalBinary(data); err != nil || !decoded.Equal(pk) {
+		t.Fatalf("public key round trip failed: %v", err)
+	}
+
+	// Key generation is deterministic on the fallback path too
+	pk2, _, _ := kem.GenerateKeyPair(seededReader("non-NTT key"))
+	if !pk2.Equal(pk) {
+		t.Fatalf("equal seeds produced different keys")
+	}
+
+	// Claiming NTT support for a shape lattigo cannot build is still an error
+	params.NTTAccelerated = true
+	if err := params.Validate(); !errors.Is(err, ErrParameterValidation) {
+		t.Fatalf("NTTAccelerated set with m = 24: got %v", err)
+	}
+}
+
+func TestParallelSamplersReportErrors(t *testing.T) {
+	q := big.NewInt(97)
+	r, err := ring.NewRing(16, []uint64{97})
+	if err != nil {
+		t.Fatalf("ring.NewRing failed: %v", err)
+	}
+	prng, _ := sampling.NewKeyedPRNG([]byte("parallel samplers"))
+	sampler := ring.NewUniformSampler(prng, r)
+
+	polyVecA, a, err := ParallelCalculatePolyVecAWithA(3, 16, q, sampler, r)
+	if err != nil || len(polyVecA) != 3 || a.Rows != 3 || a.Cols != 16 {
+		t.Fatalf("ParallelCalculatePolyVecAWithA failed: %v", err)
+	}
+	polyVecZbT, zb, err := ParallelCalculatePolyVecZbTWithZb(16, 2, q, sampler, r)
+	if err != nil || len(polyVecZbT) != 2 || zb.Rows != 16 || zb.Cols != 2 {
+		t.Fatalf("ParallelCalculatePolyVecZbTWithZb failed: %v", err)
+	}
+
+	// A ring of the wrong degree or modulus, or none at all, is an error rather than a panic
+	for name, c := range map[string]struct {
+		m       int
+		modulus *big.Int
+		r       *ring.Ring
+		want    error
+	}{
+		"degree":  {32, q, r, arithmetic.ErrInvalidDimensions},
+		"modulus": {16, big.NewInt(193), r, arithmetic.ErrInvalidRing},
+		"nil":     {16, q, nil, arithmetic.ErrInvalidRing},
+	} {
+		if _, _, err := ParallelCalculatePolyVecAWithA(3, c.m, c.modulus, sampler, c.r); !errors.Is(err, c.want) {
+			t.Fatalf("%s: ParallelCalculatePolyVecAWithA got %v", name, err)
+		}
+		if _, _, err := ParallelCalculatePolyVecZbTWithZb(c.m, 2, c.modulus, sampler, c.r); !errors.Is(err, c.want) {
+			t.Fatalf("%s: ParallelCalculatePolyVecZbTWithZb got %v", name, err)
+		}
+		if _, err := ParallelCalculateAZb(polyVecA, polyVecZbT, 3, c.m, 2, c.modulus, c.r); !errors.Is(err, c.want) {
+			t.Fatalf("%s: ParallelCalculateAZb got %v", name, err)
+		}
+	}
+	if _, err := ParallelCalculateAZb(polyVecA[:2], polyVecZbT, 3, 16, 2, q, r); !errors.Is(err, arithmetic.ErrInvalidDimensions) {
+		t.Fatalf("short polyVecA: ParallelCalculateAZb got %v", err)
+	}
+}
+
+func TestUntrustedLengths(t *testing.T) {
+	params := GetDefaultParameterSet()
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(seededReader("untrusted lengths"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	ct, _, err := kem.EncapsulateFrom(pk, seededReader("untrusted lengths ct"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+	pkBytes, _ := pk.Bytes()
+	skBytes, _ := sk.Bytes()
+
+	lengths := func(size int) []int {
+		return []int{0, 1, 7, 8, 9, size / 2, size - 1, size + 1}
+	}
+	for _, n := range lengths(len(ct)) {
+		if _, err := kem.Decapsulate(sk, append(ct[:min(n, len(ct)):min(n, len(ct))], make([]byte, max(0, n-len(ct)))...)); err == nil {
+			t.Fatalf("Decapsulate accepted a %d-byte ciphertext", n)
+		}
+	}
+	for _, n := range lengths(len(pkBytes)) {
+		data := append(pkBytes[:min(n, len(pkBytes)):min(n, len(pkBytes))], make([]byte, max(0, n-len(pkBytes)))...)
+		if err := (&PublicKey{Params: params}).UnmarshalBinaryStrict(data); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("PublicKey.UnmarshalBinaryStrict of %d bytes: got %v", n, err)
+		}
+		if err := (&PublicKey{Params: params}).UnmarshalBinary(data); n < len(pkBytes) && !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("PublicKey.UnmarshalBinary of %d bytes: got %v", n, err)
+		}
+	}
+	for _, n := range lengths(len(skBytes)) {
+		data := append(skBytes[:min(n, len(skBytes)):min(n, len(skBytes))], make([]byte, max(0, n-len(skBytes)))...)
+		if err := (&PrivateKey{Pk: &PublicKey{Params: params}}).UnmarshalBinaryStrict(data); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("PrivateKey.UnmarshalBinaryStrict of %d bytes: got %v", n, err)
+		}
+		if err := (&PrivateKey{Pk: &PublicKey{Params: params}}).UnmarshalBinary(data); n < len(skBytes) && !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("PrivateKey.UnmarshalBinary of %d bytes: got %v", n, err)
+		}
+	}
+
+	// A header announcing a huge matrix is rejected before anything is allocated for it
+	huge := append([]byte(nil), pkBytes...)
+	binary.BigEndian.PutUint32(huge[0:4], 1<<31)
+	if err := (&PublicKey{Params: params}).UnmarshalBinary(huge); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("PublicKey.UnmarshalBinary with a huge row count: got %v", err)
+	}
+}
+
+func TestKeyConfirmation(t *testing.T) {
+	plain := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	confirmed := plain.WithKeyConfirmation(true)
+	pk, sk, err := plain.GenerateKeyPair(seededReader("key confirmation"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	if got, want := confirmed.CiphertextSize(), plain.CiphertextSize()+ConfirmationTagSize; got != want {
+		t.Fatalf("CiphertextSize with key confirmation = %d, want %d", got, want)
+	}
+
+	ct, ss, err := confirmed.EncapsulateFrom(pk, seededReader("key confirmation ct"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+	if len(ct) != confirmed.CiphertextSize() {
+		t.Fatalf("ciphertext is %d bytes, expected %d", len(ct), confirmed.CiphertextSize())
+	}
+	if err := confirmed.ValidateCiphertext(ct); err != nil {
+		t.Fatalf("ValidateCiphertext rejected a tagged ciphertext: %v", err)
+	}
+	ss2, err := confirmed.Decapsulate(sk, ct)
+	if err != nil || !bytes.Equal(ss, ss2) {
+		t.Fatalf("Decapsulate with key confirmation failed: %v", err)
+	}
+
+	// The same seed without key confirmation gives the same ciphertext body but an unrelated key
+	plainCt, plainSS, err := plain.EncapsulateFrom(pk, seededReader("key confirmation ct"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+	if !bytes.Equal(plainCt, ct[:len(plainCt)]) || bytes.Equal(plainSS, ss) {
+		t.Fatalf("the two modes should share the ciphertext body and differ in the shared key")
+	}
+	if _, err := confirmed.Decapsulate(sk, plainCt); err == nil {
+		t.Fatalf("a KEM with key confirmation accepted an untagged ciphertext")
+	}
+	if _, err := plain.Decapsulate(sk, ct); err == nil {
+		t.Fatalf("a KEM without key confirmation accepted a tagged ciphertext")
+	}
+	if err := plain.ValidateCiphertext(ct); err == nil {
+		t.Fatalf("ValidateCiphertext without key confirmation accepted a tagged ciphertext")
+	}
+
+	// A tampered tag is reported as a key confirmation failure
+	badTag := bytes.Clone(ct)
+	badTag[len(badTag)-1] ^= 1
+	if _, err := confirmed.Decapsulate(sk, badTag); !errors.Is(err, ErrKeyConfirmationFailed) || !errors.Is(err, ErrDecapsulationFailed) {
+		t.Fatalf("Decapsulate with a tampered tag: got %v", err)
+	}
+
+	// The tag is checked before re-encryption, so a tampered body and a wrong key are both
+	// reported as key confirmation failures
+	badBody := bytes.Clone(ct)
+	badBody[0] ^= 1
+	if _, err := confirmed.Decapsulate(sk, badBody); !errors.Is(err, ErrKeyConfirmationFailed) {
+		t.Fatalf("Decapsulate with a tampered body: got %v", err)
+	}
+	_, otherSK, err := plain.GenerateKeyPair(seededReader("key confirmation other key"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	if _, err := confirmed.Decapsulate(otherSK, ct); !errors.Is(err, ErrKeyConfirmationFailed) {
+		t.Fatalf("Decapsulate with the wrong key: got %v", err)
+	}
+
+	// A body the sender malformed under a valid tag passes confirmation and fails re-encryption
+	r := make([]byte, plain.Params.LatticeParams.Lambda/8)
+	io.ReadFull(seededReader("key confirmation ct"), r)
+	body := bytes.Clone(plainCt)
+	layout := plain.Params.CiphertextLayout()
+	hatHnb := layout.HatH1 // the component r does not depend on
+	if sk.b {
+		hatHnb = layout.HatH0
+	}
+	body[hatHnb.End()-1] ^= 1
+	malformed := append(body, confirmationTag(r, body)...)
+	if _, err := confirmed.Decapsulate(sk, malformed); !errors.Is(err, ErrDecapsulationFailed) || errors.Is(err, ErrKeyConfirmationFailed) {
+		t.Fatalf("Decapsulate of a malformed ciphertext with a valid tag: got %v", err)
+	}
+}
+
+func TestKeyConfirmationOptions(t *testing.T) {
+	confirmed := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true).WithKeyConfirmation(true)
+	pk, sk, err := confirmed.GenerateKeyPair(seededReader("key confirmation options"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	ct, ss, err := confirmed.EncapsulateFrom(pk, seededReader("key confirmation options ct"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+
+	enc, err := pk.NewEncapsulator(WithKeyConfirmation(true))
+	if err != nil {
+		t.Fatalf("NewEncapsulator failed: %v", err)
+	}
+	if got, _, err := enc.EncapsulateFrom(seededReader("key confirmation options ct")); err != nil || !bytes.Equal(got, ct) {
+		t.Fatalf("Encapsulator with key confirmation differs from the KEM: %v", err)
+	}
+	dec, err := sk.NewDecapsulator(WithKeyConfirmation(true))
+	if err != nil {
+		t.Fatalf("NewDecapsulator failed: %v", err)
+	}
+	if got, err := dec.Decapsulate(ct); err != nil || !bytes.Equal(got, ss) {
+		t.Fatalf("Decapsulator with key confirmation: %v", err)
+	}
+	if plainDec, _ := sk.NewDecapsulator(); plainDec != nil {
+		if _, err := plainDec.Decapsulate(ct); err == nil {
+			t.Fatalf("a Decapsulator without key confirmation accepted a tagged ciphertext")
+		}
+	}
+
+	if got, index, err := DecapsulateAny([]*PrivateKey{sk}, ct, WithKeyConfirmationTag()); err != nil || index != 0 || !bytes.Equal(got, ss) {
+		t.Fatalf("DecapsulateAny with key confirmation: index %d, %v", index, err)
+	}
+	kr, err := NewKeyRing(sk)
+	if err != nil {
+		t.Fatalf("NewKeyRing failed: %v", err)
+	}
+	if got, _, err := kr.Decapsulate(ct, WithKeyConfirmationTag()); err != nil || !bytes.Equal(got, ss) {
+		t.Fatalf("KeyRing.Decapsulate with key confirmation: %v", err)
+	}
+	if _, _, err := kr.Decapsulate(ct); err == nil {
+		t.Fatalf("KeyRing.Decapsulate without key confirmation accepted a tagged ciphertext")
+	}
+}
+
+func TestConcurrentKeyUse(t *testing.T) {
+	const goroutines = 32
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(seededReader("concurrent use"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	cts := make([][]byte, goroutines)
+	sss := make([][]byte, goroutines)
+	errs := make([]error, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cts[i], sss[i], errs[i] = kem.Encapsulate(pk)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent Encapsulate %d failed: %v", i, err)
+		}
+	}
+
+	got := make([][]byte, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i], errs[i] = kem.Decapsulate(sk, cts[i])
+		}(i)
+	}
+	wg.Wait()
+	for i := range got {
+		if errs[i] != nil || !bytes.Equal(got[i], sss[i]) {
+			t.Fatalf("concurrent Decapsulate %d failed: %v", i, errs[i])
+		}
+	}
+}
+
+func TestDecapsulateBatch(t *testing.T) {
+	const count = 32
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(seededReader("decapsulate batch"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	cts := make([][]byte, count)
+	want := make([][]byte, count)
+	for i := range cts {
+		if cts[i], want[i], err = kem.Encapsulate(pk); err != nil {
+			t.Fatalf("Encapsulate %d failed: %v", i, err)
+		}
+	}
+	// One tampered and one truncated ciphertext fail only at their own index
+	cts[5] = append([]byte(nil), cts[5]...)
+	cts[5][0] ^= 1
+	cts[17] = cts[17][:10]
+
+	got, errs := kem.DecapsulateBatch(sk, cts)
+	if len(got) != count || len(errs) != count {
+		t.Fatalf("DecapsulateBatch returned %d keys and %d errors for %d ciphertexts", len(got), len(errs), count)
+	}
+	for i := range cts {
+		switch i {
+		case 5, 17:
+			if errs[i] == nil {
+				t.Errorf("ciphertext %d: expected an error", i)
+			}
+		default:
+			if errs[i] != nil || !bytes.Equal(got[i], want[i]) {
+				t.Errorf("ciphertext %d: got %x, %v; want %x", i, got[i], errs[i], want[i])
+			}
+		}
+	}
+
+	if got, errs := kem.DecapsulateBatch(sk, nil); len(got) != 0 || len(errs) != 0 {
+		t.Fatalf("DecapsulateBatch of no ciphertexts returned %d results", len(got))
+	}
+	_, errs = kem.DecapsulateBatch(nil, cts[:2])
+	for i, err := range errs {
+		if !errors.Is(err, ErrInvalidPrivateKey) {
+			t.Fatalf("DecapsulateBatch with a nil key, entry %d: got %v", i, err)
+		}
+	}
+}
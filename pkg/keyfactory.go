@@ -0,0 +1,160 @@
+package pkg
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/sha3"
+	"github.com/tuneinsight/lattigo/v6/ring"
+)
+
+// keyFactorySeedSize is the length of the master seed a KeyFactory reads from its random source
+const keyFactorySeedSize = 32
+
+// keyFactoryDomain separates the per-key streams of a KeyFactory from other uses of SHAKE256
+const keyFactoryDomain = "OW-ChCCA-KEM key factory"
+
+// KeyPair is a public key together with its private key
+type KeyPair struct {
+	Pk *PublicKey
+	Sk *PrivateKey
+}
+
+// KeyFactory generates key pairs in bulk. Key i is generated from SHAKE256 over a master seed
+// and i, so the sequence of keys is determined by the seed read at construction and does not
+// depend on the number of workers or on scheduling. A KeyFactory is safe for concurrent use.
+type KeyFactory struct {
+	kem     OwChCCAKEM
+	pRing   *ring.Ring
+	seed    []byte
+	workers int
+	next    atomic.Uint64
+}
+
+// NewKeyFactory validates params, builds the ring once and reads the master seed from randSource.
+// workers bounds the number of keys GenerateN generates concurrently; workers <= 0 means
+// runtime.NumCPU(). A nil randSource means crypto/rand.
+func NewKeyFactory(params Parameters, workers int, randSource io.Reader) (*KeyFactory, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	if randSource == nil {
+		randSource = rand.Reader
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	pRing, err := newParamsRing(params)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := make([]byte, keyFactorySeedSize)
+	if _, err := (&fullReader{r: randSource}).Read(seed); err != nil {
+		return nil, fmt.Errorf("failed to read master seed: %w", err)
+	}
+
+	return &KeyFactory{
+		kem:     OwChCCAKEM{Params: params},
+		pRing:   pRing,
+		seed:    seed,
+		workers: workers,
+	}, nil
+}
+
+// keyStream returns the random stream of key index
+func (f *KeyFactory) keyStream(index uint64) io.Reader {
+	h := sha3.NewShake256()
+	h.Write([]byte(keyFactoryDomain))
+	h.Write(f.seed)
+	h.Write(binary.BigEndian.AppendUint64(nil, index))
+	return &h
+}
+
+// generate generates the key pair with the given index
+func (f *KeyFactory) generate(index uint64) (*PublicKey, *PrivateKey, error) {
+	pk, sk, err := f.kem.generateKeyPair(f.keyStream(index), f.pRing)
+	if err != nil {
+		return nil, nil, fmt.Errorf("key %d: %w", index, err)
+	}
+	return pk, sk, nil
+}
+
+// Next generates the next key pair
+func (f *KeyFactory) Next() (*PublicKey, *PrivateKey, error) {
+	return f.generate(f.next.Add(1) - 1)
+}
+
+// GenerateN generates the next n key pairs on up to f.workers goroutines and returns them in
+// order. On error no keys are returned, but the indices of the whole batch are consumed.
+func (f *KeyFactory) GenerateN(n int) ([]*KeyPair, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("%w: negative key count %d", ErrParameterValidation, n)
+	}
+	first := f.next.Add(uint64(n)) - uint64(n)
+	pairs := make([]*KeyPair, n)
+
+	var wg sync.WaitGroup
+	var firstErr error
+	var errOnce sync.Once
+	indices := make(chan int)
+	for w := 0; w < min(f.workers, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				pk, sk, err := f.generate(first + uint64(i))
+				if err != nil {
+					errOnce.Do(func() { firstErr = err })
+					continue
+				}
+				pairs[i] = &KeyPair{Pk: pk, Sk: sk}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return pairs, nil
+}
+
+// GenerateKeyPairConcurrent generates n key pairs on runtime.NumCPU() goroutines. It reads a
+// single master seed from randSource and derives every key from it as a KeyFactory does, so
+// randSource is never shared between goroutines and a seeded source gives the same keys on any
+// machine. Toy parameter sets must be allowed as for GenerateKeyPair. A nil randSource means
+// crypto/rand.
+func (kem *OwChCCAKEM) GenerateKeyPairConcurrent(n int, randSource io.Reader) ([]*PublicKey, []*PrivateKey, error) {
+	if err := kem.checkToyParameters(); err != nil {
+		return nil, nil, err
+	}
+	if n < 0 {
+		return nil, nil, fmt.Errorf("%w: negative key count %d", ErrParameterValidation, n)
+	}
+	f, err := NewKeyFactory(kem.Params, 0, randSource)
+	if err != nil {
+		return nil, nil, err
+	}
+	pairs, err := f.GenerateN(n)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pks := make([]*PublicKey, n)
+	sks := make([]*PrivateKey, n)
+	for i, pair := range pairs {
+		pks[i], sks[i] = pair.Pk, pair.Sk
+	}
+	return pks, sks, nil
+}
@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestKeyFactory(t *testing.T) {
+	params := GetDefaultParameterSet()
+	f, err := NewKeyFactory(params, 2, seededReader("key factory"))
+	if err != nil {
+		t.Fatalf("NewKeyFactory failed: %v", err)
+	}
+
+	pairs, err := f.GenerateN(5)
+	if err != nil {
+		t.Fatalf("GenerateN failed: %v", err)
+	}
+	pk, sk, err := f.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	pairs = append(pairs, &KeyPair{Pk: pk, Sk: sk})
+
+	seen := make(map[string]int)
+	for i, kp := range pairs {
+		if err := CheckKeyPair(kp.Pk, kp.Sk); err != nil {
+			t.Fatalf("key %d: CheckKeyPair failed: %v", i, err)
+		}
+		encoded, err := kp.Pk.Bytes()
+		if err != nil {
+			t.Fatalf("key %d: Bytes failed: %v", i, err)
+		}
+		if j, ok := seen[string(encoded)]; ok {
+			t.Fatalf("keys %d and %d are equal", j, i)
+		}
+		seen[string(encoded)] = i
+	}
+
+	if err := CheckKeyPair(pairs[0].Pk, pairs[1].Sk); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("mismatched key pair: got %v", err)
+	}
+
+	// The same seed reproduces the same sequence regardless of the worker count
+	again, err := NewKeyFactory(params, 5, seededReader("key factory"))
+	if err != nil {
+		t.Fatalf("NewKeyFactory failed: %v", err)
+	}
+	for i, kp := range pairs {
+		pk, sk, err := again.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if !pk.Equal(kp.Pk) || !sk.Equal(kp.Sk) {
+			t.Fatalf("key %d differs between factories with the same seed", i)
+		}
+	}
+
+	if _, err := f.GenerateN(-1); !errors.Is(err, ErrParameterValidation) {
+		t.Fatalf("negative count: got %v", err)
+	}
+	if empty, err := f.GenerateN(0); err != nil || len(empty) != 0 {
+		t.Fatalf("GenerateN(0): got %d keys, %v", len(empty), err)
+	}
+}
+
+func TestWorkerRangesFixed(t *testing.T) {
+	// The split must not depend on the machine, or a seed would not reproduce the same keys
+	ranges := workerRanges(1000)
+	if len(ranges) != samplingChunks || ranges[0] != [2]int{0, 63} || ranges[len(ranges)-1][1] != 1000 {
+		t.Fatalf("workerRanges(1000): got %v", ranges)
+	}
+	if got := workerRanges(3); len(got) != 3 {
+		t.Fatalf("workerRanges(3): got %v", got)
+	}
+}
+
+// BenchmarkKeyFactory reports key generation throughput with one worker and with one per CPU
+func BenchmarkKeyFactory(b *testing.B) {
+	params := GetDefaultParameterSet()
+	counts := []int{1}
+	if n := runtime.NumCPU(); n > 1 {
+		counts = append(counts, n)
+	}
+	for _, workers := range counts {
+		b.Run(fmt.Sprintf("Workers%d", workers), func(b *testing.B) {
+			f, err := NewKeyFactory(params, workers, nil)
+			if err != nil {
+				b.Fatalf("NewKeyFactory failed: %v", err)
+			}
+			b.ResetTimer()
+			if _, err := f.GenerateN(b.N); err != nil {
+				b.Fatalf("GenerateN failed: %v", err)
+			}
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "keys/s")
+		})
+	}
+}
+
+func TestGenerateKeyPairConcurrent(t *testing.T) {
+	const n = 8
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pks, sks, err := kem.GenerateKeyPairConcurrent(n, seededReader("concurrent keygen"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPairConcurrent failed: %v", err)
+	}
+	if len(pks) != n || len(sks) != n {
+		t.Fatalf("GenerateKeyPairConcurrent returned %d public and %d private keys, want %d", len(pks), len(sks), n)
+	}
+	for i := range pks {
+		if err := CheckKeyPair(pks[i], sks[i]); err != nil {
+			t.Fatalf("pair %d: %v", i, err)
+		}
+		ct, ss, err := kem.Encapsulate(pks[i])
+		if err != nil {
+			t.Fatalf("pair %d: Encapsulate failed: %v", i, err)
+		}
+		got, err := kem.Decapsulate(sks[i], ct)
+		if err != nil || !bytes.Equal(got, ss) {
+			t.Fatalf("pair %d: shared keys differ: %v", i, err)
+		}
+		if i > 0 && pks[i].Equal(pks[i-1]) {
+			t.Fatalf("pairs %d and %d are equal", i-1, i)
+		}
+	}
+
+	// The same seed gives the same keys, whatever the scheduling
+	again, _, err := kem.GenerateKeyPairConcurrent(n, seededReader("concurrent keygen"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPairConcurrent failed: %v", err)
+	}
+	for i := range again {
+		if !again[i].Equal(pks[i]) {
+			t.Fatalf("pair %d differs between runs with the same seed", i)
+		}
+	}
+
+	if _, _, err := kem.GenerateKeyPairConcurrent(-1, nil); !errors.Is(err, ErrParameterValidation) {
+		t.Fatalf("GenerateKeyPairConcurrent(-1): got %v", err)
+	}
+	strict := OwChCCAKEM{Params: GetDefaultParameterSet()}
+	if _, _, err := strict.GenerateKeyPairConcurrent(1, nil); !errors.Is(err, ErrToyParameters) {
+		t.Fatalf("GenerateKeyPairConcurrent with a toy set: got %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package pkg
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// DecapsulateOption configures DecapsulateAny and KeyRing.Decapsulate
+type DecapsulateOption func(*decapsulateOptions)
+
+type decapsulateOptions struct {
+	parallel        bool
+	constantTrials  bool
+	keyConfirmation bool
+}
+
+func newDecapsulateOptions(opts []DecapsulateOption) decapsulateOptions {
+	var o decapsulateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithParallelTrials tries all keys concurrently. Every key is then evaluated, as with
+// WithConstantTrials, and the lowest index that succeeds is reported.
+func WithParallelTrials() DecapsulateOption {
+	return func(o *decapsulateOptions) {
+		o.parallel = true
+	}
+}
+
+// WithConstantTrials tries every key even after one has succeeded, so that the running time
+// does not reveal which key the ciphertext was encapsulated to
+func WithConstantTrials() DecapsulateOption {
+	return func(o *decapsulateOptions) {
+		o.constantTrials = true
+	}
+}
+
+// WithKeyConfirmationTag expects ciphertexts that carry a key confirmation tag; see
+// OwChCCAKEM.WithKeyConfirmation
+func WithKeyConfirmationTag() DecapsulateOption {
+	return func(o *decapsulateOptions) {
+		o.keyConfirmation = true
+	}
+}
+
+// decapsulateFunc decapsulates with one candidate key
+type decapsulateFunc func(ciphertext []byte) ([]byte, error)
+
+// DecapsulateAny decapsulates ciphertext with each of sks in order and returns the shared key
+// of the first key that succeeds together with its index. It fails with ErrDecapsulationFailed
+// only if every key fails.
+//
+// By default the keys are tried one after another and the search stops at the first success,
+// so the running time grows with the index of the matching key and reveals it to anyone who
+// can time the call. Pass WithConstantTrials to always evaluate every key, or
+// WithParallelTrials to evaluate them concurrently.
+func DecapsulateAny(sks []*PrivateKey, ciphertext []byte, opts ...DecapsulateOption) (sharedKey []byte, usedIndex int, err error) {
+	if len(sks) == 0 {
+		return nil, -1, fmt.Errorf("%w: no private keys", ErrInvalidPrivateKey)
+	}
+	o := newDecapsulateOptions(opts)
+	try := make([]decapsulateFunc, len(sks))
+	for i, sk := range sks {
+		if sk == nil || sk.Pk == nil {
+			return nil, -1, fmt.Errorf("%w: key %d is nil", ErrInvalidPrivateKey, i)
+		}
+		try[i] = func(ct []byte) ([]byte, error) {
+			kem := OwChCCAKEM{Params: sk.Pk.Params}.WithKeyConfirmation(o.keyConfirmation)
+			return kem.Decapsulate(sk, ct)
+		}
+	}
+	return decapsulateAny(try, ciphertext, o)
+}
+
+// decapsulateAny runs the trials of DecapsulateAny
+func decapsulateAny(try []decapsulateFunc, ciphertext []byte, o decapsulateOptions) ([]byte, int, error) {
+
+	results := make([][]byte, len(try))
+	if o.parallel {
+		var wg sync.WaitGroup
+		for i, f := range try {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], _ = f(ciphertext)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i, f := range try {
+			results[i], _ = f(ciphertext)
+			if results[i] != nil && !o.constantTrials {
+				break
+			}
+		}
+	}
+
+	for i, ss := range results {
+		if ss != nil {
+			return ss, i, nil
+		}
+	}
+	return nil, -1, ErrDecapsulationFailed
+}
+
+// keyRingMagic starts every encoded key ring
+var keyRingMagic = [4]byte{'O', 'W', 'K', 'R'}
+
+// keyRingVersion is the version of the key ring encoding
+const keyRingVersion byte = 1
+
+// keyRingEntry is a key of a KeyRing with its lazily built Decapsulator
+type keyRingEntry struct {
+	sk  *PrivateKey
+	dec *Decapsulator
+}
+
+// KeyRing holds the private keys of a service that rotates keys, newest first. Decapsulate
+// tries them in that order, so ciphertexts to the current key are recovered fastest. The
+// precomputed state of each key is built on first use and kept until the key is retired.
+// A KeyRing is safe for concurrent use.
+type KeyRing struct {
+	mu      sync.RWMutex
+	entries []*keyRingEntry
+}
+
+// NewKeyRing returns a key ring holding keys, newest first
+func NewKeyRing(keys ...*PrivateKey) (*KeyRing, error) {
+	kr := &KeyRing{}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if err := kr.Add(keys[i]); err != nil {
+			return nil, err
+		}
+	}
+	return kr, nil
+}
+
+// Add validates sk and makes it the current key. A key that is already in the ring is rejected.
+func (kr *KeyRing) Add(sk *PrivateKey) error {
+	if err := sk.Validate(); err != nil {
+		return err
+	}
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if kr.indexLocked(sk.Pk) >= 0 {
+		return fmt.Errorf("%w: key is already in the key ring", ErrInvalidPrivateKey)
+	}
+	kr.entries = append([]*keyRingEntry{{sk: sk}}, kr.entries...)
+	return nil
+}
+
+// Retire removes the key for pk and reports whether it was in the ring
+func (kr *KeyRing) Retire(pk *PublicKey) bool {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	i := kr.indexLocked(pk)
+	if i < 0 {
+		return false
+	}
+	kr.entries = append(kr.entries[:i:i], kr.entries[i+1:]...)
+	return true
+}
+
+// indexLocked returns the index of the key for pk, or -1. The caller must hold kr.mu.
+func (kr *KeyRing) indexLocked(pk *PublicKey) int {
+	for i, e := range kr.entries {
+		if e.sk.Pk.Equal(pk) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Len returns the number of keys in the ring
+func (kr *KeyRing) Len() int {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return len(kr.entries)
+}
+
+// Current returns the newest key, or nil if the ring is empty
+func (kr *KeyRing) Current() *PrivateKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	if len(kr.entries) == 0 {
+		return nil
+	}
+	return kr.entries[0].sk
+}
+
+// Keys returns the keys of the ring, newest first
+func (kr *KeyRing) Keys() []*PrivateKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	keys := make([]*PrivateKey, len(kr.entries))
+	for i, e := range kr.entries {
+		keys[i] = e.sk
+	}
+	return keys
+}
+
+// Decapsulate is DecapsulateAny over the keys of the ring, newest first. usedIndex is the
+// position of the key that succeeded at the time of the call.
+func (kr *KeyRing) Decapsulate(ciphertext []byte, opts ...DecapsulateOption) (sharedKey []byte, usedIndex int, err error) {
+	o := newDecapsulateOptions(opts)
+	kr.mu.Lock()
+	try := make([]decapsulateFunc, len(kr.entries))
+	for i, e := range kr.entries {
+		if e.dec == nil {
+			dec, err := newDecapsulator(e.sk.Pk.Params, e.sk)
+			if err != nil {
+				kr.mu.Unlock()
+				return nil, -1, err
+			}
+			e.dec = dec
+		}
+		dec := e.dec
+		try[i] = func(ct []byte) ([]byte, error) {
+			return dec.decapsulate(context.Background(), ct, o.keyConfirmation)
+		}
+	}
+	kr.mu.Unlock()
+
+	if len(try) == 0 {
+		return nil, -1, fmt.Errorf("%w: key ring is empty", ErrInvalidPrivateKey)
+	}
+	return decapsulateAny(try, ciphertext, o)
+}
+
+// MarshalBinary encodes the ring as "OWKR" | version (1 byte) | key count (4 bytes), followed
+// by the length (4 bytes) and MarshalWithHeader encoding of each key, newest first. The
+// encoding contains the private keys in the clear.
+func (kr *KeyRing) MarshalBinary() ([]byte, error) {
+	keys := kr.Keys()
+	out := append([]byte(nil), keyRingMagic[:]...)
+	out = append(out, keyRingVersion)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(keys)))
+	for i, sk := range keys {
+		data, err := sk.MarshalWithHeader()
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		out = binary.BigEndian.AppendUint32(out, uint32(len(data)))
+		out = append(out, data...)
+		clear(data)
+	}
+	return out, nil
+}
+
+// UnmarshalBinary replaces the keys of the ring with those decoded from data
+func (kr *KeyRing) UnmarshalBinary(data []byte) error {
+	if len(data) < 9 || [4]byte(data[:4]) != keyRingMagic {
+		return fmt.Errorf("%w: not a key ring", ErrDeserializationError)
+	}
+	if data[4] != keyRingVersion {
+		return fmt.Errorf("%w: unsupported key ring version %d", ErrDeserializationError, data[4])
+	}
+	count := binary.BigEndian.Uint32(data[5:9])
+	rest := data[9:]
+
+	var entries []*keyRingEntry
+	for i := uint32(0); i < count; i++ {
+		if len(rest) < 4 {
+			return fmt.Errorf("%w: key ring truncated at key %d", ErrDeserializationError, i)
+		}
+		size := binary.BigEndian.Uint32(rest)
+		rest = rest[4:]
+		if uint64(len(rest)) < uint64(size) {
+			return fmt.Errorf("%w: key ring truncated at key %d", ErrDeserializationError, i)
+		}
+		sk, err := ParsePrivateKeyWithHeader(rest[:size])
+		if err != nil {
+			return fmt.Errorf("key %d: %w", i, err)
+		}
+		entries = append(entries, &keyRingEntry{sk: sk})
+		rest = rest[size:]
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes after key ring", ErrDeserializationError, len(rest))
+	}
+
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.entries = entries
+	return nil
+}
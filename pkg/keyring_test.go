@@ -0,0 +1,178 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// rotationKeys generates n key pairs under the default parameter set
+func rotationKeys(t testing.TB, n int) []*PrivateKey {
+	t.Helper()
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	keys := make([]*PrivateKey, n)
+	for i := range keys {
+		_, sk, err := kem.GenerateKeyPair(seededReader(fmt.Sprintf("rotation key %d", i)))
+		if err != nil {
+			t.Fatalf("GenerateKeyPair failed: %v", err)
+		}
+		keys[i] = sk
+	}
+	return keys
+}
+
+func TestDecapsulateAny(t *testing.T) {
+	keys := rotationKeys(t, 4)
+	candidates := keys[:3]
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}
+
+	ct, ss, err := kem.EncapsulateFrom(candidates[2].Pk, seededReader("decapsulate any"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+	for name, opts := range map[string][]DecapsulateOption{
+		"sequential": nil,
+		"constant":   {WithConstantTrials()},
+		"parallel":   {WithParallelTrials()},
+	} {
+		got, index, err := DecapsulateAny(candidates, ct, opts...)
+		if err != nil || index != 2 || !bytes.Equal(got, ss) {
+			t.Fatalf("%s: DecapsulateAny = index %d, %v", name, index, err)
+		}
+	}
+
+	// A ciphertext to a key outside the candidates fails only after every key is tried
+	other, _, err := kem.EncapsulateFrom(keys[3].Pk, seededReader("decapsulate any"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+	if _, index, err := DecapsulateAny(candidates, other); !errors.Is(err, ErrDecapsulationFailed) || index != -1 {
+		t.Fatalf("DecapsulateAny with no matching key: index %d, %v", index, err)
+	}
+	if _, _, err := DecapsulateAny(candidates, ct[:10]); !errors.Is(err, ErrDecapsulationFailed) {
+		t.Fatalf("DecapsulateAny of a truncated ciphertext: %v", err)
+	}
+	if _, _, err := DecapsulateAny(nil, ct); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("DecapsulateAny without keys: %v", err)
+	}
+	if _, _, err := DecapsulateAny([]*PrivateKey{keys[0], nil}, ct); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("DecapsulateAny with a nil key: %v", err)
+	}
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	keys := rotationKeys(t, 3)
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}
+
+	kr, err := NewKeyRing(keys[0])
+	if err != nil {
+		t.Fatalf("NewKeyRing failed: %v", err)
+	}
+	ct0, ss0, err := kem.EncapsulateFrom(kr.Current().Pk, seededReader("key ring 0"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+
+	// Rotate: the new key becomes current and the old one still decapsulates
+	if err := kr.Add(keys[1]); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if kr.Current() != keys[1] || kr.Len() != 2 {
+		t.Fatalf("after Add the current key should be the new key")
+	}
+	if err := kr.Add(keys[0]); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("adding a key twice: got %v", err)
+	}
+	ct1, ss1, err := kem.EncapsulateFrom(kr.Current().Pk, seededReader("key ring 1"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+	if got, index, err := kr.Decapsulate(ct0); err != nil || index != 1 || !bytes.Equal(got, ss0) {
+		t.Fatalf("old ciphertext: index %d, %v", index, err)
+	}
+	if got, index, err := kr.Decapsulate(ct1, WithConstantTrials()); err != nil || index != 0 || !bytes.Equal(got, ss1) {
+		t.Fatalf("new ciphertext: index %d, %v", index, err)
+	}
+
+	// Retiring the old key ends support for its ciphertexts
+	if !kr.Retire(keys[0].Pk) || kr.Retire(keys[0].Pk) {
+		t.Fatalf("Retire should remove the key exactly once")
+	}
+	if _, _, err := kr.Decapsulate(ct0); !errors.Is(err, ErrDecapsulationFailed) {
+		t.Fatalf("ciphertext to a retired key: got %v", err)
+	}
+	if got, index, err := kr.Decapsulate(ct1, WithParallelTrials()); err != nil || index != 0 || !bytes.Equal(got, ss1) {
+		t.Fatalf("ciphertext to the current key after Retire: index %d, %v", index, err)
+	}
+
+	empty, _ := NewKeyRing()
+	if empty.Current() != nil {
+		t.Fatalf("an empty ring has no current key")
+	}
+	if _, _, err := empty.Decapsulate(ct1); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("Decapsulate with an empty ring: got %v", err)
+	}
+	if err := kr.Add(&PrivateKey{}); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("adding an invalid key: got %v", err)
+	}
+}
+
+func TestKeyRingSerialization(t *testing.T) {
+	keys := rotationKeys(t, 2)
+	kr, err := NewKeyRing(keys[1], keys[0])
+	if err != nil {
+		t.Fatalf("NewKeyRing failed: %v", err)
+	}
+	data, err := kr.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	decoded := &KeyRing{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %v", err)
+	}
+	got := decoded.Keys()
+	if len(got) != 2 || !got[0].Equal(keys[1]) || !got[1].Equal(keys[0]) {
+		t.Fatalf("decoded ring does not hold the keys in order")
+	}
+
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}
+	ct, ss, err := kem.EncapsulateFrom(keys[0].Pk, seededReader("key ring serialization"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+	if shared, index, err := decoded.Decapsulate(ct); err != nil || index != 1 || !bytes.Equal(shared, ss) {
+		t.Fatalf("decoded ring: index %d, %v", index, err)
+	}
+
+	for name, bad := range map[string][]byte{
+		"magic":     append([]byte("XXXX"), data[4:]...),
+		"version":   append(append(append([]byte(nil), data[:4]...), 9), data[5:]...),
+		"truncated": data[:len(data)-1],
+		"trailing":  append(append([]byte(nil), data...), 0),
+		"short":     data[:6],
+	} {
+		if err := (&KeyRing{}).UnmarshalBinary(bad); err == nil {
+			t.Fatalf("%s: UnmarshalBinary should fail", name)
+		}
+	}
+}
+
+func ExampleKeyRing() {
+	params := GetDefaultParameterSet()
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	_, oldKey, _ := kem.GenerateKeyPair(seededReader("example old key"))
+	_, newKey, _ := kem.GenerateKeyPair(seededReader("example new key"))
+
+	// A client encapsulated to the old key before the rotation
+	ct, ss, _ := kem.EncapsulateFrom(oldKey.Pk, seededReader("example ciphertext"))
+
+	kr, _ := NewKeyRing(oldKey)
+	kr.Add(newKey)
+
+	shared, index, err := kr.Decapsulate(ct, WithConstantTrials())
+	fmt.Println(index, bytes.Equal(shared, ss), err)
+	// Output: 1 true <nil>
+}
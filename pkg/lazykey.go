@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/sha3"
+)
+
+// LazyPublicKey is a public key that keeps only its encoding in memory until it is needed.
+// The encoding is read through an io.ReaderAt, so it can be backed by a byte slice, an open
+// file or a memory-mapped region. Only the header is parsed by NewLazyPublicKey; A, U0 and U1
+// are decoded by Load or Encapsulate and can be dropped again with Release.
+// A LazyPublicKey is safe for concurrent use.
+type LazyPublicKey struct {
+	params Parameters
+	src    io.ReaderAt
+	offset int64 // start of the bare encoding within src
+
+	mu sync.Mutex
+	pk *PublicKey
+}
+
+// NewLazyPublicKey wraps the size-byte public key encoding readable from src. The encoding is
+// either the MarshalWithHeader form or a bare encoding whose length identifies a registered set.
+// src must not change while the LazyPublicKey is in use.
+func NewLazyPublicKey(src io.ReaderAt, size int64) (*LazyPublicKey, error) {
+	header := make([]byte, HeaderSize)
+	if size >= HeaderSize {
+		if _, err := src.ReadAt(header, 0); err != nil {
+			return nil, fmt.Errorf("%w: failed to read header: %v", ErrDeserializationError, err)
+		}
+	}
+
+	lpk := &LazyPublicKey{src: src}
+	if size >= HeaderSize && isHeadered(header, KindPublicKey) {
+		params, err := parseHeader(header, KindPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		lpk.params, lpk.offset = params, HeaderSize
+	} else {
+		params, err := inferParameterSet(int(size), "public keys", func(p Parameters) int { return p.KeyParams.PublicKeySize })
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDeserializationError, err)
+		}
+		lpk.params = params
+	}
+
+	if want := lpk.offset + int64(lpk.params.KeyParams.PublicKeySize); size != want {
+		return nil, fmt.Errorf("%w: public key is %d bytes, expected %d", ErrDeserializationError, size, want)
+	}
+	return lpk, nil
+}
+
+// NewLazyPublicKeyFromBytes wraps an in-memory encoding; data must not be modified afterwards
+func NewLazyPublicKeyFromBytes(data []byte) (*LazyPublicKey, error) {
+	return NewLazyPublicKey(bytes.NewReader(data), int64(len(data)))
+}
+
+// Parameters returns the parameter set named by the encoding
+func (lpk *LazyPublicKey) Parameters() Parameters {
+	return lpk.params
+}
+
+// raw returns a reader over the bare public key encoding
+func (lpk *LazyPublicKey) raw() *io.SectionReader {
+	return io.NewSectionReader(lpk.src, lpk.offset, int64(lpk.params.KeyParams.PublicKeySize))
+}
+
+// Load decodes the public key if it is not already decoded and returns it. The decoded key is
+// cached until Release.
+func (lpk *LazyPublicKey) Load() (*PublicKey, error) {
+	lpk.mu.Lock()
+	defer lpk.mu.Unlock()
+	if lpk.pk != nil {
+		return lpk.pk, nil
+	}
+
+	data := make([]byte, lpk.params.KeyParams.PublicKeySize)
+	if _, err := io.ReadFull(lpk.raw(), data); err != nil {
+		return nil, fmt.Errorf("%w: failed to read public key: %v", ErrDeserializationError, err)
+	}
+	pk := &PublicKey{Params: lpk.params}
+	if err := pk.UnmarshalBinaryStrict(data); err != nil {
+		return nil, err
+	}
+	lpk.pk = pk
+	return pk, nil
+}
+
+// Loaded reports whether the decoded key is currently cached
+func (lpk *LazyPublicKey) Loaded() bool {
+	lpk.mu.Lock()
+	defer lpk.mu.Unlock()
+	return lpk.pk != nil
+}
+
+// Release drops the cached decoded key; the next Load or Encapsulate decodes it again.
+// Keys previously returned by Load stay valid.
+func (lpk *LazyPublicKey) Release() {
+	lpk.mu.Lock()
+	defer lpk.mu.Unlock()
+	lpk.pk = nil
+}
+
+// Encapsulate loads the key if needed and encapsulates to it, drawing r from randSource;
+// a nil randSource means crypto/rand
+func (lpk *LazyPublicKey) Encapsulate(randSource io.Reader) (ciphertext, sharedKey []byte, err error) {
+	pk, err := lpk.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	kem := OwChCCAKEM{Params: lpk.params}
+	return kem.EncapsulateFrom(pk, randSource)
+}
+
+// Fingerprint returns the SHA3-256 digest of the bare public key encoding, streamed from the
+// source without decoding the key. It equals Decapsulator.PublicKeyDigest for the same key.
+func (lpk *LazyPublicKey) Fingerprint() ([]byte, error) {
+	h := sha3.New256()
+	if _, err := io.Copy(&h, lpk.raw()); err != nil {
+		return nil, fmt.Errorf("%w: failed to read public key: %v", ErrDeserializationError, err)
+	}
+	return h.Sum(nil), nil
+}
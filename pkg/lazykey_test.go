@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestLazyPublicKey(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	encoded, err := pk.MarshalWithHeader()
+	if err != nil {
+		t.Fatalf("MarshalWithHeader failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "pk.bin")
+	if err := os.WriteFile(path, encoded, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+
+	lpk, err := NewLazyPublicKey(f, int64(len(encoded)))
+	if err != nil {
+		t.Fatalf("NewLazyPublicKey failed: %v", err)
+	}
+	if lpk.Parameters().Name != kem.Params.Name || lpk.Loaded() {
+		t.Fatalf("unexpected state after construction: %s, loaded %v", lpk.Parameters().Name, lpk.Loaded())
+	}
+
+	d, err := sk.NewDecapsulator()
+	if err != nil {
+		t.Fatalf("NewDecapsulator failed: %v", err)
+	}
+	fingerprint, err := lpk.Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint failed: %v", err)
+	}
+	if !bytes.Equal(fingerprint, d.PublicKeyDigest()) {
+		t.Fatalf("fingerprint differs from the public key digest")
+	}
+	if lpk.Loaded() {
+		t.Fatalf("Fingerprint materialized the key")
+	}
+
+	ct, ss, err := lpk.Encapsulate(nil)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+	if !lpk.Loaded() {
+		t.Fatalf("Encapsulate did not load the key")
+	}
+	ss2, err := d.Decapsulate(ct)
+	if err != nil || !bytes.Equal(ss, ss2) {
+		t.Fatalf("decapsulation of lazily encapsulated ciphertext failed: %v", err)
+	}
+
+	loaded, err := lpk.Load()
+	if err != nil || !loaded.Equal(pk) {
+		t.Fatalf("Load returned a different key: %v", err)
+	}
+	if again, _ := lpk.Load(); again != loaded {
+		t.Fatalf("Load decoded the key again while cached")
+	}
+
+	// Once released and no longer referenced elsewhere, the decoded key is collectable
+	collected := make(chan struct{})
+	runtime.SetFinalizer(loaded, func(*PublicKey) { close(collected) })
+	loaded = nil
+	lpk.Release()
+	if lpk.Loaded() {
+		t.Fatalf("Release did not drop the key")
+	}
+	deadline := time.After(5 * time.Second)
+	for done := false; !done; {
+		runtime.GC()
+		select {
+		case <-collected:
+			done = true
+		case <-deadline:
+			t.Fatalf("released key was not garbage collected")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// Reloading after Release still works
+	if _, _, err := lpk.Encapsulate(rand.Reader); err != nil {
+		t.Fatalf("Encapsulate after Release failed: %v", err)
+	}
+}
+
+func TestLazyPublicKeyBare(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, _, err := kem.GenerateKeyPair(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	encoded, err := pk.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes failed: %v", err)
+	}
+
+	lpk, err := NewLazyPublicKeyFromBytes(encoded)
+	if err != nil {
+		t.Fatalf("NewLazyPublicKeyFromBytes failed: %v", err)
+	}
+	if loaded, err := lpk.Load(); err != nil || !loaded.Equal(pk) {
+		t.Fatalf("bare encoding did not load: %v", err)
+	}
+
+	if _, err := NewLazyPublicKeyFromBytes(encoded[:len(encoded)-1]); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("truncated bare encoding: got %v", err)
+	}
+	headered, _ := pk.MarshalWithHeader()
+	if _, err := NewLazyPublicKeyFromBytes(headered[:len(headered)-1]); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("truncated headered encoding: got %v", err)
+	}
+}
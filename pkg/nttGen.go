@@ -5,15 +5,34 @@ import (
 	"math/big"
 )
 
-// BigNTTFriendlyPrimesGenerator generates NTT-friendly primes of arbitrary size
+// BigNTTFriendlyPrimesGenerator generates NTT-friendly primes of arbitrary size.
+// The order is deterministic: upstream primes are returned in increasing order starting above
+// 2^{BitSize}, downstream primes in decreasing order starting below it, so two generators with
+// the same BitSize and NthRoot always produce the same sequences.
 type BigNTTFriendlyPrimesGenerator struct {
 	BitSize                        int
 	NextPrime, PrevPrime, NthRoot  *big.Int
 	CheckNextPrime, CheckPrevPrime bool
+
+	upstreamCandidates, downstreamCandidates int
+
+	// pendingUp and pendingDown hold primes found by NextAlternatingPrimes but not yet returned
+	pendingUp, pendingDown *big.Int
 }
 
 // NewBigNTTFriendlyPrimesGenerator creates a generator for primes of the form 2^{BitSize} ± k * {NthRoot} + 1
 func NewBigNTTFriendlyPrimesGenerator(bitSize int, nthRoot *big.Int) *BigNTTFriendlyPrimesGenerator {
+	n := &BigNTTFriendlyPrimesGenerator{
+		BitSize: bitSize,
+		NthRoot: new(big.Int).Set(nthRoot),
+	}
+	n.Reset()
+	return n
+}
+
+// Reset rewinds both sequences to their first candidate
+func (n *BigNTTFriendlyPrimesGenerator) Reset() {
+	bitSize, nthRoot := n.BitSize, n.NthRoot
 	// Initialize constants
 	one := big.NewInt(1)
 	twoPowBitSize := new(big.Int).Lsh(one, uint(bitSize))
@@ -28,18 +47,44 @@ func NewBigNTTFriendlyPrimesGenerator(bitSize int, nthRoot *big.Int) *BigNTTFrie
 	// Check if prev prime would be valid
 	checkPrevPrime := prevPrime.Sign() > 0
 
-	return &BigNTTFriendlyPrimesGenerator{
-		BitSize:        bitSize,
-		NextPrime:      nextPrime,
-		PrevPrime:      prevPrime,
-		NthRoot:        new(big.Int).Set(nthRoot),
-		CheckNextPrime: true,
-		CheckPrevPrime: checkPrevPrime,
+	n.NextPrime = nextPrime
+	n.PrevPrime = prevPrime
+	n.CheckNextPrime = true
+	n.CheckPrevPrime = checkPrevPrime
+	n.upstreamCandidates = 0
+	n.downstreamCandidates = 0
+	n.pendingUp, n.pendingDown = nil, nil
+}
+
+// IsNTTFriendly reports whether q is a prime with q ≡ 1 mod nthRoot, so that Z_q has the
+// nthRoot-th roots of unity an NTT of size nthRoot/2 needs
+func IsNTTFriendly(q, nthRoot *big.Int) bool {
+	if q == nil || nthRoot == nil || q.Sign() <= 0 || nthRoot.Sign() <= 0 {
+		return false
+	}
+	qMinusOne := new(big.Int).Sub(q, big.NewInt(1))
+	if qMinusOne.Mod(qMinusOne, nthRoot).Sign() != 0 {
+		return false
 	}
+	return q.ProbablyPrime(20)
+}
+
+// UpstreamCandidates returns how many upstream candidates have been tested since the last Reset
+func (n *BigNTTFriendlyPrimesGenerator) UpstreamCandidates() int {
+	return n.upstreamCandidates
+}
+
+// DownstreamCandidates returns how many downstream candidates have been tested since the last Reset
+func (n *BigNTTFriendlyPrimesGenerator) DownstreamCandidates() int {
+	return n.downstreamCandidates
 }
 
 // NextUpstreamPrime returns the next prime of the form 2^{BitSize} + k * {NthRoot} + 1
 func (n *BigNTTFriendlyPrimesGenerator) NextUpstreamPrime() (*big.Int, error) {
+	if p := n.pendingUp; p != nil {
+		n.pendingUp = nil
+		return p, nil
+	}
 	if !n.CheckNextPrime {
 		return nil, fmt.Errorf("cannot NextUpstreamPrime: prime list for upstream primes is exhausted")
 	}
@@ -56,6 +101,7 @@ func (n *BigNTTFriendlyPrimesGenerator) NextUpstreamPrime() (*big.Int, error) {
 		}
 
 		// Check if the current candidate is prime
+		n.upstreamCandidates++
 		if n.NextPrime.ProbablyPrime(20) {
 			// Save result
 			result := new(big.Int).Set(n.NextPrime)
@@ -73,6 +119,10 @@ func (n *BigNTTFriendlyPrimesGenerator) NextUpstreamPrime() (*big.Int, error) {
 
 // NextDownstreamPrime returns the next prime of the form 2^{BitSize} - k * {NthRoot} + 1
 func (n *BigNTTFriendlyPrimesGenerator) NextDownstreamPrime() (*big.Int, error) {
+	if p := n.pendingDown; p != nil {
+		n.pendingDown = nil
+		return p, nil
+	}
 	if !n.CheckPrevPrime {
 		return nil, fmt.Errorf("cannot NextDownstreamPrime: prime list for downstream primes is exhausted")
 	}
@@ -89,6 +139,7 @@ func (n *BigNTTFriendlyPrimesGenerator) NextDownstreamPrime() (*big.Int, error)
 		}
 
 		// Check if the current candidate is prime
+		n.downstreamCandidates++
 		if n.PrevPrime.ProbablyPrime(20) {
 			// Save result
 			result := new(big.Int).Set(n.PrevPrime)
@@ -133,3 +184,44 @@ func (n *BigNTTFriendlyPrimesGenerator) NextDownstreamPrimes(k int) ([]*big.Int,
 
 	return primes, nil
 }
+
+// NextAlternatingPrimes returns the next k primes closest to 2^{BitSize}, taking from whichever of
+// the upstream and downstream sequences has the nearer candidate (downstream on ties). When one
+// direction is exhausted the other is used alone; an error is returned once both are.
+func (n *BigNTTFriendlyPrimesGenerator) NextAlternatingPrimes(k int) ([]*big.Int, error) {
+	center := new(big.Int).Lsh(big.NewInt(1), uint(n.BitSize))
+	primes := make([]*big.Int, 0, k)
+
+	for len(primes) < k {
+		if n.pendingUp == nil && n.CheckNextPrime {
+			n.pendingUp, _ = n.NextUpstreamPrime()
+		}
+		if n.pendingDown == nil && n.CheckPrevPrime {
+			n.pendingDown, _ = n.NextDownstreamPrime()
+		}
+
+		up, down := n.pendingUp, n.pendingDown
+		switch {
+		case up == nil && down == nil:
+			return primes, fmt.Errorf("cannot NextAlternatingPrimes: both prime lists are exhausted")
+		case up == nil:
+			primes = append(primes, down)
+			n.pendingDown = nil
+		case down == nil:
+			primes = append(primes, up)
+			n.pendingUp = nil
+		default:
+			upDist := new(big.Int).Sub(up, center)
+			downDist := new(big.Int).Sub(center, down)
+			if downDist.Cmp(upDist) <= 0 {
+				primes = append(primes, down)
+				n.pendingDown = nil
+			} else {
+				primes = append(primes, up)
+				n.pendingUp = nil
+			}
+		}
+	}
+
+	return primes, nil
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"slices"
+	"strings"
 	"sync"
 
-	"github.com/tuneinsight/lattigo/v6/ring"
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/arithmetic"
 )
 
 // SecurityLevel represents a standardized security level in bits
@@ -27,10 +29,56 @@ const (
 	Security256 SecurityLevel = 256
 )
 
+// SecurityClass says what a parameter set may be used for
+type SecurityClass int
+
+const (
+	// SecurityClassToy marks sets that are only fit for tests and demonstrations. Key generation
+	// with them is refused unless the KEM allows toy parameters. It is the zero value, so sets
+	// that were never classified are treated as toy sets.
+	SecurityClassToy SecurityClass = iota
+	// SecurityClassExperimental marks sets that are usable but below the standard levels
+	SecurityClassExperimental
+	// SecurityClassStandard marks sets at a standard security level
+	SecurityClassStandard
+)
+
+// String returns the lower-case name of the class
+func (c SecurityClass) String() string {
+	switch c {
+	case SecurityClassToy:
+		return "toy"
+	case SecurityClassExperimental:
+		return "experimental"
+	case SecurityClassStandard:
+		return "standard"
+	}
+	return fmt.Sprintf("SecurityClass(%d)", int(c))
+}
+
+// securityClassFor classifies the built-in sets by their security level
+func securityClassFor(level SecurityLevel) SecurityClass {
+	switch {
+	case level < Security64:
+		return SecurityClassToy
+	case level < Security128:
+		return SecurityClassExperimental
+	}
+	return SecurityClassStandard
+}
+
 type Parameters struct {
 	Name string
+	// id is the stable wire identifier of the parameter set, 0 if none has been assigned
+	id uint16
 	// SecurityLevel is the estimated security level in bits
 	SecurityLevel SecurityLevel
+	// SecurityClass says whether the set is a toy, experimental or standard set
+	SecurityClass SecurityClass
+	// NTTAccelerated reports that m and q define a ring lattigo can build. Sets without it are
+	// still valid, but key generation, encapsulation and decapsulation fall back to slower
+	// pure-Go sampling and arithmetic.
+	NTTAccelerated bool
 	// LatticeParams defines the lattice dimensions
 	LatticeParams LatticeParameters
 	// GaussianParams defines the Gaussian sampling parameters
@@ -67,6 +115,9 @@ type GaussianParameters struct {
 	Eta float64
 	// LogEta is log2(Eta)
 	LogEta int
+	// CompressionBits is the number of high-order bits of each hatH0/hatH1 coefficient kept in
+	// ciphertexts; 0 means no compression
+	CompressionBits int
 }
 
 // KeyParameters contains parameters related to keys
@@ -81,23 +132,75 @@ type KeyParameters struct {
 	SharedKeySize int
 }
 
-// ParameterRegistry manages parameter sets
+// Stable wire identifiers of the built-in parameter sets, equal to their security level
+const (
+	ParamIDOWChCCA16  uint16 = 16
+	ParamIDOWChCCA32  uint16 = 32
+	ParamIDOWChCCA64  uint16 = 64
+	ParamIDOWChCCA128 uint16 = 128
+	ParamIDOWChCCA192 uint16 = 192
+	ParamIDOWChCCA256 uint16 = 256
+)
+
+// Encoding sizes in bytes of the registered built-in parameter sets, for sizing buffers without
+// a registry lookup. They equal the KeyParams of the corresponding set.
+const (
+	OWChCCA16PublicKeySize  = 8421400
+	OWChCCA16PrivateKeySize = 9469985
+	OWChCCA16CiphertextSize = 65808
+	OWChCCA16SharedKeySize  = 2
+
+	OWChCCA32PublicKeySize  = 33685528
+	OWChCCA32PrivateKeySize = 37879841
+	OWChCCA32CiphertextSize = 131604
+	OWChCCA32SharedKeySize  = 4
+
+	OWChCCA64PublicKeySize  = 134742040
+	OWChCCA64PrivateKeySize = 151519265
+	OWChCCA64CiphertextSize = 263196
+	OWChCCA64SharedKeySize  = 8
+)
+
+// MaxSharedKeySize bounds the shared key size of every parameter set that passes Validate, so
+// a buffer of this size can hold the shared key of any registered set
+const MaxSharedKeySize = int(Security256) / 8
+
+// builtinParamIDs maps the standard security levels to their reserved identifiers
+var builtinParamIDs = map[SecurityLevel]uint16{
+	Security16:  ParamIDOWChCCA16,
+	Security32:  ParamIDOWChCCA32,
+	Security64:  ParamIDOWChCCA64,
+	Security128: ParamIDOWChCCA128,
+	Security192: ParamIDOWChCCA192,
+	Security256: ParamIDOWChCCA256,
+}
+
+// ParameterRegistry manages parameter sets. Every registry function takes its lock, so they are
+// safe for concurrent use. The Parameters values they return share their Q with the registry and
+// must be treated as read-only.
 type ParameterRegistry struct {
 	mu         sync.RWMutex
 	paramSets  map[string]Parameters
+	ids        map[uint16]string
 	defaultSet string
 }
 
 var globalRegistry = &ParameterRegistry{
 	paramSets:  make(map[string]Parameters),
+	ids:        make(map[uint16]string),
 	defaultSet: "OWChCCA-64",
 }
 
 // Initialize the registry with default parameter sets
 func init() {
-	RegisterParameterSet(CalculateParameters(Security16))
-	RegisterParameterSet(CalculateParameters(Security32))
-	RegisterParameterSet(CalculateParameters(Security64))
+	if err := checkStandardModuli(); err != nil {
+		panic("owchcca: " + err.Error())
+	}
+	for _, level := range []SecurityLevel{Security16, Security32, Security64} {
+		if err := RegisterParameterSet(CalculateParameters(level)); err != nil {
+			panic(fmt.Sprintf("owchcca: registering security level %d: %v", level, err))
+		}
+	}
 	// RegisterParameterSet(CalculateParameters(Security128))
 	// RegisterParameterSet(CalculateParameters(Security192))
 	// RegisterParameterSet(CalculateParameters(Security256))
@@ -105,12 +208,105 @@ func init() {
 	SetDefaultParameterSet("OWChCCA-16")
 }
 
-// RegisterParameterSet adds a parameter set to the registry
-func RegisterParameterSet(params Parameters) {
+// RegisterParameterSet adds a parameter set to the registry under its existing ID, if any.
+// A copy of a registered set that was given a new name still carries the ID of the original;
+// it is registered without an ID, and RegisterParameterSetWithID can assign it one of its own.
+func RegisterParameterSet(params Parameters) error {
+	return registerParameterSet(params, params.id, true)
+}
+
+// RegisterParameterSetWithID adds a parameter set to the registry with the given wire ID.
+// An ID of 0 registers the set without an ID. IDs already used by a different name are rejected,
+// as are sets that fail Validate.
+func RegisterParameterSetWithID(params Parameters, id uint16) error {
+	return registerParameterSet(params, id, false)
+}
+
+// registerParameterSet registers params under id. An inherited id that belongs to a set of
+// another name is dropped instead of being reported as a collision.
+func registerParameterSet(params Parameters, id uint16, inherited bool) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
 	globalRegistry.mu.Lock()
 	defer globalRegistry.mu.Unlock()
 
+	if owner, ok := globalRegistry.ids[id]; ok && id != 0 && owner != params.Name {
+		if !inherited {
+			return fmt.Errorf("parameter set ID %d is already registered to %s", id, owner)
+		}
+		id = 0
+	}
+	if old, ok := globalRegistry.paramSets[params.Name]; ok && old.id != 0 && old.id != id {
+		delete(globalRegistry.ids, old.id)
+	}
+
+	params.id = id
 	globalRegistry.paramSets[params.Name] = params
+	if id != 0 {
+		globalRegistry.ids[id] = params.Name
+	}
+	return nil
+}
+
+// ParameterSetByID retrieves a parameter set by its wire ID
+func ParameterSetByID(id uint16) (Parameters, error) {
+	globalRegistry.mu.RLock()
+	defer globalRegistry.mu.RUnlock()
+
+	name, ok := globalRegistry.ids[id]
+	if !ok || id == 0 {
+		return Parameters{}, fmt.Errorf("parameter set with ID %d not found", id)
+	}
+
+	return globalRegistry.paramSets[name], nil
+}
+
+// inferParameterSet finds the registered parameter set whose encoding of objects of the named
+// kind has the given size, as reported by sizeOf. It refuses to guess: it fails with
+// ErrParameterInference unless exactly one set matches, and lists the candidates when several do
+func inferParameterSet(size int, kind string, sizeOf func(Parameters) int) (Parameters, error) {
+	globalRegistry.mu.RLock()
+	defer globalRegistry.mu.RUnlock()
+
+	var candidates []string
+	for name, params := range globalRegistry.paramSets {
+		if sizeOf(params) == size {
+			candidates = append(candidates, name)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return Parameters{}, fmt.Errorf("%w: no registered parameter set has %d-byte %s", ErrParameterInference, size, kind)
+	case 1:
+		return globalRegistry.paramSets[candidates[0]], nil
+	}
+	slices.Sort(candidates)
+	return Parameters{}, fmt.Errorf("%w: %d-byte %s match %s", ErrParameterInference, size, kind, strings.Join(candidates, ", "))
+}
+
+// InferParameterSet returns the registered parameter set whose bare public keys are len(data)
+// bytes long. It fails with ErrParameterInference if no set or more than one set matches.
+// Only the length of data is examined.
+func InferParameterSet(data []byte) (Parameters, error) {
+	return inferParameterSet(len(data), "public keys", func(p Parameters) int { return p.KeyParams.PublicKeySize })
+}
+
+// InferFromCiphertext is InferParameterSet for ciphertexts
+func InferFromCiphertext(ct []byte) (Parameters, error) {
+	return inferParameterSet(len(ct), "ciphertexts", func(p Parameters) int { return p.KeyParams.CiphertextSize })
+}
+
+// InferFromPrivateKey is InferParameterSet for bare private keys
+func InferFromPrivateKey(data []byte) (Parameters, error) {
+	return inferParameterSet(len(data), "private keys", func(p Parameters) int { return p.KeyParams.PrivateKeySize })
+}
+
+// ID returns the stable wire identifier of the parameter set, or 0 if it has none
+func (p Parameters) ID() uint16 {
+	return p.id
 }
 
 // GetParameterSet retrieves a parameter set by name
@@ -126,61 +322,275 @@ func GetParameterSet(name string) (Parameters, error) {
 	return params, nil
 }
 
-// GetDefaultParameterSet returns the default parameter set
+// GetDefaultParameterSet returns the default parameter set. A toy set is never the default once
+// a standard set is registered; the standard set with the lowest security level is returned instead.
 func GetDefaultParameterSet() Parameters {
 	globalRegistry.mu.RLock()
 	defer globalRegistry.mu.RUnlock()
 
-	return globalRegistry.paramSets[globalRegistry.defaultSet]
+	params := globalRegistry.paramSets[globalRegistry.defaultSet]
+	if params.SecurityClass == SecurityClassToy {
+		if standard, ok := globalRegistry.lowestStandardSet(); ok {
+			return standard
+		}
+	}
+	return params
 }
 
-// SetDefaultParameterSet sets the default parameter set
+// lowestStandardSet returns the registered standard set with the lowest security level, breaking
+// ties by name. The caller must hold the registry lock.
+func (r *ParameterRegistry) lowestStandardSet() (Parameters, bool) {
+	var best Parameters
+	found := false
+	for _, params := range r.paramSets {
+		if params.SecurityClass != SecurityClassStandard {
+			continue
+		}
+		if !found || params.SecurityLevel < best.SecurityLevel ||
+			(params.SecurityLevel == best.SecurityLevel && params.Name < best.Name) {
+			best, found = params, true
+		}
+	}
+	return best, found
+}
+
+// SetDefaultParameterSet sets the default parameter set. A toy set cannot be made the default
+// while a standard set is registered.
 func SetDefaultParameterSet(name string) error {
 	globalRegistry.mu.Lock()
 	defer globalRegistry.mu.Unlock()
 
-	if _, ok := globalRegistry.paramSets[name]; !ok {
+	params, ok := globalRegistry.paramSets[name]
+	if !ok {
 		return fmt.Errorf("parameter set %s not found", name)
 	}
+	if _, ok := globalRegistry.lowestStandardSet(); ok && params.SecurityClass == SecurityClassToy {
+		return fmt.Errorf("%w: %s is a toy parameter set and a standard set is registered", ErrToyParameters, name)
+	}
 
 	globalRegistry.defaultSet = name
 	return nil
 }
 
-// ListParameterSets returns a list of all registered parameter set names
-func ListParameterSets() []string {
+// compareParameters orders parameter sets by security level, then by name
+func compareParameters(a, b Parameters) int {
+	if a.SecurityLevel != b.SecurityLevel {
+		return int(a.SecurityLevel - b.SecurityLevel)
+	}
+	return strings.Compare(a.Name, b.Name)
+}
+
+// ListParameters returns every registered parameter set, ordered by security level and then by name
+func ListParameters() []Parameters {
 	globalRegistry.mu.RLock()
-	defer globalRegistry.mu.RUnlock()
+	sets := make([]Parameters, 0, len(globalRegistry.paramSets))
+	for _, params := range globalRegistry.paramSets {
+		sets = append(sets, params)
+	}
+	globalRegistry.mu.RUnlock()
 
-	names := make([]string, 0, len(globalRegistry.paramSets))
-	for name := range globalRegistry.paramSets {
-		names = append(names, name)
+	slices.SortFunc(sets, compareParameters)
+	return sets
+}
+
+// ListParameterSets returns the names of all registered parameter sets in the order of ListParameters
+func ListParameterSets() []string {
+	sets := ListParameters()
+	names := make([]string, len(sets))
+	for i, params := range sets {
+		names[i] = params.Name
 	}
+	return names
+}
 
+// ListParameterSetsByClass returns the names of the registered parameter sets of the given class,
+// in the order of ListParameters
+func ListParameterSetsByClass(class SecurityClass) []string {
+	var names []string
+	for _, params := range ListParameters() {
+		if params.SecurityClass == class {
+			names = append(names, params.Name)
+		}
+	}
 	return names
 }
 
-// DefaultParameters returns the parameter set for the given security level
+// RangeParameterSets calls f for each registered parameter set in the order of ListParameters,
+// stopping early if f returns false. Only the names are snapshotted up front and each set is
+// looked up as it is visited, so f runs without the registry lock held and may itself register
+// parameter sets; sets registered during the iteration are not visited.
+func RangeParameterSets(f func(Parameters) bool) {
+	// The sort keys carry only the name and level of each set
+	globalRegistry.mu.RLock()
+	keys := make([]Parameters, 0, len(globalRegistry.paramSets))
+	for name, params := range globalRegistry.paramSets {
+		keys = append(keys, Parameters{Name: name, SecurityLevel: params.SecurityLevel})
+	}
+	globalRegistry.mu.RUnlock()
+	slices.SortFunc(keys, compareParameters)
+
+	for _, key := range keys {
+		globalRegistry.mu.RLock()
+		params, ok := globalRegistry.paramSets[key.Name]
+		globalRegistry.mu.RUnlock()
+		if ok && !f(params) {
+			return
+		}
+	}
+}
+
+// DefaultParameters returns the parameter set for the given security level, calculating and
+// registering it if needed. If the calculated set cannot be registered it is returned
+// unregistered; use LookupParameters to see the error.
 func DefaultParameters(level SecurityLevel) Parameters {
+	params, _ := defaultParameters(level)
+	return params
+}
+
+// LookupParameters is like DefaultParameters but reports a failure to register the calculated
+// parameter set
+func LookupParameters(level SecurityLevel) (Parameters, error) {
+	params, err := defaultParameters(level)
+	if err != nil {
+		return Parameters{}, err
+	}
+	return params, nil
+}
+
+// defaultParameters returns the registered set for level, or the calculated one together with
+// any registration error
+func defaultParameters(level SecurityLevel) (Parameters, error) {
 	name := fmt.Sprintf("OWChCCA-%d", level)
 	params, err := GetParameterSet(name)
+	if err == nil {
+		return params, nil
+	}
+
+	// If not found, calculate and register it
+	params = CalculateParameters(level)
+	if err := RegisterParameterSet(params); err != nil {
+		return params, err
+	}
+	return GetParameterSet(params.Name)
+}
+
+// standardModuli pins the modulus q of each built-in security level. They were produced by the
+// downstream prime search and are embedded so that a change to the search cannot silently change
+// q, and with it every key and ciphertext format. init checks that each is still a valid choice
+// and TestStandardModuliMatchSearch reruns the search.
+var standardModuli = map[SecurityLevel]string{
+	Security16:  "2305843009213317121",
+	Security32:  "2305843009211662337",
+	Security64:  "2305843009211662337",
+	Security128: "2305843009211596801",
+	Security192: "2305843009211596801",
+	Security256: "9223372036836950017",
+}
+
+// standardModulus returns the embedded modulus for level, or nil for non-standard levels
+func standardModulus(level SecurityLevel) *big.Int {
+	s, ok := standardModuli[level]
+	if !ok {
+		return nil
+	}
+	q, _ := new(big.Int).SetString(s, 10)
+	return q
+}
+
+// checkStandardModuli verifies that every embedded modulus is a prime of the expected size that
+// supports an NTT of the dimension m chosen for its level
+func checkStandardModuli() error {
+	for level := range standardModuli {
+		q := standardModulus(level)
+		m, logQ := dimensionsFor(level)
+		if q == nil || !q.ProbablyPrime(20) {
+			return fmt.Errorf("standard modulus for level %d is not prime", level)
+		}
+		if q.BitLen() != logQ+1 {
+			return fmt.Errorf("standard modulus for level %d has %d bits, expected %d", level, q.BitLen(), logQ+1)
+		}
+		if new(big.Int).Mod(q, big.NewInt(int64(2*m))).Cmp(big.NewInt(1)) != 0 {
+			return fmt.Errorf("standard modulus for level %d is not 1 mod 2m", level)
+		}
+	}
+	return nil
+}
+
+// PrimeSearchStep is one attempt of the modulus search: the dimension m tried, the bit size the
+// search ran below, how many NTT-friendly candidates were examined and the prime selected, if any.
+// Upstream is set when the downstream primes ran out and q was taken from just above 2^BitSize.
+type PrimeSearchStep struct {
+	M          int
+	BitSize    int
+	Candidates int
+	Upstream   bool
+	Q          *big.Int
+}
+
+// PrimeSearchRecord is the trajectory of a seeded modulus search. Running
+// CalculateParametersSeeded again with the same level and seed reproduces it exactly.
+type PrimeSearchRecord struct {
+	Level SecurityLevel
+	Seed  int
+	Steps []PrimeSearchStep
+}
+
+// CalculateParameters computes parameter values according to the paper's formulas.
+// Built-in levels use the embedded modulus instead of searching for one.
+func CalculateParameters(lambda SecurityLevel) Parameters {
+	if q := standardModulus(lambda); q != nil {
+		m, logQ := dimensionsFor(lambda)
+		return buildParameters(lambda, m, logQ, q)
+	}
+	params, _, err := calculateParametersSearch(lambda, 0)
 	if err != nil {
-		// If not found, calculate and register it
-		params = CalculateParameters(level)
-		RegisterParameterSet(params)
+		return Parameters{}
 	}
 	return params
 }
 
-// CalculateParameters computes parameter values according to the paper's formulas
-func CalculateParameters(lambda SecurityLevel) Parameters {
+// CalculateParametersSeeded computes a custom parameter set by searching for the modulus: seed
+// selects the seed-th prime in the search order, so seed 0 finds the same q as the built-in sets.
+// The returned set has no wire ID and its name includes the seed; the record lists every step.
+func CalculateParametersSeeded(lambda SecurityLevel, seed int) (Parameters, PrimeSearchRecord, error) {
+	if seed < 0 {
+		return Parameters{}, PrimeSearchRecord{}, fmt.Errorf("%w: negative seed %d", ErrParameterValidation, seed)
+	}
+	params, record, err := calculateParametersSearch(lambda, seed)
+	if err != nil {
+		return Parameters{}, record, err
+	}
+	params.Name = fmt.Sprintf("OWChCCA-%d-seed%d", lambda, seed)
+	params.id = 0
+	return params, record, nil
+}
+
+// dimensionsFor returns the smallest power-of-two m above 6n·log n and the matching log q
+func dimensionsFor(lambda SecurityLevel) (m, logQ int) {
+	n := 8 * int(lambda)
+	logN := int(math.Log2(float64(n)))
+	if math.Pow(2, float64(logN)) != float64(n) {
+		logN++
+	}
+	minM := 6*n*logN + 1
+	minLogM := int(math.Log2(float64(minM)))
+	if math.Pow(2, float64(minLogM)) != float64(minM) {
+		minLogM++
+	}
+	m = int(math.Exp2(float64(minLogM)))
+	return m, max(60, min(62, m/(2*n)))
+}
+
+// calculateParametersSearch runs the modulus search, taking the seed-th downstream prime for
+// each candidate m until one is found
+func calculateParametersSearch(lambda SecurityLevel, seed int) (Parameters, PrimeSearchRecord, error) {
 	// Convert to integer for calculations
 	level := int(lambda)
+	record := PrimeSearchRecord{Level: lambda, Seed: seed}
 
 	// Calculate parameters according to the formulas
 	// n := 70 * level
 	n := 8 * level
-	k := level
 
 	logN := int(math.Log2(float64(n)))
 	if math.Pow(2, float64(logN)) != float64(n) {
@@ -189,41 +599,70 @@ func CalculateParameters(lambda SecurityLevel) Parameters {
 	// if let m := 2n Log q
 	// then 12n Log n < m <= 14n Log n
 	// Calculate minimal q that satisfies n^6 < q ≤ n^7
-	var m, logQ int
-	var q *big.Int
-	var err error
 	// minLogQ := 6*logN + 1
 	// maxLogQ := 7 * logN
 	// minM := 12*n*logN + 1
 	// maxM := 14 * n * logN
-	minM := 6*n*logN + 1
 	maxM := 7 * n * logN
-	// Find Closest M with power of 2
-	minLogM := int(math.Log2(float64(minM)))
-	if math.Pow(2, float64(minLogM)) != float64(minM) {
-		minLogM++
-	}
 	maxLogM := int(math.Log2(float64(maxM)))
 	if math.Pow(2, float64(maxLogM)) != float64(maxM) {
 		maxLogM++
 	}
 
-	for m = int(math.Exp2(float64(minLogM))); m <= int(math.Exp2(float64(maxLogM))); m = m * 2 {
+	m, _ := dimensionsFor(lambda)
+	for ; m <= int(math.Exp2(float64(maxLogM))); m = m * 2 {
 		// find q
 		// logQ = m / (2 * n)
-		logQ = max(60, min(62, m/(2*n)))
-		nttGenerator := NewBigNTTFriendlyPrimesGenerator(logQ+1, new(big.Int).SetInt64(int64(2*m)))
-		q, err = nttGenerator.NextDownstreamPrime()
-		if err != nil {
-			if m != maxM {
-				continue
-			} else {
-				return Parameters{}
+		logQ := max(60, min(62, m/(2*n)))
+		step, err := searchModulus(logQ, m, seed)
+		record.Steps = append(record.Steps, step)
+		if err == nil {
+			if step.Upstream {
+				logQ++
 			}
-		} else {
+			return buildParameters(lambda, m, logQ, step.Q), record, nil
+		}
+	}
+
+	return Parameters{}, record, fmt.Errorf("%w: no modulus found for level %d with seed %d", ErrParameterValidation, lambda, seed)
+}
+
+// searchModulus finds the seed-th NTT-friendly prime for dimension m, walking down from
+// 2^(logQ+1). If the (logQ+1)-bit primes run out it falls back to the primes just above
+// 2^(logQ+1), which are one bit longer; step.Upstream records that.
+func searchModulus(logQ, m, seed int) (PrimeSearchStep, error) {
+	nttGenerator := NewBigNTTFriendlyPrimesGenerator(logQ+1, new(big.Int).SetInt64(int64(2*m)))
+	step := PrimeSearchStep{M: m, BitSize: logQ + 1}
+
+	var q *big.Int
+	var err error
+	found := 0
+	for ; found <= seed; found++ {
+		if q, err = nttGenerator.NextDownstreamPrime(); err != nil {
 			break
 		}
 	}
+	if err != nil {
+		step.Upstream = true
+		for err = nil; found <= seed; found++ {
+			if q, err = nttGenerator.NextUpstreamPrime(); err != nil {
+				break
+			}
+		}
+	}
+	step.Candidates = nttGenerator.DownstreamCandidates() + nttGenerator.UpstreamCandidates()
+	if err != nil {
+		return step, err
+	}
+	step.Q = new(big.Int).Set(q)
+	return step, nil
+}
+
+// buildParameters derives the remaining parameters of level lambda from m, log q and q
+func buildParameters(lambda SecurityLevel, m, logQ int, q *big.Int) Parameters {
+	level := int(lambda)
+	n := 8 * level
+	k := level
 
 	// Gaussian parameters
 	sqrtN := math.Sqrt(float64(n))
@@ -236,8 +675,11 @@ func CalculateParameters(lambda SecurityLevel) Parameters {
 	alphaPrime := math.Pow(float64(n), 2.5) * float64(m)
 
 	param := Parameters{
-		Name:          fmt.Sprintf("OWChCCA-%d", lambda),
-		SecurityLevel: lambda,
+		Name:           fmt.Sprintf("OWChCCA-%d", lambda),
+		id:             builtinParamIDs[lambda],
+		SecurityLevel:  lambda,
+		SecurityClass:  securityClassFor(lambda),
+		NTTAccelerated: arithmetic.CheckRingParameters(m, q) == nil,
 		LatticeParams: LatticeParameters{
 			N:      n,
 			M:      m,
@@ -289,15 +731,7 @@ func (p Parameters) PrivateKeySize() int {
 }
 
 func (p Parameters) CiphertextSize() int {
-	q := p.LatticeParams.Q
-	m := p.LatticeParams.M
-	level := int(p.SecurityLevel)
-	modulus := new(big.Int).Set(q)
-	elementSize := (modulus.BitLen() + 7) / 8
-	cbSize := level / 8
-	xSize := 4 + m*elementSize
-	hatHSize := 4 + level*elementSize
-	return 2*cbSize + xSize + 2*hatHSize
+	return p.CiphertextLayout().Size()
 }
 
 func (p Parameters) SharedKeySize() int {
@@ -318,9 +752,19 @@ func (p Parameters) Validate() error {
 	alphaPrime := p.GaussianParams.AlphaPrime
 	eta := p.GaussianParams.Eta
 
-	// Check basic parameter ranges
+	// Check basic parameter ranges; hand-built structs would otherwise fail deep inside the
+	// matrix constructors
 	if n <= 0 || m <= 0 || lambda <= 0 {
-		return fmt.Errorf("invalid dimension parameters")
+		return fmt.Errorf("invalid dimension parameters: n=%d, m=%d, lambda=%d must be positive", n, m, lambda)
+	}
+
+	// Check that m and q define an NTT-friendly ring, or at least a usable modulus
+	if err := p.checkRing(); err != nil {
+		return err
+	}
+	// A ring check alone admits q = 1, which is 1 mod 2m for every m
+	if q == nil || q.Cmp(big.NewInt(1)) <= 0 {
+		return fmt.Errorf("%w: modulus q must be set and greater than 1", ErrParameterValidation)
 	}
 
 	//// Check that n = 70λ
@@ -374,10 +818,33 @@ func (p Parameters) Validate() error {
 		return fmt.Errorf("alphaPrime should be n^2.5 * m")
 	}
 
-	_, err := ring.NewRing(m, []uint64{q.Uint64()})
-	if err != nil {
-		return fmt.Errorf("error creating ring: %v", err)
+	// Compressed coefficients must keep fewer bits than q has, and at least one
+	if bits := p.GaussianParams.CompressionBits; bits < 0 || bits >= q.BitLen()-1 {
+		return fmt.Errorf("compressionBits should be 0 or in [1, %d)", q.BitLen()-1)
+	}
+	if size := p.KeyParams.SharedKeySize; size > MaxSharedKeySize {
+		return fmt.Errorf("sharedKeySize %d exceeds MaxSharedKeySize (%d)", size, MaxSharedKeySize)
+	}
+	if size := p.KeyParams.CiphertextSize; size != 0 && size != p.CiphertextSize() {
+		return fmt.Errorf("ciphertextSize %d does not match the ciphertext layout (%d bytes)", size, p.CiphertextSize())
 	}
 
 	return nil
 }
+
+// checkRing is the pre-flight run before building the ring of degree m modulo q, so that an
+// unusable modulus is reported as ErrParameterValidation instead of failing inside lattigo.
+// Sets that are not NTTAccelerated only need a modulus the fallback arithmetic can handle.
+func (p Parameters) checkRing() error {
+	q := p.LatticeParams.Q
+	if p.NTTAccelerated {
+		if err := arithmetic.CheckRingParameters(p.LatticeParams.M, q); err != nil {
+			return fmt.Errorf("%w: %v", ErrParameterValidation, err)
+		}
+		return nil
+	}
+	if q == nil || q.Cmp(big.NewInt(1)) <= 0 || q.BitLen() > arithmetic.MaxModulusBits {
+		return fmt.Errorf("%w: modulus must be greater than 1 and below 2^%d", ErrParameterValidation, arithmetic.MaxModulusBits)
+	}
+	return nil
+}
@@ -1,8 +1,16 @@
 package pkg
 
 import (
+	"errors"
+	"math/big"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/tuneinsight/lattigo/v6/ring"
 )
 
 func TestCalculateParametersDefaultLevels(t *testing.T) {
@@ -20,3 +28,496 @@ func TestCalculateParametersDefaultLevels(t *testing.T) {
 		})
 	}
 }
+
+func TestParameterSetIDs(t *testing.T) {
+	for level, id := range map[SecurityLevel]uint16{Security16: ParamIDOWChCCA16, Security32: ParamIDOWChCCA32, Security64: ParamIDOWChCCA64} {
+		params, err := ParameterSetByID(id)
+		if err != nil {
+			t.Fatalf("ParameterSetByID(%d) failed: %v", id, err)
+		}
+		if params.SecurityLevel != level || params.ID() != id {
+			t.Fatalf("ParameterSetByID(%d) returned %s with ID %d", id, params.Name, params.ID())
+		}
+	}
+	if _, err := ParameterSetByID(0); err == nil {
+		t.Fatalf("ParameterSetByID(0) should fail")
+	}
+	if _, err := ParameterSetByID(0xFFFF); err == nil {
+		t.Fatalf("ParameterSetByID of an unknown ID should fail")
+	}
+
+	custom := GetDefaultParameterSet()
+	custom.Name = "OWChCCA-16-custom-id-test"
+	if err := RegisterParameterSetWithID(custom, ParamIDOWChCCA16); err == nil {
+		t.Fatalf("registering a different set under an existing ID should fail")
+	}
+	if _, err := GetParameterSet(custom.Name); err == nil {
+		t.Fatalf("rejected parameter set should not be registered")
+	}
+	if err := RegisterParameterSetWithID(custom, 0xFFF0); err != nil {
+		t.Fatalf("RegisterParameterSetWithID failed: %v", err)
+	}
+	byID, err := ParameterSetByID(0xFFF0)
+	if err != nil || byID.Name != custom.Name {
+		t.Fatalf("ParameterSetByID(0xFFF0) = %s, %v", byID.Name, err)
+	}
+
+	// A renamed copy of a built-in set is registered without the ID it inherited
+	renamed := GetDefaultParameterSet()
+	renamed.Name = "OWChCCA-16-renamed-test"
+	if err := RegisterParameterSet(renamed); err != nil {
+		t.Fatalf("RegisterParameterSet of a renamed copy failed: %v", err)
+	}
+	defer func() {
+		globalRegistry.mu.Lock()
+		delete(globalRegistry.paramSets, renamed.Name)
+		globalRegistry.mu.Unlock()
+	}()
+	if registered, err := GetParameterSet(renamed.Name); err != nil || registered.ID() != 0 {
+		t.Fatalf("renamed copy registered with ID %d: %v", registered.ID(), err)
+	}
+	if owner, err := ParameterSetByID(ParamIDOWChCCA16); err != nil || owner.Name != "OWChCCA-16" {
+		t.Fatalf("ParameterSetByID(%d) = %s, %v", ParamIDOWChCCA16, owner.Name, err)
+	}
+}
+
+func TestDefaultParameters(t *testing.T) {
+	params := DefaultParameters(Security32)
+	if params.Name != "OWChCCA-32" || params.ID() != ParamIDOWChCCA32 {
+		t.Fatalf("DefaultParameters(Security32) = %s (ID %d)", params.Name, params.ID())
+	}
+	params, err := LookupParameters(Security32)
+	if err != nil || params.Name != "OWChCCA-32" || params.ID() != ParamIDOWChCCA32 {
+		t.Fatalf("LookupParameters(Security32) = %s (ID %d), %v", params.Name, params.ID(), err)
+	}
+}
+
+func TestStandardModuliMatchSearch(t *testing.T) {
+	if err := checkStandardModuli(); err != nil {
+		t.Fatalf("checkStandardModuli failed: %v", err)
+	}
+	for level := range standardModuli {
+		searched, _, err := calculateParametersSearch(level, 0)
+		if err != nil {
+			t.Fatalf("level %d: search failed: %v", level, err)
+		}
+		embedded := CalculateParameters(level)
+		if !reflect.DeepEqual(searched, embedded) {
+			t.Fatalf("level %d: search found q=%v, embedded q=%v", level, searched.LatticeParams.Q, embedded.LatticeParams.Q)
+		}
+	}
+}
+
+func TestCalculateParametersSeeded(t *testing.T) {
+	base, record, err := CalculateParametersSeeded(Security16, 0)
+	if err != nil {
+		t.Fatalf("CalculateParametersSeeded failed: %v", err)
+	}
+	if base.LatticeParams.Q.Cmp(standardModulus(Security16)) != 0 {
+		t.Fatalf("seed 0 found q=%v, expected the standard modulus", base.LatticeParams.Q)
+	}
+	if base.ID() != 0 || base.Name != "OWChCCA-16-seed0" {
+		t.Fatalf("seeded set has name %q and ID %d", base.Name, base.ID())
+	}
+	last := record.Steps[len(record.Steps)-1]
+	if last.Q.Cmp(base.LatticeParams.Q) != 0 || last.M != base.LatticeParams.M || last.Candidates < 1 {
+		t.Fatalf("record does not end with the chosen modulus: %+v", last)
+	}
+
+	seeded, record1, err := CalculateParametersSeeded(Security16, 3)
+	if err != nil {
+		t.Fatalf("CalculateParametersSeeded failed: %v", err)
+	}
+	if err := seeded.Validate(); err != nil {
+		t.Fatalf("seeded parameters invalid: %v", err)
+	}
+	if seeded.LatticeParams.Q.Cmp(base.LatticeParams.Q) >= 0 {
+		t.Fatalf("seed 3 should select a smaller downstream prime")
+	}
+	again, record2, err := CalculateParametersSeeded(Security16, 3)
+	if err != nil || !reflect.DeepEqual(seeded, again) || !reflect.DeepEqual(record1, record2) {
+		t.Fatalf("seeded search is not reproducible: %v", err)
+	}
+
+	if _, _, err := CalculateParametersSeeded(Security16, -1); !errors.Is(err, ErrParameterValidation) {
+		t.Fatalf("negative seed: got %v", err)
+	}
+}
+
+func TestPrimesGeneratorDeterministic(t *testing.T) {
+	nthRoot := big.NewInt(1 << 14)
+	gen := NewBigNTTFriendlyPrimesGenerator(61, nthRoot)
+	down, err := gen.NextDownstreamPrimes(4)
+	if err != nil {
+		t.Fatalf("NextDownstreamPrimes failed: %v", err)
+	}
+	up, err := gen.NextUpstreamPrimes(4)
+	if err != nil {
+		t.Fatalf("NextUpstreamPrimes failed: %v", err)
+	}
+	for i := 1; i < len(down); i++ {
+		if down[i].Cmp(down[i-1]) >= 0 || up[i].Cmp(up[i-1]) <= 0 {
+			t.Fatalf("primes are not strictly ordered: down %v, up %v", down, up)
+		}
+	}
+	one := big.NewInt(1)
+	for _, q := range append(down, up...) {
+		if !q.ProbablyPrime(20) || new(big.Int).Mod(q, nthRoot).Cmp(one) != 0 {
+			t.Fatalf("%v is not an NTT-friendly prime", q)
+		}
+	}
+	candidates := gen.DownstreamCandidates()
+	if candidates < len(down) {
+		t.Fatalf("%d downstream candidates for %d primes", candidates, len(down))
+	}
+
+	gen.Reset()
+	if gen.DownstreamCandidates() != 0 || gen.UpstreamCandidates() != 0 {
+		t.Fatalf("Reset did not clear the candidate counters")
+	}
+	again, _ := gen.NextDownstreamPrimes(4)
+	fresh, _ := NewBigNTTFriendlyPrimesGenerator(61, nthRoot).NextDownstreamPrimes(4)
+	if !reflect.DeepEqual(down, again) || !reflect.DeepEqual(down, fresh) {
+		t.Fatalf("sequence after Reset or from a new generator differs")
+	}
+	if gen.DownstreamCandidates() != candidates {
+		t.Fatalf("Reset changed the search trajectory")
+	}
+}
+
+func TestNextAlternatingPrimes(t *testing.T) {
+	// Below 2^10 the candidates 1 mod 64 hold three primes, above it four
+	gen := NewBigNTTFriendlyPrimesGenerator(10, big.NewInt(64))
+	primes, err := gen.NextAlternatingPrimes(8)
+	if err == nil {
+		t.Fatalf("expected exhaustion after 7 primes, got %v", primes)
+	}
+	want := []int64{1153, 1217, 769, 641, 1409, 577, 1601}
+	if len(primes) != len(want) {
+		t.Fatalf("got %v, expected %v", primes, want)
+	}
+	center := big.NewInt(1 << 10)
+	prevDist := new(big.Int)
+	for i, q := range primes {
+		if q.Int64() != want[i] {
+			t.Fatalf("got %v, expected %v", primes, want)
+		}
+		dist := new(big.Int).Sub(q, center)
+		dist.Abs(dist)
+		if dist.Cmp(prevDist) < 0 {
+			t.Fatalf("prime %v is closer to 2^10 than its predecessor", q)
+		}
+		prevDist = dist
+	}
+
+	// Primes buffered by the alternating walk are not skipped by the one-directional calls
+	gen.Reset()
+	if _, err := gen.NextAlternatingPrimes(1); err != nil {
+		t.Fatalf("NextAlternatingPrimes failed: %v", err)
+	}
+	if q, err := gen.NextDownstreamPrime(); err != nil || q.Int64() != 769 {
+		t.Fatalf("NextDownstreamPrime after alternating = %v, %v; expected 769", q, err)
+	}
+}
+
+func TestNTTFriendlyPrimesBuildRing(t *testing.T) {
+	const m = 8192
+	nthRoot := big.NewInt(2 * m)
+	primes, err := NewBigNTTFriendlyPrimesGenerator(60, nthRoot).NextAlternatingPrimes(4)
+	if err != nil {
+		t.Fatalf("NextAlternatingPrimes failed: %v", err)
+	}
+	for _, q := range primes {
+		if !q.ProbablyPrime(20) || !IsNTTFriendly(q, nthRoot) {
+			t.Fatalf("%v is not an NTT-friendly prime", q)
+		}
+		if new(big.Int).Mod(q, nthRoot).Int64() != 1 {
+			t.Fatalf("%v is not 1 mod %v", q, nthRoot)
+		}
+		if _, err := ring.NewRing(m, []uint64{q.Uint64()}); err != nil {
+			t.Fatalf("ring.NewRing(%d, %v) failed: %v", m, q, err)
+		}
+	}
+
+	if IsNTTFriendly(big.NewInt(1217), big.NewInt(128)) {
+		t.Fatalf("1217 is not 1 mod 128")
+	}
+	if IsNTTFriendly(big.NewInt(1089), big.NewInt(64)) {
+		t.Fatalf("1089 = 33^2 is not prime")
+	}
+	if !IsNTTFriendly(big.NewInt(1153), big.NewInt(64)) {
+		t.Fatalf("1153 is prime and 1 mod 64")
+	}
+}
+
+func TestSearchModulusFallsBackUpstream(t *testing.T) {
+	// Three 10-bit primes are 1 mod 64, so the fifth choice comes from above 2^10
+	step, err := searchModulus(9, 32, 4)
+	if err != nil {
+		t.Fatalf("searchModulus failed: %v", err)
+	}
+	if !step.Upstream || step.Q.Int64() != 1217 {
+		t.Fatalf("got %+v, expected the second upstream prime 1217", step)
+	}
+	if step, err := searchModulus(9, 32, 2); err != nil || step.Upstream || step.Q.Int64() != 577 {
+		t.Fatalf("seed 2 should stay downstream: %+v, %v", step, err)
+	}
+}
+
+func TestRingPreflight(t *testing.T) {
+	base := GetDefaultParameterSet()
+	q := base.LatticeParams.Q
+	tests := []struct {
+		name   string
+		m      int
+		q      *big.Int
+		reason string
+	}{
+		{"m not a power of two", 12288, q, "not a power of two"},
+		{"m below 16", 8, big.NewInt(17), "less than 16"},
+		{"q too large", base.LatticeParams.M, new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 62), big.NewInt(1)), "must be below 2^61"},
+		{"q not 1 mod 2m", base.LatticeParams.M, new(big.Int).Add(q, big.NewInt(2)), "not 1 mod 2*degree"},
+		{"q not set", base.LatticeParams.M, nil, "modulus must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := base
+			params.Name = "OWChCCA-ring-preflight-test"
+			params.LatticeParams.M = tt.m
+			params.LatticeParams.Q = tt.q
+
+			check := func(op string, err error) {
+				t.Helper()
+				if !errors.Is(err, ErrParameterValidation) {
+					t.Fatalf("%s: expected ErrParameterValidation, got %v", op, err)
+				}
+				if !strings.Contains(err.Error(), tt.reason) {
+					t.Fatalf("%s: error %q does not name the violation %q", op, err, tt.reason)
+				}
+			}
+			check("Validate", params.Validate())
+			check("RegisterParameterSetWithID", RegisterParameterSetWithID(params, 0))
+			if _, err := GetParameterSet(params.Name); err == nil {
+				t.Fatalf("invalid parameter set should not be registered")
+			}
+
+			kem := OwChCCAKEM{Params: params}
+			_, _, err := kem.GenerateKeyPair(seededReader("ring preflight"))
+			check("GenerateKeyPair", err)
+			_, _, err = kem.EncapsulateFrom(&PublicKey{Params: params}, seededReader("ring preflight"))
+			check("Encapsulate", err)
+		})
+	}
+}
+
+func TestSecurityClasses(t *testing.T) {
+	for name, want := range map[string]SecurityClass{
+		"OWChCCA-16": SecurityClassToy,
+		"OWChCCA-32": SecurityClassToy,
+		"OWChCCA-64": SecurityClassExperimental,
+	} {
+		params, err := GetParameterSet(name)
+		if err != nil {
+			t.Fatalf("GetParameterSet(%s) failed: %v", name, err)
+		}
+		if params.SecurityClass != want {
+			t.Fatalf("%s is %v, want %v", name, params.SecurityClass, want)
+		}
+		if !slices.Contains(ListParameterSetsByClass(want), name) {
+			t.Fatalf("ListParameterSetsByClass(%v) does not list %s", want, name)
+		}
+	}
+	if got := CalculateParameters(Security128).SecurityClass; got != SecurityClassStandard {
+		t.Fatalf("OWChCCA-128 is %v, want standard", got)
+	}
+}
+
+func TestToyParameterGating(t *testing.T) {
+	params, _ := GetParameterSet("OWChCCA-16")
+	kem := OwChCCAKEM{Params: params}
+	if _, _, err := kem.GenerateKeyPair(seededReader("toy gating")); !errors.Is(err, ErrToyParameters) {
+		t.Fatalf("GenerateKeyPair with a toy set: expected ErrToyParameters, got %v", err)
+	}
+
+	allowed := kem.WithAllowToyParameters(true)
+	pk, sk, err := allowed.GenerateKeyPair(seededReader("toy gating"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair with toy parameters allowed failed: %v", err)
+	}
+	if err := CheckKeyPair(pk, sk); err != nil {
+		t.Fatalf("CheckKeyPair failed: %v", err)
+	}
+	if kem.allowToy {
+		t.Fatalf("WithAllowToyParameters should not modify the receiver")
+	}
+}
+
+func TestDefaultParameterSetPrefersStandard(t *testing.T) {
+	if got := GetDefaultParameterSet(); got.Name != "OWChCCA-16" {
+		t.Fatalf("default set is %s before a standard set is registered", got.Name)
+	}
+
+	standard := GetDefaultParameterSet()
+	standard.Name = "OWChCCA-standard-default-test"
+	standard.SecurityClass = SecurityClassStandard
+	if err := RegisterParameterSetWithID(standard, 0); err != nil {
+		t.Fatalf("RegisterParameterSetWithID failed: %v", err)
+	}
+	t.Cleanup(func() {
+		globalRegistry.mu.Lock()
+		delete(globalRegistry.paramSets, standard.Name)
+		globalRegistry.mu.Unlock()
+	})
+
+	if got := GetDefaultParameterSet(); got.Name != standard.Name {
+		t.Fatalf("default set is %s, want the standard set %s", got.Name, standard.Name)
+	}
+	if err := SetDefaultParameterSet("OWChCCA-16"); !errors.Is(err, ErrToyParameters) {
+		t.Fatalf("making a toy set the default: expected ErrToyParameters, got %v", err)
+	}
+	if err := SetDefaultParameterSet("OWChCCA-64"); err != nil {
+		t.Fatalf("making an experimental set the default failed: %v", err)
+	}
+	t.Cleanup(func() {
+		globalRegistry.mu.Lock()
+		globalRegistry.defaultSet = "OWChCCA-16"
+		globalRegistry.mu.Unlock()
+	})
+	if got := GetDefaultParameterSet(); got.Name != "OWChCCA-64" {
+		t.Fatalf("default set is %s, want OWChCCA-64", got.Name)
+	}
+}
+
+func TestSizeConstants(t *testing.T) {
+	for name, want := range map[string]KeyParameters{
+		"OWChCCA-16": {OWChCCA16PublicKeySize, OWChCCA16PrivateKeySize, OWChCCA16CiphertextSize, OWChCCA16SharedKeySize},
+		"OWChCCA-32": {OWChCCA32PublicKeySize, OWChCCA32PrivateKeySize, OWChCCA32CiphertextSize, OWChCCA32SharedKeySize},
+		"OWChCCA-64": {OWChCCA64PublicKeySize, OWChCCA64PrivateKeySize, OWChCCA64CiphertextSize, OWChCCA64SharedKeySize},
+	} {
+		params, err := GetParameterSet(name)
+		if err != nil {
+			t.Fatalf("GetParameterSet(%s) failed: %v", name, err)
+		}
+		if params.KeyParams != want {
+			t.Errorf("%s: constants %+v drifted from KeyParams %+v", name, want, params.KeyParams)
+		}
+	}
+
+	for _, name := range ListParameterSets() {
+		params, _ := GetParameterSet(name)
+		if params.KeyParams.SharedKeySize > MaxSharedKeySize {
+			t.Errorf("%s: shared key size %d exceeds MaxSharedKeySize", name, params.KeyParams.SharedKeySize)
+		}
+	}
+	oversized := GetDefaultParameterSet()
+	oversized.KeyParams.SharedKeySize = MaxSharedKeySize + 1
+	if err := oversized.Validate(); err == nil {
+		t.Fatalf("Validate accepted a shared key size above MaxSharedKeySize")
+	}
+}
+
+func TestValidateRejectsUnsetFields(t *testing.T) {
+	mutations := map[string]func(*Parameters){
+		"zero n":           func(p *Parameters) { p.LatticeParams.N = 0 },
+		"negative m":       func(p *Parameters) { p.LatticeParams.M = -1 },
+		"zero lambda":      func(p *Parameters) { p.LatticeParams.Lambda = 0 },
+		"nil modulus":      func(p *Parameters) { p.LatticeParams.Q = nil },
+		"modulus one":      func(p *Parameters) { p.LatticeParams.Q = big.NewInt(1) },
+		"negative modulus": func(p *Parameters) { p.LatticeParams.Q = big.NewInt(-12289) },
+	}
+	for name, mutate := range mutations {
+		params := GetDefaultParameterSet()
+		mutate(&params)
+		if err := params.Validate(); err == nil {
+			t.Errorf("Validate accepted parameters with %s", name)
+		}
+	}
+
+	// Parsing under a hand-built set with a zero dimension fails instead of building an empty key
+	bad := GetDefaultParameterSet()
+	bad.LatticeParams.M = 0
+	pk := PublicKey{Params: bad}
+	if err := pk.UnmarshalBinary(make([]byte, bad.KeyParams.PublicKeySize)); !errors.Is(err, ErrDeserializationError) {
+		t.Errorf("UnmarshalBinary with m = 0: got %v", err)
+	}
+}
+
+func TestListParametersOrder(t *testing.T) {
+	sets := ListParameters()
+	names := ListParameterSets()
+	if len(sets) != len(names) {
+		t.Fatalf("ListParameters has %d sets, ListParameterSets %d names", len(sets), len(names))
+	}
+	for i := range sets {
+		if sets[i].Name != names[i] {
+			t.Fatalf("position %d: ListParameters has %s, ListParameterSets %s", i, sets[i].Name, names[i])
+		}
+		if i > 0 && compareParameters(sets[i-1], sets[i]) >= 0 {
+			t.Fatalf("%s sorts before %s", sets[i-1].Name, sets[i].Name)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if again := ListParameterSets(); !slices.Equal(again, names) {
+			t.Fatalf("ListParameterSets is not stable: %v, then %v", names, again)
+		}
+	}
+
+	var ranged []string
+	RangeParameterSets(func(p Parameters) bool {
+		ranged = append(ranged, p.Name)
+		return true
+	})
+	if !slices.Equal(ranged, names) {
+		t.Fatalf("RangeParameterSets visited %v, want %v", ranged, names)
+	}
+	var visited int
+	RangeParameterSets(func(Parameters) bool {
+		visited++
+		return visited < 2
+	})
+	if visited != 2 {
+		t.Fatalf("RangeParameterSets visited %d sets after the callback returned false", visited)
+	}
+}
+
+func TestRangeParameterSetsConcurrentRegistration(t *testing.T) {
+	base, err := GetParameterSet("OWChCCA-16")
+	if err != nil {
+		t.Fatalf("GetParameterSet failed: %v", err)
+	}
+	var registered []string
+	t.Cleanup(func() {
+		globalRegistry.mu.Lock()
+		for _, name := range registered {
+			delete(globalRegistry.paramSets, name)
+		}
+		globalRegistry.mu.Unlock()
+	})
+
+	before := len(ListParameterSets())
+	visited := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Registering from inside the callback takes the write lock, which would deadlock if
+		// Range held the read lock across callbacks
+		RangeParameterSets(func(Parameters) bool {
+			extra := base
+			extra.Name = "OWChCCA-range-test-" + strconv.Itoa(visited)
+			if err := RegisterParameterSetWithID(extra, 0); err != nil {
+				t.Errorf("RegisterParameterSetWithID failed: %v", err)
+			}
+			registered = append(registered, extra.Name)
+			visited++
+			return true
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("RangeParameterSets deadlocked against RegisterParameterSetWithID")
+	}
+	if visited != before {
+		t.Fatalf("RangeParameterSets visited %d sets, want the %d registered when it started", visited, before)
+	}
+}
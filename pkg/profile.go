@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"context"
+	"runtime/pprof"
+	"sync/atomic"
+	"unsafe"
+)
+
+// phaseLabel is the pprof label key under which Encapsulate and Decapsulate record their phase
+const phaseLabel = "owchcca_phase"
+
+// Phases of encapsulation and decapsulation, as they appear in the phaseLabel label
+const (
+	phaseSeedExpansion = "seed-expansion"
+	phaseMatVec        = "matvec"
+	phaseHash          = "hash"
+	phaseSerialization = "serialization"
+)
+
+var profilingEnabled atomic.Bool
+
+// EnableProfiling turns pprof labelling of the Encapsulate and Decapsulate phases on or off.
+// While enabled, CPU profiles attribute samples to seed expansion, matrix-vector products,
+// hashing and serialization under the "owchcca_phase" label. Encapsulator.EncapsulateContext
+// and Decapsulator.DecapsulateContext add the phase to the labels of their context; the other
+// entry points label the phases on their own. Every entry point leaves the goroutine with the
+// labels it had on entry, so labels set with pprof.Do survive either way. Profiling is
+// disabled by default.
+func EnableProfiling(enabled bool) {
+	profilingEnabled.Store(enabled)
+}
+
+// phaseLabels holds the label set of each phase on an unlabelled context, so that starting a
+// phase from context.Background() does not allocate
+var phaseLabels = map[string]context.Context{
+	phaseSeedExpansion: pprof.WithLabels(context.Background(), pprof.Labels(phaseLabel, phaseSeedExpansion)),
+	phaseMatVec:        pprof.WithLabels(context.Background(), pprof.Labels(phaseLabel, phaseMatVec)),
+	phaseHash:          pprof.WithLabels(context.Background(), pprof.Labels(phaseLabel, phaseHash)),
+	phaseSerialization: pprof.WithLabels(context.Background(), pprof.Labels(phaseLabel, phaseSerialization)),
+}
+
+// setPhase labels the current goroutine with the labels of ctx plus phase, replacing the
+// previous phase. When profiling is disabled it only loads a flag.
+func setPhase(ctx context.Context, phase string) {
+	if profilingEnabled.Load() {
+		if ctx == context.Background() {
+			pprof.SetGoroutineLabels(phaseLabels[phase])
+			return
+		}
+		pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels(phaseLabel, phase)))
+	}
+}
+
+// labelScope holds the labels a goroutine had when it entered Encapsulate or Decapsulate
+type labelScope struct {
+	labels unsafe.Pointer
+}
+
+// enterPhases records the labels of the current goroutine; callers defer restore on the result
+// once, so that early returns are covered
+func enterPhases() labelScope {
+	return labelScope{labels: getProfLabel()}
+}
+
+// restore gives the goroutine back the labels recorded by enterPhases. When no phase was
+// labelled it only compares two pointers.
+func (s labelScope) restore() {
+	if getProfLabel() != s.labels {
+		setProfLabel(s.labels)
+	}
+}
+
+// getProfLabel and setProfLabel read and replace the label set of the current goroutine.
+// runtime/pprof offers no getter, so they are linked to the runtime functions behind
+// pprof.SetGoroutineLabels, which the runtime keeps available to other packages (go.dev/issue/67401).
+//
+//go:linkname getProfLabel runtime/pprof.runtime_getProfLabel
+func getProfLabel() unsafe.Pointer
+
+//go:linkname setProfLabel runtime/pprof.runtime_setProfLabel
+func setProfLabel(labels unsafe.Pointer)
@@ -0,0 +1,155 @@
+package pkg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/sha3"
+)
+
+// ParamReport summarizes the sizes and, optionally, the measured speed of a parameter set
+type ParamReport struct {
+	Name          string
+	ID            uint16
+	SecurityLevel SecurityLevel
+
+	PublicKeySize  int
+	PrivateKeySize int
+	CiphertextSize int
+	SharedKeySize  int
+
+	// KeyGenMemory estimates the bytes needed to hold A during key generation: n·m·elementSize
+	KeyGenMemory int
+
+	// Timings holds measured median durations; nil unless WithMeasurement was given
+	Timings *ReportTimings
+}
+
+// ReportTimings holds the median duration of each operation over Iterations runs
+type ReportTimings struct {
+	Iterations  int
+	KeyGen      time.Duration
+	Encapsulate time.Duration
+	Decapsulate time.Duration
+}
+
+// ReportOption configures Report and ReportAll
+type ReportOption func(*reportOptions)
+
+type reportOptions struct {
+	iterations int
+}
+
+// WithMeasurement makes Report time key generation, encapsulation and decapsulation over
+// iterations runs. Key generation and encapsulation read from a SHAKE256 stream seeded with the
+// parameter set name, so every run measures the same keys and ciphertexts.
+func WithMeasurement(iterations int) ReportOption {
+	return func(o *reportOptions) {
+		o.iterations = iterations
+	}
+}
+
+// Report returns the sizes of the artifacts produced under params and, with WithMeasurement,
+// the median time of each operation
+func Report(params Parameters, opts ...ReportOption) (ParamReport, error) {
+	var o reportOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	elementSize := (params.LatticeParams.Q.BitLen() + 7) / 8
+	report := ParamReport{
+		Name:           params.Name,
+		ID:             params.ID(),
+		SecurityLevel:  params.SecurityLevel,
+		PublicKeySize:  params.KeyParams.PublicKeySize,
+		PrivateKeySize: params.KeyParams.PrivateKeySize,
+		CiphertextSize: params.KeyParams.CiphertextSize,
+		SharedKeySize:  params.KeyParams.SharedKeySize,
+		KeyGenMemory:   params.LatticeParams.N * params.LatticeParams.M * elementSize,
+	}
+
+	if o.iterations > 0 {
+		timings, err := measure(params, o.iterations)
+		if err != nil {
+			return ParamReport{}, fmt.Errorf("measuring %s: %w", params.Name, err)
+		}
+		report.Timings = timings
+	}
+
+	return report, nil
+}
+
+// ReportAll returns a report for every registered parameter set, ordered by security level
+func ReportAll(opts ...ReportOption) ([]ParamReport, error) {
+	sets := ListParameters()
+	reports := make([]ParamReport, 0, len(sets))
+	for _, params := range sets {
+		report, err := Report(params, opts...)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// measure runs each operation iterations times and returns the median durations
+func measure(params Parameters, iterations int) (*ReportTimings, error) {
+	// The keys are only timed and discarded, so toy sets are measured too
+	kem := OwChCCAKEM{Params: params, allowToy: true}
+	keyGen := make([]time.Duration, iterations)
+	encap := make([]time.Duration, iterations)
+	decap := make([]time.Duration, iterations)
+
+	for i := 0; i < iterations; i++ {
+		if _, _, err := measureTrial(&kem, i, &keyGen[i], &encap[i], &decap[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ReportTimings{
+		Iterations:  iterations,
+		KeyGen:      median(keyGen),
+		Encapsulate: median(encap),
+		Decapsulate: median(decap),
+	}, nil
+}
+
+// measureTrial runs trial i of measure, recording each operation's duration. Key generation and
+// encapsulation both read from a SHAKE256 stream seeded with the parameter set name and i, so
+// the ciphertext and shared key it returns are the same on every run.
+func measureTrial(kem *OwChCCAKEM, i int, keyGen, encap, decap *time.Duration) (ciphertext, sharedKey []byte, err error) {
+	seed := sha3.NewShake256()
+	seed.Write([]byte(kem.Params.Name))
+	seed.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+
+	start := time.Now()
+	pk, sk, err := kem.GenerateKeyPair(&seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	*keyGen = time.Since(start)
+
+	start = time.Now()
+	ciphertext, sharedKey, err = kem.EncapsulateFrom(pk, &seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	*encap = time.Since(start)
+
+	start = time.Now()
+	if _, err := kem.Decapsulate(sk, ciphertext); err != nil {
+		return nil, nil, err
+	}
+	*decap = time.Since(start)
+	return ciphertext, sharedKey, nil
+}
+
+// median returns the median of durations, sorting them in place
+func median(durations []time.Duration) time.Duration {
+	slices.Sort(durations)
+	return durations[len(durations)/2]
+}
@@ -0,0 +1,113 @@
+package pkg
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestReportMatchesArtifacts(t *testing.T) {
+	params := GetDefaultParameterSet()
+	report, err := Report(params, WithMeasurement(1))
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if report.Name != params.Name || report.ID != params.ID() || report.SecurityLevel != params.SecurityLevel {
+		t.Fatalf("report identifies %s/%d, expected %s/%d", report.Name, report.ID, params.Name, params.ID())
+	}
+	if report.Timings == nil || report.Timings.Iterations != 1 || report.Timings.KeyGen <= 0 {
+		t.Fatalf("measured report is missing timings: %+v", report.Timings)
+	}
+
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(nil)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	pkBytes, err := pk.Bytes()
+	if err != nil {
+		t.Fatalf("public key Bytes failed: %v", err)
+	}
+	skBytes, err := sk.Bytes()
+	if err != nil {
+		t.Fatalf("private key Bytes failed: %v", err)
+	}
+	ct, ss, err := kem.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+
+	sizes := []struct {
+		name           string
+		report, actual int
+	}{
+		{"public key", report.PublicKeySize, len(pkBytes)},
+		{"private key", report.PrivateKeySize, len(skBytes)},
+		{"ciphertext", report.CiphertextSize, len(ct)},
+		{"shared key", report.SharedKeySize, len(ss)},
+	}
+	for _, s := range sizes {
+		if s.report != s.actual {
+			t.Fatalf("%s: report says %d bytes, artifact is %d", s.name, s.report, s.actual)
+		}
+	}
+	a := pk.a.ToMatrix()
+	if want := a.EncodedSize() - 8; report.KeyGenMemory != want {
+		t.Fatalf("KeyGenMemory %d, expected %d", report.KeyGenMemory, want)
+	}
+}
+
+func TestReportAll(t *testing.T) {
+	reports, err := ReportAll()
+	if err != nil {
+		t.Fatalf("ReportAll failed: %v", err)
+	}
+	if len(reports) != len(ListParameterSets()) {
+		t.Fatalf("ReportAll returned %d reports for %d parameter sets", len(reports), len(ListParameterSets()))
+	}
+	for i, r := range reports {
+		if r.Timings != nil {
+			t.Fatalf("%s: unmeasured report has timings", r.Name)
+		}
+		if i > 0 && reports[i-1].SecurityLevel > r.SecurityLevel {
+			t.Fatalf("reports are not ordered by security level")
+		}
+	}
+}
+
+func TestReportDeterministic(t *testing.T) {
+	params := GetDefaultParameterSet()
+	first, err := Report(params, WithMeasurement(2))
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	second, err := Report(params, WithMeasurement(2))
+	if err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	first.Timings, second.Timings = nil, nil
+	if first != second {
+		t.Fatalf("two runs reported %+v and %+v", first, second)
+	}
+
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	var keyGen, encap, decap time.Duration
+	ct1, ss1, err := measureTrial(&kem, 0, &keyGen, &encap, &decap)
+	if err != nil {
+		t.Fatalf("measureTrial failed: %v", err)
+	}
+	ct2, ss2, err := measureTrial(&kem, 0, &keyGen, &encap, &decap)
+	if err != nil {
+		t.Fatalf("measureTrial failed: %v", err)
+	}
+	if !bytes.Equal(ct1, ct2) || !bytes.Equal(ss1, ss2) {
+		t.Fatalf("measured encapsulation is not deterministic")
+	}
+	ct3, _, err := measureTrial(&kem, 1, &keyGen, &encap, &decap)
+	if err != nil {
+		t.Fatalf("measureTrial failed: %v", err)
+	}
+	if bytes.Equal(ct1, ct3) {
+		t.Fatalf("trials 0 and 1 measured the same ciphertext")
+	}
+}
@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/sha3"
+)
+
+// KeySeedSize is the length in bytes of the seeds DeriveKeyPair and DerivePrivateKey accept
+const KeySeedSize = 32
+
+// keySeedLabel separates the key generation stream from every other use of SHAKE256
+const keySeedLabel = "OW-ChCCA-KEM-KeyGen"
+
+// keySeedReader returns the randomness stream a key pair is generated from when derived from
+// seed under params. The stream absorbs the domain label and the parameter set ID before the
+// seed, so one seed derives unrelated keys under different parameter sets.
+func keySeedReader(params Parameters, seed []byte) io.Reader {
+	h := sha3.NewShake256()
+	h.Write([]byte(keySeedLabel))
+	h.Write(binary.BigEndian.AppendUint16(nil, params.ID()))
+	h.Write(seed)
+	return &h
+}
+
+// DeriveKeyPair deterministically generates the key pair for a KeySeedSize-byte seed: the same
+// seed and parameters always give the same keys. The private key remembers its seed, so it can
+// be stored compactly with MarshalSeed. Toy parameter sets must be allowed as for GenerateKeyPair.
+func (kem *OwChCCAKEM) DeriveKeyPair(seed []byte) (*PublicKey, *PrivateKey, error) {
+	if len(seed) != KeySeedSize {
+		return nil, nil, fmt.Errorf("%w: seed is %d bytes, expected %d", ErrInvalidPrivateKey, len(seed), KeySeedSize)
+	}
+	pk, sk, err := kem.GenerateKeyPair(keySeedReader(kem.Params, seed))
+	if err != nil {
+		return nil, nil, err
+	}
+	sk.seed = append([]byte(nil), seed...)
+	return pk, sk, nil
+}
+
+// DerivePrivateKey re-derives the private key generated from seed under params, including its
+// public key. It refuses toy parameter sets; use OwChCCAKEM.DeriveKeyPair to allow them.
+func DerivePrivateKey(params Parameters, seed []byte) (*PrivateKey, error) {
+	kem := OwChCCAKEM{Params: params}
+	_, sk, err := kem.DeriveKeyPair(seed)
+	return sk, err
+}
+
+// Seed returns a copy of the seed the key was derived from. ok is false for keys that were
+// generated from a random source or parsed from a full encoding.
+func (sk *PrivateKey) Seed() (seed []byte, ok bool) {
+	if sk == nil || sk.seed == nil {
+		return nil, false
+	}
+	return append([]byte(nil), sk.seed...), true
+}
+
+// MarshalSeed returns the compact encoding of a derived private key: a header carrying the
+// parameter set ID followed by the seed. It fails for keys without a seed.
+func (sk *PrivateKey) MarshalSeed() ([]byte, error) {
+	if sk == nil || sk.Pk == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	if sk.seed == nil {
+		return nil, fmt.Errorf("%w: private key was not derived from a seed", ErrSerializationError)
+	}
+	buf, err := appendHeader(make([]byte, 0, HeaderSize+KeySeedSize), KindPrivateKeySeed, sk.Pk.Params)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, sk.seed...), nil
+}
+
+// ParsePrivateKeySeed parses an encoding produced by MarshalSeed and re-derives the private key
+// and its public key. Re-deriving runs key generation, so like DerivePrivateKey it refuses toy
+// parameter sets; use OwChCCAKEM.ParsePrivateKeySeed to allow them.
+func ParsePrivateKeySeed(data []byte) (*PrivateKey, error) {
+	params, err := parseHeader(data, KindPrivateKeySeed)
+	if err != nil {
+		return nil, err
+	}
+	kem := OwChCCAKEM{Params: params}
+	return kem.ParsePrivateKeySeed(data)
+}
+
+// ParsePrivateKeySeed is like the package-level ParsePrivateKeySeed, but the encoding must name
+// kem.Params and toy parameter sets are accepted if kem allows them
+func (kem *OwChCCAKEM) ParsePrivateKeySeed(data []byte) (*PrivateKey, error) {
+	params, err := parseHeader(data, KindPrivateKeySeed)
+	if err != nil {
+		return nil, err
+	}
+	if params.ID() != kem.Params.ID() {
+		return nil, fmt.Errorf("%w: seed encoding is for %s, expected %s", ErrDeserializationError, params.Name, kem.Params.Name)
+	}
+	if len(data) != HeaderSize+KeySeedSize {
+		return nil, fmt.Errorf("%w: seed encoding is %d bytes, expected %d", ErrDeserializationError, len(data), HeaderSize+KeySeedSize)
+	}
+	_, sk, err := kem.DeriveKeyPair(data[HeaderSize:])
+	return sk, err
+}
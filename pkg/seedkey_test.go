@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestDeriveKeyPair(t *testing.T) {
+	kem := OwChCCAKEM{Params: GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	seed := bytes.Repeat([]byte{0x5a}, KeySeedSize)
+	pk, sk, err := kem.DeriveKeyPair(seed)
+	if err != nil {
+		t.Fatalf("DeriveKeyPair failed: %v", err)
+	}
+	if got, ok := sk.Seed(); !ok || !bytes.Equal(got, seed) {
+		t.Fatalf("Seed() = %x, %v; want %x", got, ok, seed)
+	}
+
+	// Ciphertexts made before the key is stored still decapsulate after re-derivation
+	ct, ss, err := kem.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+	compact, err := sk.MarshalSeed()
+	if err != nil {
+		t.Fatalf("MarshalSeed failed: %v", err)
+	}
+	if len(compact) != HeaderSize+KeySeedSize {
+		t.Fatalf("compact encoding is %d bytes, want %d", len(compact), HeaderSize+KeySeedSize)
+	}
+	if _, err := ParsePrivateKeySeed(compact); !errors.Is(err, ErrToyParameters) {
+		t.Fatalf("ParsePrivateKeySeed of a toy set: got %v", err)
+	}
+	restored, err := kem.ParsePrivateKeySeed(compact)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeySeed failed: %v", err)
+	}
+	if !restored.Equal(sk) || !restored.Pk.Equal(pk) {
+		t.Fatalf("re-derived key differs from the original")
+	}
+	if got, ok := restored.Seed(); !ok || !bytes.Equal(got, seed) {
+		t.Fatalf("re-derived key lost its seed")
+	}
+	got, err := kem.Decapsulate(restored, ct)
+	if err != nil || !bytes.Equal(got, ss) {
+		t.Fatalf("Decapsulate with the re-derived key = %x, %v; want %x", got, err, ss)
+	}
+	full, _ := sk.Bytes()
+	fullRestored, _ := restored.Bytes()
+	if !bytes.Equal(full, fullRestored) {
+		t.Fatalf("re-derived key does not encode identically")
+	}
+
+	// Other seeds give other keys, and Seed hands out copies
+	other := bytes.Repeat([]byte{0xa5}, KeySeedSize)
+	if _, skOther, err := kem.DeriveKeyPair(other); err != nil || skOther.Equal(sk) {
+		t.Fatalf("a different seed derived the same key: %v", err)
+	}
+	// The parameter set is part of the derivation: the same seed gives an unrelated stream under
+	// another set, whose kem refuses the encoding
+	otherParams, err := GetParameterSet("OWChCCA-32")
+	if err != nil {
+		t.Fatalf("GetParameterSet failed: %v", err)
+	}
+	stream, otherStream := make([]byte, 32), make([]byte, 32)
+	io.ReadFull(keySeedReader(kem.Params, seed), stream)
+	io.ReadFull(keySeedReader(otherParams, seed), otherStream)
+	if bytes.Equal(stream, otherStream) {
+		t.Fatalf("the same seed gave the same key generation stream under %s and %s", kem.Params.Name, otherParams.Name)
+	}
+	otherKEM := OwChCCAKEM{Params: otherParams}.WithAllowToyParameters(true)
+	if _, err := otherKEM.ParsePrivateKeySeed(compact); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("ParsePrivateKeySeed under another parameter set: got %v", err)
+	}
+
+	s, _ := sk.Seed()
+	s[0] ^= 1
+	if again, _ := sk.Seed(); !bytes.Equal(again, seed) {
+		t.Fatalf("mutating the result of Seed changed the key")
+	}
+}
+
+func TestDeriveKeyPairErrors(t *testing.T) {
+	params := GetDefaultParameterSet()
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	if _, _, err := kem.DeriveKeyPair(make([]byte, KeySeedSize-1)); !errors.Is(err, ErrInvalidPrivateKey) {
+		t.Fatalf("DeriveKeyPair with a short seed: got %v", err)
+	}
+	if _, err := DerivePrivateKey(params, make([]byte, KeySeedSize)); !errors.Is(err, ErrToyParameters) {
+		t.Fatalf("DerivePrivateKey with a toy set: got %v", err)
+	}
+
+	// Keys from a random source or a full encoding have no seed
+	_, sk, err := kem.GenerateKeyPair(seededReader("unseeded"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	if _, ok := sk.Seed(); ok {
+		t.Fatalf("Seed() reported a seed for a randomly generated key")
+	}
+	if _, err := sk.MarshalSeed(); !errors.Is(err, ErrSerializationError) {
+		t.Fatalf("MarshalSeed of a randomly generated key: got %v", err)
+	}
+	_, derived, err := kem.DeriveKeyPair(make([]byte, KeySeedSize))
+	if err != nil {
+		t.Fatalf("DeriveKeyPair failed: %v", err)
+	}
+	data, _ := derived.MarshalWithHeader()
+	parsed, err := ParsePrivateKeyWithHeader(data)
+	if err != nil {
+		t.Fatalf("ParsePrivateKeyWithHeader failed: %v", err)
+	}
+	if _, ok := parsed.Seed(); ok {
+		t.Fatalf("Seed() reported a seed for a key parsed from its full encoding")
+	}
+
+	compact, _ := derived.MarshalSeed()
+	if _, err := kem.ParsePrivateKeySeed(compact[:len(compact)-1]); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("ParsePrivateKeySeed of a truncated encoding: got %v", err)
+	}
+	if _, err := kem.ParsePrivateKeySeed(data); !errors.Is(err, ErrDeserializationError) {
+		t.Fatalf("ParsePrivateKeySeed of a full encoding: got %v", err)
+	}
+}
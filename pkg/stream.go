@@ -0,0 +1,287 @@
+package pkg
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/arithmetic"
+)
+
+var (
+	_ io.WriterTo   = (*Ciphertext)(nil)
+	_ io.ReaderFrom = (*Ciphertext)(nil)
+	_ io.WriterTo   = (*PublicKey)(nil)
+	_ io.ReaderFrom = (*PublicKey)(nil)
+	_ io.WriterTo   = (*PrivateKey)(nil)
+	_ io.ReaderFrom = (*PrivateKey)(nil)
+)
+
+// Ciphertext is an encoded ciphertext bound to its parameter set, so that it can be streamed
+// with WriteTo and ReadFrom. KeyConfirmation says whether Data ends in a key confirmation tag
+// (see OwChCCAKEM.WithKeyConfirmation); like Params, it must be set before ReadFrom.
+type Ciphertext struct {
+	Params          Parameters
+	Data            []byte
+	KeyConfirmation bool
+}
+
+// size returns the encoded length of the ciphertext, including the tag if there is one
+func (ct *Ciphertext) size() int {
+	if ct.KeyConfirmation {
+		return ct.Params.KeyParams.CiphertextSize + ConfirmationTagSize
+	}
+	return ct.Params.KeyParams.CiphertextSize
+}
+
+// components returns the ranges of the ciphertext components in encoding order, ending with
+// the key confirmation tag if there is one
+func (ct *Ciphertext) components() []Range {
+	l := ct.Params.CiphertextLayout()
+	components := []Range{l.C0, l.C1, l.X, l.HatH0, l.HatH1}
+	if ct.KeyConfirmation {
+		components = append(components, Range{Offset: l.Size(), Length: ConfirmationTagSize})
+	}
+	return components
+}
+
+// WriteTo writes the ciphertext to w one component at a time
+func (ct *Ciphertext) WriteTo(w io.Writer) (int64, error) {
+	if size := ct.size(); len(ct.Data) != size {
+		return 0, fmt.Errorf("%w: ciphertext is %d bytes, expected %d", ErrInvalidCiphertext, len(ct.Data), size)
+	}
+	var written int64
+	for _, r := range ct.components() {
+		n, err := w.Write(ct.Data[r.Offset:r.End()])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ReadFrom reads exactly CiphertextSize bytes for ct.Params from r, one component at a time,
+// followed by the ConfirmationTagSize-byte tag if ct.KeyConfirmation is set. A stream that ends
+// early is reported as ErrInvalidCiphertext.
+func (ct *Ciphertext) ReadFrom(r io.Reader) (int64, error) {
+	if ct.Params.LatticeParams.Q == nil {
+		return 0, fmt.Errorf("%w: ciphertext has no parameters", ErrInvalidCiphertext)
+	}
+	data := make([]byte, ct.size())
+	var read int64
+	for i, c := range ct.components() {
+		n, err := io.ReadFull(r, data[c.Offset:c.End()])
+		read += int64(n)
+		if err != nil {
+			return read, fmt.Errorf("%w: stream ended in component %d: %v", ErrInvalidCiphertext, i, err)
+		}
+	}
+	ct.Data = data
+	return read, nil
+}
+
+// countingWriter counts the bytes written to w
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeMatrixHeader writes the rows x cols header of the matrix encoding
+func writeMatrixHeader(w io.Writer, rows, cols int) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(rows))
+	binary.BigEndian.PutUint32(header[4:], uint32(cols))
+	_, err := w.Write(header[:])
+	return err
+}
+
+// writeMatrix writes the encoding of m row by row
+func writeMatrix(w io.Writer, m arithmetic.Matrix, elementSize int) error {
+	if err := writeMatrixHeader(w, m.Rows, m.Cols); err != nil {
+		return err
+	}
+	for i := 0; i < m.Rows; i++ {
+		row := arithmetic.Vector{Values: m.Values[i], Modulus: m.Modulus}
+		if err := row.WriteCanonical(w, elementSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePolyMatrix writes the matrix encoding of p row by row, without converting it to big.Int
+func writePolyMatrix(w io.Writer, p *arithmetic.PolyMatrix, elementSize int) error {
+	if err := writeMatrixHeader(w, p.Rows, p.Cols); err != nil {
+		return err
+	}
+	buf := make([]byte, p.Cols*elementSize)
+	var word [8]byte
+	for _, poly := range p.Values {
+		for j, c := range poly.Coeffs[0] {
+			binary.BigEndian.PutUint64(word[:], c)
+			copy(buf[j*elementSize:(j+1)*elementSize], word[8-elementSize:])
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTo streams A || U0 || U1 to w
+func (pk *PublicKey) writeTo(w io.Writer) error {
+	if pk.a == nil {
+		return fmt.Errorf("%w: matrix A is missing", ErrSerializationError)
+	}
+	elementSize := (pk.Params.LatticeParams.Q.BitLen() + 7) / 8
+	if err := writePolyMatrix(w, pk.a, elementSize); err != nil {
+		return err
+	}
+	if err := writeMatrix(w, pk.u0, elementSize); err != nil {
+		return err
+	}
+	return writeMatrix(w, pk.u1, elementSize)
+}
+
+// WriteTo streams the encoding returned by Bytes to w without building it in memory
+func (pk *PublicKey) WriteTo(w io.Writer) (int64, error) {
+	if pk == nil || pk.Params.LatticeParams.Q == nil {
+		return 0, ErrInvalidPublicKey
+	}
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	err := pk.writeTo(cw)
+	if err == nil {
+		err = cw.w.Flush()
+	}
+	if err == nil && cw.n != int64(pk.Params.KeyParams.PublicKeySize) {
+		err = fmt.Errorf("%w: public key is %d bytes, expected %d", ErrSerializationError, cw.n, pk.Params.KeyParams.PublicKeySize)
+	}
+	return cw.n, err
+}
+
+// readMatrix reads one rows x cols matrix encoding from r and decodes it strictly
+func readMatrix(r io.Reader, read *int64, name string, rows, cols int, modulus *big.Int) (arithmetic.Matrix, error) {
+	elementSize := (modulus.BitLen() + 7) / 8
+	buf := make([]byte, 8+rows*cols*elementSize)
+	n, err := io.ReadFull(r, buf)
+	*read += int64(n)
+	if err != nil {
+		return arithmetic.Matrix{}, fmt.Errorf("%w: stream ended in matrix %s: %v", ErrDeserializationError, name, err)
+	}
+	mat, _, err := decodeMatrixStrict(buf, 0, name, rows, cols, modulus)
+	return mat, err
+}
+
+// readFrom reads A, U0 and U1 from r
+func (pk *PublicKey) readFrom(r io.Reader, read *int64) error {
+	n := pk.Params.LatticeParams.N
+	m := pk.Params.LatticeParams.M
+	lambda := pk.Params.LatticeParams.Lambda
+	modulus := pk.Params.LatticeParams.Q
+
+	a, err := readMatrix(r, read, "A", n, m, modulus)
+	if err != nil {
+		return err
+	}
+	u0, err := readMatrix(r, read, "U0", n, lambda, modulus)
+	if err != nil {
+		return err
+	}
+	u1, err := readMatrix(r, read, "U1", n, lambda, modulus)
+	if err != nil {
+		return err
+	}
+	aPoly, err := arithmetic.PolyMatrixFromMatrix(a)
+	if err != nil {
+		return fmt.Errorf("%w: matrix A: %v", ErrDeserializationError, err)
+	}
+	pk.a, pk.u0, pk.u1, pk.digest = aPoly, u0, u1, new(keyDigest)
+	return nil
+}
+
+// ReadFrom reads exactly PublicKeySize bytes for pk.Params from r, one matrix at a time, with
+// the checks of UnmarshalBinaryStrict. A stream that ends early is reported as
+// ErrDeserializationError.
+func (pk *PublicKey) ReadFrom(r io.Reader) (int64, error) {
+	if pk == nil || pk.Params.LatticeParams.Q == nil {
+		return 0, ErrInvalidPublicKey
+	}
+	var read int64
+	err := pk.readFrom(r, &read)
+	return read, err
+}
+
+// WriteTo streams the encoding returned by Bytes to w without building it in memory
+func (sk *PrivateKey) WriteTo(w io.Writer) (int64, error) {
+	if sk == nil || sk.Pk == nil || sk.Pk.Params.LatticeParams.Q == nil {
+		return 0, ErrInvalidPrivateKey
+	}
+	params := sk.Pk.Params
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	err := sk.Pk.writeTo(cw)
+	if err == nil {
+		err = writeMatrix(cw, sk.zb, (params.LatticeParams.Q.BitLen()+7)/8)
+	}
+	if err == nil {
+		var bFlag byte
+		if sk.b {
+			bFlag = 1
+		}
+		_, err = cw.Write([]byte{bFlag})
+	}
+	if err == nil {
+		err = cw.w.Flush()
+	}
+	if err == nil && cw.n != int64(params.KeyParams.PrivateKeySize) {
+		err = fmt.Errorf("%w: private key is %d bytes, expected %d", ErrSerializationError, cw.n, params.KeyParams.PrivateKeySize)
+	}
+	return cw.n, err
+}
+
+// ReadFrom reads exactly PrivateKeySize bytes for sk.Pk.Params from r, including the public
+// key, with the checks of UnmarshalBinaryStrict. A stream that ends early is reported as
+// ErrDeserializationError. sk is only changed once the whole key has been read.
+func (sk *PrivateKey) ReadFrom(r io.Reader) (int64, error) {
+	if sk == nil || sk.Pk == nil || sk.Pk.Params.LatticeParams.Q == nil {
+		return 0, ErrInvalidPrivateKey
+	}
+	params := sk.Pk.Params
+	pk := &PublicKey{Params: params}
+	var read int64
+	if err := pk.readFrom(r, &read); err != nil {
+		return read, err
+	}
+	zb, err := readMatrix(r, &read, "Zb", params.LatticeParams.M, params.LatticeParams.Lambda, params.LatticeParams.Q)
+	if err != nil {
+		return read, err
+	}
+
+	var bFlag [1]byte
+	n, err := io.ReadFull(r, bFlag[:])
+	read += int64(n)
+	if err != nil {
+		return read, fmt.Errorf("%w: stream ended before the b flag: %v", ErrDeserializationError, err)
+	}
+	if bFlag[0] > 1 {
+		return read, fmt.Errorf("%w: b flag is %d, expected 0 or 1", ErrDeserializationError, bFlag[0])
+	}
+
+	if pk, err = matchPublicKey(sk.Pk, pk); err != nil {
+		return read, err
+	}
+
+	sk.Pk = pk
+	sk.zb = zb
+	sk.b = bFlag[0] == 1
+	sk.seed = nil
+	return read, nil
+}
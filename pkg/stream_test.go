@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestCiphertextOverPipe(t *testing.T) {
+	params := GetDefaultParameterSet()
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(seededReader("stream key"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	data, ss, err := kem.EncapsulateFrom(pk, seededReader("stream ciphertext"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		defer client.Close()
+		(&Ciphertext{Params: params, Data: data}).WriteTo(client)
+	}()
+
+	received := &Ciphertext{Params: params}
+	n, err := received.ReadFrom(server)
+	if err != nil || n != int64(params.KeyParams.CiphertextSize) {
+		t.Fatalf("ReadFrom = %d, %v", n, err)
+	}
+	shared, err := kem.Decapsulate(sk, received.Data)
+	if err != nil || !bytes.Equal(shared, ss) {
+		t.Fatalf("Decapsulate of the received ciphertext failed: %v", err)
+	}
+
+	truncated := &Ciphertext{Params: params}
+	if _, err := truncated.ReadFrom(bytes.NewReader(data[:len(data)-1])); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("ReadFrom of a truncated stream: got %v", err)
+	}
+	if _, err := (&Ciphertext{Params: params, Data: data[:10]}).WriteTo(&bytes.Buffer{}); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("WriteTo of a short ciphertext: got %v", err)
+	}
+	if _, err := (&Ciphertext{}).ReadFrom(bytes.NewReader(data)); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("ReadFrom without parameters: got %v", err)
+	}
+}
+
+func TestTaggedCiphertextStreaming(t *testing.T) {
+	params := GetDefaultParameterSet()
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true).WithKeyConfirmation(true)
+	pk, sk, err := kem.GenerateKeyPair(seededReader("stream tagged key"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	data, ss, err := kem.EncapsulateFrom(pk, seededReader("stream tagged ciphertext"))
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if n, err := (&Ciphertext{Params: params, Data: data, KeyConfirmation: true}).WriteTo(&buf); err != nil || n != int64(len(data)) {
+		t.Fatalf("WriteTo = %d, %v", n, err)
+	}
+	received := &Ciphertext{Params: params, KeyConfirmation: true}
+	if n, err := received.ReadFrom(&buf); err != nil || n != int64(kem.CiphertextSize()) {
+		t.Fatalf("ReadFrom = %d, %v", n, err)
+	}
+	if !bytes.Equal(received.Data, data) {
+		t.Fatalf("the tagged ciphertext did not round-trip")
+	}
+	if shared, err := kem.Decapsulate(sk, received.Data); err != nil || !bytes.Equal(shared, ss) {
+		t.Fatalf("Decapsulate of the received ciphertext failed: %v", err)
+	}
+
+	// Without the flag the tagged ciphertext has the wrong length, and a stream without the tag is short
+	if _, err := (&Ciphertext{Params: params, Data: data}).WriteTo(&bytes.Buffer{}); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("WriteTo of a tagged ciphertext without KeyConfirmation: got %v", err)
+	}
+	untagged := bytes.NewReader(data[:params.KeyParams.CiphertextSize])
+	if _, err := (&Ciphertext{Params: params, KeyConfirmation: true}).ReadFrom(untagged); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("ReadFrom of a stream without the tag: got %v", err)
+	}
+}
+
+func TestKeyStreaming(t *testing.T) {
+	params := GetDefaultParameterSet()
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(seededReader("stream key"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+
+	pkBytes, err := pk.Bytes()
+	if err != nil {
+		t.Fatalf("PublicKey.Bytes failed: %v", err)
+	}
+	skBytes, err := sk.Bytes()
+	if err != nil {
+		t.Fatalf("PrivateKey.Bytes failed: %v", err)
+	}
+
+	var pkBuf bytes.Buffer
+	if n, err := pk.WriteTo(&pkBuf); err != nil || n != int64(params.KeyParams.PublicKeySize) {
+		t.Fatalf("PublicKey.WriteTo = %d, %v", n, err)
+	}
+	if !bytes.Equal(pkBuf.Bytes(), pkBytes) {
+		t.Fatalf("PublicKey.WriteTo differs from Bytes")
+	}
+	var skBuf bytes.Buffer
+	if n, err := sk.WriteTo(&skBuf); err != nil || n != int64(params.KeyParams.PrivateKeySize) {
+		t.Fatalf("PrivateKey.WriteTo = %d, %v", n, err)
+	}
+	if !bytes.Equal(skBuf.Bytes(), skBytes) {
+		t.Fatalf("PrivateKey.WriteTo differs from Bytes")
+	}
+
+	gotPk := &PublicKey{Params: params}
+	if _, err := gotPk.ReadFrom(bytes.NewReader(pkBuf.Bytes())); err != nil || !gotPk.Equal(pk) {
+		t.Fatalf("PublicKey.ReadFrom failed: %v", err)
+	}
+	gotSk := &PrivateKey{Pk: &PublicKey{Params: params}}
+	if _, err := gotSk.ReadFrom(bytes.NewReader(skBuf.Bytes())); err != nil || !gotSk.Equal(sk) {
+		t.Fatalf("PrivateKey.ReadFrom failed: %v", err)
+	}
+
+	// Reading stops at the end of the key, leaving the rest of the stream
+	stream := bytes.NewReader(append(pkBuf.Bytes(), 0xAB))
+	if n, err := (&PublicKey{Params: params}).ReadFrom(stream); err != nil || n != int64(params.KeyParams.PublicKeySize) || stream.Len() != 1 {
+		t.Fatalf("PublicKey.ReadFrom consumed %d bytes: %v", n, err)
+	}
+
+	for name, data := range map[string][]byte{
+		"public key":  pkBuf.Bytes()[:pkBuf.Len()-1],
+		"zb":          skBuf.Bytes()[:pkBuf.Len()+100],
+		"b flag":      skBuf.Bytes()[:skBuf.Len()-1],
+		"bad b flag":  append(append([]byte(nil), skBuf.Bytes()[:skBuf.Len()-1]...), 2),
+		"empty input": nil,
+	} {
+		if _, err := (&PrivateKey{Pk: &PublicKey{Params: params}}).ReadFrom(bytes.NewReader(data)); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("%s: PrivateKey.ReadFrom got %v", name, err)
+		}
+	}
+
+	// A supplied public key is checked, never overwritten, even when the read fails part way
+	kept := &PrivateKey{Pk: pk}
+	if _, err := kept.ReadFrom(bytes.NewReader(skBuf.Bytes())); err != nil || kept.Pk != pk || !kept.Equal(sk) {
+		t.Fatalf("PrivateKey.ReadFrom did not keep the supplied public key: %v", err)
+	}
+	other, _, err := kem.GenerateKeyPair(seededReader("stream key other"))
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	otherBytes, _ := other.Bytes()
+	for name, data := range map[string][]byte{
+		"mismatched key": skBuf.Bytes(),
+		"truncated zb":   skBuf.Bytes()[:pkBuf.Len()+100],
+	} {
+		target := &PrivateKey{Pk: other}
+		if _, err := target.ReadFrom(bytes.NewReader(data)); !errors.Is(err, ErrDeserializationError) {
+			t.Fatalf("%s: PrivateKey.ReadFrom got %v", name, err)
+		}
+		if got, _ := other.Bytes(); target.Pk != other || target.zb.Values != nil || !bytes.Equal(got, otherBytes) {
+			t.Fatalf("%s: failed PrivateKey.ReadFrom modified its receiver", name)
+		}
+	}
+
+	if _, err := (&PublicKey{}).ReadFrom(bytes.NewReader(pkBuf.Bytes())); !errors.Is(err, ErrInvalidPublicKey) {
+		t.Fatalf("PublicKey.ReadFrom without parameters: got %v", err)
+	}
+}
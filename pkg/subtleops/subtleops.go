@@ -0,0 +1,25 @@
+// Package subtleops provides constant-time byte operations on secret data.
+package subtleops
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+// ErrLengthMismatch is returned when the operands of a byte operation have different lengths
+var ErrLengthMismatch = errors.New("length mismatch")
+
+// XORBytes sets dst[i] = a[i] ^ b[i] for every i < len(a). a and b must have the same length and
+// dst must be at least as long; otherwise dst is left untouched. The running time depends only
+// on the lengths.
+func XORBytes(dst, a, b []byte) error {
+	if len(a) != len(b) {
+		return fmt.Errorf("%w: operands are %d and %d bytes", ErrLengthMismatch, len(a), len(b))
+	}
+	if len(dst) < len(a) {
+		return fmt.Errorf("%w: destination is %d bytes, need %d", ErrLengthMismatch, len(dst), len(a))
+	}
+	subtle.XORBytes(dst, a, b)
+	return nil
+}
@@ -0,0 +1,64 @@
+package subtleops
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestXORBytes(t *testing.T) {
+	a := make([]byte, 32)
+	b := make([]byte, 32)
+	rand.Read(a)
+	rand.Read(b)
+
+	dst := make([]byte, 32)
+	if err := XORBytes(dst, a, b); err != nil {
+		t.Fatalf("XORBytes failed: %v", err)
+	}
+	for i := range dst {
+		if dst[i] != a[i]^b[i] {
+			t.Fatalf("byte %d: got %02x, expected %02x", i, dst[i], a[i]^b[i])
+		}
+	}
+
+	// XORing twice recovers the operand, in place
+	if err := XORBytes(dst, dst, b); err != nil || !bytes.Equal(dst, a) {
+		t.Fatalf("in-place XOR did not recover a: %v", err)
+	}
+
+	// A longer destination keeps its tail
+	long := bytes.Repeat([]byte{0xAA}, 40)
+	if err := XORBytes(long, a, b); err != nil {
+		t.Fatalf("XORBytes into longer dst failed: %v", err)
+	}
+	if !bytes.Equal(long[32:], bytes.Repeat([]byte{0xAA}, 8)) {
+		t.Fatalf("XORBytes wrote past len(a)")
+	}
+}
+
+func TestXORBytesLengthMismatch(t *testing.T) {
+	a := make([]byte, 16)
+	cases := []struct {
+		name   string
+		dst, b []byte
+	}{
+		{"short b", make([]byte, 16), make([]byte, 15)},
+		{"long b", make([]byte, 16), make([]byte, 17)},
+		{"short dst", make([]byte, 15), make([]byte, 16)},
+		{"nil dst", nil, make([]byte, 16)},
+	}
+	for _, c := range cases {
+		for i := range c.dst {
+			c.dst[i] = 0x5A
+		}
+		before := append([]byte(nil), c.dst...)
+		if err := XORBytes(c.dst, a, c.b); !errors.Is(err, ErrLengthMismatch) {
+			t.Fatalf("%s: got %v", c.name, err)
+		}
+		if !bytes.Equal(c.dst, before) {
+			t.Fatalf("%s: dst modified on error", c.name)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Package testingkem helps applications test how they handle failed decapsulations: it corrupts
+// chosen ciphertext components and wraps a KEM so that a chosen decapsulation fails.
+package testingkem
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg"
+)
+
+// Component names a part of the ciphertext encoding c0 || c1 || x || hatH0 || hatH1
+type Component int
+
+const (
+	// C0 is the masked seed of branch 0
+	C0 Component = iota
+	// C1 is the masked seed of branch 1
+	C1
+	// X is the encoded vector x = A^T*s + e
+	X
+	// HatH0 is the encoded vector hatH0
+	HatH0
+	// HatH1 is the encoded vector hatH1
+	HatH1
+)
+
+// String returns the name the ciphertext layout uses for the component
+func (c Component) String() string {
+	switch c {
+	case C0:
+		return "c0"
+	case C1:
+		return "c1"
+	case X:
+		return "x"
+	case HatH0:
+		return "hatH0"
+	case HatH1:
+		return "hatH1"
+	default:
+		return fmt.Sprintf("Component(%d)", int(c))
+	}
+}
+
+// rangeOf returns the byte range of the component in layout
+func (c Component) rangeOf(layout pkg.CiphertextLayout) (pkg.Range, bool) {
+	switch c {
+	case C0:
+		return layout.C0, true
+	case C1:
+		return layout.C1, true
+	case X:
+		return layout.X, true
+	case HatH0:
+		return layout.HatH0, true
+	case HatH1:
+		return layout.HatH1, true
+	default:
+		return pkg.Range{}, false
+	}
+}
+
+// CorruptCiphertext returns a copy of ct with the lowest bit of the last byte of component
+// flipped. For the vector components this changes the last element by one and leaves the
+// length header intact, so the ciphertext still parses but fails the re-encryption check.
+// Every other byte, including a trailing key confirmation tag, is left untouched. It returns
+// nil if ct does not have the length of a ciphertext under params, with or without a tag, or
+// if component is unknown.
+func CorruptCiphertext(ct []byte, params pkg.Parameters, component Component) []byte {
+	layout := params.CiphertextLayout()
+	if len(ct) != layout.Size() && len(ct) != layout.Size()+pkg.ConfirmationTagSize {
+		return nil
+	}
+	r, ok := component.rangeOf(layout)
+	if !ok || r.Length == 0 {
+		return nil
+	}
+
+	corrupted := append([]byte(nil), ct...)
+	corrupted[r.End()-1] ^= 1
+	return corrupted
+}
+
+// ErrForcedFailure is returned by ForcedFailureKEM for the decapsulation it was told to fail.
+// It wraps pkg.ErrDecapsulationFailed, so callers handle it like a genuine failure.
+var ErrForcedFailure = fmt.Errorf("%w: forced by testingkem", pkg.ErrDecapsulationFailed)
+
+// ForcedFailureKEM wraps a KEM and fails exactly one decapsulation, the Nth counted from one,
+// without running it. Every other call is passed through. It is safe for concurrent use; with
+// concurrent callers, the Nth call is the Nth to enter Decapsulate.
+type ForcedFailureKEM struct {
+	kem    pkg.OwChCCAKEM
+	failAt int64
+	calls  atomic.Int64
+}
+
+// NewForcedFailureKEM returns a ForcedFailureKEM around kem that fails decapsulation number n.
+// A non-positive n never fails.
+func NewForcedFailureKEM(kem pkg.OwChCCAKEM, n int) *ForcedFailureKEM {
+	return &ForcedFailureKEM{kem: kem, failAt: int64(n)}
+}
+
+// Encapsulate passes through to the wrapped KEM
+func (f *ForcedFailureKEM) Encapsulate(pk *pkg.PublicKey) (ciphertext, sharedKey []byte, err error) {
+	return f.kem.Encapsulate(pk)
+}
+
+// Decapsulate fails with ErrForcedFailure on the Nth call and otherwise passes through
+func (f *ForcedFailureKEM) Decapsulate(sk *pkg.PrivateKey, ciphertext []byte) (sharedKey []byte, err error) {
+	if f.calls.Add(1) == f.failAt {
+		return nil, ErrForcedFailure
+	}
+	return f.kem.Decapsulate(sk, ciphertext)
+}
+
+// Calls returns the number of Decapsulate calls so far, including the forced failure
+func (f *ForcedFailureKEM) Calls() int {
+	return int(f.calls.Load())
+}
@@ -0,0 +1,102 @@
+package testingkem
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg"
+)
+
+func newKeys(t *testing.T) (pkg.OwChCCAKEM, *pkg.PublicKey, *pkg.PrivateKey) {
+	t.Helper()
+	kem := pkg.OwChCCAKEM{Params: pkg.GetDefaultParameterSet()}.WithAllowToyParameters(true)
+	pk, sk, err := kem.GenerateKeyPair(nil)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	return kem, pk, sk
+}
+
+func TestCorruptCiphertext(t *testing.T) {
+	kem, pk, sk := newKeys(t)
+	params := kem.Params
+	layout := params.CiphertextLayout()
+	ct, _, err := kem.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+
+	for _, c := range []Component{C0, C1, X, HatH0, HatH1} {
+		corrupted := CorruptCiphertext(ct, params, c)
+		if len(corrupted) != len(ct) {
+			t.Fatalf("%v: corrupted ciphertext is %d bytes, want %d", c, len(corrupted), len(ct))
+		}
+		r, _ := c.rangeOf(layout)
+		if bytes.Equal(corrupted[r.Offset:r.End()], ct[r.Offset:r.End()]) {
+			t.Errorf("%v: component unchanged", c)
+		}
+		if !bytes.Equal(corrupted[:r.Offset], ct[:r.Offset]) || !bytes.Equal(corrupted[r.End():], ct[r.End():]) {
+			t.Errorf("%v: bytes outside the component changed", c)
+		}
+		if _, err := kem.Decapsulate(sk, corrupted); err == nil {
+			t.Errorf("%v: Decapsulate accepted the corrupted ciphertext", c)
+		}
+	}
+	if got, err := kem.Decapsulate(sk, ct); err != nil || got == nil {
+		t.Fatalf("CorruptCiphertext modified its input: %v", err)
+	}
+
+	if CorruptCiphertext(ct[:len(ct)-1], params, X) != nil {
+		t.Errorf("CorruptCiphertext accepted a truncated ciphertext")
+	}
+	if CorruptCiphertext(ct, params, Component(99)) != nil {
+		t.Errorf("CorruptCiphertext accepted an unknown component")
+	}
+}
+
+func TestCorruptCiphertextKeyConfirmation(t *testing.T) {
+	kem, pk, sk := newKeys(t)
+	kem = kem.WithKeyConfirmation(true)
+	ct, _, err := kem.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+	corrupted := CorruptCiphertext(ct, kem.Params, HatH1)
+	if corrupted == nil || !bytes.Equal(corrupted[len(ct)-pkg.ConfirmationTagSize:], ct[len(ct)-pkg.ConfirmationTagSize:]) {
+		t.Fatalf("CorruptCiphertext changed the key confirmation tag")
+	}
+	if _, err := kem.Decapsulate(sk, corrupted); err == nil {
+		t.Fatalf("Decapsulate accepted the corrupted ciphertext")
+	}
+}
+
+func TestForcedFailureKEM(t *testing.T) {
+	kem, pk, sk := newKeys(t)
+	forced := NewForcedFailureKEM(kem, 3)
+	ct, ss, err := forced.Encapsulate(pk)
+	if err != nil {
+		t.Fatalf("Encapsulate failed: %v", err)
+	}
+
+	for call := 1; call <= 5; call++ {
+		got, err := forced.Decapsulate(sk, ct)
+		if call == 3 {
+			if !errors.Is(err, ErrForcedFailure) || !errors.Is(err, pkg.ErrDecapsulationFailed) || got != nil {
+				t.Fatalf("call %d: got %x, %v; want ErrForcedFailure", call, got, err)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(got, ss) {
+			t.Fatalf("call %d: got %x, %v; want %x", call, got, err, ss)
+		}
+	}
+	if forced.Calls() != 5 {
+		t.Fatalf("Calls() = %d, want 5", forced.Calls())
+	}
+
+	never := NewForcedFailureKEM(kem, 0)
+	if _, err := never.Decapsulate(sk, ct); err != nil {
+		t.Fatalf("NewForcedFailureKEM(kem, 0) failed a decapsulation: %v", err)
+	}
+}
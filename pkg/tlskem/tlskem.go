@@ -0,0 +1,135 @@
+// Package tlskem adapts OW-ChCCA-KEM to the key share plumbing of a crypto/tls-style
+// handshake: fixed-size key shares and ciphertexts, and state passed around as bytes only.
+package tlskem
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg"
+)
+
+// CurveID identifies a parameter set in the supported_groups and key_share extensions
+type CurveID uint16
+
+// Code points are taken from the private use range 0xFE00-0xFEFF, offset by the parameter set ID
+const (
+	OWChCCA16 CurveID = 0xFE00 + CurveID(pkg.ParamIDOWChCCA16)
+	OWChCCA32 CurveID = 0xFE00 + CurveID(pkg.ParamIDOWChCCA32)
+	OWChCCA64 CurveID = 0xFE00 + CurveID(pkg.ParamIDOWChCCA64)
+)
+
+// params returns the parameter set named by id
+func (id CurveID) params() (pkg.Parameters, error) {
+	if id < 0xFE00 || id > 0xFEFF {
+		return pkg.Parameters{}, fmt.Errorf("%w: unknown curve ID 0x%04x", pkg.ErrParameterValidation, uint16(id))
+	}
+	params, err := pkg.ParameterSetByID(uint16(id - 0xFE00))
+	if err != nil {
+		return pkg.Parameters{}, fmt.Errorf("%w: curve ID 0x%04x: %v", pkg.ErrParameterValidation, uint16(id), err)
+	}
+	return params, nil
+}
+
+// KeyShareSize returns the length of the key share sent by the client
+func (id CurveID) KeyShareSize() (int, error) {
+	params, err := id.params()
+	if err != nil {
+		return 0, err
+	}
+	return params.KeyParams.PublicKeySize, nil
+}
+
+// CiphertextSize returns the length of the ciphertext sent by the server
+func (id CurveID) CiphertextSize() (int, error) {
+	params, err := id.params()
+	if err != nil {
+		return 0, err
+	}
+	return params.CiphertextSize(), nil
+}
+
+// Option configures GenerateKeyShare
+type Option func(*options)
+
+type options struct {
+	allowToy bool
+}
+
+// WithAllowToyParameters lets GenerateKeyShare generate key shares for the code points of toy
+// parameter sets, which it refuses by default (see pkg.SecurityClassToy)
+func WithAllowToyParameters(allow bool) Option {
+	return func(o *options) {
+		o.allowToy = allow
+	}
+}
+
+// GenerateKeyShare generates a key pair. keyShare is the encoded public key to send to the peer;
+// privState is the self-contained private key encoding to keep until DecapsulateShare.
+// Toy parameter sets are refused with pkg.ErrToyParameters unless WithAllowToyParameters(true)
+// is given.
+func (id CurveID) GenerateKeyShare(rand io.Reader, opts ...Option) (privState, keyShare []byte, err error) {
+	params, err := id.params()
+	if err != nil {
+		return nil, nil, err
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	kem := pkg.OwChCCAKEM{Params: params}.WithAllowToyParameters(o.allowToy)
+	pk, sk, err := kem.GenerateKeyPair(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyShare, err = pk.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	privState, err = sk.MarshalWithHeader()
+	if err != nil {
+		return nil, nil, err
+	}
+	return privState, keyShare, nil
+}
+
+// EncapsulateShare encapsulates a fresh shared secret to the peer's key share
+func (id CurveID) EncapsulateShare(keyShare []byte, rand io.Reader) (ciphertext, sharedSecret []byte, err error) {
+	params, err := id.params()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(keyShare) != params.KeyParams.PublicKeySize {
+		return nil, nil, fmt.Errorf("%w: key share is %d bytes, expected %d", pkg.ErrInvalidPublicKey, len(keyShare), params.KeyParams.PublicKeySize)
+	}
+
+	pk := &pkg.PublicKey{Params: params}
+	if err := pk.UnmarshalBinaryStrict(keyShare); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", pkg.ErrInvalidPublicKey, err)
+	}
+	kem := pkg.OwChCCAKEM{Params: params}
+	return kem.EncapsulateFrom(pk, rand)
+}
+
+// DecapsulateShare recovers the shared secret from the peer's ciphertext using the state
+// returned by GenerateKeyShare
+func (id CurveID) DecapsulateShare(privState, ciphertext []byte) (sharedSecret []byte, err error) {
+	params, err := id.params()
+	if err != nil {
+		return nil, err
+	}
+	sk, err := pkg.ParsePrivateKeyWithHeader(privState)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", pkg.ErrInvalidPrivateKey, err)
+	}
+	if sk.Pk.Params.ID() != params.ID() {
+		return nil, fmt.Errorf("%w: private state is for parameter set %s, not %s", pkg.ErrInvalidPrivateKey, sk.Pk.Params.Name, params.Name)
+	}
+
+	kem := pkg.OwChCCAKEM{Params: params}
+	if err := kem.ValidateCiphertext(ciphertext); err != nil {
+		return nil, err
+	}
+	return kem.Decapsulate(sk, ciphertext)
+}
@@ -0,0 +1,80 @@
+package tlskem
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg"
+)
+
+func TestHandshakeSimulation(t *testing.T) {
+	curve := OWChCCA16
+
+	// Client: generate the key share and keep only the serialized state
+	privState, keyShare, err := curve.GenerateKeyShare(rand.Reader, WithAllowToyParameters(true))
+	if err != nil {
+		t.Fatalf("GenerateKeyShare failed: %v", err)
+	}
+	if size, _ := curve.KeyShareSize(); len(keyShare) != size {
+		t.Fatalf("key share is %d bytes, expected %d", len(keyShare), size)
+	}
+
+	// Server: encapsulate to the received key share
+	ciphertext, serverSecret, err := curve.EncapsulateShare(append([]byte(nil), keyShare...), rand.Reader)
+	if err != nil {
+		t.Fatalf("EncapsulateShare failed: %v", err)
+	}
+	if size, _ := curve.CiphertextSize(); len(ciphertext) != size {
+		t.Fatalf("ciphertext is %d bytes, expected %d", len(ciphertext), size)
+	}
+
+	// Client: decapsulate from the stored state
+	clientSecret, err := curve.DecapsulateShare(privState, append([]byte(nil), ciphertext...))
+	if err != nil {
+		t.Fatalf("DecapsulateShare failed: %v", err)
+	}
+	if !bytes.Equal(clientSecret, serverSecret) {
+		t.Fatalf("client and server derived different secrets")
+	}
+
+	t.Run("LengthMismatch", func(t *testing.T) {
+		if _, _, err := curve.EncapsulateShare(keyShare[:len(keyShare)-1], rand.Reader); !errors.Is(err, pkg.ErrInvalidPublicKey) {
+			t.Fatalf("short key share: got %v", err)
+		}
+		if _, _, err := curve.EncapsulateShare(append(keyShare, 0), rand.Reader); !errors.Is(err, pkg.ErrInvalidPublicKey) {
+			t.Fatalf("long key share: got %v", err)
+		}
+		if _, _, err := curve.EncapsulateShare(nil, rand.Reader); !errors.Is(err, pkg.ErrInvalidPublicKey) {
+			t.Fatalf("empty key share: got %v", err)
+		}
+		if _, err := curve.DecapsulateShare(privState, ciphertext[:len(ciphertext)-1]); !errors.Is(err, pkg.ErrInvalidCiphertext) {
+			t.Fatalf("short ciphertext: got %v", err)
+		}
+		if _, err := curve.DecapsulateShare(privState, nil); !errors.Is(err, pkg.ErrInvalidCiphertext) {
+			t.Fatalf("empty ciphertext: got %v", err)
+		}
+		if _, err := curve.DecapsulateShare(privState[:len(privState)-1], ciphertext); !errors.Is(err, pkg.ErrInvalidPrivateKey) {
+			t.Fatalf("truncated private state: got %v", err)
+		}
+		if _, err := curve.DecapsulateShare(nil, ciphertext); !errors.Is(err, pkg.ErrInvalidPrivateKey) {
+			t.Fatalf("empty private state: got %v", err)
+		}
+	})
+
+	t.Run("ToyParameters", func(t *testing.T) {
+		if _, _, err := curve.GenerateKeyShare(rand.Reader); !errors.Is(err, pkg.ErrToyParameters) {
+			t.Fatalf("toy curve without opt-in: got %v", err)
+		}
+	})
+
+	t.Run("WrongCurve", func(t *testing.T) {
+		if _, err := OWChCCA32.DecapsulateShare(privState, ciphertext); !errors.Is(err, pkg.ErrInvalidPrivateKey) {
+			t.Fatalf("private state for another curve: got %v", err)
+		}
+		if _, _, err := CurveID(0x001d).GenerateKeyShare(rand.Reader); !errors.Is(err, pkg.ErrParameterValidation) {
+			t.Fatalf("unknown curve ID: got %v", err)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MingLLuo/OW-ChCCA-KEM/pkg/sha3"
+)
+
+//go:generate go test -run TestVectors -update
+
+var updateVectors = flag.Bool("update", false, "regenerate testdata/vectors.json")
+
+const vectorsFile = "testdata/vectors.json"
+
+// vectorFile is the layout of testdata/vectors.json
+type vectorFile struct {
+	Description string       `json:"description"`
+	Vectors     []testVector `json:"vectors"`
+}
+
+// testVector pins the output of one deterministic key generation and encapsulation. Keys and
+// ciphertexts are recorded as the SHA3-256 digest of their encoding to keep the file small.
+type testVector struct {
+	Seed   string `json:"seed"`
+	Params string `json:"params"`
+	PK     string `json:"pk_sha3"`
+	SK     string `json:"sk_sha3"`
+	CT     string `json:"ct_sha3"`
+	SS     string `json:"ss"`
+}
+
+// vectorSeeds are the seeds of the recorded vectors, all under the default parameter set
+var vectorSeeds = []string{"00", "4f572d43684343412d4b454d", "ffffffffffffffffffffffffffffffff"}
+
+func digestHex(data []byte) string {
+	digest := sha3.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
+
+// computeVector runs key generation and encapsulation from SHAKE256(seed) under the named
+// parameter set and checks that decapsulation agrees
+func computeVector(t *testing.T, seedHex, paramsName string) testVector {
+	seed, err := hex.DecodeString(seedHex)
+	if err != nil {
+		t.Fatalf("seed %q: %v", seedHex, err)
+	}
+	params, err := GetParameterSet(paramsName)
+	if err != nil {
+		t.Fatalf("GetParameterSet(%s) failed: %v", paramsName, err)
+	}
+	kem := OwChCCAKEM{Params: params}.WithAllowToyParameters(true)
+
+	stream := sha3.NewShake256()
+	stream.Write(seed)
+	pk, sk, err := kem.GenerateKeyPair(&stream)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair failed: %v", err)
+	}
+	ct, ss, err := kem.EncapsulateFrom(pk, &stream)
+	if err != nil {
+		t.Fatalf("EncapsulateFrom failed: %v", err)
+	}
+	ss2, err := kem.Decapsulate(sk, ct)
+	if err != nil || !bytes.Equal(ss, ss2) {
+		t.Fatalf("Decapsulate does not recover the shared key: %v", err)
+	}
+
+	pkBytes, err := pk.Bytes()
+	if err != nil {
+		t.Fatalf("PublicKey.Bytes failed: %v", err)
+	}
+	skBytes, err := sk.Bytes()
+	if err != nil {
+		t.Fatalf("PrivateKey.Bytes failed: %v", err)
+	}
+	return testVector{
+		Seed:   seedHex,
+		Params: paramsName,
+		PK:     digestHex(pkBytes),
+		SK:     digestHex(skBytes),
+		CT:     digestHex(ct),
+		SS:     hex.EncodeToString(ss),
+	}
+}
+
+func TestVectors(t *testing.T) {
+	if *updateVectors {
+		file := vectorFile{
+			Description: "OW-ChCCA-KEM known-answer vectors. Key generation and then encapsulation read " +
+				"from SHAKE256(seed); pk_sha3, sk_sha3 and ct_sha3 are SHA3-256 digests of the " +
+				"encodings and ss is the shared key. Regenerate with go generate ./pkg.",
+		}
+		for _, seed := range vectorSeeds {
+			file.Vectors = append(file.Vectors, computeVector(t, seed, GetDefaultParameterSet().Name))
+		}
+		data, err := json.MarshalIndent(file, "", "  ")
+		if err != nil {
+			t.Fatalf("MarshalIndent failed: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(vectorsFile), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(vectorsFile, append(data, '\n'), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(vectorsFile)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	var file vectorFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(file.Vectors) == 0 {
+		t.Fatalf("%s holds no vectors", vectorsFile)
+	}
+
+	for i, want := range file.Vectors {
+		got := computeVector(t, want.Seed, want.Params)
+		for _, field := range []struct{ name, got, want string }{
+			{"pk_sha3", got.PK, want.PK},
+			{"sk_sha3", got.SK, want.SK},
+			{"ct_sha3", got.CT, want.CT},
+			{"ss", got.SS, want.SS},
+		} {
+			if field.got != field.want {
+				t.Errorf("vector %d (seed %s, %s): field %s: got %s, expected %s",
+					i, want.Seed, want.Params, field.name, field.got, field.want)
+			}
+		}
+	}
+}